@@ -0,0 +1,140 @@
+// Package auditsink declares the hook used to keep an audit trail of every decision taken by the SDK: who was
+// evaluated, for which feature, what they got, why and when. It's kept apart from impressions, which are meant
+// for analytics and may be deduplicated, sampled or dropped along the way.
+package auditsink
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// DroppedCounter is the counter incremented each time a decision can't be queued for the audit sink
+const DroppedCounter = "sdk.auditSink.dropped"
+
+// droppedWarningInterval is the minimum time between warnings about dropped decisions
+const droppedWarningInterval = time.Minute
+
+// Decision holds everything about the evaluation of a feature for a key. Label is the reason the treatment was
+// returned and MatchedConditionIndex the zero-based index of the condition that matched, -1 if none did
+type Decision struct {
+	Key                   string
+	BucketingKey          string
+	Feature               string
+	Treatment             string
+	Label                 string
+	MatchedConditionIndex int
+	ChangeNumber          int64
+	Time                  time.Time
+}
+
+// AuditSink declaration of AuditSink interface
+type AuditSink interface {
+	LogDecision(decision Decision)
+}
+
+// WrapperAuditSink dispatches decisions to an AuditSink from a background goroutine through a bounded queue,
+// so that a slow sink never stalls evaluations. When the queue is full, decisions are dropped for the sink
+// and the DroppedCounter is incremented
+type WrapperAuditSink struct {
+	dropped     int64 // accessed atomically, kept first for 64-bit alignment
+	lastWarning int64
+	AuditSink   AuditSink
+	queue       chan Decision
+	metrics     storage.MetricsStorageProducer
+	logger      logging.LoggerInterface
+	stopped     bool
+	mutex       *sync.RWMutex
+}
+
+// NewAuditSinkWrapper instantiates a new WrapperAuditSink queueing up to queueSize decisions
+func NewAuditSinkWrapper(
+	auditSink AuditSink,
+	queueSize int,
+	metrics storage.MetricsStorageProducer,
+	logger logging.LoggerInterface,
+) *WrapperAuditSink {
+	wrapper := &WrapperAuditSink{
+		AuditSink: auditSink,
+		queue:     make(chan Decision, queueSize),
+		metrics:   metrics,
+		logger:    logger,
+		mutex:     &sync.RWMutex{},
+	}
+	go wrapper.dispatch()
+	return wrapper
+}
+
+// dispatch forwards queued decisions to the sink until the queue is closed
+func (a *WrapperAuditSink) dispatch() {
+	for decision := range a.queue {
+		a.logDecision(decision)
+	}
+}
+
+// logDecision forwards a decision to the sink, recovering if it panics so that later decisions are still dispatched
+func (a *WrapperAuditSink) logDecision(decision Decision) {
+	defer func() {
+		if r := recover(); r != nil && a.logger != nil {
+			a.logger.Error(
+				fmt.Sprintf("Audit sink is panicking, dropping decision for feature %s:", decision.Feature), r, "\n",
+				string(debug.Stack()),
+			)
+		}
+	}()
+	a.AuditSink.LogDecision(decision)
+}
+
+// Audit queues a decision for the sink without blocking
+func (a *WrapperAuditSink) Audit(decision Decision) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.stopped {
+		return
+	}
+
+	select {
+	case a.queue <- decision:
+	default:
+		a.drop(decision)
+	}
+}
+
+// drop accounts for a decision that didn't fit in the queue. The warning is throttled so that a stalled sink
+// doesn't flood the logs on every evaluation
+func (a *WrapperAuditSink) drop(decision Decision) {
+	if a.metrics != nil {
+		a.metrics.IncCounter(DroppedCounter)
+	}
+	atomic.AddInt64(&a.dropped, 1)
+	if a.logger == nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&a.lastWarning)
+	if now-last < int64(droppedWarningInterval) || !atomic.CompareAndSwapInt64(&a.lastWarning, last, now) {
+		return
+	}
+	a.logger.Warning(fmt.Sprintf(
+		"Audit sink queue is full, %d decisions dropped since the last warning, the latest for feature %s",
+		atomic.SwapInt64(&a.dropped, 0),
+		decision.Feature,
+	))
+}
+
+// Stop stops accepting decisions for the sink. Decisions already queued are still dispatched
+func (a *WrapperAuditSink) Stop() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.stopped {
+		return
+	}
+	a.stopped = true
+	close(a.queue)
+}
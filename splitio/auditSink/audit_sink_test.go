@@ -0,0 +1,118 @@
+package auditsink
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type blockingSink struct {
+	release   chan struct{}
+	decisions chan Decision
+}
+
+func (s *blockingSink) LogDecision(decision Decision) {
+	<-s.release
+	s.decisions <- decision
+}
+
+func TestAuditSinkWrapperDropsWhenFull(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{}), decisions: make(chan Decision, 10)}
+	metrics := mutexmap.NewMMMetricsStorage()
+	wrapper := NewAuditSinkWrapper(sink, 2, metrics, logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone}))
+
+	// The first decision is taken by the dispatcher, which blocks on the sink, and the next two fill the queue
+	wrapper.Audit(Decision{Feature: "feature1"})
+	time.Sleep(50 * time.Millisecond)
+	before := time.Now()
+	for _, feature := range []string{"feature2", "feature3", "feature4"} {
+		wrapper.Audit(Decision{Feature: feature})
+	}
+	if time.Since(before) > 100*time.Millisecond {
+		t.Error("Auditing should never block on the sink")
+	}
+	if dropped := metrics.PopCounters(); len(dropped) != 1 || dropped[0].Count != 1 {
+		t.Error("The decision that didn't fit in the queue should be counted as dropped", dropped)
+	}
+
+	close(sink.release)
+	wrapper.Stop()
+	wrapper.Audit(Decision{Feature: "feature5"})
+	for _, expected := range []string{"feature1", "feature2", "feature3"} {
+		select {
+		case decision := <-sink.decisions:
+			if decision.Feature != expected {
+				t.Error("Decisions should be dispatched in order", expected, decision)
+			}
+		case <-time.After(time.Second):
+			t.Error("Queued decisions should be dispatched even after stopping", expected)
+		}
+	}
+	select {
+	case decision := <-sink.decisions:
+		t.Error("No decisions should be accepted after stopping", decision)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAuditSinkWrapperThrottlesDropWarnings(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{}), decisions: make(chan Decision, 10)}
+	defer close(sink.release)
+	warnings := &bytes.Buffer{}
+	metrics := mutexmap.NewMMMetricsStorage()
+	wrapper := NewAuditSinkWrapper(sink, 1, metrics, logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: warnings}))
+
+	// The first decision is taken by the dispatcher, which blocks on the sink, and the next one fills the queue
+	wrapper.Audit(Decision{Feature: "feature1"})
+	time.Sleep(50 * time.Millisecond)
+	wrapper.Audit(Decision{Feature: "feature2"})
+	for i := 0; i < 5; i++ {
+		wrapper.Audit(Decision{Feature: "dropped"})
+	}
+
+	if dropped := metrics.PopCounters(); len(dropped) != 1 || dropped[0].Count != 5 {
+		t.Error("Every dropped decision should be counted", dropped)
+	}
+	if lines := strings.Count(warnings.String(), "\n"); lines != 1 {
+		t.Error("A single warning should be logged per interval. Got:", warnings.String())
+	}
+
+	atomic.StoreInt64(&wrapper.lastWarning, 0)
+	wrapper.Audit(Decision{Feature: "dropped"})
+	if !strings.Contains(warnings.String(), "5 decisions dropped since the last warning") {
+		t.Error("The warning should report the decisions dropped since the previous one. Got:", warnings.String())
+	}
+}
+
+type panickingSink struct {
+	decisions chan Decision
+}
+
+func (s *panickingSink) LogDecision(decision Decision) {
+	if decision.Feature == "panic" {
+		panic("something went wrong")
+	}
+	s.decisions <- decision
+}
+
+func TestAuditSinkWrapperRecoversFromPanics(t *testing.T) {
+	sink := &panickingSink{decisions: make(chan Decision, 10)}
+	wrapper := NewAuditSinkWrapper(sink, 10, nil, logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone}))
+	defer wrapper.Stop()
+
+	wrapper.Audit(Decision{Feature: "panic"})
+	wrapper.Audit(Decision{Feature: "feature1"})
+	select {
+	case decision := <-sink.decisions:
+		if decision.Feature != "feature1" {
+			t.Error("Unexpected decision", decision)
+		}
+	case <-time.After(time.Second):
+		t.Error("Decisions should still be dispatched after the sink panics")
+	}
+}
@@ -1,16 +1,28 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"runtime/debug"
 	"time"
 
+	auditsink "github.com/splitio/go-client/splitio/auditSink"
+	"github.com/splitio/go-client/splitio/engine"
 	"github.com/splitio/go-client/splitio/engine/evaluator"
 	"github.com/splitio/go-client/splitio/engine/evaluator/impressionlabels"
+	impressionenricher "github.com/splitio/go-client/splitio/impressionEnricher"
 	impressionlistener "github.com/splitio/go-client/splitio/impressionListener"
+	metricssink "github.com/splitio/go-client/splitio/metricsSink"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexqueue"
+	"github.com/splitio/go-client/splitio/storage/redisdb"
+	"github.com/splitio/go-client/splitio/util/diagnostics"
+	impressionsutil "github.com/splitio/go-client/splitio/util/impressions"
 	"github.com/splitio/go-client/splitio/util/metrics"
+	"github.com/splitio/go-client/splitio/util/ratelimit"
 	"github.com/splitio/go-toolkit/logging"
 )
 
@@ -18,20 +30,132 @@ import (
 type SplitClient struct {
 	logger             logging.LoggerInterface
 	evaluator          evaluator.Interface
+	splitStorage       storage.SplitStorageConsumer
+	segmentStorage     storage.SegmentStorageConsumer
+	splitsLoaded       *splitsLoadedCheck
 	impressions        storage.ImpressionStorageProducer
 	metrics            storage.MetricsStorageProducer
 	events             storage.EventStorageProducer
 	validator          inputValidation
 	factory            *SplitFactory
 	impressionListener *impressionlistener.WrapperImpressionListener
+	impressionEnricher *impressionenricher.WrapperImpressionEnricher
+	auditSink          *auditsink.WrapperAuditSink
+	impressionDeduper  *impressionsutil.ImpressionDeduper
+	snapshotEvaluator  evaluator.Interface
+	trackLimiter       *ratelimit.TokenBucket
+	metricsSink        metricssink.MetricsSink
+	evaluationSlots    *ratelimit.Semaphore
+	evaluationTimeout  time.Duration
+	now                func() time.Time
 }
 
+// TypeMismatchCounter is incremented each time an evaluation returns CONTROL due to an attribute type mismatch
+const TypeMismatchCounter = "sdk.exception.typeMismatch"
+
+// TrackRateLimitedCounter is incremented each time an event is dropped because Track exceeded the configured rate
+const TrackRateLimitedCounter = "sdk.track.rateLimited"
+
+// TrackQueueFullCounter is incremented each time an event is dropped because the events queue is full
+const TrackQueueFullCounter = "sdk.track.queueFull"
+
+// OverloadedCounter is incremented each time an evaluation returns CONTROL because it couldn't get a slot in time
+const OverloadedCounter = "sdk.evaluation.overloaded"
+
+// UnknownFeatureCounter is incremented each time a feature that isn't in storage is evaluated once the SDK is ready,
+// if StrictFeatureNames is enabled
+const UnknownFeatureCounter = "sdk.getTreatment.unknownFeature"
+
+// UnknownDataAge is the age reported by TreatmentWithFreshness when the split storage can't tell when it was last
+// synchronized, or it never was
+const UnknownDataAge time.Duration = -1
+
+// SegmentMissing is the size reported by SegmentStats for segments referenced by splits but not present in storage
+const SegmentMissing int64 = -1
+
 // TreatmentResult struct that includes the Treatment evaluation with the corresponding Config
 type TreatmentResult struct {
 	Treatment string  `json:"treatment"`
 	Config    *string `json:"config"`
 }
 
+// FreshnessResult struct that includes the Treatment evaluation with its Config along with the time elapsed since the
+// split data it was computed from was last synchronized
+type FreshnessResult struct {
+	TreatmentResult
+	DataAge time.Duration `json:"dataAge"`
+}
+
+// DecisionResult struct that includes the Treatment evaluation with its Config along with details of how it was decided
+type DecisionResult struct {
+	TreatmentResult
+	Label                 string `json:"label"`
+	ChangeNumber          int64  `json:"changeNumber"`
+	MatchedConditionIndex int    `json:"matchedConditionIndex"`
+}
+
+// acquireEvaluationSlot waits for a slot to run an evaluation if concurrent evaluations are limited.
+// Returns false if none could be taken in time, in which case releaseEvaluationSlot must not be called
+func (c *SplitClient) acquireEvaluationSlot() bool {
+	return c.evaluationSlots == nil || c.evaluationSlots.Acquire(c.evaluationTimeout)
+}
+
+func (c *SplitClient) releaseEvaluationSlot() {
+	if c.evaluationSlots != nil {
+		c.evaluationSlots.Release()
+	}
+}
+
+func overloadedResult() *evaluator.Result {
+	return &evaluator.Result{
+		Treatment:             evaluator.Control,
+		Label:                 impressionlabels.Overloaded,
+		Config:                nil,
+		MatchedConditionIndex: engine.NoConditionIndex,
+	}
+}
+
+// splits returns the split storage the client evaluates features against, which is the factory's one unless the
+// client was returned by TenantClient
+func (c *SplitClient) splits() storage.SplitStorageConsumer {
+	if c.splitStorage != nil {
+		return c.splitStorage
+	}
+	return c.factory.storages.splits
+}
+
+// segments returns the segment storage the client evaluates features against, which is the factory's one unless
+// the client was returned by TenantClient
+func (c *SplitClient) segments() storage.SegmentStorageConsumer {
+	if c.segmentStorage != nil {
+		return c.segmentStorage
+	}
+	return c.factory.storages.segments
+}
+
+// checkSplitsLoaded turns the label of features not found into NoSplitsLoaded if the split storage is empty,
+// warning once per factory. The storage is only inspected when a feature isn't found, at most once per second
+func (c *SplitClient) checkSplitsLoaded(label string) string {
+	splits := c.splits()
+	if label != impressionlabels.SplitNotFound || splits == nil {
+		return label
+	}
+	loaded := c.splitsLoaded
+	if loaded == nil {
+		loaded = &c.factory.splitsLoaded
+	}
+	if loaded.hasSplits(splits) {
+		return label
+	}
+	c.factory.noSplitsWarning.Do(func() {
+		c.logger.Warning(
+			"No splits are loaded even though the SDK is ready, every evaluation will return CONTROL. " +
+				"Please check that the SDK is connected to the right environment, or redis prefix, and that it has splits",
+		)
+	})
+	return impressionlabels.NoSplitsLoaded
+}
+
 // getEvaluationResult calls evaluation for one particular split
 func (c *SplitClient) getEvaluationResult(
 	matchingKey string,
@@ -41,13 +165,28 @@ func (c *SplitClient) getEvaluationResult(
 	operation string,
 ) *evaluator.Result {
 	if c.isReady() {
-		return c.evaluator.EvaluateFeature(matchingKey, bucketingKey, feature, attributes)
+		if !c.acquireEvaluationSlot() {
+			c.countOverloaded(operation)
+			return overloadedResult()
+		}
+		defer c.releaseEvaluationSlot()
+		result := c.evaluator.EvaluateFeature(matchingKey, bucketingKey, feature, attributes)
+		result.Label = c.checkSplitsLoaded(result.Label)
+		return result
 	}
 	c.logger.Warning(operation + ": the SDK is not ready, results may be incorrect. Make sure to wait for SDK readiness before using this method")
+	if c.snapshotEvaluator != nil {
+		result := c.snapshotEvaluator.EvaluateFeature(matchingKey, bucketingKey, feature, attributes)
+		if result.Label != impressionlabels.SplitNotFound {
+			result.Label = impressionlabels.NotReadyFromSnapshot
+			return result
+		}
+	}
 	return &evaluator.Result{
-		Treatment: evaluator.Control,
-		Label:     impressionlabels.ClientNotReady,
-		Config:    nil,
+		Treatment:             evaluator.Control,
+		Label:                 impressionlabels.ClientNotReady,
+		Config:                nil,
+		MatchedConditionIndex: engine.NoConditionIndex,
 	}
 }
 
@@ -60,18 +199,43 @@ func (c *SplitClient) getEvaluationsResult(
 	operation string,
 ) evaluator.Results {
 	if c.isReady() {
-		return c.evaluator.EvaluateFeatures(matchingKey, bucketingKey, features, attributes)
+		if !c.acquireEvaluationSlot() {
+			c.countOverloaded(operation)
+			result := evaluator.Results{Evaluations: make(map[string]evaluator.Result, len(features))}
+			for _, feature := range features {
+				result.Evaluations[feature] = *overloadedResult()
+			}
+			return result
+		}
+		defer c.releaseEvaluationSlot()
+		result := c.evaluator.EvaluateFeatures(matchingKey, bucketingKey, features, attributes)
+		for feature, evaluation := range result.Evaluations {
+			if label := c.checkSplitsLoaded(evaluation.Label); label != evaluation.Label {
+				evaluation.Label = label
+				result.Evaluations[feature] = evaluation
+			}
+		}
+		return result
 	}
 	c.logger.Warning(operation + ": the SDK is not ready, results may be incorrect. Make sure to wait for SDK readiness before using this method")
 	result := evaluator.Results{
 		EvaluationTimeNs: 0,
 		Evaluations:      make(map[string]evaluator.Result),
 	}
+	if c.snapshotEvaluator != nil {
+		result = c.snapshotEvaluator.EvaluateFeatures(matchingKey, bucketingKey, features, attributes)
+	}
 	for _, feature := range features {
+		if evaluation, ok := result.Evaluations[feature]; ok && evaluation.Label != impressionlabels.SplitNotFound {
+			evaluation.Label = impressionlabels.NotReadyFromSnapshot
+			result.Evaluations[feature] = evaluation
+			continue
+		}
 		result.Evaluations[feature] = evaluator.Result{
-			Treatment: evaluator.Control,
-			Label:     impressionlabels.ClientNotReady,
-			Config:    nil,
+			Treatment:             evaluator.Control,
+			Label:                 impressionlabels.ClientNotReady,
+			Config:                nil,
+			MatchedConditionIndex: engine.NoConditionIndex,
 		}
 	}
 	return result
@@ -107,15 +271,102 @@ func (c *SplitClient) createImpression(
 	}
 }
 
+// incCounter increments a counter if there's a metrics storage set in the client
+func (c *SplitClient) incCounter(counter string) {
+	if c.metrics != nil {
+		c.metrics.IncCounter(counter)
+	}
+}
+
+// countOverloaded reports an evaluation that couldn't get a slot in time. The warning is throttled across the
+// clients of the factory, while the OverloadedCounter & the Overloaded label account for every evaluation
+func (c *SplitClient) countOverloaded(operation string) {
+	c.incCounter(OverloadedCounter)
+	if c.factory.shouldWarnOverloaded() {
+		c.logger.Warning(operation + ": too many concurrent evaluations, returning CONTROL. Further occurrences are counted as " + OverloadedCounter)
+	}
+}
+
+// countUnknownFeature reports the evaluation of a feature missing from storage if StrictFeatureNames is enabled.
+// Features evaluated before the SDK is ready are not reported, since none of them are known yet
+func (c *SplitClient) countUnknownFeature(feature string, operation string) {
+	if !c.factory.cfg.Advanced.StrictFeatureNames || !c.isReady() {
+		return
+	}
+	c.logger.Warning(fmt.Sprintf(
+		"%s: unknown feature %s was evaluated after the SDK was ready, please check it for typos.", operation, feature,
+	))
+	c.incCounter(UnknownFeatureCounter)
+}
+
+// countTypeMismatch increments the exception counter if the evaluation failed due to an attribute type mismatch
+func (c *SplitClient) countTypeMismatch(label string) {
+	if label == impressionlabels.TypeMismatch {
+		c.incCounter(TypeMismatchCounter)
+	}
+}
+
+// enrichImpressions sets the properties computed by the impression enricher, if any, once per key
+func (c *SplitClient) enrichImpressions(impressions []storage.Impression, attributes map[string]interface{}) {
+	if c.impressionEnricher == nil {
+		return
+	}
+
+	propertiesByKey := make(map[string]string)
+	for index := range impressions {
+		key := impressions[index].KeyName
+		properties, ok := propertiesByKey[key]
+		if !ok {
+			if enriched := c.impressionEnricher.Properties(key, attributes); len(enriched) > 0 {
+				raw, err := json.Marshal(enriched)
+				if err != nil {
+					c.logger.Warning("Could not serialize the impression properties of key ", key, ": ", err.Error())
+				} else {
+					properties = string(raw)
+				}
+			}
+			propertiesByKey[key] = properties
+		}
+		impressions[index].Properties = properties
+	}
+}
+
+// audit sends the decision taken by an evaluation to the audit sink, if any
+func (c *SplitClient) audit(matchingKey string, bucketingKey *string, feature string, result *evaluator.Result) {
+	if c.auditSink == nil {
+		return
+	}
+	decision := auditsink.Decision{
+		Key:                   matchingKey,
+		Feature:               feature,
+		Treatment:             result.Treatment,
+		Label:                 result.Label,
+		MatchedConditionIndex: result.MatchedConditionIndex,
+		ChangeNumber:          result.SplitChangeNumber,
+		Time:                  time.Now(),
+	}
+	if bucketingKey != nil {
+		decision.BucketingKey = *bucketingKey
+	}
+	c.auditSink.Audit(decision)
+}
+
 // storeData stores impression, runs listener and stores metrics
 func (c *SplitClient) storeData(impressions []storage.Impression, attributes map[string]interface{}, metricsLabel string, evaluationTimeNs int64) {
 	// Store impression
 	if c.impressions != nil {
-		c.impressions.LogImpressions(impressions)
+		stored := impressions
+		if c.impressionDeduper != nil {
+			stored = c.impressionDeduper.Process(impressions)
+		}
+		if len(stored) > 0 {
+			c.enrichImpressions(stored, attributes)
+			c.impressions.LogImpressions(stored)
 
-		// Custom Impression Listener
-		if c.impressionListener != nil {
-			c.impressionListener.SendDataToClient(impressions, attributes)
+			// Custom Impression Listener
+			if c.impressionListener != nil {
+				c.impressionListener.SendDataToClient(stored, attributes)
+			}
 		}
 	} else {
 		c.logger.Warning("No impression storage set in client. Not sending impressions!")
@@ -128,31 +379,144 @@ func (c *SplitClient) storeData(impressions []storage.Impression, attributes map
 	} else {
 		c.logger.Warning("No metrics storage set in client. Not sending latencies!")
 	}
+
+	// Custom Metrics Sink
+	if c.metricsSink != nil {
+		c.metricsSink.ObserveLatency(metricsLabel, time.Duration(evaluationTimeNs))
+	}
+}
+
+// storeControlImpressions stores a CONTROL impression labeled with label for each of the features evaluated for
+// matchingKey, if ControlImpressions is enabled. It's called from the panic guards too, so a panic while storing
+// the impressions is ignored instead of propagated
+func (c *SplitClient) storeControlImpressions(
+	matchingKey string,
+	bucketingKey *string,
+	features []string,
+	label string,
+	attributes map[string]interface{},
+	metricsLabel string,
+) {
+	if len(features) == 0 || !c.factory.cfg.Advanced.ControlImpressions {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error("Control impressions couldn't be stored: ", r)
+		}
+	}()
+	impressions := make([]storage.Impression, 0, len(features))
+	for _, feature := range features {
+		impressions = append(impressions, c.createImpression(feature, bucketingKey, label, matchingKey, evaluator.Control, 0))
+	}
+	c.storeData(impressions, attributes, metricsLabel, 0)
+}
+
+// countTreatment reports a treatment returned by an evaluation to the metrics sink, if any. Treatments are counted
+// apart from impressions, which aren't stored for every evaluation
+func (c *SplitClient) countTreatment(feature string, treatment string) {
+	if c.metricsSink != nil {
+		c.metricsSink.IncTreatment(feature, treatment)
+	}
+}
+
+// recordException counts an unexpected failure of an operation in the metrics storage & sink, if they support it
+func (c *SplitClient) recordException(operation string) {
+	if exceptions, ok := c.metrics.(storage.MetricsExceptionProducer); ok {
+		exceptions.IncException(operation)
+	}
+	if c.metricsSink != nil {
+		c.metricsSink.IncException(operation)
+	}
+}
+
+// withContext returns a copy of the client whose evaluations read splits & segments from redis through ctx, so that
+// they're abandoned when it's cancelled. The client itself is returned if its storages can't be bound to a context
+func (c *SplitClient) withContext(ctx context.Context) *SplitClient {
+	rebindable, ok := c.evaluator.(evaluator.Rebindable)
+	if !ok || ctx == context.Background() {
+		return c
+	}
+
+	splits, segments := rebindable.SplitStorage(), rebindable.SegmentStorage()
+	bound := false
+	if redisSplits, ok := splits.(*redisdb.RedisSplitStorage); ok {
+		splits, bound = redisSplits.WithContext(ctx), true
+	}
+	if binder, ok := segments.(storage.SegmentStorageContextBinder); ok {
+		segments, bound = binder.WithContext(ctx), true
+	}
+	if !bound {
+		return c
+	}
+
+	client := *c
+	client.evaluator = rebindable.Rebind(splits, segments)
+	return &client
+}
+
+// baseEvaluator returns the Evaluator the client's evaluator wraps, if any, or nil otherwise. It's meant for
+// evaluations that must skip wrappers such as the evaluation cache because they read other splits or segments
+func (c *SplitClient) baseEvaluator() *evaluator.Evaluator {
+	current := c.evaluator
+	for {
+		if base, ok := current.(*evaluator.Evaluator); ok {
+			return base
+		}
+		wrapper, ok := current.(evaluator.Wrapper)
+		if !ok {
+			return nil
+		}
+		current = wrapper.Unwrap()
+	}
+}
+
+// evaluationCancelled returns true, logging it, if ctx was cancelled or its deadline exceeded, in which case CONTROL
+// must be returned
+func (c *SplitClient) evaluationCancelled(ctx context.Context, operation string) bool {
+	err := ctx.Err()
+	if err == nil {
+		return false
+	}
+	c.logger.Warning(fmt.Sprintf("%s: the evaluation was cancelled: %s. Returning CONTROL", operation, err.Error()))
+	return true
 }
 
 // doTreatmentCall retrieves treatments of an specific feature with configurations object if it is present
 // for a certain key and set of attributes
 func (c *SplitClient) doTreatmentCall(
+	ctx context.Context,
 	key interface{},
 	feature string,
 	attributes map[string]interface{},
 	operation string,
 	metricsLabel string,
-) (t TreatmentResult) {
-	controlTreatment := TreatmentResult{
-		Treatment: evaluator.Control,
-		Config:    nil,
+) (t DecisionResult) {
+	controlTreatment := DecisionResult{
+		TreatmentResult: TreatmentResult{
+			Treatment: evaluator.Control,
+			Config:    nil,
+		},
+		MatchedConditionIndex: engine.NoConditionIndex,
 	}
+	var matchingKey string
+	var bucketingKey *string
+	validated := false
 
 	// Set up a guard deferred function to recover if the SDK starts panicking
 	defer func() {
 		if r := recover(); r != nil {
+			c.recordException(metricsLabel)
 			// At this point we'll only trust that the logger isn't panicking trust
 			// that the logger isn't panicking
 			c.logger.Error(
 				"SDK is panicking with the following error", r, "\n",
 				string(debug.Stack()), "\n",
 				"Returning CONTROL", "\n")
+			if validated {
+				c.storeControlImpressions(matchingKey, bucketingKey, []string{feature}, impressionlabels.Exception, attributes, metricsLabel)
+				controlTreatment.Label = impressionlabels.Exception
+			}
 			t = controlTreatment
 		}
 	}()
@@ -164,45 +528,126 @@ func (c *SplitClient) doTreatmentCall(
 
 	matchingKey, bucketingKey, err := c.validator.ValidateTreatmentKey(key, operation)
 	if err != nil {
-		c.logger.Error(err.Error())
+		// The feature is included so that the offending call can be found
+		c.logger.Error(fmt.Sprintf("%s. Feature: '%s'. Returning CONTROL", err.Error(), feature))
 		return controlTreatment
 	}
 
 	feature, err = c.validator.ValidateFeatureName(feature, operation)
 	if err != nil {
-		c.logger.Error(err.Error())
+		c.validator.logError(err)
+		return controlTreatment
+	}
+	validated = true
+	attributes = c.validator.ValidateAttributes(attributes, operation)
+	if c.evaluationCancelled(ctx, operation) {
+		c.storeControlImpressions(matchingKey, bucketingKey, []string{feature}, impressionlabels.Cancelled, attributes, metricsLabel)
+		controlTreatment.Label = impressionlabels.Cancelled
 		return controlTreatment
 	}
 
-	evaluationResult := c.getEvaluationResult(matchingKey, bucketingKey, feature, attributes, operation)
+	evaluationResult := c.withContext(ctx).getEvaluationResult(matchingKey, bucketingKey, feature, attributes, operation)
+	if c.evaluationCancelled(ctx, operation) {
+		c.storeControlImpressions(matchingKey, bucketingKey, []string{feature}, impressionlabels.Cancelled, attributes, metricsLabel)
+		controlTreatment.Label = impressionlabels.Cancelled
+		return controlTreatment
+	}
+	c.countTypeMismatch(evaluationResult.Label)
+	c.audit(matchingKey, bucketingKey, feature, evaluationResult)
 
 	if !c.validator.IsSplitFound(evaluationResult.Label, feature, operation) {
+		c.countUnknownFeature(feature, operation)
+		c.countTreatment(feature, evaluator.Control)
+		if c.factory.cfg.Advanced.ControlImpressions {
+			c.storeData(
+				[]storage.Impression{c.createImpression(feature, bucketingKey, evaluationResult.Label, matchingKey, evaluator.Control, 0)},
+				attributes,
+				metricsLabel,
+				evaluationResult.EvaluationTimeNs,
+			)
+		}
+		controlTreatment.Label = evaluationResult.Label
 		return controlTreatment
 	}
 
-	c.storeData(
-		[]storage.Impression{c.createImpression(feature, bucketingKey, evaluationResult.Label, matchingKey, evaluationResult.Treatment, evaluationResult.SplitChangeNumber)},
-		attributes,
-		metricsLabel,
-		evaluationResult.EvaluationTimeNs,
-	)
+	// The latency is recorded even if the split doesn't track impressions
+	c.countTreatment(feature, evaluationResult.Treatment)
+	var impressions []storage.Impression
+	if !evaluationResult.ImpressionsDisabled {
+		impressions = []storage.Impression{c.createImpression(feature, bucketingKey, evaluationResult.Label, matchingKey, evaluationResult.Treatment, evaluationResult.SplitChangeNumber)}
+	}
+	c.storeData(impressions, attributes, metricsLabel, evaluationResult.EvaluationTimeNs)
 
-	return TreatmentResult{
-		Treatment: evaluationResult.Treatment,
-		Config:    evaluationResult.Config,
+	return DecisionResult{
+		TreatmentResult: TreatmentResult{
+			Treatment: evaluationResult.Treatment,
+			Config:    evaluationResult.Config,
+		},
+		Label:                 evaluationResult.Label,
+		ChangeNumber:          evaluationResult.SplitChangeNumber,
+		MatchedConditionIndex: evaluationResult.MatchedConditionIndex,
 	}
 }
 
 // Treatment implements the main functionality of split. Retrieve treatments of a specific feature
 // for a certain key and set of attributes
 func (c *SplitClient) Treatment(key interface{}, feature string, attributes map[string]interface{}) string {
-	return c.doTreatmentCall(key, feature, attributes, "Treatment", "sdk.getTreatment").Treatment
+	return c.TreatmentCtx(context.Background(), key, feature, attributes)
+}
+
+// TreatmentCtx is Treatment bound to ctx: redis reads are abandoned and CONTROL is returned if it's cancelled
+func (c *SplitClient) TreatmentCtx(ctx context.Context, key interface{}, feature string, attributes map[string]interface{}) string {
+	return c.doTreatmentCall(ctx, key, feature, attributes, "Treatment", "sdk.getTreatment").Treatment
 }
 
 // TreatmentWithConfig implements the main functionality of split. Retrieves the treatment of a specific feature with
 // the corresponding configuration if it is present
 func (c *SplitClient) TreatmentWithConfig(key interface{}, feature string, attributes map[string]interface{}) TreatmentResult {
-	return c.doTreatmentCall(key, feature, attributes, "TreatmentWithConfig", "sdk.getTreatmentWithConfig")
+	return c.TreatmentWithConfigCtx(context.Background(), key, feature, attributes)
+}
+
+// TreatmentWithConfigCtx is TreatmentWithConfig bound to ctx: redis reads are abandoned and CONTROL is returned if
+// it's cancelled
+func (c *SplitClient) TreatmentWithConfigCtx(ctx context.Context, key interface{}, feature string, attributes map[string]interface{}) TreatmentResult {
+	return c.doTreatmentCall(ctx, key, feature, attributes, "TreatmentWithConfig", "sdk.getTreatmentWithConfig").TreatmentResult
+}
+
+// TreatmentWithDecision retrieves the treatment of a specific feature along with its configuration, the label,
+// the changeNumber and the zero-based index of the condition that matched (-1 if the treatment didn't come from a condition)
+func (c *SplitClient) TreatmentWithDecision(key interface{}, feature string, attributes map[string]interface{}) DecisionResult {
+	return c.doTreatmentCall(context.Background(), key, feature, attributes, "TreatmentWithDecision", "sdk.getTreatmentWithDecision")
+}
+
+// TreatmentWithLabel retrieves the treatment of a specific feature along with the label and changeNumber
+// recorded in its impression
+func (c *SplitClient) TreatmentWithLabel(key interface{}, feature string, attributes map[string]interface{}) (string, string, int64) {
+	decision := c.doTreatmentCall(context.Background(), key, feature, attributes, "TreatmentWithLabel", "sdk.getTreatmentWithLabel")
+	return decision.Treatment, decision.Label, decision.ChangeNumber
+}
+
+// splitDataAge returns the time elapsed since splits were last synchronized, or UnknownDataAge
+func (c *SplitClient) splitDataAge() time.Duration {
+	freshness, ok := c.splits().(storage.SplitStorageFreshness)
+	if !ok {
+		return UnknownDataAge
+	}
+	lastUpdated := freshness.LastUpdated()
+	if lastUpdated.IsZero() {
+		return UnknownDataAge
+	}
+
+	now := time.Now()
+	if c.now != nil {
+		now = c.now()
+	}
+	return now.Sub(lastUpdated)
+}
+
+// TreatmentWithFreshness retrieves the treatment of a specific feature with its configuration along with the age of
+// the split data it was computed from, so that callers can degrade or alert if synchronization has been failing
+func (c *SplitClient) TreatmentWithFreshness(key interface{}, feature string, attributes map[string]interface{}) FreshnessResult {
+	result := c.doTreatmentCall(context.Background(), key, feature, attributes, "TreatmentWithFreshness", "sdk.getTreatmentWithFreshness")
+	return FreshnessResult{TreatmentResult: result.TreatmentResult, DataAge: c.splitDataAge()}
 }
 
 // Generates control treatments
@@ -221,9 +666,19 @@ func (c *SplitClient) generateControlTreatments(features []string, operation str
 	return treatments
 }
 
+// sanitizeFeatureNames runs the given feature names through the regular validation with logging suppressed,
+// so a fallback result is keyed exactly like a regular one would have been
+func (c *SplitClient) sanitizeFeatureNames(features []string, operation string) []string {
+	silent := c.validator
+	silent.logger = logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	sanitized, _ := silent.ValidateFeatureNames(features, operation)
+	return sanitized
+}
+
 // doTreatmentsCall retrieves treatments of an specific array of features with configurations object if it is present
 // for a certain key and set of attributes
 func (c *SplitClient) doTreatmentsCall(
+	ctx context.Context,
 	key interface{},
 	features []string,
 	attributes map[string]interface{},
@@ -231,15 +686,40 @@ func (c *SplitClient) doTreatmentsCall(
 	metricsLabel string,
 ) (t map[string]TreatmentResult) {
 	treatments := make(map[string]TreatmentResult)
+	var filteredFeatures []string
+	var matchingKey string
+	var bucketingKey *string
 
 	// Set up a guard deferred function to recover if the SDK starts panicking
 	defer func() {
 		if r := recover(); r != nil {
+			c.recordException(metricsLabel)
+			// Only hand back names that went through validation, so the fallback
+			// map is keyed the same way as a regular result would have been
+			recovered := filteredFeatures
+			if recovered == nil {
+				recovered = c.sanitizeFeatureNames(features, operation)
+			}
 			// At this point we'll only trust that the logger isn't panicking trust
 			// that the logger isn't panicking
 			c.logger.Error(
 				"SDK is panicking with the following error", r, "\n",
-				string(debug.Stack()), "\n")
+				string(debug.Stack()), "\n",
+				"Returning CONTROL for the features without a result", recovered, "\n")
+			var missing []string
+			for _, feature := range recovered {
+				if _, ok := treatments[feature]; !ok {
+					missing = append(missing, feature)
+					treatments[feature] = TreatmentResult{
+						Treatment: evaluator.Control,
+						Config:    nil,
+					}
+				}
+			}
+			// Names are only validated once the key is, so there's a key to attribute the impressions to
+			if filteredFeatures != nil {
+				c.storeControlImpressions(matchingKey, bucketingKey, missing, impressionlabels.Exception, attributes, metricsLabel)
+			}
 			t = treatments
 		}
 	}()
@@ -251,26 +731,45 @@ func (c *SplitClient) doTreatmentsCall(
 
 	matchingKey, bucketingKey, err := c.validator.ValidateTreatmentKey(key, operation)
 	if err != nil {
-		c.logger.Error(err.Error())
+		c.logger.Error(fmt.Sprintf("%s. Features: %v. Returning CONTROL", err.Error(), features))
 		return c.generateControlTreatments(features, operation)
 	}
 
-	filteredFeatures, err := c.validator.ValidateFeatureNames(features, operation)
+	filteredFeatures, err = c.validator.ValidateFeatureNames(features, operation)
 	if err != nil {
 		c.logger.Error(err.Error())
 		return map[string]TreatmentResult{}
 	}
+	attributes = c.validator.ValidateAttributes(attributes, operation)
+	if c.evaluationCancelled(ctx, operation) {
+		c.storeControlImpressions(matchingKey, bucketingKey, filteredFeatures, impressionlabels.Cancelled, attributes, metricsLabel)
+		return c.generateControlTreatments(filteredFeatures, operation)
+	}
 
 	var bulkImpressions []storage.Impression
-	evaluationsResult := c.getEvaluationsResult(matchingKey, bucketingKey, filteredFeatures, attributes, operation)
+	evaluationsResult := c.withContext(ctx).getEvaluationsResult(matchingKey, bucketingKey, filteredFeatures, attributes, operation)
+	if c.evaluationCancelled(ctx, operation) {
+		c.storeControlImpressions(matchingKey, bucketingKey, filteredFeatures, impressionlabels.Cancelled, attributes, metricsLabel)
+		return c.generateControlTreatments(filteredFeatures, operation)
+	}
 	for feature, evaluation := range evaluationsResult.Evaluations {
+		c.countTypeMismatch(evaluation.Label)
+		c.audit(matchingKey, bucketingKey, feature, &evaluation)
 		if !c.validator.IsSplitFound(evaluation.Label, feature, operation) {
+			c.countUnknownFeature(feature, operation)
+			c.countTreatment(feature, evaluator.Control)
+			if c.factory.cfg.Advanced.ControlImpressions {
+				bulkImpressions = append(bulkImpressions, c.createImpression(feature, bucketingKey, evaluation.Label, matchingKey, evaluator.Control, 0))
+			}
 			treatments[feature] = TreatmentResult{
 				Treatment: evaluator.Control,
 				Config:    nil,
 			}
 		} else {
-			bulkImpressions = append(bulkImpressions, c.createImpression(feature, bucketingKey, evaluation.Label, matchingKey, evaluation.Treatment, evaluation.SplitChangeNumber))
+			c.countTreatment(feature, evaluation.Treatment)
+			if !evaluation.ImpressionsDisabled {
+				bulkImpressions = append(bulkImpressions, c.createImpression(feature, bucketingKey, evaluation.Label, matchingKey, evaluation.Treatment, evaluation.SplitChangeNumber))
+			}
 
 			treatments[feature] = TreatmentResult{
 				Treatment: evaluation.Treatment,
@@ -286,8 +785,36 @@ func (c *SplitClient) doTreatmentsCall(
 
 // Treatments evaluates multiple featers for a single user and set of attributes at once
 func (c *SplitClient) Treatments(key interface{}, features []string, attributes map[string]interface{}) map[string]string {
+	return c.TreatmentsCtx(context.Background(), key, features, attributes)
+}
+
+// TreatmentsCtx is Treatments bound to ctx: redis reads are abandoned and CONTROL is returned if it's cancelled
+func (c *SplitClient) TreatmentsCtx(ctx context.Context, key interface{}, features []string, attributes map[string]interface{}) map[string]string {
 	treatments := map[string]string{}
-	result := c.doTreatmentsCall(key, features, attributes, "Treatments", "sdk.getTreatments")
+	result := c.doTreatmentsCall(ctx, key, features, attributes, "Treatments", "sdk.getTreatments")
+	for feature, treatmentResult := range result {
+		treatments[feature] = treatmentResult.Treatment
+	}
+	return treatments
+}
+
+// TreatmentsByFlagSet evaluates every feature in a flag set for a single user and set of attributes at once.
+// An empty map is returned if the flag set is unknown
+func (c *SplitClient) TreatmentsByFlagSet(key interface{}, flagSet string, attributes map[string]interface{}) map[string]string {
+	treatments := map[string]string{}
+	flagSets, ok := c.splits().(storage.SplitStorageFlagSetConsumer)
+	if !ok {
+		c.logger.Info("TreatmentsByFlagSet: the split storage doesn't support flag sets, returning an empty map")
+		return treatments
+	}
+
+	features := flagSets.SplitNamesByFlagSet(flagSet)
+	if len(features) == 0 {
+		c.logger.Info(fmt.Sprintf("TreatmentsByFlagSet: flag set %s has no splits, returning an empty map", flagSet))
+		return treatments
+	}
+
+	result := c.doTreatmentsCall(context.Background(), key, features, attributes, "TreatmentsByFlagSet", "sdk.getTreatmentsByFlagSet")
 	for feature, treatmentResult := range result {
 		treatments[feature] = treatmentResult.Treatment
 	}
@@ -296,7 +823,446 @@ func (c *SplitClient) Treatments(key interface{}, features []string, attributes
 
 // TreatmentsWithConfig evaluates multiple featers for a single user and set of attributes at once and returns configurations
 func (c *SplitClient) TreatmentsWithConfig(key interface{}, features []string, attributes map[string]interface{}) map[string]TreatmentResult {
-	return c.doTreatmentsCall(key, features, attributes, "TreatmentsWithConfig", "sdk.getTreatmentsWithConfig")
+	return c.TreatmentsWithConfigCtx(context.Background(), key, features, attributes)
+}
+
+// TreatmentsWithConfigCtx is TreatmentsWithConfig bound to ctx: redis reads are abandoned and CONTROL is returned if
+// it's cancelled
+func (c *SplitClient) TreatmentsWithConfigCtx(ctx context.Context, key interface{}, features []string, attributes map[string]interface{}) map[string]TreatmentResult {
+	return c.doTreatmentsCall(ctx, key, features, attributes, "TreatmentsWithConfig", "sdk.getTreatmentsWithConfig")
+}
+
+// prefetchedSegments serves the segment memberships fetched in bulk for a TreatmentForKeys call,
+// reading through to the underlying storage for the ones that weren't prefetched
+type prefetchedSegments struct {
+	storage.SegmentStorageConsumer
+	memberships map[string]map[string]bool
+}
+
+// SegmentContainsKey returns the prefetched membership if present, otherwise asks the underlying storage
+func (p *prefetchedSegments) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	if member, ok := p.memberships[segmentName][key]; ok {
+		return member, nil
+	}
+	return p.SegmentStorageConsumer.SegmentContainsKey(segmentName, key)
+}
+
+// splitSegmentNames returns the segments referenced by the conditions of a split
+func splitSegmentNames(split *dtos.SplitDTO) []string {
+	segmentNames := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, condition := range split.Conditions {
+		for _, matcher := range condition.MatcherGroup.Matchers {
+			if matcher.UserDefinedSegment == nil {
+				continue
+			}
+			segmentName := matcher.UserDefinedSegment.SegmentName
+			if _, ok := seen[segmentName]; !ok {
+				seen[segmentName] = struct{}{}
+				segmentNames = append(segmentNames, segmentName)
+			}
+		}
+	}
+	return segmentNames
+}
+
+// keysEvaluator returns an evaluator whose segment memberships for the feature's segments have been fetched in bulk
+// for every matching key. If the segment storage can't check many keys at once, the regular evaluator is returned
+func (c *SplitClient) keysEvaluator(feature string, matchingKeys []string) evaluator.Interface {
+	rebindable, ok := c.evaluator.(evaluator.Rebindable)
+	if !ok || rebindable.SplitStorage() == nil {
+		return c.evaluator
+	}
+	segments := rebindable.SegmentStorage()
+	bulk, ok := segments.(storage.SegmentStorageBulkConsumer)
+	if !ok {
+		return c.evaluator
+	}
+	split := rebindable.SplitStorage().Get(feature)
+	if split == nil {
+		return c.evaluator
+	}
+
+	memberships := make(map[string]map[string]bool)
+	for _, segmentName := range splitSegmentNames(split) {
+		segmentMemberships, err := bulk.SegmentContainsKeys(segmentName, matchingKeys)
+		if err != nil {
+			c.logger.Warning("Error prefetching memberships of segment ", segmentName, ": ", err.Error())
+			continue
+		}
+		memberships[segmentName] = segmentMemberships
+	}
+	return rebindable.Rebind(rebindable.SplitStorage(), &prefetchedSegments{
+		SegmentStorageConsumer: segments,
+		memberships:            memberships,
+	})
+}
+
+// TreatmentForKeys evaluates a single feature for many keys sharing the same set of attributes and returns the
+// treatments indexed by matching key. Segment memberships are fetched in bulk for all the keys and the impressions
+// are stored at once. Invalid keys are logged and left out of the result
+func (c *SplitClient) TreatmentForKeys(keys []interface{}, feature string, attributes map[string]interface{}) (t map[string]string) {
+	operation := "TreatmentForKeys"
+	treatments := make(map[string]string)
+	matchingKeys := make([]string, 0, len(keys))
+	bucketingKeys := make(map[string]*string, len(keys))
+	validated := false
+
+	// Set up a guard deferred function to recover if the SDK starts panicking
+	defer func() {
+		if r := recover(); r != nil {
+			c.recordException("sdk.getTreatmentForKeys")
+			// At this point we'll only trust that the logger isn't panicking trust
+			// that the logger isn't panicking
+			c.logger.Error(
+				"SDK is panicking with the following error", r, "\n",
+				string(debug.Stack()), "\n",
+				"Returning CONTROL for the keys without a result", "\n")
+			for _, matchingKey := range matchingKeys {
+				if _, ok := treatments[matchingKey]; !ok {
+					treatments[matchingKey] = evaluator.Control
+					if validated {
+						c.storeControlImpressions(matchingKey, bucketingKeys[matchingKey], []string{feature}, impressionlabels.Exception, attributes, "sdk.getTreatmentForKeys")
+					}
+				}
+			}
+			t = treatments
+		}
+	}()
+
+	for _, key := range keys {
+		matchingKey, bucketingKey, err := c.validator.ValidateTreatmentKey(key, operation)
+		if err != nil {
+			c.logger.Error(err.Error())
+			continue
+		}
+		if _, ok := bucketingKeys[matchingKey]; !ok {
+			matchingKeys = append(matchingKeys, matchingKey)
+		}
+		bucketingKeys[matchingKey] = bucketingKey
+	}
+
+	controlTreatments := func() map[string]string {
+		for _, matchingKey := range matchingKeys {
+			treatments[matchingKey] = evaluator.Control
+		}
+		return treatments
+	}
+
+	if c.isDestroyed() {
+		c.logger.Error("Client has already been destroyed - no calls possible")
+		return controlTreatments()
+	}
+
+	feature, err := c.validator.ValidateFeatureName(feature, operation)
+	if err != nil {
+		c.validator.logError(err)
+		return controlTreatments()
+	}
+	validated = true
+	attributes = c.validator.ValidateAttributes(attributes, operation)
+
+	var keysEvaluator evaluator.Interface
+	overloaded := false
+	if c.isReady() {
+		if c.acquireEvaluationSlot() {
+			defer c.releaseEvaluationSlot()
+			keysEvaluator = c.keysEvaluator(feature, matchingKeys)
+		} else {
+			c.countOverloaded(operation)
+			overloaded = true
+		}
+	}
+
+	var evaluationTimeNs int64
+	bulkImpressions := make([]storage.Impression, 0, len(matchingKeys))
+	for _, matchingKey := range matchingKeys {
+		bucketingKey := bucketingKeys[matchingKey]
+		var evaluationResult *evaluator.Result
+		if overloaded {
+			evaluationResult = overloadedResult()
+		} else if keysEvaluator != nil {
+			evaluationResult = keysEvaluator.EvaluateFeature(matchingKey, bucketingKey, feature, attributes)
+			evaluationResult.Label = c.checkSplitsLoaded(evaluationResult.Label)
+		} else {
+			evaluationResult = c.getEvaluationResult(matchingKey, bucketingKey, feature, attributes, operation)
+		}
+		c.countTypeMismatch(evaluationResult.Label)
+		c.audit(matchingKey, bucketingKey, feature, evaluationResult)
+
+		if !c.validator.IsSplitFound(evaluationResult.Label, feature, operation) {
+			c.countUnknownFeature(feature, operation)
+			for range matchingKeys {
+				c.countTreatment(feature, evaluator.Control)
+			}
+			if c.factory.cfg.Advanced.ControlImpressions {
+				controlImpressions := make([]storage.Impression, 0, len(matchingKeys))
+				for _, matchingKey := range matchingKeys {
+					controlImpressions = append(controlImpressions, c.createImpression(feature, bucketingKeys[matchingKey], evaluationResult.Label, matchingKey, evaluator.Control, 0))
+				}
+				c.storeData(controlImpressions, attributes, "sdk.getTreatmentForKeys", evaluationResult.EvaluationTimeNs)
+			}
+			return controlTreatments()
+		}
+
+		evaluationTimeNs += evaluationResult.EvaluationTimeNs
+		treatments[matchingKey] = evaluationResult.Treatment
+		c.countTreatment(feature, evaluationResult.Treatment)
+		if !evaluationResult.ImpressionsDisabled {
+			bulkImpressions = append(bulkImpressions, c.createImpression(feature, bucketingKey, evaluationResult.Label, matchingKey, evaluationResult.Treatment, evaluationResult.SplitChangeNumber))
+		}
+	}
+
+	if len(treatments) > 0 {
+		c.storeData(bulkImpressions, attributes, "sdk.getTreatmentForKeys", evaluationTimeNs)
+	}
+	return treatments
+}
+
+// overlaySplits serves the proposed splits supplied to a WhatIf call, reading through to the underlying storage
+// for the rest. The underlying storage is never written
+type overlaySplits struct {
+	storage.SplitStorageConsumer
+	proposed map[string]*dtos.SplitDTO
+}
+
+// Get returns the proposed split if present, otherwise asks the underlying storage
+func (o *overlaySplits) Get(splitName string) *dtos.SplitDTO {
+	if split, ok := o.proposed[splitName]; ok {
+		return split
+	}
+	return o.SplitStorageConsumer.Get(splitName)
+}
+
+// FetchMany returns the proposed splits if present, otherwise asks the underlying storage
+func (o *overlaySplits) FetchMany(splitNames []string) map[string]*dtos.SplitDTO {
+	splits := make(map[string]*dtos.SplitDTO, len(splitNames))
+	missing := make([]string, 0, len(splitNames))
+	for _, splitName := range splitNames {
+		if split, ok := o.proposed[splitName]; ok {
+			splits[splitName] = split
+		} else {
+			missing = append(missing, splitName)
+		}
+	}
+	if len(missing) > 0 {
+		for splitName, split := range o.SplitStorageConsumer.FetchMany(missing) {
+			splits[splitName] = split
+		}
+	}
+	return splits
+}
+
+// WhatIf evaluates the features for a key as if the proposed splits had replaced the ones in storage, ie: to preview
+// a rule change before publishing it. Features without a proposed split are evaluated against the splits in storage.
+// Neither storage nor impressions are written
+func (c *SplitClient) WhatIf(
+	proposed []dtos.SplitDTO,
+	key interface{},
+	features []string,
+	attributes map[string]interface{},
+) (t map[string]string) {
+	operation := "WhatIf"
+	treatments := make(map[string]string)
+
+	// Set up a guard deferred function to recover if the SDK starts panicking
+	defer func() {
+		if r := recover(); r != nil {
+			c.recordException("sdk.whatIf")
+			// At this point we'll only trust that the logger isn't panicking
+			c.logger.Error(
+				"SDK is panicking with the following error", r, "\n",
+				string(debug.Stack()), "\n",
+				"Returning CONTROL for the features without a result", "\n")
+			for _, feature := range features {
+				if _, ok := treatments[feature]; !ok {
+					treatments[feature] = evaluator.Control
+				}
+			}
+			t = treatments
+		}
+	}()
+
+	if c.isDestroyed() {
+		c.logger.Error("Client has already been destroyed - no calls possible")
+		for _, feature := range features {
+			treatments[feature] = evaluator.Control
+		}
+		return treatments
+	}
+
+	matchingKey, bucketingKey, err := c.validator.ValidateTreatmentKey(key, operation)
+	if err != nil {
+		c.logger.Error(err.Error())
+		for _, feature := range features {
+			treatments[feature] = evaluator.Control
+		}
+		return treatments
+	}
+
+	filteredFeatures, err := c.validator.ValidateFeatureNames(features, operation)
+	if err != nil {
+		c.logger.Error(err.Error())
+		return treatments
+	}
+
+	if !c.isReady() {
+		c.logger.Warning(operation + ": the SDK is not ready, features without a proposed split may return CONTROL")
+	}
+
+	overlay := &overlaySplits{
+		SplitStorageConsumer: c.splits(),
+		proposed:             make(map[string]*dtos.SplitDTO, len(proposed)),
+	}
+	for index := range proposed {
+		split := proposed[index]
+		overlay.proposed[split.Name] = &split
+	}
+
+	var whatIfEvaluator *evaluator.Evaluator
+	if base := c.baseEvaluator(); base != nil {
+		whatIfEvaluator = base.WithSplitStorage(overlay)
+	} else {
+		whatIfEvaluator = evaluator.NewEvaluator(overlay, c.segments(), c.factory.evaluationEngine(), c.logger)
+	}
+
+	for feature, evaluation := range whatIfEvaluator.EvaluateFeatures(matchingKey, bucketingKey, filteredFeatures, attributes).Evaluations {
+		treatments[feature] = evaluation.Treatment
+	}
+	return treatments
+}
+
+// overriddenSegments answers the membership checks of the overridden segments with the supplied values regardless
+// of the key, reading through to the underlying storage for the rest. It records whether any override was used
+type overriddenSegments struct {
+	storage.SegmentStorageConsumer
+	overrides map[string]bool
+	used      bool
+}
+
+// SegmentContainsKey returns the overridden membership if present, otherwise asks the underlying storage
+func (o *overriddenSegments) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	if isMember, ok := o.overrides[segmentName]; ok {
+		o.used = true
+		return isMember, nil
+	}
+	if o.SegmentStorageConsumer == nil {
+		return false, nil
+	}
+	return o.SegmentStorageConsumer.SegmentContainsKey(segmentName, key)
+}
+
+// segmentOverridesEvaluator labels the evaluations that relied on overridden segment memberships
+type segmentOverridesEvaluator struct {
+	*evaluator.Evaluator
+	segments *overriddenSegments
+}
+
+// EvaluateFeature evaluates the feature, appending the segment override label if an override was used
+func (e *segmentOverridesEvaluator) EvaluateFeature(
+	key string,
+	bucketingKey *string,
+	feature string,
+	attributes map[string]interface{},
+) *evaluator.Result {
+	e.segments.used = false
+	result := e.Evaluator.EvaluateFeature(key, bucketingKey, feature, attributes)
+	if e.segments.used {
+		result.Label = result.Label + " - " + impressionlabels.SegmentOverride
+	}
+	return result
+}
+
+// TreatmentWithSegmentOverrides evaluates a feature as if the key's membership of the overridden segments were
+// the one supplied, without reading those segments from storage. Intended for tests and tooling: evaluations that
+// relied on an override carry the segment override label, also in their impressions
+func (c *SplitClient) TreatmentWithSegmentOverrides(
+	key interface{},
+	feature string,
+	attributes map[string]interface{},
+	segmentOverrides map[string]bool,
+) DecisionResult {
+	segments := &overriddenSegments{overrides: segmentOverrides}
+	var overridesEvaluator *evaluator.Evaluator
+	if base := c.baseEvaluator(); base != nil {
+		segments.SegmentStorageConsumer = base.SegmentStorage()
+		overridesEvaluator = base.WithSegmentStorage(segments)
+	} else {
+		segments.SegmentStorageConsumer = c.segments()
+		overridesEvaluator = evaluator.NewEvaluator(c.splits(), segments, c.factory.evaluationEngine(), c.logger)
+	}
+
+	// Evaluate through a copy of the client so that the regular flow, including impressions, is kept as is
+	overridden := *c
+	overridden.evaluator = &segmentOverridesEvaluator{Evaluator: overridesEvaluator, segments: segments}
+	overridden.snapshotEvaluator = nil
+	return overridden.doTreatmentCall(context.Background(), key, feature, attributes, "TreatmentWithSegmentOverrides", "sdk.getTreatmentWithSegmentOverrides")
+}
+
+// SegmentStats returns the amount of keys of every segment referenced by the splits in storage.
+// Segments that haven't been synchronized yet are reported as SegmentMissing
+func (c *SplitClient) SegmentStats() map[string]int64 {
+	stats := make(map[string]int64)
+	if c.splits() == nil {
+		return stats
+	}
+
+	segmentNames := make([]string, 0)
+	if referenced := c.splits().SegmentNames(); referenced != nil {
+		for _, name := range referenced.List() {
+			if segmentName, ok := name.(string); ok {
+				segmentNames = append(segmentNames, segmentName)
+			}
+		}
+	}
+
+	var sizes map[string]int64
+	if segmentStorage, ok := c.segments().(storage.SegmentStorageStats); ok {
+		var err error
+		sizes, err = segmentStorage.SegmentSizes(segmentNames)
+		if err != nil {
+			c.logger.Error("Error fetching segment sizes: ", err.Error())
+			return stats
+		}
+	}
+
+	for _, segmentName := range segmentNames {
+		if size, ok := sizes[segmentName]; ok {
+			stats[segmentName] = size
+		} else {
+			stats[segmentName] = SegmentMissing
+		}
+	}
+	return stats
+}
+
+// ResetTelemetry discards the impressions & metrics recorded by this SDK instance that haven't been synchronized yet.
+// Splits, segments, events and data recorded by other instances sharing the storage are not affected
+func (c *SplitClient) ResetTelemetry() error {
+	for _, telemetry := range []interface{}{c.impressions, c.metrics} {
+		purger, ok := telemetry.(storage.TelemetryPurger)
+		if !ok {
+			continue
+		}
+		if err := purger.Purge(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FailingSegments returns the segments whose last synchronization failed along with the error message.
+// It's always empty in modes where the SDK doesn't synchronize segments itself
+func (c *SplitClient) FailingSegments() map[string]string {
+	failing := make(map[string]string)
+	if c.factory.segmentSyncStatus == nil {
+		return failing
+	}
+
+	for segmentName, err := range c.factory.segmentSyncStatus.Failing() {
+		failing[segmentName] = err.Error()
+	}
+	return failing
 }
 
 // isDestroyed returns true if the client has been destroyed
@@ -316,6 +1282,12 @@ func (c *SplitClient) Destroy() {
 	}
 }
 
+// Flush synchronously submits the queued impressions, events & metrics without waiting for the periodic tasks,
+// ie: before a short-lived process exits
+func (c *SplitClient) Flush() error {
+	return c.factory.Flush()
+}
+
 // Track an event and its custom value
 func (c *SplitClient) Track(
 	key string,
@@ -327,6 +1299,7 @@ func (c *SplitClient) Track(
 
 	defer func() {
 		if r := recover(); r != nil {
+			c.recordException("sdk.track")
 			// At this point we'll only trust that the logger isn't panicking
 			c.logger.Error(
 				"SDK is panicking with the following error", r, "\n",
@@ -342,6 +1315,11 @@ func (c *SplitClient) Track(
 		return errors.New("Client has already been destroyed - no calls possible")
 	}
 
+	if c.trackLimiter != nil && !c.trackLimiter.Allow() {
+		c.incCounter(TrackRateLimitedCounter)
+		return errors.New("Track: rate limit exceeded, event dropped")
+	}
+
 	if !c.isReady() {
 		c.logger.Warning("Track: the SDK is not ready, results may be incorrect. Make sure to wait for SDK readiness before using this method")
 	}
@@ -373,6 +1351,9 @@ func (c *SplitClient) Track(
 	}, size)
 
 	if err != nil {
+		if err == mutexqueue.ErrorMaxSizeReached {
+			c.incCounter(TrackQueueFullCounter)
+		}
 		c.logger.Error("Error tracking event", err.Error())
 		return err
 	}
@@ -380,7 +1361,64 @@ func (c *SplitClient) Track(
 	return nil
 }
 
+// TreatmentAndTrack retrieves the treatment of a specific feature and tracks an event tagged with the feature and
+// the treatment served. The treatment is returned even if the event can't be tracked
+func (c *SplitClient) TreatmentAndTrack(
+	key string,
+	trafficType string,
+	feature string,
+	eventType string,
+	value *float64,
+	attributes map[string]interface{},
+) string {
+	treatment := c.doTreatmentCall(context.Background(), key, feature, attributes, "TreatmentAndTrack", "sdk.getTreatmentAndTrack").Treatment
+
+	var eventValue interface{}
+	if value != nil {
+		eventValue = *value
+	}
+	err := c.Track(key, trafficType, eventType, eventValue, map[string]interface{}{
+		"feature":   feature,
+		"treatment": treatment,
+	})
+	if err != nil {
+		c.logger.Warning(fmt.Sprintf("TreatmentAndTrack: event %s for feature %s could not be tracked: %s", eventType, feature, err.Error()))
+	}
+
+	return treatment
+}
+
 // BlockUntilReady Calls BlockUntilReady on factory to block client on readiness
 func (c *SplitClient) BlockUntilReady(timer int) error {
 	return c.factory.BlockUntilReady(timer)
 }
+
+// UpdateApikey replaces the apikey used to synchronize with Split servers once they accept it.
+// See SplitFactory.UpdateApikey
+func (c *SplitClient) UpdateApikey(apikey string) error {
+	return c.factory.UpdateApikey(apikey)
+}
+
+// RecentErrors returns the last internal errors logged by the SDK, oldest first, along with the time they happened
+// and their category: storage, sync or evaluation. Returns nil if keeping them is disabled
+func (c *SplitClient) RecentErrors() []diagnostics.SDKError {
+	if c.factory == nil || c.factory.recentErrors == nil {
+		return nil
+	}
+	return c.factory.recentErrors.Recent()
+}
+
+// CombinedReadiness returns true only if every one of the clients is ready, ie: to wait for all the factories of an
+// application sharing a redis before rolling it out. It only reads the readiness flags, so it's cheap to call often.
+// Returns false if no clients are passed or any of them is nil
+func CombinedReadiness(clients ...*SplitClient) bool {
+	if len(clients) == 0 {
+		return false
+	}
+	for _, client := range clients {
+		if client == nil || client.factory == nil || !client.isReady() {
+			return false
+		}
+	}
+	return true
+}
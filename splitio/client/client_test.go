@@ -2,26 +2,37 @@ package client
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/splitio/go-client/splitio"
+	auditsink "github.com/splitio/go-client/splitio/auditSink"
 	"github.com/splitio/go-client/splitio/conf"
+	"github.com/splitio/go-client/splitio/engine"
 	"github.com/splitio/go-client/splitio/engine/evaluator"
 	"github.com/splitio/go-client/splitio/engine/evaluator/impressionlabels"
+	impressionenricher "github.com/splitio/go-client/splitio/impressionEnricher"
 	impressionlistener "github.com/splitio/go-client/splitio/impressionListener"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/storage"
 	"github.com/splitio/go-client/splitio/storage/mutexmap"
 	"github.com/splitio/go-client/splitio/storage/mutexqueue"
 	"github.com/splitio/go-client/splitio/storage/redisdb"
+	"github.com/splitio/go-client/splitio/util/diagnostics"
+	impressionsutil "github.com/splitio/go-client/splitio/util/impressions"
+	"github.com/splitio/go-client/splitio/util/ratelimit"
 	"github.com/splitio/go-toolkit/asynctask"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
@@ -204,10 +215,25 @@ func TestTreatments(t *testing.T) {
 	client.evaluator = &mockEvaluator{}
 	factory.status.Store(sdkStatusReady)
 
+	impressions := &impressionsCountingStorage{}
+	client.impressions = impressions
+
 	res := client.Treatments("user1", []string{"feature", "notFeature"}, nil)
 
 	expectedTreatment(res["feature"], "TreatmentA", t)
 	expectedTreatment(res["notFeature"], evaluator.Control, t)
+
+	client.Treatments("user1", []string{"feature", "feature", "feature2"}, nil)
+	if impressions.writes != 2 || len(impressions.impressions) != 3 {
+		t.Error("The impressions of each call should be stored in a single write", impressions.writes, impressions.impressions)
+	}
+
+	if res = client.Treatments("user1", []string{}, nil); res == nil || len(res) != 0 {
+		t.Error("An empty map should be returned when no features are passed", res)
+	}
+	if res = client.Treatments("user1", nil, nil); res == nil || len(res) != 0 {
+		t.Error("An empty map should be returned when no features are passed", res)
+	}
 }
 
 func TestLocalhostMode(t *testing.T) {
@@ -269,6 +295,84 @@ func TestClientPanicking(t *testing.T) {
 	expectedTreatment(client.Treatment("key", "some", nil), evaluator.Control, t)
 }
 
+type exceptionCountingMetrics struct {
+	*mutexmap.MMMetricsStorage
+	exceptions map[string]int
+}
+
+func (m *exceptionCountingMetrics) IncException(method string) { m.exceptions[method]++ }
+
+func TestClientPanickingCountsExceptions(t *testing.T) {
+	factory := getFactory()
+
+	metrics := &exceptionCountingMetrics{MMMetricsStorage: mutexmap.NewMMMetricsStorage(), exceptions: map[string]int{}}
+	client := factory.Client()
+	client.evaluator = &mockEventsPanic{}
+	client.metrics = metrics
+	factory.status.Store(sdkStatusReady)
+
+	client.Treatment("key", "some", nil)
+	client.Treatments("key", []string{"some", "other"}, nil)
+	if metrics.exceptions["sdk.getTreatment"] != 1 || metrics.exceptions["sdk.getTreatments"] != 1 {
+		t.Error("Exceptions should be counted in metrics storages supporting them. Got:", metrics.exceptions)
+	}
+}
+
+type fakeMetricsSink struct {
+	latencies  map[string]int
+	treatments map[string]int
+	exceptions map[string]int
+}
+
+func newFakeMetricsSink() *fakeMetricsSink {
+	return &fakeMetricsSink{latencies: map[string]int{}, treatments: map[string]int{}, exceptions: map[string]int{}}
+}
+
+func (s *fakeMetricsSink) ObserveLatency(operation string, latency time.Duration) {
+	s.latencies[operation]++
+}
+func (s *fakeMetricsSink) IncTreatment(feature string, treatment string) {
+	s.treatments[feature+":"+treatment]++
+}
+func (s *fakeMetricsSink) IncException(operation string) { s.exceptions[operation]++ }
+
+func TestClientMetricsSink(t *testing.T) {
+	sink := newFakeMetricsSink()
+	factory := getFactory()
+	factory.cfg.Advanced.MetricsSink = sink
+
+	client := factory.Client()
+	client.evaluator = &mockEvaluator{}
+	factory.status.Store(sdkStatusReady)
+
+	client.Treatment("key", "feature", nil)
+	client.Treatment("key", "feature", nil)
+	if sink.latencies["sdk.getTreatment"] != 2 || sink.treatments["feature:TreatmentA"] != 2 {
+		t.Error("A latency observation and a treatment count should be emitted per evaluation", sink.latencies, sink.treatments)
+	}
+
+	client.Treatments("key", []string{"feature", "feature2"}, nil)
+	if sink.latencies["sdk.getTreatments"] != 1 || sink.treatments["feature:TreatmentA"] != 3 || sink.treatments["feature2:TreatmentB"] != 1 {
+		t.Error("Each treatment of a batch should be counted", sink.latencies, sink.treatments)
+	}
+
+	client.Treatment("key", "nonexistent", nil)
+	if sink.treatments["nonexistent:control"] != 1 {
+		t.Error("Treatments should be counted even if no impression is stored", sink.treatments)
+	}
+
+	client.TreatmentForKeys([]interface{}{"key1", "key2"}, "nonexistent", nil)
+	if sink.treatments["nonexistent:control"] != 3 {
+		t.Error("The treatment of each key should be counted", sink.treatments)
+	}
+
+	client.evaluator = &mockEventsPanic{}
+	client.Treatment("key", "feature", nil)
+	if sink.exceptions["sdk.getTreatment"] != 1 {
+		t.Error("Exceptions should be counted", sink.exceptions)
+	}
+}
+
 func TestClientDestroy(t *testing.T) {
 	logger := logging.NewLogger(nil)
 	resSplits := atomic.Value{}
@@ -319,6 +423,7 @@ func TestClientDestroy(t *testing.T) {
 	gaugesTask := asynctask.NewAsyncTask("gauges", gaugeSync, 100, nil, gaugeStop, logger)
 	countersTask := asynctask.NewAsyncTask("counters", counterSync, 100, nil, counterStop, logger)
 	latenciesTask := asynctask.NewAsyncTask("latencies", latencySync, 100, nil, latencyStop, logger)
+	eventsTask := asynctask.NewAsyncTask("events", func(l logging.LoggerInterface) error { return nil }, 100, nil, nil, logger)
 
 	splitTask.Start()
 	segmentsTask.Start()
@@ -326,9 +431,11 @@ func TestClientDestroy(t *testing.T) {
 	gaugesTask.Start()
 	countersTask.Start()
 	latenciesTask.Start()
+	eventsTask.Start()
 
 	factory := &SplitFactory{
 		tasks: sdkSync{
+			events:      eventsTask,
 			counters:    countersTask,
 			gauges:      gaugesTask,
 			impressions: impressionsTask,
@@ -371,6 +478,10 @@ func TestClientDestroy(t *testing.T) {
 		t.Error("latencies task should be stopped")
 	}
 
+	if eventsTask.IsRunning() {
+		t.Error("events task should be stopped")
+	}
+
 	// -----
 
 	if resSplits.Load().(int) != 1 {
@@ -538,6 +649,67 @@ func TestImpressionListener(t *testing.T) {
 	delete(ilResult, "feature")
 }
 
+type blockingImpressionListener struct {
+	release  chan struct{}
+	received int64
+}
+
+func (i *blockingImpressionListener) LogImpression(data impressionlistener.ILObject) {
+	<-i.release
+	atomic.AddInt64(&i.received, 1)
+}
+
+func TestImpressionListenerQueueFull(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(nil)
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	listener := &blockingImpressionListener{release: make(chan struct{})}
+
+	factory := &SplitFactory{cfg: cfg}
+	client := SplitClient{
+		evaluator:   &mockEvaluator{},
+		impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+		logger:      logger,
+		metrics:     metricsStorage,
+		factory:     factory,
+		impressionListener: impressionlistener.NewAsyncImpressionListenerWrapper(
+			listener,
+			&splitio.SdkMetadata{SDKVersion: "go-" + splitio.Version},
+			1,
+			metricsStorage,
+			logger,
+		),
+	}
+	factory.status.Store(sdkStatusReady)
+
+	for i := 0; i < 5; i++ {
+		expectedTreatment(client.Treatment("user1", "feature", nil), "TreatmentA", t)
+	}
+
+	impressions, _ := client.impressions.(storage.ImpressionStorage).PopN(cfg.Advanced.ImpressionsBulkSize)
+	if len(impressions) != 5 {
+		t.Error("Every impression should be stored regardless of the listener. Got:", len(impressions))
+	}
+
+	var dropped int64
+	for _, counter := range metricsStorage.PopCounters() {
+		if counter.MetricName == impressionlistener.DroppedCounter {
+			dropped = counter.Count
+		}
+	}
+	// At most one impression is being handled by the listener and one is waiting in the queue
+	if dropped < 3 {
+		t.Error("Impressions that don't fit in the listener queue should be dropped. Dropped:", dropped)
+	}
+
+	close(listener.release)
+	client.impressionListener.Stop()
+	time.Sleep(100 * time.Millisecond)
+	if received := atomic.LoadInt64(&listener.received); received+dropped != 5 {
+		t.Error("Every impression should be either dispatched or dropped. Received:", received)
+	}
+}
+
 func TestImpressionListenerForTreatments(t *testing.T) {
 	client := getClientForListener()
 
@@ -667,6 +839,28 @@ func TestBlockUntilReadyStatusLocalhost(t *testing.T) {
 	}
 }
 
+func TestClientDestroyRedis(t *testing.T) {
+	sdkConf := conf.Default()
+	sdkConf.OperationMode = "redis-consumer"
+	sdkConf.Redis.Prefix = "destroyRedis"
+	factory, err := NewSplitFactory("something", sdkConf)
+	if err != nil {
+		t.Fatal("Factory should be created. Got:", err)
+	}
+	client := factory.Client()
+
+	client.Destroy()
+	if treatment := client.Treatment("key", "feature", nil); treatment != evaluator.Control {
+		t.Error("CONTROL should be returned once destroyed. Got:", treatment)
+	}
+	if _, err := factory.redisClient.Get("SPLITIO.split.feature"); err == nil {
+		t.Error("The redis client should be closed")
+	}
+
+	// Destroying again should not fail on the closed redis client
+	client.Destroy()
+}
+
 func TestBlockUntilReadyStatusLocalhostOnDestroy(t *testing.T) {
 	file, err := ioutil.TempFile("", "splitio_tests")
 	if err != nil {
@@ -972,6 +1166,71 @@ func TestBlockUntilReadyInMemory(t *testing.T) {
 	client.Destroy()
 }
 
+func TestBlockUntilReadyInitialSyncRetries(t *testing.T) {
+	newFactory := func(status func(attempt int64) int, timeout int) (*SplitFactory, *httptest.Server) {
+		var attempts int64
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/splitChanges" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			if code := status(atomic.AddInt64(&attempts, 1)); code != http.StatusOK {
+				w.WriteHeader(code)
+				return
+			}
+			raw, _ := json.Marshal(dtos.SplitChangesDTO{Splits: []dtos.SplitDTO{}, Since: 3, Till: 3})
+			w.Write(raw)
+		}))
+
+		sdkConf := conf.Default()
+		sdkConf.Advanced.SdkURL = ts.URL
+		sdkConf.Advanced.EventsURL = ts.URL
+		sdkConf.Advanced.InitialSyncTimeout = timeout
+		sdkConf.LoggerConfig.LogLevel = logging.LevelNone
+		factory, err := NewSplitFactory("retries", sdkConf)
+		if err != nil {
+			t.Error("Factory should have been created", err)
+		}
+		return factory, ts
+	}
+
+	// Transient failure followed by a success within the timeout
+	factory, ts := newFactory(func(attempt int64) int {
+		if attempt == 1 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}, 5)
+	defer ts.Close()
+	defer factory.Destroy()
+	if err := factory.BlockUntilReady(5); err != nil || !factory.IsReady() {
+		t.Error("SDK should be ready after retrying a transient error", err)
+	}
+
+	// Invalid apikey fails fast
+	factory, ts = newFactory(func(attempt int64) int { return http.StatusUnauthorized }, 5)
+	defer ts.Close()
+	defer factory.Destroy()
+	before := time.Now()
+	err := factory.BlockUntilReady(5)
+	if err == nil || err.Error() != "SDK Initialization failed" || time.Since(before) > 2*time.Second {
+		t.Error("A permanent error should fail without waiting for the timeout", err)
+	}
+	if err = factory.BlockUntilReady(5); err == nil || err.Error() != "SDK Initialization failed" {
+		t.Error("The failure should be reported to later calls too", err)
+	}
+
+	// Transient errors persisting after the retries
+	factory, ts = newFactory(func(attempt int64) int { return http.StatusServiceUnavailable }, 1)
+	defer ts.Close()
+	defer factory.Destroy()
+	err = factory.BlockUntilReady(5)
+	expected := "SDK Initialization: splits could not be synchronized after retrying for 1 seconds"
+	if err == nil || err.Error() != expected {
+		t.Error("Exhausting the retries should be reported", err)
+	}
+}
+
 var valid = &dtos.SplitDTO{
 	Algo:                  2,
 	ChangeNumber:          1494593336752,
@@ -1243,296 +1502,2582 @@ func TestClient(t *testing.T) {
 	expectedTreatmentAndConfig(treatmentsWithConfigs["valid"], "on", "{\"color\": \"blue\",\"size\": 13}", t)
 }
 
-func TestLocalhostModeYAML(t *testing.T) {
-	sdkConf := conf.Default()
-	sdkConf.SplitFile = "../../testdata/splits.yaml"
-	factory, _ := NewSplitFactory("localhost", sdkConf)
-	client := factory.Client()
-	manager := factory.Manager()
-
-	_ = client.BlockUntilReady(5)
+func TestTreatmentsWithConfigMixedFeatures(t *testing.T) {
+	cfg := conf.Default()
+	cfg.LabelsEnabled = true
+	logger := logging.NewLogger(nil)
 
-	if !client.isReady() {
-		t.Error("Localhost should be ready")
+	factory := &SplitFactory{cfg: cfg}
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(&mockStorage{}, &mockSegmentStorage{}, nil, logger),
+		impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+		logger:      logger,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		validator:   inputValidation{logger: logger},
+		factory:     factory,
 	}
+	factory.status.Store(sdkStatusReady)
 
-	if client.factory.cfg.OperationMode != "localhost" {
-		t.Error("Localhost operation mode should be set when received apikey is 'localhost'")
+	// "valid" evaluates to "off" for this key, which has no config attached
+	treatments := client.TreatmentsWithConfig("invalid", []string{"valid", "killed", "nonexistent"}, nil)
+	if len(treatments) != 3 {
+		t.Error("There should be one result per requested feature")
 	}
+	expectedTreatmentAndConfig(treatments["valid"], "off", "", t)
+	expectedTreatmentAndConfig(treatments["killed"], "defTreatment", "{\"color\": \"orange\",\"size\": 15}", t)
+	expectedTreatmentAndConfig(treatments["nonexistent"], evaluator.Control, "", t)
 
-	if len(manager.Splits()) != 4 {
-		t.Error("Error grabbing splits for localhost mode")
+	impressions, _ := client.impressions.(storage.ImpressionStorage).PopN(cfg.Advanced.ImpressionsBulkSize)
+	if len(impressions) != 2 {
+		t.Error("Only the features found should generate impressions")
+	}
+	for _, impression := range impressions {
+		if impression.KeyName != "invalid" {
+			t.Error("All impressions should share the same key")
+		}
 	}
 
-	expectedTreatment(client.Treatment("only_key", "my_feature", nil), "off", t)
-	expectedTreatment(client.Treatment("invalid_key", "my_feature", nil), "control", t)
-	expectedTreatment(client.Treatment("key", "my_feature", nil), "on", t)
-	expectedTreatment(client.Treatment("key2", "other_feature", nil), "on", t)
-	expectedTreatment(client.Treatment("test", "other_feature_2", nil), "on", t)
-	expectedTreatment(client.Treatment("key", "other_feature_3", nil), "off", t)
-	expectedTreatment(client.Treatment("key_whitelist", "other_feature_3", nil), "on", t)
+	client.evaluator = &mockEventsPanic{}
+	treatments = client.TreatmentsWithConfig("invalid", []string{"valid", "killed"}, nil)
+	if len(treatments) != 2 {
+		t.Error("A panicking evaluation should still return a result per feature")
+	}
+	expectedTreatmentAndConfig(treatments["valid"], evaluator.Control, "", t)
+	expectedTreatmentAndConfig(treatments["killed"], evaluator.Control, "", t)
 
-	expectedTreatmentAndConfig(client.TreatmentWithConfig("only_key", "my_feature", nil), "off", "{\"desc\" : \"this applies only to OFF and only for only_key. The rest will receive ON\"}", t)
-	expectedTreatmentAndConfig(client.TreatmentWithConfig("key", "my_feature", nil), "on", "{\"desc\" : \"this applies only to ON treatment\"}", t)
-	expectedTreatmentAndConfig(client.TreatmentWithConfig("key3", "other_feature", nil), "on", "", t)
+	treatments = client.TreatmentsWithConfig("invalid", []string{" valid ", "valid", ""}, nil)
+	if len(treatments) != 1 {
+		t.Error("A panicking evaluation should only return results for validated feature names")
+	}
+	expectedTreatmentAndConfig(treatments["valid"], evaluator.Control, "", t)
+}
 
-	resultTreatments := client.Treatments("only_key", []string{"my_feature", "other_feature"}, nil)
-	expectedTreatment(resultTreatments["my_feature"], "off", t)
-	expectedTreatment(resultTreatments["other_feature"], "control", t)
+func TestSanitizeFeatureNames(t *testing.T) {
+	writer := &errorsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{
+		LogLevel:      logging.LevelAll,
+		ErrorWriter:   writer,
+		WarningWriter: writer,
+	})
+	client := SplitClient{
+		logger:    logger,
+		validator: inputValidation{logger: logger, maxFeatureNameLength: 10},
+	}
 
-	resultTreatmentsWithConfig := client.TreatmentsWithConfig("only_key", []string{"my_feature", "other_feature"}, nil)
-	expectedTreatmentAndConfig(resultTreatmentsWithConfig["my_feature"], "off", "{\"desc\" : \"this applies only to OFF and only for only_key. The rest will receive ON\"}", t)
-	expectedTreatmentAndConfig(resultTreatmentsWithConfig["other_feature"], "control", "", t)
+	sanitized := client.sanitizeFeatureNames(
+		[]string{" valid ", "valid", "", "in valid", "muchTooLongFeature", "other"},
+		"TreatmentsWithConfig",
+	)
+	sort.Strings(sanitized)
+	if len(sanitized) != 2 || sanitized[0] != "other" || sanitized[1] != "valid" {
+		t.Error("Only names passing the regular validation should be kept. Got:", sanitized)
+	}
+	if len(writer.messages) != 0 {
+		t.Error("Sanitizing feature names shouldn't log anything. Got:", writer.messages)
+	}
+	if client.validator.logger != logger {
+		t.Error("The client validator should keep its logger")
+	}
 }
 
-func getRedisConfWithIP(IPAddressesEnabled bool) *redisdb.PrefixedRedisClient {
-	// Create prefixed client for adding Split
-	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{
-		Host:     "localhost",
-		Port:     6379,
-		Database: 1,
-		Password: "",
-		Prefix:   "testPrefix",
-	})
+func TestSegmentStats(t *testing.T) {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{*valid}, 123)
+	segmentStorage := mutexmap.NewMMSegmentStorage()
 
-	splitStorage := redisdb.NewRedisSplitStorage(prefixedClient, logger)
-	splitStorage.PutMany([]dtos.SplitDTO{*valid}, 1494593336752)
+	factory := &SplitFactory{
+		cfg: conf.Default(),
+		storages: sdkStorages{
+			splits:   splitStorage,
+			segments: segmentStorage,
+		},
+	}
+	client := SplitClient{logger: logging.NewLogger(nil), factory: factory}
 
-	// Set default configs to connect Client with redis
-	cfg := conf.Default()
-	cfg.LabelsEnabled = true
-	cfg.IPAddressesEnabled = IPAddressesEnabled
-	cfg.Advanced.ImpressionListener = &ImpressionListenerTest{}
-	cfg.OperationMode = "redis-consumer"
-	cfg.Redis = conf.RedisConfig{
-		Host:     "localhost",
-		Port:     6379,
-		Database: 1,
-		Password: "",
-		Prefix:   "testPrefix",
+	stats := client.SegmentStats()
+	if len(stats) != 1 || stats["employees"] != SegmentMissing {
+		t.Error("Segments not yet synchronized should be reported as missing", stats)
 	}
 
-	factory, _ := NewSplitFactory("apikey", cfg)
-	client := factory.Client()
+	segmentStorage.Put("employees", set.NewSet("user1", "user2", "user3"), 123)
+	stats = client.SegmentStats()
+	if len(stats) != 1 || stats["employees"] != 3 {
+		t.Error("Segment size should be 3", stats)
+	}
 
-	// Deletes previous data
-	prefixedClient.Del("SPLITIO.impressions", "SPLITIO.events")
+	segmentStorage.Put("employees", set.NewSet(), 124)
+	stats = client.SegmentStats()
+	if stats["employees"] != 0 {
+		t.Error("Empty segments should be reported with size 0", stats)
+	}
+}
 
-	// Calls treatments to generate one valid impression
-	client.Treatment("user1", "valid", nil)
-	client.Track("user1", "my-traffic", "my-event", nil, nil)
+type countingSegmentStorage struct {
+	*mutexmap.MMSegmentStorage
+	bulkCalls   int
+	singleCalls int
+}
 
-	return prefixedClient
+func (s *countingSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	s.singleCalls++
+	return s.MMSegmentStorage.SegmentContainsKey(segmentName, key)
 }
 
-func deleteDataGenerated(prefixedClient *redisdb.PrefixedRedisClient) {
-	// Deletes generated data
-	keys, _ := prefixedClient.Keys(fmt.Sprintf("SPLITIO/go-%s/*/latency.sdk.getTreatment.bucket.*", splitio.Version))
-	keys = append(keys, "SPLITIO.impressions", "SPLITIO.events", "SPLITIO.split.valid", "SPLITIO.splits.till")
-	prefixedClient.Del(keys...)
+func (s *countingSegmentStorage) SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error) {
+	s.bulkCalls++
+	return s.MMSegmentStorage.SegmentContainsKeys(segmentName, keys)
 }
 
-func TestRedisClientWithIPDisabled(t *testing.T) {
-	prefixedClient := getRedisConfWithIP(false)
-	// Grabs created impression
-	jsonImpr, _ := prefixedClient.LRange("SPLITIO.impressions", 0, 1).Result()
-	impression := make(map[string]map[string]interface{})
-	json.Unmarshal([]byte(jsonImpr[0]), &impression)
-	metadata := impression["m"]
-	// Checks if metadata was created with "NA" values
-	if metadata["i"] != "NA" || metadata["n"] != "NA" {
-		t.Error("Instance Name and Machine IP should have 'NA' values")
+type impressionsCountingStorage struct {
+	writes      int
+	impressions []storage.Impression
+}
+
+func (s *impressionsCountingStorage) LogImpressions(impressions []storage.Impression) error {
+	s.writes++
+	s.impressions = append(s.impressions, impressions...)
+	return nil
+}
+
+func TestTreatmentForKeys(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{*valid}, 123)
+	segmentStorage := &countingSegmentStorage{MMSegmentStorage: mutexmap.NewMMSegmentStorage()}
+	segmentStorage.Put("employees", set.NewSet("user1", "user3"), 123)
+	impressions := &impressionsCountingStorage{}
+
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:   splitStorage,
+			segments: segmentStorage,
+		},
 	}
-	listenerData, _ := ilResult["valid"].(map[string]interface{})
-	if listenerData["InstanceName"] != "NA" {
-		t.Error("InstanceName should be 'NA")
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		logger:      logger,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
 	}
+	factory.status.Store(sdkStatusReady)
 
-	// Grabs created event
-	jsonEvent, _ := prefixedClient.LRange("SPLITIO.events", 0, 1).Result()
-	event := make(map[string]map[string]interface{})
-	json.Unmarshal([]byte(jsonEvent[0]), &event)
-	metadata = event["m"]
-	// Checks if metadata was created with "NA" values
-	if metadata["i"] != "NA" || metadata["n"] != "NA" {
-		t.Error("Instance Name and Machine IP should have 'NA' values")
+	keys := []interface{}{"user1", "user2", &Key{MatchingKey: "user3", BucketingKey: "bucket"}, "user4", ""}
+	treatments := client.TreatmentForKeys(keys, "valid", nil)
+	expected := map[string]string{"user1": "on", "user2": "off", "user3": "on", "user4": "off"}
+	if len(treatments) != len(expected) {
+		t.Error("Invalid keys should be left out of the result", treatments)
+	}
+	for key, treatment := range expected {
+		if treatments[key] != treatment {
+			t.Error("Wrong treatment for", key, treatments[key])
+		}
 	}
 
-	deleteDataGenerated(prefixedClient)
+	if segmentStorage.bulkCalls != 1 || segmentStorage.singleCalls != 0 {
+		t.Error("Memberships should be fetched with a single bulk call", segmentStorage.bulkCalls, segmentStorage.singleCalls)
+	}
+
+	if impressions.writes != 1 || len(impressions.impressions) != 4 {
+		t.Error("Impressions for every key should be stored at once", impressions.writes, len(impressions.impressions))
+	}
+	for _, impression := range impressions.impressions {
+		if impression.KeyName == "user3" && impression.BucketingKey != "bucket" {
+			t.Error("Bucketing key should be kept in the impression", impression)
+		}
+	}
+
+	treatments = client.TreatmentForKeys(keys, "nonexistent", nil)
+	if len(treatments) != 4 || treatments["user1"] != evaluator.Control || impressions.writes != 1 {
+		t.Error("Missing splits should return CONTROL for every key without impressions", treatments)
+	}
 }
 
-func TestRedisClientWithIPEnabled(t *testing.T) {
-	prefixedClient := getRedisConfWithIP(true)
-	// Grabs created impression
+func TestTreatmentForKeysCached(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{*valid}, 123)
+	segmentStorage := &countingSegmentStorage{MMSegmentStorage: mutexmap.NewMMSegmentStorage()}
+	segmentStorage.Put("employees", set.NewSet("user1"), 123)
+
+	factory := &SplitFactory{cfg: conf.Default(), storages: sdkStorages{splits: splitStorage, segments: segmentStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := SplitClient{
+		evaluator: evaluator.NewCachedEvaluator(
+			evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+			splitStorage,
+			10,
+			time.Minute,
+		),
+		impressions: &impressionsCountingStorage{},
+		logger:      logger,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	treatments := client.TreatmentForKeys([]interface{}{"user1", "user2"}, "valid", nil)
+	if treatments["user1"] != "on" || treatments["user2"] != "off" {
+		t.Error("Wrong treatments", treatments)
+	}
+	if segmentStorage.bulkCalls != 1 || segmentStorage.singleCalls != 0 {
+		t.Error("Memberships should be fetched in bulk with the evaluation cache enabled", segmentStorage.bulkCalls, segmentStorage.singleCalls)
+	}
+}
+
+func TestTrackRateLimit(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	factory := &SplitFactory{cfg: conf.Default()}
+	factory.status.Store(sdkStatusInitializing)
+	client := SplitClient{
+		logger:       logger,
+		events:       mutexqueue.NewMQEventsStorage(3, make(chan string, 10), logger),
+		metrics:      metricsStorage,
+		validator:    inputValidation{logger: logger},
+		factory:      factory,
+		trackLimiter: ratelimit.NewTokenBucket(5),
+	}
+
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		if client.Track("user1", "user", "click", nil, nil) == nil {
+			accepted++
+		}
+	}
+	if accepted != 3 {
+		t.Error("Only events within the rate and queue size should be accepted", accepted)
+	}
+
+	counters := make(map[string]int64)
+	for _, counter := range metricsStorage.PopCounters() {
+		counters[counter.MetricName] = counter.Count
+	}
+	if counters[TrackRateLimitedCounter] != 5 || counters[TrackQueueFullCounter] != 2 {
+		t.Error("Rate limited and queue full drops should be counted separately", counters)
+	}
+}
+
+func TestStrictFeatureNames(t *testing.T) {
+	writer := &errorsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer})
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	cfg := conf.Default()
+	cfg.Advanced.StrictFeatureNames = true
+	factory := &SplitFactory{cfg: cfg}
+	factory.status.Store(sdkStatusInitializing)
+	client := SplitClient{
+		evaluator:   &mockEvaluator{},
+		impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+		metrics:     metricsStorage,
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: &mockSplitStorage{}},
+		factory:     factory,
+	}
+
+	client.Treatment("key", "featur", nil)
+	if writer.contains("unknown feature") || len(metricsStorage.PopCounters()) != 0 {
+		t.Error("Unknown features should not be reported before the SDK is ready", writer.messages)
+	}
+
+	factory.status.Store(sdkStatusReady)
+	client.Treatment("key", "featur", nil)
+	client.Treatments("key", []string{"feature", "featur"}, nil)
+	if !writer.contains("Treatment: unknown feature featur") || !writer.contains("Treatments: unknown feature featur") {
+		t.Error("A warning naming the unknown feature should be logged", writer.messages)
+	}
+
+	counters := metricsStorage.PopCounters()
+	if len(counters) != 1 || counters[0].MetricName != UnknownFeatureCounter || counters[0].Count != 2 {
+		t.Error("Each evaluation of an unknown feature should be counted", counters)
+	}
+}
+
+func TestTreatmentCtx(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(nil)
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "split1",
+		Status:            "ACTIVE",
+		Algo:              2,
+		TrafficAllocation: 100,
+		DefaultTreatment:  "off",
+		Conditions: []dtos.ConditionDTO{{
+			ConditionType: "ROLLOUT",
+			MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+			Partitions:    []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+		}},
+	}}, 123)
+
+	impressions := &impressionsCountingStorage{}
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if treatment := client.TreatmentCtx(ctx, "key", "split1", nil); treatment != "on" {
+		t.Error("Evaluations should not be affected by a live context. Got:", treatment)
+	}
+
+	cancel()
+	if treatment := client.TreatmentCtx(ctx, "key", "split1", nil); treatment != evaluator.Control {
+		t.Error("CONTROL should be returned if the context is cancelled. Got:", treatment)
+	}
+	if result := client.TreatmentWithConfigCtx(ctx, "key", "split1", nil); result.Treatment != evaluator.Control {
+		t.Error("CONTROL should be returned if the context is cancelled. Got:", result)
+	}
+	if treatments := client.TreatmentsCtx(ctx, "key", []string{"split1"}, nil); treatments["split1"] != evaluator.Control {
+		t.Error("CONTROL should be returned if the context is cancelled. Got:", treatments)
+	}
+	if treatments := client.TreatmentsWithConfigCtx(ctx, "key", []string{"split1"}, nil); treatments["split1"].Treatment != evaluator.Control {
+		t.Error("CONTROL should be returned if the context is cancelled. Got:", treatments)
+	}
+	if len(impressions.impressions) != 1 {
+		t.Error("No impressions should be stored for cancelled evaluations. Got:", impressions.impressions)
+	}
+}
+
+func TestClientWithContextRedis(t *testing.T) {
+	logger := logging.NewLogger(nil)
+	redisClient, err := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, Prefix: "withContext"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer redisClient.Close()
+	splitStorage := redisdb.NewRedisSplitStorage(redisClient, logger)
+	segmentStorage := redisdb.NewRedisSegmentStorage(redisClient, logger)
+	client := &SplitClient{
+		evaluator: evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+		logger:    logger,
+	}
+
+	if client.withContext(context.Background()) != client {
+		t.Error("The client itself should be used without a context")
+	}
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	bound, ok := client.withContext(ctx).evaluator.(*evaluator.Evaluator)
+	if !ok {
+		t.Fatal("The evaluator should be bound to the context")
+	}
+	if bound.SplitStorage().(*redisdb.RedisSplitStorage) == splitStorage || bound.SegmentStorage().(*redisdb.RedisSegmentStorage) == segmentStorage {
+		t.Error("The redis storages should be replaced by ones bound to the context")
+	}
+	if client.evaluator.(*evaluator.Evaluator).SplitStorage() != splitStorage {
+		t.Error("The original client should be left untouched")
+	}
+}
+
+func TestClientWithContextCached(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	redisClient, err := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, Prefix: "withContextCached"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer redisClient.Close()
+	splitStorage := redisdb.NewRedisSplitStorage(redisClient, logger)
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "valid",
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+			Partitions:   []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}},
+	}}, 1)
+	defer deleteDataGenerated(redisClient)
+	segmentStorage := redisdb.NewRedisSegmentStorage(redisClient, logger)
+
+	cached := evaluator.NewCachedEvaluator(
+		evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+		splitStorage,
+		10,
+		time.Minute,
+	)
+	factory := &SplitFactory{cfg: conf.Default(), logger: logger, storages: sdkStorages{splits: splitStorage, segments: segmentStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := &SplitClient{
+		evaluator:   cached,
+		impressions: &impressionsCountingStorage{},
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bound, ok := client.withContext(ctx).evaluator.(*evaluator.CachedEvaluator)
+	if !ok {
+		t.Fatal("The cached evaluator should be bound to the context")
+	}
+	if bound.SplitStorage() == splitStorage || bound.SegmentStorage() == segmentStorage {
+		t.Error("The redis storages should be replaced by ones bound to the context")
+	}
+
+	if treatment := client.TreatmentCtx(ctx, "key", "valid", nil); treatment != "on" {
+		t.Error("Evaluations should not be affected by a live context. Got:", treatment)
+	}
+	if treatment := client.Treatment("key", "valid", nil); treatment != "on" || cached.Hits() != 1 {
+		t.Error("Evaluations bound to a context should share the cache", treatment, cached.Hits())
+	}
+
+	cancel()
+	if treatment := client.TreatmentCtx(ctx, "key", "valid", nil); treatment != evaluator.Control {
+		t.Error("CONTROL should be returned if the context is cancelled, even if the evaluation is cached. Got:", treatment)
+	}
+}
+
+// contextRecordingSegments is a segment storage recording the context each membership read was bound to
+type contextRecordingSegments struct {
+	*mutexmap.MMSegmentStorage
+	ctx  context.Context
+	seen *[]context.Context
+}
+
+func (s *contextRecordingSegments) WithContext(ctx context.Context) storage.SegmentStorage {
+	return &contextRecordingSegments{MMSegmentStorage: s.MMSegmentStorage, ctx: ctx, seen: s.seen}
+}
+
+func (s *contextRecordingSegments) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	*s.seen = append(*s.seen, s.ctx)
+	return s.MMSegmentStorage.SegmentContainsKey(segmentName, key)
+}
+
+func TestClientWithContextSegmentWrappers(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := tenantSegmentSplitStorage()
+
+	wrappers := map[string]func(storage.SegmentStorage) storage.SegmentStorage{
+		"cache": func(inner storage.SegmentStorage) storage.SegmentStorage {
+			return storage.NewCachedSegmentStorage(inner, 10, time.Minute)
+		},
+		"chain": func(inner storage.SegmentStorage) storage.SegmentStorage {
+			return storage.NewChainedSegmentStorage(inner)
+		},
+	}
+	for name, wrap := range wrappers {
+		seen := make([]context.Context, 0)
+		segmentStorage := wrap(&contextRecordingSegments{MMSegmentStorage: tenantSegmentStorage("key1"), seen: &seen})
+
+		factory := &SplitFactory{cfg: conf.Default(), logger: logger, storages: sdkStorages{splits: splitStorage, segments: segmentStorage}}
+		factory.status.Store(sdkStatusReady)
+		client := &SplitClient{
+			evaluator:   evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+			impressions: &impressionsCountingStorage{},
+			metrics:     mutexmap.NewMMMetricsStorage(),
+			logger:      logger,
+			validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+			factory:     factory,
+		}
+
+		type ctxKey struct{}
+		ctx := context.WithValue(context.Background(), ctxKey{}, name)
+		if treatment := client.TreatmentCtx(ctx, "key1", "segment_split", nil); treatment != "on" {
+			t.Error(name, "evaluations should not be affected by a live context. Got:", treatment)
+		}
+		if len(seen) != 1 || seen[0] != ctx {
+			t.Error(name, "segment reads should be bound to the context of the evaluation. Got:", seen)
+		}
+
+		client.Treatment("key1", "segment_split", nil)
+		if name == "cache" && len(seen) != 1 {
+			t.Error("Memberships read through the context should be cached for every evaluation. Got:", len(seen))
+		}
+	}
+}
+
+func TestTreatmentsByFlagSet(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(nil)
+
+	allOn := []dtos.ConditionDTO{{
+		ConditionType: "ROLLOUT",
+		MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+		Partitions:    []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+	}}
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "split1", Status: "ACTIVE", Algo: 2, TrafficAllocation: 100, DefaultTreatment: "off", Conditions: allOn, Sets: []string{"backend"}},
+		{Name: "split2", Status: "ACTIVE", Algo: 2, TrafficAllocation: 100, DefaultTreatment: "off", Killed: true, Sets: []string{"backend"}},
+		{Name: "split3", Status: "ACTIVE", Algo: 2, TrafficAllocation: 100, DefaultTreatment: "off", Conditions: allOn, Sets: []string{"frontend"}},
+	}, 123)
+
+	impressions := &impressionsCountingStorage{}
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	treatments := client.TreatmentsByFlagSet("key", "backend", nil)
+	if len(treatments) != 2 || treatments["split1"] != "on" || treatments["split2"] != "off" {
+		t.Error("Every split in the flag set should be evaluated. Got:", treatments)
+	}
+	if len(impressions.impressions) != 2 {
+		t.Error("An impression should be stored per split in the flag set. Got:", impressions.impressions)
+	}
+
+	treatments = client.TreatmentsByFlagSet("key", "unknown", nil)
+	if treatments == nil || len(treatments) != 0 {
+		t.Error("Unknown flag sets should return an empty map. Got:", treatments)
+	}
+}
+
+func TestTreatmentWithDecision(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(nil)
+
+	whitelistCondition := func(key string, treatment string) dtos.ConditionDTO {
+		return dtos.ConditionDTO{
+			ConditionType: "WHITELIST",
+			Label:         "whitelisted " + key,
+			MatcherGroup: dtos.MatcherGroupDTO{
+				Combiner: "AND",
+				Matchers: []dtos.MatcherDTO{
+					{
+						MatcherType: "WHITELIST",
+						Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{key}},
+					},
+				},
+			},
+			Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: treatment}},
+		}
+	}
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:              2,
+			ChangeNumber:      123,
+			DefaultTreatment:  "off",
+			Name:              "multi_condition",
+			Status:            "ACTIVE",
+			TrafficAllocation: 100,
+			Conditions: []dtos.ConditionDTO{
+				whitelistCondition("key1", "on"),
+				whitelistCondition("key2", "v2"),
+				whitelistCondition("key3", "v3"),
+			},
+		},
+	}, 123)
+
+	factory := &SplitFactory{cfg: cfg}
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+		logger:      logger,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+	factory.status.Store(sdkStatusReady)
+
+	decision := client.TreatmentWithDecision("key1", "multi_condition", nil)
+	if decision.Treatment != "on" || decision.MatchedConditionIndex != 0 || decision.Label != "whitelisted key1" || decision.ChangeNumber != 123 {
+		t.Error("key1 should match the first condition", decision)
+	}
+
+	decision = client.TreatmentWithDecision("key3", "multi_condition", nil)
+	if decision.Treatment != "v3" || decision.MatchedConditionIndex != 2 {
+		t.Error("key3 should match the third condition", decision)
+	}
+
+	decision = client.TreatmentWithDecision("other", "multi_condition", nil)
+	if decision.Treatment != "off" || decision.MatchedConditionIndex != -1 || decision.Label != impressionlabels.NoConditionMatched {
+		t.Error("Default rule should not report a condition index", decision)
+	}
+
+	decision = client.TreatmentWithDecision("key1", "nonexistent", nil)
+	if decision.Treatment != evaluator.Control || decision.MatchedConditionIndex != -1 {
+		t.Error("Missing splits should not report a condition index", decision)
+	}
+
+	treatment, label, changeNumber := client.TreatmentWithLabel("key2", "multi_condition", nil)
+	if treatment != "v2" || label != "whitelisted key2" || changeNumber != 123 {
+		t.Error("Unexpected treatment, label or changeNumber", treatment, label, changeNumber)
+	}
+
+	treatment, label, changeNumber = client.TreatmentWithLabel("key1", "nonexistent", nil)
+	if treatment != evaluator.Control || label != impressionlabels.SplitNotFound || changeNumber != 0 {
+		t.Error("Missing splits should return control with the split not found label", treatment, label, changeNumber)
+	}
+
+	impressions, _ := client.impressions.(*mutexqueue.MQImpressionsStorage).PopN(100)
+	if len(impressions) != 4 {
+		t.Error("Each evaluation of an existing split should store an impression. Got:", len(impressions))
+	}
+}
+
+func TestNotReadySnapshot(t *testing.T) {
+	cfg := conf.Default()
+	cfg.LabelsEnabled = true
+	logger := logging.NewLogger(nil)
+
+	snapshot := &dtos.SnapshotDTO{
+		Till: 123,
+		Splits: []dtos.SplitDTO{
+			{
+				Algo:              2,
+				ChangeNumber:      123,
+				DefaultTreatment:  "off",
+				Name:              "snapshot_split",
+				Status:            "ACTIVE",
+				TrafficAllocation: 100,
+				Conditions: []dtos.ConditionDTO{
+					{
+						ConditionType: "ROLLOUT",
+						Label:         "default rule",
+						MatcherGroup: dtos.MatcherGroupDTO{
+							Combiner: "AND",
+							Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}},
+						},
+						Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+					},
+				},
+			},
+		},
+	}
+
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:      mutexmap.NewMMSplitStorage(),
+			segments:    mutexmap.NewMMSegmentStorage(),
+			impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+			telemetry:   mutexmap.NewMMMetricsStorage(),
+		},
+		logger:   logger,
+		snapshot: newSnapshotStorages(snapshot),
+	}
+	factory.status.Store(sdkStatusInitializing)
+	client := factory.Client()
+
+	expectedTreatment(client.Treatment("user1", "snapshot_split", nil), "on", t)
+	impressions, _ := client.impressions.(storage.ImpressionStorage).PopN(cfg.Advanced.ImpressionsBulkSize)
+	if len(impressions) != 1 || impressions[0].Label != impressionlabels.NotReadyFromSnapshot {
+		t.Error("Impressions served from the snapshot should be labeled accordingly", impressions)
+	}
+
+	treatments := client.Treatments("user1", []string{"snapshot_split", "other_split"}, nil)
+	expectedTreatment(treatments["snapshot_split"], "on", t)
+	expectedTreatment(treatments["other_split"], evaluator.Control, t)
+
+	factory.snapshot = nil
+	expectedTreatment(factory.Client().Treatment("user1", "snapshot_split", nil), evaluator.Control, t)
+}
+
+func TestPersistedSnapshot(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(nil)
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	cfg.Advanced.SnapshotFile = path.Join(dir, "splits.snapshot")
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{*valid}, 123)
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	segmentStorage.Put("employees", set.NewSet("user1"), 123)
+
+	err := storage.WriteSnapshotFile(cfg.Advanced.SnapshotFile, storage.ExportSnapshot(splitStorage, segmentStorage))
+	if err != nil {
+		t.Error("Snapshot should have been persisted", err)
+	}
+
+	// A new factory pointing to the same file serves treatments from it until it's ready
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:      mutexmap.NewMMSplitStorage(),
+			segments:    mutexmap.NewMMSegmentStorage(),
+			impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+			telemetry:   mutexmap.NewMMMetricsStorage(),
+		},
+		logger: logger,
+	}
+	snapshot := loadNotReadySnapshot(cfg, logger)
+	if snapshot == nil {
+		t.Error("The persisted snapshot should have been loaded")
+		return
+	}
+	factory.snapshot = newSnapshotStorages(snapshot)
+	factory.status.Store(sdkStatusInitializing)
+
+	client := factory.Client()
+	expectedTreatment(client.Treatment("user1", "valid", nil), "on", t)
+	expectedTreatment(client.Treatment("user2", "valid", nil), "off", t)
+}
+
+func TestLocalhostModeYAML(t *testing.T) {
+	sdkConf := conf.Default()
+	sdkConf.SplitFile = "../../testdata/splits.yaml"
+	factory, _ := NewSplitFactory("localhost", sdkConf)
+	client := factory.Client()
+	manager := factory.Manager()
+
+	_ = client.BlockUntilReady(5)
+
+	if !client.isReady() {
+		t.Error("Localhost should be ready")
+	}
+
+	if client.factory.cfg.OperationMode != "localhost" {
+		t.Error("Localhost operation mode should be set when received apikey is 'localhost'")
+	}
+
+	if len(manager.Splits()) != 4 {
+		t.Error("Error grabbing splits for localhost mode")
+	}
+
+	expectedTreatment(client.Treatment("only_key", "my_feature", nil), "off", t)
+	expectedTreatment(client.Treatment("invalid_key", "my_feature", nil), "control", t)
+	expectedTreatment(client.Treatment("key", "my_feature", nil), "on", t)
+	expectedTreatment(client.Treatment("key2", "other_feature", nil), "on", t)
+	expectedTreatment(client.Treatment("test", "other_feature_2", nil), "on", t)
+	expectedTreatment(client.Treatment("key", "other_feature_3", nil), "off", t)
+	expectedTreatment(client.Treatment("key_whitelist", "other_feature_3", nil), "on", t)
+
+	expectedTreatmentAndConfig(client.TreatmentWithConfig("only_key", "my_feature", nil), "off", "{\"desc\" : \"this applies only to OFF and only for only_key. The rest will receive ON\"}", t)
+	expectedTreatmentAndConfig(client.TreatmentWithConfig("key", "my_feature", nil), "on", "{\"desc\" : \"this applies only to ON treatment\"}", t)
+	expectedTreatmentAndConfig(client.TreatmentWithConfig("key3", "other_feature", nil), "on", "", t)
+
+	resultTreatments := client.Treatments("only_key", []string{"my_feature", "other_feature"}, nil)
+	expectedTreatment(resultTreatments["my_feature"], "off", t)
+	expectedTreatment(resultTreatments["other_feature"], "control", t)
+
+	resultTreatmentsWithConfig := client.TreatmentsWithConfig("only_key", []string{"my_feature", "other_feature"}, nil)
+	expectedTreatmentAndConfig(resultTreatmentsWithConfig["my_feature"], "off", "{\"desc\" : \"this applies only to OFF and only for only_key. The rest will receive ON\"}", t)
+	expectedTreatmentAndConfig(resultTreatmentsWithConfig["other_feature"], "control", "", t)
+}
+
+func getRedisConfWithIP(IPAddressesEnabled bool) *redisdb.PrefixedRedisClient {
+	// Create prefixed client for adding Split
+	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	})
+
+	splitStorage := redisdb.NewRedisSplitStorage(prefixedClient, logger)
+	splitStorage.PutMany([]dtos.SplitDTO{*valid}, 1494593336752)
+
+	// Set default configs to connect Client with redis
+	cfg := conf.Default()
+	cfg.LabelsEnabled = true
+	cfg.IPAddressesEnabled = IPAddressesEnabled
+	cfg.Advanced.ImpressionListener = &ImpressionListenerTest{}
+	cfg.OperationMode = "redis-consumer"
+	cfg.Redis = conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	}
+
+	factory, _ := NewSplitFactory("apikey", cfg)
+	client := factory.Client()
+
+	// Deletes previous data
+	prefixedClient.Del("SPLITIO.impressions", "SPLITIO.events")
+
+	// Calls treatments to generate one valid impression
+	client.Treatment("user1", "valid", nil)
+	client.Track("user1", "my-traffic", "my-event", nil, nil)
+
+	return prefixedClient
+}
+
+func deleteDataGenerated(prefixedClient *redisdb.PrefixedRedisClient) {
+	// Deletes generated data
+	keys, _ := prefixedClient.Keys(fmt.Sprintf("SPLITIO/go-%s/*/latency.sdk.getTreatment.bucket.*", splitio.Version))
+	keys = append(keys, "SPLITIO.impressions", "SPLITIO.events", "SPLITIO.split.valid", "SPLITIO.splits.till")
+	prefixedClient.Del(keys...)
+}
+
+func TestImpressionAttributesOnlyInListener(t *testing.T) {
+	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	})
+	redisdb.NewRedisSplitStorage(prefixedClient, logger).PutMany([]dtos.SplitDTO{*valid}, 1494593336752)
+	defer deleteDataGenerated(prefixedClient)
+
+	cfg := conf.Default()
+	cfg.Advanced.ImpressionListener = &ImpressionListenerTest{}
+	cfg.OperationMode = "redis-consumer"
+	cfg.Redis = conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	}
+
+	factory, _ := NewSplitFactory("apikey", cfg)
+	client := factory.Client()
+	prefixedClient.Del("SPLITIO.impressions")
+
+	client.Treatment("user1", "valid", map[string]interface{}{"email": "someone@split.io"})
+
+	listenerData, _ := ilResult["valid"].(map[string]interface{})
+	attributes, _ := listenerData["Attributes"].(map[string]interface{})
+	if attributes["email"] != "someone@split.io" {
+		t.Error("Impression listener should receive the evaluation attributes")
+	}
+
+	stored, _ := prefixedClient.LRange("SPLITIO.impressions", 0, -1).Result()
+	if len(stored) != 1 {
+		t.Error("One impression should have been stored")
+		return
+	}
+	if strings.Contains(stored[0], "email") || strings.Contains(stored[0], "someone@split.io") {
+		t.Error("Stored impressions should not carry the evaluation attributes", stored[0])
+	}
+}
+
+func TestResetTelemetry(t *testing.T) {
+	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	})
+	redisdb.NewRedisSplitStorage(prefixedClient, logger).PutMany([]dtos.SplitDTO{*valid}, 1494593336752)
+	defer deleteDataGenerated(prefixedClient)
+
+	cfg := conf.Default()
+	cfg.OperationMode = "redis-consumer"
+	cfg.InstanceName = "this-instance"
+	cfg.Redis = conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	}
+
+	factory, _ := NewSplitFactory("apikey", cfg)
+	client := factory.Client()
+	prefixedClient.Del("SPLITIO.impressions")
+
+	// Telemetry recorded by another instance sharing the same redis
+	otherMetadata := &splitio.SdkMetadata{SDKVersion: "go-" + splitio.Version, MachineName: "other-instance", MachineIP: "1.2.3.4"}
+	redisdb.NewRedisImpressionStorage(prefixedClient, otherMetadata, logger).LogImpressions([]storage.Impression{
+		{FeatureName: "valid", KeyName: "other", Treatment: "on"},
+	})
+	otherMetrics := redisdb.NewRedisMetricsStorage(prefixedClient, otherMetadata, logger)
+	otherMetrics.IncCounter("other.counter")
+	otherMetrics.IncLatency("other.latency", 3)
+
+	client.Treatment("user1", "valid", nil)
+	client.Treatment("user2", "valid", nil)
+	client.metrics.IncCounter("some.counter")
+	client.metrics.PutGauge("some.gauge", 1)
+
+	instanceKeys := fmt.Sprintf("SPLITIO/go-%s/this-instance/*", splitio.Version)
+	if keys, _ := prefixedClient.Keys(instanceKeys); len(keys) == 0 {
+		t.Error("Metrics should have been recorded for this instance")
+	}
+
+	if err := client.ResetTelemetry(); err != nil {
+		t.Error("Telemetry should have been reset", err)
+	}
+
+	impressions, _ := prefixedClient.LRange("SPLITIO.impressions", 0, -1).Result()
+	if len(impressions) != 1 || !strings.Contains(impressions[0], "other-instance") {
+		t.Error("Only the impressions of this instance should have been removed", impressions)
+	}
+
+	if keys, _ := prefixedClient.Keys(instanceKeys); len(keys) != 0 {
+		t.Error("Metrics of this instance should have been removed", keys)
+	}
+
+	otherKeys, _ := prefixedClient.Keys(fmt.Sprintf("SPLITIO/go-%s/other-instance/*", splitio.Version))
+	if len(otherKeys) != 2 {
+		t.Error("Metrics of other instances should be kept", otherKeys)
+	}
+	prefixedClient.Del(otherKeys...)
+
+	if exists, _ := prefixedClient.Exists("SPLITIO.split.valid"); !exists {
+		t.Error("Splits should not be affected")
+	}
+}
+
+func TestRedisClientWithIPDisabled(t *testing.T) {
+	prefixedClient := getRedisConfWithIP(false)
+	// Grabs created impression
 	jsonImpr, _ := prefixedClient.LRange("SPLITIO.impressions", 0, 1).Result()
 	impression := make(map[string]map[string]interface{})
 	json.Unmarshal([]byte(jsonImpr[0]), &impression)
 	metadata := impression["m"]
 	// Checks if metadata was created with "NA" values
+	if metadata["i"] != "NA" || metadata["n"] != "NA" {
+		t.Error("Instance Name and Machine IP should have 'NA' values")
+	}
+	listenerData, _ := ilResult["valid"].(map[string]interface{})
+	if listenerData["InstanceName"] != "NA" {
+		t.Error("InstanceName should be 'NA")
+	}
+
+	// Grabs created event
+	jsonEvent, _ := prefixedClient.LRange("SPLITIO.events", 0, 1).Result()
+	event := make(map[string]map[string]interface{})
+	json.Unmarshal([]byte(jsonEvent[0]), &event)
+	metadata = event["m"]
+	// Checks if metadata was created with "NA" values
+	if metadata["i"] != "NA" || metadata["n"] != "NA" {
+		t.Error("Instance Name and Machine IP should have 'NA' values")
+	}
+
+	deleteDataGenerated(prefixedClient)
+}
+
+func TestRedisClientWithIPEnabled(t *testing.T) {
+	prefixedClient := getRedisConfWithIP(true)
+	// Grabs created impression
+	jsonImpr, _ := prefixedClient.LRange("SPLITIO.impressions", 0, 1).Result()
+	impression := make(map[string]map[string]interface{})
+	json.Unmarshal([]byte(jsonImpr[0]), &impression)
+	metadata := impression["m"]
+	// Checks if metadata was created with "NA" values
+	if metadata["i"] == "NA" || metadata["n"] == "NA" {
+		t.Error("Instance Name and Machine IP should not have 'NA' values")
+	}
+	listenerData, _ := ilResult["valid"].(map[string]interface{})
+	if listenerData["InstanceName"] == "NA" {
+		t.Error("InstanceName should not be 'NA")
+	}
+
+	// Grabs created event
+	jsonEvent, _ := prefixedClient.LRange("SPLITIO.events", 0, 1).Result()
+	event := make(map[string]map[string]interface{})
+	json.Unmarshal([]byte(jsonEvent[0]), &event)
+	metadata = event["m"]
+	// Checks if metadata was created with "NA" values
 	if metadata["i"] == "NA" || metadata["n"] == "NA" {
 		t.Error("Instance Name and Machine IP should not have 'NA' values")
 	}
-	listenerData, _ := ilResult["valid"].(map[string]interface{})
-	if listenerData["InstanceName"] == "NA" {
-		t.Error("InstanceName should not be 'NA")
+
+	deleteDataGenerated(prefixedClient)
+}
+
+type errorsWriter struct {
+	messages []string
+}
+
+func (w *errorsWriter) Write(p []byte) (n int, err error) {
+	w.messages = append(w.messages, string(p))
+	return len(p), nil
+}
+
+func (w *errorsWriter) contains(message string) bool {
+	for _, m := range w.messages {
+		if strings.Contains(m, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRedisPrefixValidation(t *testing.T) {
+	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "synchronizerPrefix",
+	})
+	redisdb.NewRedisSplitStorage(prefixedClient, logger).PutMany([]dtos.SplitDTO{*valid}, 1494593336752)
+	defer prefixedClient.Del("SPLITIO.split.valid", "SPLITIO.splits.till")
+
+	metadata := &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "some_machine"}
+	expectedMessage := "No split data found in redis under prefix \"otherPrefix\""
+
+	sdkConf := conf.Default()
+	sdkConf.Redis.Database = 1
+	sdkConf.Redis.Prefix = "synchronizerPrefix"
+	writer := &errorsWriter{}
+	_, err := setupRedisFactory("something", sdkConf, logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelError, ErrorWriter: writer}), metadata)
+	if err != nil {
+		t.Error("No error was expected for a matching prefix", err)
+	}
+	if len(writer.messages) != 0 {
+		t.Error("No errors should be logged for a matching prefix", writer.messages)
+	}
+
+	sdkConf.Redis.Prefix = "otherPrefix"
+	writer = &errorsWriter{}
+	factory, err := setupRedisFactory("something", sdkConf, logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelError, ErrorWriter: writer}), metadata)
+	if err != nil || factory == nil {
+		t.Error("A mismatched prefix should not fail instantiation by default", err)
+	}
+	if !writer.contains(expectedMessage) {
+		t.Error("A mismatched prefix should be reported", writer.messages)
+	}
+
+	sdkConf.Redis.FailOnMissingData = true
+	writer = &errorsWriter{}
+	factory, err = setupRedisFactory("something", sdkConf, logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelError, ErrorWriter: writer}), metadata)
+	if err == nil || factory != nil || !strings.Contains(err.Error(), expectedMessage) {
+		t.Error("A mismatched prefix should fail instantiation when FailOnMissingData is set")
+	}
+}
+
+func getInMemoryClientWithIP(IPAddressesEnabled bool, ts *httptest.Server) SplitClient {
+	// Set default configs to connect Client with redis
+	cfg := conf.Default()
+	cfg.LabelsEnabled = true
+	cfg.IPAddressesEnabled = IPAddressesEnabled
+	cfg.Advanced.EventsURL = ts.URL
+	cfg.Advanced.SdkURL = ts.URL
+	cfg.Advanced.ImpressionListener = &ImpressionListenerTest{}
+	cfg.LoggerConfig.LogLevel = logging.LevelDebug
+	cfg.TaskPeriods.ImpressionSync = 3
+	cfg.TaskPeriods.EventsSync = 3
+
+	factory, _ := NewSplitFactory("test", cfg)
+	client := factory.Client()
+	client.BlockUntilReady(2)
+	return *client
+}
+
+func TestClientWithIPEnabled(t *testing.T) {
+	var splitsMock, _ = ioutil.ReadFile("../../testdata/splits_mock.json")
+	var splitMock, _ = ioutil.ReadFile("../../testdata/split_mock.json")
+
+	postChannel := make(chan string, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/splitChanges":
+			fmt.Fprintln(w, fmt.Sprintf(string(splitsMock), splitMock))
+			return
+		case "/segmentChanges/___TEST___":
+			w.Header().Add("Content-Encoding", "gzip")
+			gzw := gzip.NewWriter(w)
+			defer gzw.Close()
+			fmt.Fprintln(gzw, "Hello, client")
+			return
+		case "/testImpressions/bulk":
+		case "/events/bulk":
+			if r.Header.Get("SplitSDKMachineIP") == "NA" || r.Header.Get("SplitSDKMachineName") == "NA" {
+				t.Error("It should not be NA when IPAddressesEnabled has been set")
+			}
+
+			rBody, _ := ioutil.ReadAll(r.Body)
+			var dataInPost []map[string]interface{}
+			err := json.Unmarshal(rBody, &dataInPost)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if len(dataInPost) < 1 {
+				t.Error("It should send data")
+			}
+			fmt.Fprintln(w, "ok")
+			postChannel <- "finished"
+		case "/segmentChanges":
+		default:
+			fmt.Fprintln(w, "ok")
+			return
+		}
+	}))
+	defer ts.Close()
+
+	client := getInMemoryClientWithIP(true, ts)
+	// Calls treatments to generate one valid impression
+	client.Track("user1", "my-traffic", "my-event", nil, nil)
+	client.Treatment("user1", "DEMO_MURMUR2", nil)
+
+	listenerData, _ := ilResult["DEMO_MURMUR2"].(map[string]interface{})
+	if listenerData["InstanceName"] == "NA" {
+		t.Error("InstanceName should not be 'NA")
+	}
+
+	select {
+	case <-postChannel:
+		return
+	case <-time.After(4 * time.Second):
+		t.Error("The test couldn't send impressions to check headers")
+		return
+	}
+}
+
+func TestClientWithIPDisabled(t *testing.T) {
+	var splitsMock, _ = ioutil.ReadFile("../../testdata/splits_mock.json")
+	var splitMock, _ = ioutil.ReadFile("../../testdata/split_mock.json")
+
+	postChannel := make(chan string, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/splitChanges":
+			fmt.Fprintln(w, fmt.Sprintf(string(splitsMock), splitMock))
+			return
+		case "/segmentChanges/___TEST___":
+			w.Header().Add("Content-Encoding", "gzip")
+			gzw := gzip.NewWriter(w)
+			defer gzw.Close()
+			fmt.Fprintln(gzw, "Hello, client")
+			return
+		case "/testImpressions/bulk":
+		case "/events/bulk":
+			for header := range r.Header {
+				if (header == "SplitSDKMachineIP") || (header == "SplitSDKMachineName") {
+					t.Error("Should not insert one of SplitSDKMachineIP, SplitSDKMachineName")
+				}
+			}
+
+			rBody, _ := ioutil.ReadAll(r.Body)
+			var dataInPost []map[string]interface{}
+			err := json.Unmarshal(rBody, &dataInPost)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if len(dataInPost) < 1 {
+				t.Error("It should send data")
+			}
+			fmt.Fprintln(w, "ok")
+			postChannel <- "finished"
+		case "/segmentChanges":
+		default:
+			fmt.Fprintln(w, "ok")
+			return
+		}
+	}))
+	defer ts.Close()
+
+	client := getInMemoryClientWithIP(false, ts)
+
+	// Calls treatments to generate one valid impression
+	client.Track("user1", "my-traffic", "my-event", nil, nil)
+	client.Treatment("user1", "DEMO_MURMUR2", nil)
+
+	listenerData, _ := ilResult["DEMO_MURMUR2"].(map[string]interface{})
+	if listenerData["InstanceName"] != "NA" {
+		t.Error("InstanceName should be 'NA")
+	}
+
+	select {
+	case <-postChannel:
+		return
+	case <-time.After(4 * time.Second):
+		t.Error("The test couldn't send impressions to check headers")
+		return
+	}
+}
+
+func TestTreatmentAndTrack(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	cfg := conf.Default()
+	factory := &SplitFactory{cfg: cfg}
+	factory.status.Store(sdkStatusReady)
+	events := mutexqueue.NewMQEventsStorage(10, make(chan string, 1), logger)
+	client := SplitClient{
+		evaluator:   &mockEvaluator{},
+		impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+		events:      events,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: &mockSplitStorage{}},
+		factory:     factory,
+	}
+
+	value := 2.5
+	if treatment := client.TreatmentAndTrack("key", "user", "feature", "conversion", &value, nil); treatment != "TreatmentA" {
+		t.Error("The evaluated treatment should be returned", treatment)
+	}
+
+	queued, _ := events.PopN(10)
+	if len(queued) != 1 {
+		t.Error("An event should have been tracked", queued)
+		return
+	}
+	event := queued[0]
+	if event.EventTypeID != "conversion" || event.Value != 2.5 || event.Properties["feature"] != "feature" || event.Properties["treatment"] != "TreatmentA" {
+		t.Error("The event should carry the feature and the treatment served", event)
+	}
+
+	if treatment := client.TreatmentAndTrack("key", "", "feature2", "conversion", nil, nil); treatment != "TreatmentB" {
+		t.Error("The treatment should be returned even if the event is invalid", treatment)
+	}
+	if queued, _ = events.PopN(10); len(queued) != 0 {
+		t.Error("Invalid events should not be tracked", queued)
+	}
+}
+
+func TestLocalhostModeYAMLDistribution(t *testing.T) {
+	sdkConf := conf.Default()
+	sdkConf.SplitFile = "../../testdata/splits_distribution.yaml"
+	factory, _ := NewSplitFactory("localhost", sdkConf)
+	client := factory.Client()
+	defer client.Destroy()
+
+	if err := client.BlockUntilReady(5); err != nil {
+		t.Error("Localhost should be ready", err)
+		return
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[client.Treatment(fmt.Sprintf("key%d", i), "rollout_feature", nil)]++
+	}
+	if len(counts) != 2 || counts["on"] < 4500 || counts["on"] > 5500 || counts["off"] < 4500 || counts["off"] > 5500 {
+		t.Error("Keys should be evenly distributed between on & off", counts)
+	}
+
+	expectedTreatment(client.Treatment("excluded_key", "rollout_feature", nil), "off", t)
+}
+
+// concurrencyTrackingEvaluator records the maximum number of evaluations running at the same time
+type concurrencyTrackingEvaluator struct {
+	mockEvaluator
+	running int64
+	max     int64
+	delay   time.Duration
+}
+
+func (e *concurrencyTrackingEvaluator) EvaluateFeature(
+	key string,
+	bucketingKey *string,
+	feature string,
+	attributes map[string]interface{},
+) *evaluator.Result {
+	running := atomic.AddInt64(&e.running, 1)
+	defer atomic.AddInt64(&e.running, -1)
+	for {
+		max := atomic.LoadInt64(&e.max)
+		if running <= max || atomic.CompareAndSwapInt64(&e.max, max, running) {
+			break
+		}
+	}
+	time.Sleep(e.delay)
+	return e.mockEvaluator.EvaluateFeature(key, bucketingKey, feature, attributes)
+}
+
+func TestConcurrentEvaluationsLimit(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	cfg := conf.Default()
+	factory := &SplitFactory{cfg: cfg}
+	factory.status.Store(sdkStatusReady)
+	tracking := &concurrencyTrackingEvaluator{delay: 5 * time.Millisecond}
+	client := SplitClient{
+		evaluator:         tracking,
+		impressions:       mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+		metrics:           mutexmap.NewMMMetricsStorage(),
+		logger:            logger,
+		validator:         inputValidation{logger: logger, splitStorage: &mockSplitStorage{}},
+		factory:           factory,
+		evaluationSlots:   ratelimit.NewSemaphore(1),
+		evaluationTimeout: 10 * time.Second,
+	}
+
+	done := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		go func() { done <- client.Treatment("key", "feature", nil) }()
+	}
+	for i := 0; i < 10; i++ {
+		if treatment := <-done; treatment != "TreatmentA" {
+			t.Error("Evaluations should wait for a slot", treatment)
+		}
+	}
+	if atomic.LoadInt64(&tracking.max) != 1 {
+		t.Error("Evaluations should be serialized through the semaphore", tracking.max)
+	}
+
+	client.evaluationTimeout = time.Millisecond
+	client.evaluationSlots.Acquire(0)
+	decision := client.TreatmentWithDecision("key", "feature", nil)
+	if decision.Treatment != evaluator.Control || decision.Label != impressionlabels.Overloaded {
+		t.Error("Evaluations that can't get a slot in time should return CONTROL", decision)
+	}
+
+	writer := &errorsWriter{}
+	client.logger = logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer})
+	client.metrics = mutexmap.NewMMMetricsStorage()
+	factory.overloadedWarnedAt = time.Time{}
+	for i := 0; i < 3; i++ {
+		client.Treatment("key", "feature", nil)
+	}
+	client.Treatments("key", []string{"feature"}, nil)
+	if len(writer.messages) != 1 {
+		t.Error("Overloaded evaluations should be warned about once per interval. Got:", writer.messages)
+	}
+	counted := int64(0)
+	for _, counter := range client.metrics.(*mutexmap.MMMetricsStorage).PopCounters() {
+		if counter.MetricName == OverloadedCounter {
+			counted = counter.Count
+		}
+	}
+	if counted != 4 {
+		t.Error("Every overloaded evaluation should be counted. Got:", counted)
+	}
+	client.evaluationSlots.Release()
+}
+
+func TestWhatIf(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	rollout := func(name string, treatment string) dtos.SplitDTO {
+		return dtos.SplitDTO{
+			Name:              name,
+			Status:            "ACTIVE",
+			DefaultTreatment:  "off",
+			TrafficAllocation: 100,
+			Algo:              2,
+			Conditions: []dtos.ConditionDTO{{
+				ConditionType: "ROLLOUT",
+				MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+				Partitions:    []dtos.PartitionDTO{{Size: 100, Treatment: treatment}},
+			}},
+		}
+	}
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{rollout("changed", "on"), rollout("unchanged", "on")}, 123)
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	impressions := &impressionsCountingStorage{}
+	factory := &SplitFactory{
+		cfg:      cfg,
+		logger:   logger,
+		storages: sdkStorages{splits: splitStorage, segments: segmentStorage},
+	}
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		logger:      logger,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+	factory.status.Store(sdkStatusReady)
+
+	whatIf := client.WhatIf(
+		[]dtos.SplitDTO{rollout("changed", "off"), rollout("proposed", "v2")},
+		"user1",
+		[]string{"changed", "unchanged", "proposed", "missing"},
+		nil,
+	)
+	expected := map[string]string{"changed": "off", "unchanged": "on", "proposed": "v2", "missing": evaluator.Control}
+	for feature, treatment := range expected {
+		if whatIf[feature] != treatment {
+			t.Error("Wrong what-if treatment for", feature, whatIf[feature])
+		}
+	}
+
+	if impressions.writes != 0 {
+		t.Error("What-if evaluations should not store impressions")
+	}
+
+	if live := client.Treatment("user1", "changed", nil); live != "on" {
+		t.Error("Proposed splits should not affect live evaluations", live)
+	}
+	if splitStorage.Get("proposed") != nil || splitStorage.Get("changed").Conditions[0].Partitions[0].Treatment != "on" {
+		t.Error("Proposed splits should not be written to storage")
+	}
+}
+
+func TestTrimKeys(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	split := dtos.SplitDTO{
+		Name:              "feature",
+		Status:            "ACTIVE",
+		DefaultTreatment:  "off",
+		TrafficAllocation: 100,
+		Algo:              2,
+		Conditions: []dtos.ConditionDTO{
+			{
+				ConditionType: "WHITELIST",
+				MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{
+					MatcherType: "WHITELIST",
+					Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"vip"}},
+				}}},
+				Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "vip"}},
+			},
+			{
+				ConditionType: "ROLLOUT",
+				MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+				Partitions:    []dtos.PartitionDTO{{Size: 50, Treatment: "on"}, {Size: 50, Treatment: "off"}},
+			},
+		},
+	}
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{split}, 123)
+	factory := &SplitFactory{cfg: cfg}
+	factory.status.Store(sdkStatusReady)
+	newClient := func(trimKeys bool, impressions storage.ImpressionStorageProducer) *SplitClient {
+		return &SplitClient{
+			evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+			impressions: impressions,
+			logger:      logger,
+			metrics:     mutexmap.NewMMMetricsStorage(),
+			validator:   inputValidation{logger: logger, splitStorage: splitStorage, trimKeys: trimKeys},
+			factory:     factory,
+		}
+	}
+
+	impressions := &impressionsCountingStorage{}
+	trimming := newClient(true, impressions)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user%d", i)
+		if padded, trimmed := trimming.Treatment(" \t"+key+" ", "feature", nil), trimming.Treatment(key, "feature", nil); padded != trimmed {
+			t.Error("Padded keys should be bucketed as the trimmed ones", key, padded, trimmed)
+		}
+	}
+	if treatment := trimming.Treatment(&Key{MatchingKey: " vip ", BucketingKey: " bucket "}, "feature", nil); treatment != "vip" {
+		t.Error("Padded keys should match whitelists", treatment)
+	}
+	for _, impression := range impressions.impressions {
+		if strings.TrimSpace(impression.KeyName) != impression.KeyName || strings.TrimSpace(impression.BucketingKey) != impression.BucketingKey {
+			t.Error("Impressions should carry the trimmed keys", impression)
+		}
+	}
+
+	if treatment := newClient(false, &impressionsCountingStorage{}).Treatment(" vip ", "feature", nil); treatment == "vip" {
+		t.Error("Keys should not be trimmed by default")
+	}
+}
+
+func TestTreatmentWithSegmentOverrides(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "gated",
+		Status:            "ACTIVE",
+		DefaultTreatment:  "off",
+		TrafficAllocation: 100,
+		Algo:              2,
+		Conditions: []dtos.ConditionDTO{{
+			ConditionType: "ROLLOUT",
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{
+				MatcherType:        "IN_SEGMENT",
+				UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "employees"},
+			}}},
+			Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+			Label:      "in segment employees",
+		}},
+	}}, 123)
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	impressions := &impressionsCountingStorage{}
+	factory := &SplitFactory{
+		cfg:      cfg,
+		logger:   logger,
+		storages: sdkStorages{splits: splitStorage, segments: segmentStorage},
+	}
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		logger:      logger,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+	factory.status.Store(sdkStatusReady)
+
+	member := client.TreatmentWithSegmentOverrides("user1", "gated", nil, map[string]bool{"employees": true})
+	if member.Treatment != "on" || member.Label != "in segment employees - "+impressionlabels.SegmentOverride {
+		t.Error("Overridden members should match the segment", member)
+	}
+
+	notMember := client.TreatmentWithSegmentOverrides("user1", "gated", nil, map[string]bool{"employees": false})
+	if notMember.Treatment != "off" || notMember.Label != impressionlabels.NoConditionMatched+" - "+impressionlabels.SegmentOverride {
+		t.Error("Overridden non members should not match the segment", notMember)
+	}
+
+	if len(impressions.impressions) != 2 || impressions.impressions[0].Label != member.Label {
+		t.Error("Impressions should carry the segment override label", impressions.impressions)
+	}
+
+	if treatment := client.Treatment("user1", "gated", nil); treatment != "off" {
+		t.Error("Overrides should not affect regular evaluations", treatment)
+	}
+}
+
+type regionEnricher struct{}
+
+func (r *regionEnricher) Enrich(key string, attributes map[string]interface{}) map[string]interface{} {
+	if ip, ok := attributes["ip"].(string); ok && strings.HasPrefix(ip, "10.") {
+		return map[string]interface{}{"region": "eu-west"}
+	}
+	return nil
+}
+
+func TestImpressionEnricher(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	factory := &SplitFactory{cfg: cfg}
+	factory.status.Store(sdkStatusReady)
+	impressions := &impressionsCountingStorage{}
+	client := SplitClient{
+		evaluator:          &mockEvaluator{},
+		impressions:        impressions,
+		logger:             logger,
+		metrics:            mutexmap.NewMMMetricsStorage(),
+		validator:          inputValidation{logger: logger, splitStorage: mutexmap.NewMMSplitStorage()},
+		factory:            factory,
+		impressionEnricher: impressionenricher.NewImpressionEnricherWrapper(&regionEnricher{}, time.Second, logger),
+	}
+
+	client.Treatments("user1", []string{"feature", "feature2"}, map[string]interface{}{"ip": "10.0.0.1"})
+	client.Treatment("user2", "feature", map[string]interface{}{"ip": "192.168.0.1"})
+
+	if len(impressions.impressions) != 3 {
+		t.Error("Three impressions should have been stored", impressions.impressions)
+		return
+	}
+	for _, impression := range impressions.impressions[:2] {
+		if impression.Properties != `{"region":"eu-west"}` {
+			t.Error("Enriched properties should be added to the stored impression", impression)
+		}
+	}
+	if impressions.impressions[2].Properties != "" {
+		t.Error("No properties should be added if the enricher returns none", impressions.impressions[2])
+	}
+}
+
+func TestCombinedReadiness(t *testing.T) {
+	cfg := conf.Default()
+	readyFactory := &SplitFactory{cfg: cfg}
+	readyFactory.status.Store(sdkStatusReady)
+	syncingFactory := &SplitFactory{cfg: cfg}
+	syncingFactory.status.Store(sdkStatusInitializing)
+	ready := &SplitClient{factory: readyFactory}
+	syncing := &SplitClient{factory: syncingFactory}
+
+	if CombinedReadiness(ready, syncing) {
+		t.Error("Combined readiness should be false while any client is not ready")
+	}
+	if CombinedReadiness() || CombinedReadiness(ready, nil) {
+		t.Error("Combined readiness should be false without clients or with nil ones")
+	}
+
+	syncingFactory.status.Store(sdkStatusReady)
+	if !CombinedReadiness(ready, syncing) {
+		t.Error("Combined readiness should be true once every client is ready")
+	}
+}
+
+type splitNamesCountingStorage struct {
+	*mutexmap.MMSplitStorage
+	calls int
+}
+
+func (s *splitNamesCountingStorage) SplitNames() []string {
+	s.calls++
+	return s.MMSplitStorage.SplitNames()
+}
+
+func TestNoSplitsLoaded(t *testing.T) {
+	writer := &errorsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer, ErrorWriter: writer})
+	cfg := conf.Default()
+	splitStorage := mutexmap.NewMMSplitStorage()
+	countingStorage := &splitNamesCountingStorage{MMSplitStorage: splitStorage}
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: countingStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: &impressionsCountingStorage{},
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	decision := client.TreatmentWithDecision("key", "feature", nil)
+	if decision.Treatment != evaluator.Control || decision.Label != impressionlabels.NoSplitsLoaded {
+		t.Error("Evaluations against an empty storage should be labeled as no splits loaded", decision)
+	}
+	if treatments := client.Treatments("key", []string{"feature", "feature2"}, nil); treatments["feature2"] != evaluator.Control {
+		t.Error("Evaluations against an empty storage should return CONTROL", treatments)
+	}
+
+	warnings := 0
+	for _, message := range writer.messages {
+		if strings.Contains(message, "No splits are loaded") {
+			warnings++
+		}
+	}
+	if warnings != 1 || writer.contains("does not exist in this environment") {
+		t.Error("A single warning about the empty storage should be logged", writer.messages)
+	}
+	if countingStorage.calls != 1 {
+		t.Error("The split names should be listed once per second at most. Got:", countingStorage.calls)
+	}
+
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "other", Status: "ACTIVE"}}, 1)
+	factory.splitsLoaded.checkedAt = time.Now().Add(-splitsLoadedCheckInterval)
+	if decision = client.TreatmentWithDecision("key", "feature", nil); decision.Label != impressionlabels.SplitNotFound {
+		t.Error("Unknown features should be labeled as not found once splits are loaded", decision)
+	}
+}
+
+func TestTreatmentWithFreshness(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	now := time.Now()
+	client := SplitClient{
+		evaluator:   &mockEvaluator{},
+		impressions: &impressionsCountingStorage{},
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+		now:         func() time.Time { return now },
+	}
+
+	if result := client.TreatmentWithFreshness("key", "feature", nil); result.DataAge != UnknownDataAge {
+		t.Error("Data age should be unknown before splits are synchronized", result)
+	}
+
+	splitStorage.PutMany([]dtos.SplitDTO{}, 1)
+	synchronized := splitStorage.LastUpdated()
+	now = synchronized.Add(time.Second)
+	result := client.TreatmentWithFreshness("key", "feature", nil)
+	if result.Treatment != "TreatmentA" || result.DataAge != time.Second {
+		t.Error("The treatment should be returned along with the data age", result)
+	}
+
+	now = synchronized.Add(time.Minute)
+	if result = client.TreatmentWithFreshness("key", "feature", nil); result.DataAge != time.Minute {
+		t.Error("Data age should increase while splits aren't synchronized", result)
+	}
+
+	splitStorage.PutMany([]dtos.SplitDTO{}, 1)
+	now = splitStorage.LastUpdated()
+	if result = client.TreatmentWithFreshness("key", "feature", nil); result.DataAge != 0 {
+		t.Error("Data age should be reset by a synchronization, even if nothing changed", result)
+	}
+}
+
+func TestRecentErrors(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	recentErrors := diagnostics.NewRecorder(cfg.Advanced.RecentErrorsSize)
+	// Fetching a split that's malformed in redis fails
+	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "recentErrors",
+	})
+	prefixedClient.Set("SPLITIO.split.feature", "{not json", 0)
+	defer prefixedClient.Del("SPLITIO.split.feature")
+	splitStorage := redisdb.NewRedisSplitStorage(
+		prefixedClient,
+		diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategoryStorage),
+	)
+	factory := &SplitFactory{cfg: cfg, logger: logger, recentErrors: recentErrors}
+	factory.status.Store(sdkStatusReady)
+	client := factory.Client()
+	client.evaluator = evaluator.NewEvaluator(
+		splitStorage,
+		mutexmap.NewMMSegmentStorage(),
+		engine.NewEngine(logger, engine.Options{}),
+		logger,
+	)
+	client.impressions = &impressionsCountingStorage{}
+	client.metrics = mutexmap.NewMMMetricsStorage()
+
+	if len(client.RecentErrors()) != 0 {
+		t.Error("No errors should have been recorded yet")
+	}
+
+	before := time.Now()
+	if treatment := client.Treatment("key", "feature", nil); treatment != evaluator.Control {
+		t.Error("Treatment should be CONTROL when the split can't be fetched", treatment)
+	}
+
+	recent := client.RecentErrors()
+	if len(recent) != 2 {
+		t.Fatal("Both the storage & the evaluation errors should have been recorded", recent)
+	}
+	if recent[0].Category != diagnostics.CategoryStorage || !strings.Contains(recent[0].Message, "could not parse feature \"feature\"") {
+		t.Error("The storage error should be recorded first", recent[0])
+	}
+	if recent[1].Category != diagnostics.CategoryEvaluation || !strings.Contains(recent[1].Message, "does not exist") {
+		t.Error("The evaluation error should be recorded after the storage one", recent[1])
+	}
+	if recent[0].Time.Before(before) || recent[1].Time.Before(recent[0].Time) {
+		t.Error("Errors should be recorded with the time they happened", recent)
+	}
+
+	cfg.Advanced.RecentErrorsSize = 0
+	disabled := &SplitFactory{cfg: cfg, logger: logger, recentErrors: diagnostics.NewRecorder(cfg.Advanced.RecentErrorsSize)}
+	if disabled.Client().RecentErrors() != nil {
+		t.Error("No errors should be returned when keeping them is disabled")
+	}
+}
+
+func TestControlImpressions(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:   "other",
+		Status: "ACTIVE",
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+			Partitions:   []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}},
+	}}, 1)
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	impressions := &impressionsCountingStorage{}
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	client.Treatment("key", "feature", nil)
+	client.Treatments("key", []string{"feature", "other"}, nil)
+	if len(impressions.impressions) != 1 || impressions.impressions[0].FeatureName != "other" {
+		t.Error("No impressions should be stored for CONTROL results by default", impressions.impressions)
+	}
+
+	cfg.Advanced.ControlImpressions = true
+	impressions.impressions = nil
+	if treatment := client.Treatment("key", "feature", nil); treatment != evaluator.Control {
+		t.Error("Unknown features should still be evaluated as CONTROL", treatment)
+	}
+	if len(impressions.impressions) != 1 {
+		t.Fatal("A CONTROL impression should have been stored", impressions.impressions)
+	}
+	impression := impressions.impressions[0]
+	if impression.FeatureName != "feature" || impression.KeyName != "key" || impression.Treatment != evaluator.Control ||
+		impression.Label != impressionlabels.SplitNotFound {
+		t.Error("The CONTROL impression should be labeled with the reason", impression)
+	}
+
+	impressions.impressions = nil
+	treatments := client.Treatments("key", []string{"feature", "other"}, nil)
+	if treatments["feature"] != evaluator.Control || treatments["other"] != "on" || len(impressions.impressions) != 2 {
+		t.Fatal("CONTROL impressions should be stored along with the evaluated ones", treatments, impressions.impressions)
+	}
+	for _, impression := range impressions.impressions {
+		if impression.FeatureName == "feature" && (impression.Treatment != evaluator.Control || impression.Label != impressionlabels.SplitNotFound) {
+			t.Error("The CONTROL impression should be labeled with the reason", impression)
+		}
+		if impression.FeatureName == "other" && impression.Treatment != "on" {
+			t.Error("Evaluated features should not be affected", impression)
+		}
+	}
+
+	impressions.impressions = nil
+	keyTreatments := client.TreatmentForKeys([]interface{}{"key1", "key2"}, "feature", nil)
+	if keyTreatments["key1"] != evaluator.Control || keyTreatments["key2"] != evaluator.Control || len(impressions.impressions) != 2 {
+		t.Fatal("A CONTROL impression should be stored for each key", keyTreatments, impressions.impressions)
+	}
+	for _, impression := range impressions.impressions {
+		if impression.Treatment != evaluator.Control || impression.Label != impressionlabels.SplitNotFound {
+			t.Error("The CONTROL impression should be labeled with the reason", impression)
+		}
+	}
+}
+
+func TestControlImpressionsLabels(t *testing.T) {
+	cfg := conf.Default()
+	cfg.Advanced.ControlImpressions = true
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	impressions := &impressionsCountingStorage{}
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+	expectLabels := func(operation string, label string, count int) {
+		if len(impressions.impressions) != count {
+			t.Error(operation, "should store", count, "CONTROL impressions. Got:", impressions.impressions)
+		}
+		for _, impression := range impressions.impressions {
+			if impression.Treatment != evaluator.Control || impression.Label != label {
+				t.Error(operation, "should store CONTROL impressions labeled", label, impression)
+			}
+		}
+		impressions.impressions = nil
+	}
+
+	client.Treatment(nil, "feature", nil)
+	client.Treatments("", []string{"feature"}, nil)
+	client.Treatment("key", "in valid", nil)
+	client.Treatments("key", []string{"in valid"}, nil)
+	expectLabels("Invalid keys & feature names", "", 0)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if decision := client.TreatmentWithDecision("key", "feature", nil); decision.Label != impressionlabels.NoSplitsLoaded {
+		t.Error("Features should be labeled as not loaded while the storage is empty", decision)
+	}
+	expectLabels("A Treatment with no splits loaded", impressionlabels.NoSplitsLoaded, 1)
+	client.TreatmentCtx(cancelled, "key", "feature", nil)
+	expectLabels("A cancelled Treatment", impressionlabels.Cancelled, 1)
+	client.TreatmentsCtx(cancelled, "key", []string{"feature", "other"}, nil)
+	expectLabels("A cancelled Treatments", impressionlabels.Cancelled, 2)
+
+	client.evaluator = &mockEventsPanic{}
+	if decision := client.TreatmentWithDecision("key", "feature", nil); decision.Treatment != evaluator.Control ||
+		decision.Label != impressionlabels.Exception {
+		t.Error("A panicking evaluation should be labeled as an exception", decision)
+	}
+	expectLabels("A panicking Treatment", impressionlabels.Exception, 1)
+	client.Treatments("key", []string{"feature", "other"}, nil)
+	expectLabels("A panicking Treatments", impressionlabels.Exception, 2)
+	client.TreatmentForKeys([]interface{}{"key1", "key2"}, "feature", nil)
+	expectLabels("A panicking TreatmentForKeys", impressionlabels.Exception, 2)
+
+	cfg.Advanced.ControlImpressions = false
+	client.Treatment("key", "feature", nil)
+	expectLabels("A panicking Treatment without ControlImpressions", "", 0)
+	factory.status.Store(sdkStatusInitializing)
+	client.Treatment("key", "feature", nil)
+	expectLabels("A Treatment before the SDK is ready", impressionlabels.ClientNotReady, 1)
+}
+
+func TestUpdateApikey(t *testing.T) {
+	var mutex sync.Mutex
+	var authorizations []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "Bearer revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/splitChanges" {
+			mutex.Lock()
+			authorizations = append(authorizations, authorization)
+			mutex.Unlock()
+		}
+		raw, _ := json.Marshal(dtos.SplitChangesDTO{Splits: []dtos.SplitDTO{}, Since: 3, Till: 3})
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	sdkConf := conf.Default()
+	sdkConf.Advanced.SdkURL = ts.URL
+	sdkConf.Advanced.EventsURL = ts.URL
+	sdkConf.TaskPeriods.SplitSync = 1
+	sdkConf.LoggerConfig.LogLevel = logging.LevelNone
+	factory, err := NewSplitFactory("rotation1", sdkConf)
+	if err != nil {
+		t.Fatal("Factory should have been created", err)
+	}
+	defer factory.Destroy()
+	if err = factory.BlockUntilReady(5); err != nil {
+		t.Fatal("SDK should be ready", err)
+	}
+	client := factory.Client()
+
+	if err = client.UpdateApikey("revoked"); err == nil {
+		t.Error("An apikey rejected by Split servers should not be accepted")
+	}
+	if err = client.UpdateApikey("rotation2"); err != nil {
+		t.Error("An apikey accepted by Split servers should be used from now on", err)
+	}
+
+	rotatedAt := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for index, authorization := range authorizations {
+			if authorization == "Bearer rotation2" {
+				return index
+			}
+		}
+		return -1
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for rotatedAt() < 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	index := rotatedAt()
+	if index < 0 {
+		t.Fatal("Splits should be synchronized with the new apikey")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, authorization := range authorizations[:index] {
+		if authorization != "Bearer rotation1" {
+			t.Error("Requests should be authenticated with the first apikey until it's rotated", authorizations)
+		}
+	}
+	for _, authorization := range authorizations[index:] {
+		if authorization != "Bearer rotation2" {
+			t.Error("Requests should be authenticated with the new apikey once rotated", authorizations)
+		}
+	}
+}
+
+type recordingAuditSink struct {
+	decisions chan auditsink.Decision
+}
+
+func (s *recordingAuditSink) LogDecision(decision auditsink.Decision) {
+	s.decisions <- decision
+}
+
+func TestAuditSink(t *testing.T) {
+	cfg := conf.Default()
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "feature",
+		DefaultTreatment:  "off",
+		Status:            "ACTIVE",
+		ChangeNumber:      7,
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{
+			{
+				ConditionType: "WHITELIST",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{{MatcherType: "WHITELIST", Whitelist: &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"admin"}}}},
+				},
+				Partitions: []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+				Label:      "whitelisted",
+			},
+			{
+				ConditionType: "ROLLOUT",
+				MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+				Partitions:    []dtos.PartitionDTO{{Treatment: "off", Size: 100}},
+				Label:         "default rule",
+			},
+		},
+	}}, 7)
+	sink := &recordingAuditSink{decisions: make(chan auditsink.Decision, 10)}
+	wrapper := auditsink.NewAuditSinkWrapper(sink, 10, nil, logger)
+	defer wrapper.Stop()
+	factory := &SplitFactory{cfg: cfg, logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: &impressionsCountingStorage{},
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+		auditSink:   wrapper,
+	}
+
+	before := time.Now()
+	client.Treatment("admin", "feature", nil)
+	client.Treatment(&Key{MatchingKey: "user", BucketingKey: "bucket"}, "feature", nil)
+	client.Treatment("user", "missing", nil)
+
+	expected := []auditsink.Decision{
+		{Key: "admin", Feature: "feature", Treatment: "on", Label: "whitelisted", MatchedConditionIndex: 0, ChangeNumber: 7},
+		{Key: "user", BucketingKey: "bucket", Feature: "feature", Treatment: "off", Label: "default rule", MatchedConditionIndex: 1, ChangeNumber: 7},
+		{Key: "user", Feature: "missing", Treatment: evaluator.Control, Label: impressionlabels.SplitNotFound, MatchedConditionIndex: -1},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-sink.decisions:
+			if got.Time.Before(before) || got.Time.After(time.Now()) {
+				t.Error("The decision should be timestamped when it's taken", got)
+			}
+			got.Time = time.Time{}
+			if got != want {
+				t.Error("Unexpected decision. Expected:", want, "Got:", got)
+			}
+		case <-time.After(time.Second):
+			t.Error("A decision should be audited for every evaluation", want)
+		}
+	}
+	select {
+	case got := <-sink.decisions:
+		t.Error("No more decisions should be audited", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOptimizedImpressions(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "feature",
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+			Partitions:   []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}},
+	}}, 1)
+	factory := &SplitFactory{cfg: conf.Default(), logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	impressions := &impressionsCountingStorage{}
+	metrics := mutexmap.NewMMMetricsStorage()
+	client := SplitClient{
+		evaluator:         evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions:       impressions,
+		metrics:           metrics,
+		logger:            logger,
+		validator:         inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:           factory,
+		impressionDeduper: impressionsutil.NewImpressionDeduper(10, time.Hour, metrics),
+	}
+
+	client.Treatment("key1", "feature", nil)
+	client.Treatment("key1", "feature", nil)
+	client.Treatments("key1", []string{"feature"}, nil)
+	client.Treatment("key2", "feature", nil)
+	if impressions.writes != 2 || len(impressions.impressions) != 2 {
+		t.Error("Only the first impression of each key should be stored. Got:", impressions.impressions)
+	}
+
+	client.impressionDeduper.ReportMetrics()
+	var deduped int64
+	for _, counter := range metrics.PopCounters() {
+		if counter.MetricName == impressionsutil.DedupedCounter {
+			deduped = counter.Count
+		}
+	}
+	if deduped != 2 {
+		t.Error("Dropped impressions should be counted. Got:", deduped)
+	}
+}
+
+func TestTreatmentWithTimeAttribute(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	attribute := "registered"
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "feature",
+		Status:            "ACTIVE",
+		DefaultTreatment:  "off",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{
+				MatcherType:  "GREATER_THAN_OR_EQUAL_TO",
+				KeySelector:  &dtos.KeySelectorDTO{Attribute: &attribute},
+				UnaryNumeric: &dtos.UnaryNumericMatcherDataDTO{DataType: "DATETIME", Value: 1546300800000}, // 2019-01-01
+			}}},
+			Partitions: []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}},
+	}}, 1)
+	factory := &SplitFactory{cfg: conf.Default(), logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: &impressionsCountingStorage{},
+		metrics:     mutexmap.NewMMMetricsStorage(),
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	after := map[string]interface{}{attribute: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if treatment := client.Treatment("key", "feature", after); treatment != "on" {
+		t.Error("time.Time attributes should be compared as datetimes. Got:", treatment)
+	}
+	before := map[string]interface{}{attribute: time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if treatments := client.Treatments("key", []string{"feature"}, before); treatments["feature"] != "off" {
+		t.Error("time.Time attributes should be compared as datetimes. Got:", treatments)
+	}
+}
+
+func TestTrackImpressionsDisabled(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	tracked := false
+	splitStorage := mutexmap.NewMMSplitStorage()
+	condition := dtos.ConditionDTO{
+		MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+		Partitions:   []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+	}
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "untracked", Status: "ACTIVE", TrafficAllocation: 100, TrackImpressions: &tracked, Conditions: []dtos.ConditionDTO{condition}},
+		{Name: "tracked", Status: "ACTIVE", TrafficAllocation: 100, Conditions: []dtos.ConditionDTO{condition}},
+	}, 1)
+	factory := &SplitFactory{cfg: conf.Default(), logger: logger, storages: sdkStorages{splits: splitStorage}}
+	factory.status.Store(sdkStatusReady)
+	impressions := &impressionsCountingStorage{}
+	metrics := mutexmap.NewMMMetricsStorage()
+	sink := newFakeMetricsSink()
+	client := SplitClient{
+		evaluator:   evaluator.NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger),
+		impressions: impressions,
+		metrics:     metrics,
+		metricsSink: sink,
+		logger:      logger,
+		validator:   inputValidation{logger: logger, splitStorage: splitStorage},
+		factory:     factory,
+	}
+
+	if treatment := client.Treatment("key", "untracked", nil); treatment != "on" {
+		t.Error("Splits not tracking impressions should still be evaluated. Got:", treatment)
+	}
+	if len(impressions.impressions) != 0 {
+		t.Error("No impression should be stored for splits not tracking impressions. Got:", impressions.impressions)
+	}
+	if latencies := metrics.PopLatencies(); len(latencies) != 1 || latencies[0].MetricName != "sdk.getTreatment" {
+		t.Error("The latency should still be recorded. Got:", latencies)
+	}
+	if sink.treatments["untracked:on"] != 1 {
+		t.Error("The treatment should still be counted. Got:", sink.treatments)
+	}
+
+	treatments := client.Treatments("key", []string{"untracked", "tracked"}, nil)
+	if treatments["untracked"] != "on" || treatments["tracked"] != "on" {
+		t.Error("Both splits should be evaluated. Got:", treatments)
+	}
+	if len(impressions.impressions) != 1 || impressions.impressions[0].FeatureName != "tracked" {
+		t.Error("Only the impressions of splits tracking them should be stored. Got:", impressions.impressions)
+	}
+}
+
+func TestImpressionsModeNone(t *testing.T) {
+	factory := getFactory()
+	factory.cfg.Advanced.ImpressionsMode = conf.ImpressionsModeNone
+	factory.storages.impressions = applyImpressionsMode(factory.storages.impressions.(storage.ImpressionStorage), factory.cfg)
+	client := factory.Client()
+	client.evaluator = &mockEvaluator{}
+	factory.status.Store(sdkStatusReady)
+
+	expectedTreatment(client.Treatment("key", "feature", nil), "TreatmentA", t)
+	impressions, _ := client.impressions.(storage.ImpressionStorage).PopN(10)
+	if len(impressions) != 0 {
+		t.Error("No impressions should be stored in none mode. Got:", impressions)
+	}
+}
+
+func TestImpressionsModeNoneLocalhost(t *testing.T) {
+	file, err := ioutil.TempFile("", "splitio_tests")
+	if err != nil {
+		t.Fatal("Couldn't create temporary file for localhost client tests: ", err)
 	}
+	defer os.Remove(file.Name())
+	file.Write([]byte("feature1 on\n"))
+	file.Sync()
 
-	// Grabs created event
-	jsonEvent, _ := prefixedClient.LRange("SPLITIO.events", 0, 1).Result()
-	event := make(map[string]map[string]interface{})
-	json.Unmarshal([]byte(jsonEvent[0]), &event)
-	metadata = event["m"]
-	// Checks if metadata was created with "NA" values
-	if metadata["i"] == "NA" || metadata["n"] == "NA" {
-		t.Error("Instance Name and Machine IP should not have 'NA' values")
+	sdkConf := conf.Default()
+	sdkConf.SplitFile = file.Name()
+	sdkConf.Advanced.ImpressionsMode = conf.ImpressionsModeNone
+	factory, err := NewSplitFactory("localhost", sdkConf)
+	if err != nil {
+		t.Fatal("The factory should be created", err)
 	}
+	defer factory.Destroy()
 
-	deleteDataGenerated(prefixedClient)
+	if _, ok := factory.storages.impressions.(*storage.NoopImpressionStorage); !ok {
+		t.Error("No impressions should be stored in none mode in localhost either")
+	}
 }
 
-func getInMemoryClientWithIP(IPAddressesEnabled bool, ts *httptest.Server) SplitClient {
-	// Set default configs to connect Client with redis
+func TestEvaluationCacheStillRecordsImpressions(t *testing.T) {
+	factory := getFactory()
+	factory.cfg.Advanced.EvaluationCacheTTL = 60000
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:              "feature",
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+			Partitions:   []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}},
+	}}, 1)
+	factory.storages.splits = splitStorage
+	factory.storages.segments = mutexmap.NewMMSegmentStorage()
+	factory.status.Store(sdkStatusReady)
+	client := factory.Client()
+	impressions := &impressionsCountingStorage{}
+	client.impressions = impressions
+
+	cached, ok := client.evaluator.(*evaluator.CachedEvaluator)
+	if !ok {
+		t.Fatal("Evaluations should be cached when EvaluationCacheTTL is set")
+	}
+	expectedTreatment(client.Treatment("key", "feature", nil), "on", t)
+	expectedTreatment(client.Treatment("key", "feature", nil), "on", t)
+	if cached.Hits() != 1 {
+		t.Error("The second evaluation should be served from cache", cached.Hits())
+	}
+	if len(impressions.impressions) != 2 {
+		t.Error("An impression should be recorded for each evaluation, even if cached", impressions.impressions)
+	}
+}
+
+func TestEvaluatorSharedPerStorages(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
 	cfg := conf.Default()
-	cfg.LabelsEnabled = true
-	cfg.IPAddressesEnabled = IPAddressesEnabled
-	cfg.Advanced.EventsURL = ts.URL
-	cfg.Advanced.SdkURL = ts.URL
-	cfg.Advanced.ImpressionListener = &ImpressionListenerTest{}
-	cfg.LoggerConfig.LogLevel = logging.LevelDebug
-	cfg.TaskPeriods.ImpressionSync = 3
-	cfg.TaskPeriods.EventsSync = 3
+	cfg.Advanced.EvaluationCacheTTL = 60000
+	cfg.Advanced.StorageResolver = &mockTenantResolver{
+		splits: map[string]storage.SplitStorageConsumer{
+			"tenant1": tenantSplitStorage("on"),
+			"tenant2": tenantSplitStorage("v2"),
+		},
+		segments: map[string]storage.SegmentStorageConsumer{
+			"tenant1": mutexmap.NewMMSegmentStorage(),
+			"tenant2": mutexmap.NewMMSegmentStorage(),
+		},
+	}
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:      tenantSplitStorage("default"),
+			segments:    mutexmap.NewMMSegmentStorage(),
+			impressions: mutexqueue.NewMQImpressionsStorage(100, make(chan string, 1), logger),
+			telemetry:   mutexmap.NewMMMetricsStorage(),
+			events:      &mockEvents{},
+		},
+		logger: logger,
+	}
+	factory.status.Store(sdkStatusReady)
 
-	factory, _ := NewSplitFactory("test", cfg)
-	client := factory.Client()
-	client.BlockUntilReady(2)
-	return *client
+	first, second := factory.Client(), factory.Client()
+	if first.evaluator != second.evaluator {
+		t.Error("Clients reading the same storages should share the evaluator")
+	}
+	expectedTreatment(first.Treatment("key", "tenant_split", nil), "default", t)
+	expectedTreatment(second.Treatment("key", "tenant_split", nil), "default", t)
+	if cached, ok := second.evaluator.(*evaluator.CachedEvaluator); !ok || cached.Hits() != 1 {
+		t.Error("The evaluation cache should be shared by clients reading the same storages")
+	}
+
+	tenant1 := factory.TenantClient("tenant1")
+	if tenant1.evaluator != factory.TenantClient("tenant1").evaluator {
+		t.Error("Clients of the same tenant should share the evaluator")
+	}
+	if tenant1.evaluator == first.evaluator || tenant1.evaluator == factory.TenantClient("tenant2").evaluator {
+		t.Error("Clients reading other storages should get an evaluator of their own")
+	}
+	expectedTreatment(tenant1.Treatment("key", "tenant_split", nil), "on", t)
 }
 
-func TestClientWithIPEnabled(t *testing.T) {
-	var splitsMock, _ = ioutil.ReadFile("../../testdata/splits_mock.json")
-	var splitMock, _ = ioutil.ReadFile("../../testdata/split_mock.json")
+func TestPersistImpressionObserverAcrossRestarts(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	redisCfg := conf.RedisConfig{Host: "localhost", Port: 6379, Database: 1, Prefix: "observerRestart"}
+	prefixedClient, _ := redisdb.NewPrefixedRedisClient(&redisCfg)
+	defer prefixedClient.Close()
+	redisdb.NewRedisSplitStorage(prefixedClient, logger).PutMany([]dtos.SplitDTO{{
+		Name:              "valid",
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+			Partitions:   []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}},
+	}}, 1)
+	defer deleteDataGenerated(prefixedClient)
+	observerKeys := fmt.Sprintf("SPLITIO/go-%s/observer-instance/impressionObserver", splitio.Version)
+	defer prefixedClient.Del(observerKeys)
+
+	newFactory := func() *SplitFactory {
+		cfg := conf.Default()
+		cfg.OperationMode = "redis-consumer"
+		cfg.InstanceName = "observer-instance"
+		cfg.Redis = redisCfg
+		cfg.Advanced.ImpressionsMode = conf.ImpressionsModeOptimized
+		cfg.Advanced.PersistImpressionObserver = true
+		factory, err := NewSplitFactory("apikey", cfg)
+		if err != nil {
+			t.Fatal("The factory should be created", err)
+		}
+		return factory
+	}
 
-	postChannel := make(chan string, 1)
+	factory := newFactory()
+	expectedTreatment(factory.Client().Treatment("user1", "valid", nil), "on", t)
+	factory.Destroy()
 
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/splitChanges":
-			fmt.Fprintln(w, fmt.Sprintf(string(splitsMock), splitMock))
-			return
-		case "/segmentChanges/___TEST___":
-			w.Header().Add("Content-Encoding", "gzip")
-			gzw := gzip.NewWriter(w)
-			defer gzw.Close()
-			fmt.Fprintln(gzw, "Hello, client")
-			return
-		case "/testImpressions/bulk":
-		case "/events/bulk":
-			if r.Header.Get("SplitSDKMachineIP") == "NA" || r.Header.Get("SplitSDKMachineName") == "NA" {
-				t.Error("It should not be NA when IPAddressesEnabled has been set")
-			}
+	restarted := newFactory()
+	defer restarted.Destroy()
+	client := restarted.Client()
+	expectedTreatment(client.Treatment("user1", "valid", nil), "on", t)
+	expectedTreatment(client.Treatment("user2", "valid", nil), "on", t)
 
-			rBody, _ := ioutil.ReadAll(r.Body)
-			var dataInPost []map[string]interface{}
-			err := json.Unmarshal(rBody, &dataInPost)
-			if err != nil {
-				t.Error(err)
-				return
-			}
+	impressions, _ := prefixedClient.LRange("SPLITIO.impressions", 0, -1).Result()
+	if len(impressions) != 2 || !strings.Contains(impressions[0], "user1") || !strings.Contains(impressions[1], "user2") {
+		t.Error("The impression repeated after the restart should be dropped", impressions)
+	}
+}
 
-			if len(dataInPost) < 1 {
-				t.Error("It should send data")
-			}
-			fmt.Fprintln(w, "ok")
-			postChannel <- "finished"
-		case "/segmentChanges":
-		default:
-			fmt.Fprintln(w, "ok")
-			return
-		}
-	}))
-	defer ts.Close()
+type mockTenantResolver struct {
+	splits   map[string]storage.SplitStorageConsumer
+	segments map[string]storage.SegmentStorageConsumer
+}
 
-	client := getInMemoryClientWithIP(true, ts)
-	// Calls treatments to generate one valid impression
-	client.Track("user1", "my-traffic", "my-event", nil, nil)
-	client.Treatment("user1", "DEMO_MURMUR2", nil)
+func (r *mockTenantResolver) Splits(tenant string) storage.SplitStorageConsumer {
+	return r.splits[tenant]
+}
 
-	listenerData, _ := ilResult["DEMO_MURMUR2"].(map[string]interface{})
-	if listenerData["InstanceName"] == "NA" {
-		t.Error("InstanceName should not be 'NA")
+func (r *mockTenantResolver) Segments(tenant string) storage.SegmentStorageConsumer {
+	return r.segments[tenant]
+}
+
+func tenantSegmentSplitStorage() *mutexmap.MMSplitStorage {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:              2,
+			ChangeNumber:      123,
+			DefaultTreatment:  "off",
+			Name:              "segment_split",
+			Status:            "ACTIVE",
+			TrafficAllocation: 100,
+			TrafficTypeName:   "user",
+			Sets:              []string{"backend"},
+			Conditions: []dtos.ConditionDTO{
+				{
+					ConditionType: "ROLLOUT",
+					Label:         "in segment employees",
+					MatcherGroup: dtos.MatcherGroupDTO{
+						Combiner: "AND",
+						Matchers: []dtos.MatcherDTO{
+							{
+								KeySelector:        &dtos.KeySelectorDTO{TrafficType: "user"},
+								MatcherType:        "IN_SEGMENT",
+								UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "employees"},
+							},
+						},
+					},
+					Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+				},
+			},
+		},
+	}, 123)
+	return splitStorage
+}
+
+func tenantSegmentStorage(keys ...interface{}) *mutexmap.MMSegmentStorage {
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	segmentStorage.Put("employees", set.NewSet(keys...), 123)
+	return segmentStorage
+}
+
+func tenantSplitStorage(treatment string) *mutexmap.MMSplitStorage {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:              2,
+			ChangeNumber:      123,
+			DefaultTreatment:  "off",
+			Name:              "tenant_split",
+			Status:            "ACTIVE",
+			TrafficAllocation: 100,
+			TrafficTypeName:   "user",
+			Conditions: []dtos.ConditionDTO{
+				{
+					ConditionType: "ROLLOUT",
+					Label:         "default rule",
+					MatcherGroup: dtos.MatcherGroupDTO{
+						Combiner: "AND",
+						Matchers: []dtos.MatcherDTO{
+							{
+								KeySelector: &dtos.KeySelectorDTO{TrafficType: "user"},
+								MatcherType: "ALL_KEYS",
+							},
+						},
+					},
+					Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: treatment}},
+				},
+			},
+		},
+	}, 123)
+	return splitStorage
+}
+
+func TestTenantClient(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	cfg := conf.Default()
+	cfg.Advanced.StorageResolver = &mockTenantResolver{
+		splits: map[string]storage.SplitStorageConsumer{
+			"tenant1": tenantSplitStorage("on"),
+			"tenant2": tenantSplitStorage("v2"),
+		},
+		segments: map[string]storage.SegmentStorageConsumer{
+			"tenant1": mutexmap.NewMMSegmentStorage(),
+			"tenant2": mutexmap.NewMMSegmentStorage(),
+		},
+	}
+	impressionStorage := mutexqueue.NewMQImpressionsStorage(100, make(chan string, 1), logger)
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:      tenantSplitStorage("default"),
+			segments:    mutexmap.NewMMSegmentStorage(),
+			impressions: impressionStorage,
+			telemetry:   mutexmap.NewMMMetricsStorage(),
+			events:      &mockEvents{},
+		},
+		logger: logger,
 	}
+	factory.status.Store(sdkStatusReady)
 
-	select {
-	case <-postChannel:
-		return
-	case <-time.After(4 * time.Second):
-		t.Error("The test couldn't send impressions to check headers")
-		return
+	if treatment := factory.TenantClient("tenant1").Treatment("key", "tenant_split", nil); treatment != "on" {
+		t.Error("tenant1 evaluations should use tenant1 splits. Got:", treatment)
+	}
+	treatments := factory.TenantClient("tenant2").Treatments("key", []string{"tenant_split"}, nil)
+	if treatments["tenant_split"] != "v2" {
+		t.Error("tenant2 evaluations should use tenant2 splits. Got:", treatments["tenant_split"])
+	}
+	if treatment := factory.Client().Treatment("key", "tenant_split", nil); treatment != "default" {
+		t.Error("Client should keep using the SDK's own splits. Got:", treatment)
 	}
-}
 
-func TestClientWithIPDisabled(t *testing.T) {
-	var splitsMock, _ = ioutil.ReadFile("../../testdata/splits_mock.json")
-	var splitMock, _ = ioutil.ReadFile("../../testdata/split_mock.json")
+	if impressions, _ := impressionStorage.PopN(100); len(impressions) != 3 {
+		t.Error("Impressions of every tenant should be stored in the shared storage. Got:", len(impressions))
+	}
 
-	postChannel := make(chan string, 1)
+	cfg.Advanced.StorageResolver = storage.NewSingleTenantResolver(tenantSplitStorage("single"), mutexmap.NewMMSegmentStorage())
+	for _, tenant := range []string{"", "tenant1", "tenant2"} {
+		if treatment := factory.TenantClient(tenant).Treatment("key", "tenant_split", nil); treatment != "single" {
+			t.Error("Every tenant should resolve to the same storages. Got:", treatment)
+		}
+	}
+}
 
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/splitChanges":
-			fmt.Fprintln(w, fmt.Sprintf(string(splitsMock), splitMock))
-			return
-		case "/segmentChanges/___TEST___":
-			w.Header().Add("Content-Encoding", "gzip")
-			gzw := gzip.NewWriter(w)
-			defer gzw.Close()
-			fmt.Fprintln(gzw, "Hello, client")
-			return
-		case "/testImpressions/bulk":
-		case "/events/bulk":
-			for header := range r.Header {
-				if (header == "SplitSDKMachineIP") || (header == "SplitSDKMachineName") {
-					t.Error("Should not insert one of SplitSDKMachineIP, SplitSDKMachineName")
-				}
-			}
+func TestTenantClientSegmentsAndFlagSets(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	cfg := conf.Default()
+	cfg.Advanced.StorageResolver = &mockTenantResolver{
+		splits: map[string]storage.SplitStorageConsumer{
+			"tenant1": tenantSegmentSplitStorage(),
+			"tenant2": tenantSegmentSplitStorage(),
+		},
+		segments: map[string]storage.SegmentStorageConsumer{
+			"tenant1": tenantSegmentStorage("key1"),
+			"tenant2": tenantSegmentStorage("key2"),
+		},
+	}
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:      mutexmap.NewMMSplitStorage(),
+			segments:    tenantSegmentStorage("key1", "key2"),
+			impressions: mutexqueue.NewMQImpressionsStorage(100, make(chan string, 1), logger),
+			telemetry:   mutexmap.NewMMMetricsStorage(),
+			events:      &mockEvents{},
+		},
+		logger: logger,
+	}
+	factory.status.Store(sdkStatusReady)
 
-			rBody, _ := ioutil.ReadAll(r.Body)
-			var dataInPost []map[string]interface{}
-			err := json.Unmarshal(rBody, &dataInPost)
-			if err != nil {
-				t.Error(err)
-				return
+	expected := map[string]map[string]string{
+		"tenant1": {"key1": "on", "key2": "off"},
+		"tenant2": {"key1": "off", "key2": "on"},
+	}
+	for tenant, treatments := range expected {
+		client := factory.TenantClient(tenant)
+
+		byKey := client.TreatmentForKeys([]interface{}{"key1", "key2"}, "segment_split", nil)
+		for key, treatment := range treatments {
+			if byKey[key] != treatment {
+				t.Error("TreatmentForKeys should use the segments of", tenant, "for", key, ". Got:", byKey[key])
 			}
 
-			if len(dataInPost) < 1 {
-				t.Error("It should send data")
+			byFlagSet := client.TreatmentsByFlagSet(key, "backend", nil)
+			if len(byFlagSet) != 1 || byFlagSet["segment_split"] != treatment {
+				t.Error("TreatmentsByFlagSet should use the splits & segments of", tenant, "for", key, ". Got:", byFlagSet)
 			}
-			fmt.Fprintln(w, "ok")
-			postChannel <- "finished"
-		case "/segmentChanges":
-		default:
-			fmt.Fprintln(w, "ok")
-			return
 		}
-	}))
-	defer ts.Close()
+	}
 
-	client := getInMemoryClientWithIP(false, ts)
+	if treatments := factory.Client().TreatmentsByFlagSet("key1", "backend", nil); len(treatments) != 0 {
+		t.Error("Client should keep using the SDK's own splits. Got:", treatments)
+	}
+}
 
-	// Calls treatments to generate one valid impression
-	client.Track("user1", "my-traffic", "my-event", nil, nil)
-	client.Treatment("user1", "DEMO_MURMUR2", nil)
+func TestEngineWarningsSharedAcrossClients(t *testing.T) {
+	writer := &errorsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer})
+	cfg := conf.Default()
+	cfg.Advanced.MaxConditionsPerSplit = 1
+	splitStorage := tenantSplitStorage("on")
+	split := splitStorage.Get("tenant_split")
+	whitelisted := dtos.ConditionDTO{
+		ConditionType: "WHITELIST",
+		MatcherGroup: dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{
+			MatcherType: "WHITELIST",
+			Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"other"}},
+		}}},
+		Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "off"}},
+	}
+	split.Conditions = append([]dtos.ConditionDTO{whitelisted}, split.Conditions...)
+	splitStorage.PutMany([]dtos.SplitDTO{*split}, 124)
+	factory := &SplitFactory{
+		cfg: cfg,
+		storages: sdkStorages{
+			splits:      splitStorage,
+			segments:    mutexmap.NewMMSegmentStorage(),
+			impressions: mutexqueue.NewMQImpressionsStorage(100, make(chan string, 1), logger),
+			telemetry:   mutexmap.NewMMMetricsStorage(),
+			events:      &mockEvents{},
+		},
+		logger: logger,
+	}
+	factory.status.Store(sdkStatusReady)
 
-	listenerData, _ := ilResult["DEMO_MURMUR2"].(map[string]interface{})
-	if listenerData["InstanceName"] != "NA" {
-		t.Error("InstanceName should be 'NA")
+	factory.Client().Treatment("key", "tenant_split", nil)
+	factory.Client().Treatment("key", "tenant_split", nil)
+	warnings := 0
+	for _, message := range writer.messages {
+		if strings.Contains(message, "has more than 1 conditions") {
+			warnings++
+		}
 	}
+	if warnings != 1 {
+		t.Error("Warnings should be throttled across the clients of a factory. Got:", warnings)
+	}
+}
 
-	select {
-	case <-postChannel:
-		return
-	case <-time.After(4 * time.Second):
-		t.Error("The test couldn't send impressions to check headers")
-		return
+func TestTrackLimiterSharedAcrossClients(t *testing.T) {
+	factory := getFactory()
+	if factory.Client().trackLimiter != nil {
+		t.Error("No rate limiter should be used if no rate limit has been configured")
+	}
+
+	factory = getFactory()
+	factory.cfg.Advanced.TrackRateLimit = 5
+	accepted := 0
+	for _, client := range []*SplitClient{factory.Client(), factory.Client()} {
+		for i := 0; i < 5; i++ {
+			if client.trackLimiter.Allow() {
+				accepted++
+			}
+		}
+	}
+	if accepted != 5 {
+		t.Error("The rate limit should apply to every client of the factory as a whole. Got:", accepted)
 	}
 }
@@ -5,22 +5,29 @@ package client
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/splitio/go-client/splitio"
+	auditsink "github.com/splitio/go-client/splitio/auditSink"
 	"github.com/splitio/go-client/splitio/conf"
 	"github.com/splitio/go-client/splitio/engine"
 	"github.com/splitio/go-client/splitio/engine/evaluator"
+	impressionenricher "github.com/splitio/go-client/splitio/impressionEnricher"
 	impressionlistener "github.com/splitio/go-client/splitio/impressionListener"
 	"github.com/splitio/go-client/splitio/service/api"
+	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/service/local"
 	"github.com/splitio/go-client/splitio/storage"
 	"github.com/splitio/go-client/splitio/storage/mutexmap"
 	"github.com/splitio/go-client/splitio/storage/mutexqueue"
 	"github.com/splitio/go-client/splitio/storage/redisdb"
 	"github.com/splitio/go-client/splitio/tasks"
+	"github.com/splitio/go-client/splitio/util/diagnostics"
+	impressionsutil "github.com/splitio/go-client/splitio/util/impressions"
+	"github.com/splitio/go-client/splitio/util/ratelimit"
 	"github.com/splitio/go-toolkit/asynctask"
 	"github.com/splitio/go-toolkit/logging"
 )
@@ -30,9 +37,16 @@ const (
 	sdkStatusInitializing
 	sdkStatusReady
 
-	sdkInitializationFailed = -1
+	sdkInitializationFailed   = -1
+	sdkInitializationTimedOut = -2
 )
 
+// splitsLoadedCheckInterval is the minimum time between checks of whether the split storage is empty
+const splitsLoadedCheckInterval = time.Second
+
+// overloadedWarningInterval is the minimum time between warnings about evaluations that couldn't get a slot
+const overloadedWarningInterval = time.Minute
+
 type sdkStorages struct {
 	splits      storage.SplitStorageConsumer
 	segments    storage.SegmentStorageConsumer
@@ -41,6 +55,12 @@ type sdkStorages struct {
 	telemetry   storage.MetricsStorageProducer
 }
 
+// snapshotStorages holds the storages loaded from the snapshot used until the SDK is ready
+type snapshotStorages struct {
+	splits   storage.SplitStorageConsumer
+	segments storage.SegmentStorageConsumer
+}
+
 type sdkSync struct {
 	splits      *asynctask.AsyncTask
 	segments    *asynctask.AsyncTask
@@ -48,7 +68,13 @@ type sdkSync struct {
 	gauges      *asynctask.AsyncTask
 	counters    *asynctask.AsyncTask
 	latencies   *asynctask.AsyncTask
+	metrics     *asynctask.AsyncTask
 	events      *asynctask.AsyncTask
+	snapshot    *asynctask.AsyncTask
+	queueDepths *asynctask.AsyncTask
+	splitsCheck *asynctask.AsyncTask
+	observer    *asynctask.AsyncTask
+	dedup       *asynctask.AsyncTask
 }
 
 // SplitFactory struct is responsible for instantiating and storing instances of client and manager.
@@ -59,27 +85,264 @@ type SplitFactory struct {
 	apikey                string
 	status                atomic.Value
 	readinessSubscriptors map[int]chan int
+	initializationError   int
 	operationMode         string
 	mutex                 sync.Mutex
 	cfg                   *conf.SplitSdkConfig
 	impressionListener    *impressionlistener.WrapperImpressionListener
+	impressionEnricher    *impressionenricher.WrapperImpressionEnricher
+	auditSink             *auditsink.WrapperAuditSink
+	impressionDeduper     *impressionsutil.ImpressionDeduper
+	observerState         storage.ImpressionObserverStateStorage
+	snapshot              *snapshotStorages
+	segmentSyncStatus     *tasks.SegmentSyncStatus
+	evaluationSlots       *ratelimit.Semaphore
+	overloadedMutex       sync.Mutex
+	overloadedWarnedAt    time.Time
+	noSplitsWarning       sync.Once
+	splitsLoaded          splitsLoadedCheck
+	engineOnce            sync.Once
+	engine                *engine.Engine
+	evaluatorsMutex       sync.Mutex
+	evaluators            map[evaluatorKey]evaluator.Interface
+	trackBucketOnce       sync.Once
+	trackBucket           *ratelimit.TokenBucket
+	recentErrors          *diagnostics.Recorder
+	apikeyTransport       *api.APIKeyTransport
+	apikeyMutex           sync.Mutex
+	redisClient           *redisdb.PrefixedRedisClient
+	flush                 func() error
 	logger                logging.LoggerInterface
 }
 
+// evaluatorKey identifies the storages a shared evaluator reads from
+type evaluatorKey struct {
+	splits   storage.SplitStorageConsumer
+	segments storage.SegmentStorageConsumer
+}
+
+// splitsLoadedCheck remembers whether a split storage holds any split
+type splitsLoadedCheck struct {
+	mutex     sync.Mutex
+	checkedAt time.Time
+	loaded    bool
+}
+
+// hasSplits returns whether the split storage holds any split. Listing the splits may scan redis, so the answer
+// is reused for splitsLoadedCheckInterval
+func (s *splitsLoadedCheck) hasSplits(splits storage.SplitStorageConsumer) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if now := time.Now(); s.checkedAt.IsZero() || now.Sub(s.checkedAt) >= splitsLoadedCheckInterval {
+		s.loaded = len(splits.SplitNames()) > 0
+		s.checkedAt = now
+	}
+	return s.loaded
+}
+
+// shouldWarnOverloaded returns true at most once per overloadedWarningInterval, so that evaluations rejected
+// for lack of a slot don't flood the logs precisely when the process is overloaded
+func (f *SplitFactory) shouldWarnOverloaded() bool {
+	f.overloadedMutex.Lock()
+	defer f.overloadedMutex.Unlock()
+	now := time.Now()
+	if !f.overloadedWarnedAt.IsZero() && now.Sub(f.overloadedWarnedAt) < overloadedWarningInterval {
+		return false
+	}
+	f.overloadedWarnedAt = now
+	return true
+}
+
 // Client returns the split client instantiated by the factory
 func (f *SplitFactory) Client() *SplitClient {
+	client := f.newClient(f.storages.splits, f.storages.segments, &f.splitsLoaded)
+	client.snapshotEvaluator = f.snapshotEvaluator()
+	return client
+}
+
+// TenantClient returns a split client whose evaluations read splits & segments from the storages
+// Advanced.StorageResolver resolves for tenant. Impressions, events & metrics are shared with every other client.
+// The NotReadySnapshot only holds the SDK's own splits & segments, so it isn't used by tenant clients
+func (f *SplitFactory) TenantClient(tenant string) *SplitClient {
+	resolver := f.cfg.Advanced.StorageResolver
+	if resolver == nil {
+		return f.Client()
+	}
+	return f.newClient(resolver.Splits(tenant), resolver.Segments(tenant), &splitsLoadedCheck{})
+}
+
+// newClient returns a split client evaluating features against the supplied storages
+func (f *SplitFactory) newClient(
+	splits storage.SplitStorageConsumer,
+	segments storage.SegmentStorageConsumer,
+	splitsLoaded *splitsLoadedCheck,
+) *SplitClient {
+	logger := diagnostics.NewRecordingLogger(f.logger, f.recentErrors, diagnostics.CategoryEvaluation)
 	return &SplitClient{
-		logger:      f.logger,
-		evaluator:   evaluator.NewEvaluator(f.storages.splits, f.storages.segments, engine.NewEngine(f.logger), f.logger),
-		impressions: f.storages.impressions,
-		metrics:     f.storages.telemetry,
-		events:      f.storages.events,
+		logger:         logger,
+		evaluator:      f.sharedEvaluator(splits, segments),
+		splitStorage:   splits,
+		segmentStorage: segments,
+		splitsLoaded:   splitsLoaded,
+		impressions:    f.storages.impressions,
+		metrics:        f.storages.telemetry,
+		events:         f.storages.events,
 		validator: inputValidation{
-			logger:       f.logger,
-			splitStorage: f.storages.splits,
+			logger:               logger,
+			splitStorage:         splits,
+			trimKeys:             f.cfg.Advanced.TrimKeys,
+			maxFeatureNameLength: f.cfg.Advanced.MaxFeatureNameLength,
 		},
 		factory:            f,
 		impressionListener: f.impressionListener,
+		impressionEnricher: f.impressionEnricher,
+		auditSink:          f.auditSink,
+		impressionDeduper:  f.impressionDeduper,
+		trackLimiter:       f.trackLimiter(),
+		metricsSink:        f.cfg.Advanced.MetricsSink,
+		evaluationSlots:    f.evaluationSlots,
+		evaluationTimeout:  time.Duration(f.cfg.Advanced.EvaluationSlotTimeout) * time.Millisecond,
+	}
+}
+
+// trackLimiter returns the rate limiter applied to Track, or nil if no rate limit has been configured. It's shared
+// by every client, so that the rate limit applies to the factory as a whole
+func (f *SplitFactory) trackLimiter() *ratelimit.TokenBucket {
+	if f.cfg.Advanced.TrackRateLimit <= 0 {
+		return nil
+	}
+	f.trackBucketOnce.Do(func() {
+		f.trackBucket = ratelimit.NewTokenBucket(f.cfg.Advanced.TrackRateLimit)
+	})
+	return f.trackBucket
+}
+
+// evaluationEngine returns the engine enforcing the evaluation limits set in the config. It's shared by every
+// client, so that its warnings are throttled across them
+func (f *SplitFactory) evaluationEngine() *engine.Engine {
+	f.engineOnce.Do(func() {
+		f.engine = engine.NewEngine(f.logger, engine.Options{
+			MaxConditions:        f.cfg.Advanced.MaxConditionsPerSplit,
+			StrictAttributeTypes: f.cfg.Advanced.StrictAttributeTypes,
+			AttributeLimits: engine.AttributeLimits{
+				MaxLength:  f.cfg.Advanced.MaxAttributeLength,
+				MaxSetSize: f.cfg.Advanced.MaxAttributeSetSize,
+			},
+			AllowlistOnly:          f.cfg.Advanced.AllowlistOnlyFeatures,
+			CaseInsensitiveStrings: f.cfg.Advanced.CaseInsensitiveStrings,
+		})
+	})
+	return f.engine
+}
+
+// sharedEvaluator returns the evaluator of the clients reading from the supplied storages, building it for the
+// first one. Sharing it lets them share the evaluation cache & the compiled index of the splits too. Storages that
+// can't be compared, and so can't be told apart, get an evaluator of their own
+func (f *SplitFactory) sharedEvaluator(splits storage.SplitStorageConsumer, segments storage.SegmentStorageConsumer) evaluator.Interface {
+	if !isComparable(splits) || !isComparable(segments) {
+		return f.newEvaluator(splits, segments)
+	}
+	key := evaluatorKey{splits: splits, segments: segments}
+	f.evaluatorsMutex.Lock()
+	defer f.evaluatorsMutex.Unlock()
+	if shared, ok := f.evaluators[key]; ok {
+		return shared
+	}
+	if f.evaluators == nil {
+		f.evaluators = make(map[evaluatorKey]evaluator.Interface)
+	}
+	shared := f.newEvaluator(splits, segments)
+	f.evaluators[key] = shared
+	return shared
+}
+
+// isComparable returns whether value can be used as a map key without panicking
+func isComparable(value interface{}) bool {
+	return value == nil || reflect.TypeOf(value).Comparable()
+}
+
+// newEvaluator returns the evaluator used by clients, which works on a precompiled index of the splits
+// if CompiledEvaluation is enabled, and caches its evaluations if EvaluationCacheTTL is set
+func (f *SplitFactory) newEvaluator(splits storage.SplitStorageConsumer, segments storage.SegmentStorageConsumer) evaluator.Interface {
+	base := f.newBaseEvaluator(splits, segments)
+	if f.cfg.Advanced.EvaluationCacheTTL > 0 {
+		return evaluator.NewCachedEvaluator(
+			base,
+			splits,
+			f.cfg.Advanced.EvaluationCacheSize,
+			time.Duration(f.cfg.Advanced.EvaluationCacheTTL)*time.Millisecond,
+		)
+	}
+	return base
+}
+
+// newBaseEvaluator returns the evaluator that actually evaluates features
+func (f *SplitFactory) newBaseEvaluator(splits storage.SplitStorageConsumer, segments storage.SegmentStorageConsumer) evaluator.Interface {
+	if f.cfg.Advanced.CompiledEvaluation {
+		if f.cfg.Advanced.PinBatchSegments {
+			f.logger.Warning("PinBatchSegments is not supported along with CompiledEvaluation and will be ignored")
+		}
+		return evaluator.NewCompiledEvaluator(splits, segments, f.evaluationEngine(), f.logger)
+	}
+	return evaluator.NewEvaluator(splits, segments, f.evaluationEngine(), f.logger).
+		WithPinnedSegments(f.cfg.Advanced.PinBatchSegments)
+}
+
+// snapshotEvaluator returns an evaluator that reads from the snapshot storages, or nil if there's no snapshot
+func (f *SplitFactory) snapshotEvaluator() evaluator.Interface {
+	if f.snapshot == nil {
+		return nil
+	}
+	return evaluator.NewEvaluator(
+		f.snapshot.splits,
+		f.snapshot.segments,
+		f.evaluationEngine(),
+		f.logger,
+	)
+}
+
+// applyImpressionsMode returns a storage discarding every impression in "none" ImpressionsMode, or the supplied one otherwise
+func applyImpressionsMode(impressions storage.ImpressionStorage, cfg *conf.SplitSdkConfig) storage.ImpressionStorage {
+	if cfg.Advanced.ImpressionsMode == conf.ImpressionsModeNone {
+		return storage.NewNoopImpressionStorage()
+	}
+	return impressions
+}
+
+// withSecondaryImpressionStorage wraps the impression storage so that impressions are also written to the
+// secondary storage set in the config, if any
+func withSecondaryImpressionStorage(
+	impressions storage.ImpressionStorage,
+	cfg *conf.SplitSdkConfig,
+	logger logging.LoggerInterface,
+) storage.ImpressionStorage {
+	if cfg.Advanced.SecondaryImpressionStorage == nil {
+		return impressions
+	}
+	return storage.NewDualImpressionStorage(impressions, cfg.Advanced.SecondaryImpressionStorage, logger)
+}
+
+// loadNotReadySnapshot returns the snapshot supplied in the config or, if there's none, the one persisted in SnapshotFile
+func loadNotReadySnapshot(cfg *conf.SplitSdkConfig, logger logging.LoggerInterface) *dtos.SnapshotDTO {
+	if cfg.Advanced.NotReadySnapshot != nil || cfg.Advanced.SnapshotFile == "" {
+		return cfg.Advanced.NotReadySnapshot
+	}
+
+	persisted, err := storage.ReadSnapshotFile(cfg.Advanced.SnapshotFile)
+	if err != nil {
+		logger.Warning("Could not load snapshot from ", cfg.Advanced.SnapshotFile, ": ", err.Error())
+		return nil
+	}
+	return persisted
+}
+
+// newSnapshotStorages loads a snapshot into in-memory storages
+func newSnapshotStorages(snapshot *dtos.SnapshotDTO) *snapshotStorages {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany(snapshot.Splits, snapshot.Till)
+	return &snapshotStorages{
+		splits:   splitStorage,
+		segments: mutexmap.NewMMSegmentStorageFromSnapshot(snapshot.Segments),
 	}
 }
 
@@ -126,17 +389,38 @@ func (f *SplitFactory) initializationInMemory(readyChannel chan string, syncTask
 		// Broadcast on error
 		f.broadcastReadiness(sdkInitializationFailed)
 		return
+	case "SPLITS_TIMEOUT":
+		// Broadcast when transient errors persisted after retrying for InitialSyncTimeout seconds
+		f.broadcastReadiness(sdkInitializationTimedOut)
+		return
 	}
 
 	msg = <-readyChannel
 	switch msg {
-	case "SEGMENTS_READY":
+	case "SEGMENTS_READY", "SEGMENTS_PARTIAL":
+		if msg == "SEGMENTS_PARTIAL" {
+			// Failing segments are retried in the background, they shouldn't keep the SDK from being used
+			f.logger.Warning("Some segments could not be fetched, keys may not match them until they're retried successfully")
+		}
 		// Once segments are ready, start impressions and metrics recording tasks
 		syncTasks.impressions.Start()
-		syncTasks.latencies.Start()
-		syncTasks.counters.Start()
-		syncTasks.gauges.Start()
+		if syncTasks.metrics != nil {
+			syncTasks.metrics.Start()
+		} else {
+			syncTasks.latencies.Start()
+			syncTasks.counters.Start()
+			syncTasks.gauges.Start()
+		}
 		syncTasks.events.Start()
+		if syncTasks.snapshot != nil {
+			syncTasks.snapshot.Start()
+		}
+		if syncTasks.queueDepths != nil {
+			syncTasks.queueDepths.Start()
+		}
+		if syncTasks.splitsCheck != nil {
+			syncTasks.splitsCheck.Start()
+		}
 		// Broadcast ready status for SDK
 		f.broadcastReadiness(sdkStatusReady)
 	}
@@ -170,18 +454,14 @@ func (f *SplitFactory) broadcastReadiness(status int) {
 	if f.status.Load() == sdkStatusInitializing && status == sdkStatusReady {
 		f.status.Store(sdkStatusReady)
 	}
+	if status < 0 {
+		f.initializationError = status
+	}
 	for _, subscriptor := range f.readinessSubscriptors {
 		subscriptor <- status
 	}
 }
 
-// subscribes listener
-func (f *SplitFactory) subscribe(name int, subscriptor chan int) {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
-	f.readinessSubscriptors[name] = subscriptor
-}
-
 // removes a particular subscriptor from the list
 func (f *SplitFactory) unsubscribe(name int, subscriptor chan int) {
 	f.mutex.Lock()
@@ -205,10 +485,21 @@ func (f *SplitFactory) BlockUntilReady(timer int) error {
 	}
 	block := make(chan int, 1)
 
+	// Checking for a failed initialization and subscribing happen under the same lock,
+	// so that a failure broadcasted in between isn't missed
 	f.mutex.Lock()
 	subscriptorName := len(f.readinessSubscriptors)
+	initializationError := f.initializationError
+	if initializationError == 0 {
+		f.readinessSubscriptors[subscriptorName] = block
+	}
 	f.mutex.Unlock()
 
+	// Initialization already failed, there's no point in waiting
+	if initializationError < 0 {
+		return f.initializationErrorMessage(initializationError)
+	}
+
 	defer func() {
 		// Unsubscription will happen only if a block channel has been created
 		if block != nil {
@@ -217,15 +508,13 @@ func (f *SplitFactory) BlockUntilReady(timer int) error {
 		}
 	}()
 
-	f.subscribe(subscriptorName, block)
-
 	select {
 	case status := <-block:
 		switch status {
 		case sdkStatusReady:
 			break
-		case sdkInitializationFailed:
-			return errors.New("SDK Initialization failed")
+		case sdkInitializationFailed, sdkInitializationTimedOut:
+			return f.initializationErrorMessage(status)
 		}
 	case <-time.After(time.Second * time.Duration(timer)):
 		return fmt.Errorf("SDK Initialization: time of %d exceeded", timer)
@@ -234,18 +523,85 @@ func (f *SplitFactory) BlockUntilReady(timer int) error {
 	return nil
 }
 
+// initializationErrorMessage returns the error reported by BlockUntilReady for a failed initialization status
+func (f *SplitFactory) initializationErrorMessage(status int) error {
+	if status == sdkInitializationTimedOut {
+		return fmt.Errorf(
+			"SDK Initialization: splits could not be synchronized after retrying for %d seconds",
+			f.cfg.Advanced.InitialSyncTimeout,
+		)
+	}
+	return errors.New("SDK Initialization failed")
+}
+
+// UpdateApikey replaces the apikey the synchronization & submission tasks authenticate with, without recreating
+// the factory. The new apikey is checked against Split servers first: if they reject it, the current one is kept
+// and the error is returned. Only supported in "inmemory-standalone" mode
+func (f *SplitFactory) UpdateApikey(apikey string) error {
+	if f.apikeyTransport == nil {
+		err := fmt.Errorf("apikey rotation is not supported in \"%s\" mode", f.operationMode)
+		f.logger.Error(err.Error())
+		return err
+	}
+
+	f.apikeyMutex.Lock()
+	defer f.apikeyMutex.Unlock()
+	if err := api.VerifyApikey(apikey, f.cfg.Advanced); err != nil {
+		f.logger.Error("The new apikey was rejected, the current one is kept: ", err.Error())
+		return err
+	}
+	f.apikeyTransport.SetApikey(apikey)
+	f.logger.Info("Apikey updated to ", logging.ObfuscateAPIKey(apikey))
+	return nil
+}
+
 // Destroy stops all async tasks and clears all storages
 func (f *SplitFactory) Destroy() {
-	if !f.IsDestroyed() {
+	alreadyDestroyed := f.IsDestroyed()
+	if !alreadyDestroyed {
 		removeInstanceFromTracker(f.apikey)
 	}
 	f.status.Store(sdkStatusDestroyed)
 
+	if f.impressionListener != nil {
+		f.impressionListener.Stop()
+	}
+
+	if f.auditSink != nil {
+		f.auditSink.Stop()
+	}
+
+	if f.tasks.splitsCheck != nil {
+		f.tasks.splitsCheck.Stop()
+	}
+
+	// Reported one last time before the redis client is closed
+	if f.tasks.dedup != nil {
+		f.tasks.dedup.Stop()
+		if !alreadyDestroyed {
+			f.impressionDeduper.ReportMetrics()
+		}
+	}
+
+	// Saved one last time before the redis client is closed
+	if f.tasks.observer != nil {
+		f.tasks.observer.Stop()
+		if !alreadyDestroyed {
+			f.impressionDeduper.Persist(f.observerState)
+		}
+	}
+
 	if f.cfg.OperationMode == "redis-consumer" {
+		// Closed once, as a closed client fails to close again
+		if f.redisClient != nil && !alreadyDestroyed {
+			if err := f.redisClient.Close(); err != nil {
+				f.logger.Warning("Error closing the redis client: ", err.Error())
+			}
+		}
 		return
 	}
 
-	// Stop all tasks
+	// Stop all tasks. Submission tasks flush pending data one last time when stopped
 	if f.tasks.splits != nil {
 		f.tasks.splits.Stop()
 	}
@@ -259,12 +615,36 @@ func (f *SplitFactory) Destroy() {
 	if f.tasks.gauges != nil {
 		f.tasks.gauges.Stop()
 	}
+	if f.tasks.snapshot != nil {
+		f.tasks.snapshot.Stop()
+	}
 	if f.tasks.counters != nil {
 		f.tasks.counters.Stop()
 	}
 	if f.tasks.latencies != nil {
 		f.tasks.latencies.Stop()
 	}
+	if f.tasks.events != nil {
+		f.tasks.events.Stop()
+	}
+	if f.tasks.metrics != nil {
+		f.tasks.metrics.Stop()
+	}
+	if f.tasks.queueDepths != nil {
+		f.tasks.queueDepths.Stop()
+	}
+}
+
+// Flush synchronously submits the impressions, events & metrics queued in memory, returning the first error.
+// In modes where they're stored in redis or not submitted at all there's nothing to flush
+func (f *SplitFactory) Flush() error {
+	if f.IsDestroyed() {
+		return errors.New("Client has already been destroyed - no calls possible")
+	}
+	if f.flush == nil {
+		return nil
+	}
+	return f.flush()
 }
 
 // setupLogger sets up the logger according to the parameters submitted by the sdk user
@@ -279,6 +659,18 @@ func setupLogger(cfg *conf.SplitSdkConfig) logging.LoggerInterface {
 	return logger
 }
 
+// apiConfig returns the config to create fetchers & recorders with. It's a copy whose transport is shared by all
+// of them, so that they authenticate with the apikey set in apikeyTransport and, if MaxInFlightRequests is set,
+// requests to Split servers are limited per factory
+func apiConfig(cfg *conf.SplitSdkConfig, apikeyTransport *api.APIKeyTransport) *conf.SplitSdkConfig {
+	shared := *cfg
+	shared.Advanced.HTTPTransport = apikeyTransport
+	if cfg.Advanced.MaxInFlightRequests > 0 {
+		shared.Advanced.HTTPTransport = api.NewLimitedTransport(apikeyTransport, cfg.Advanced.MaxInFlightRequests)
+	}
+	return &shared
+}
+
 func setupInMemoryFactory(
 	apikey string,
 	cfg *conf.SplitSdkConfig,
@@ -290,68 +682,98 @@ func setupInMemoryFactory(
 		return nil, err
 	}
 
+	apikeyTransport := api.NewAPIKeyTransport(cfg.Advanced.HTTPTransport, apikey)
+	apiCfg := apiConfig(cfg, apikeyTransport)
+
+	recentErrors := diagnostics.NewRecorder(cfg.Advanced.RecentErrorsSize)
+	storageLogger := diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategoryStorage)
+	syncLogger := diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategorySync)
+
 	inMememoryFullQueue := make(chan string, 2) // Size 2: So that it's able to accept one event from each resource simultaneously.
 
+	impressionsQueue := mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, inMememoryFullQueue, storageLogger)
+	eventsQueue := mutexqueue.NewMQEventsStorage(cfg.Advanced.EventsQueueSize, inMememoryFullQueue, storageLogger)
 	storages := sdkStorages{
 		splits:      mutexmap.NewMMSplitStorage(),
 		segments:    mutexmap.NewMMSegmentStorage(),
-		impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, inMememoryFullQueue, logger),
+		impressions: applyImpressionsMode(withSecondaryImpressionStorage(impressionsQueue, cfg, storageLogger), cfg),
 		telemetry:   mutexmap.NewMMMetricsStorage(),
-		events:      mutexqueue.NewMQEventsStorage(cfg.Advanced.EventsQueueSize, inMememoryFullQueue, logger),
+		events:      eventsQueue,
 	}
 
 	readyChannel := make(chan string, 1)
+	segmentSyncStatus := tasks.NewSegmentSyncStatus()
+	impressionRecorder := api.NewHTTPImpressionRecorder(apikey, apiCfg, metadata, syncLogger)
+	eventsRecorder := api.NewHTTPEventsRecorder(apikey, apiCfg, metadata, syncLogger)
+	metricsRecorder := api.NewHTTPMetricsRecorder(apikey, apiCfg, metadata, syncLogger)
 
 	syncTasks := sdkSync{
 		splits: tasks.NewFetchSplitsTask(
 			storages.splits.(storage.SplitStorage),
-			api.NewHTTPSplitFetcher(apikey, cfg, logger),
-			cfg.TaskPeriods.SplitSync,
-			logger,
+			api.NewHTTPSplitFetcher(apikey, apiCfg, syncLogger),
+			tasks.SplitSyncOptions{
+				Period:             cfg.TaskPeriods.SplitSync,
+				InitialSyncTimeout: time.Duration(cfg.Advanced.InitialSyncTimeout) * time.Second,
+				StrictPartitions:   cfg.Advanced.StrictPartitions,
+				BackoffStrategy:    cfg.Advanced.BackoffStrategy,
+			},
+			syncLogger,
 			readyChannel,
 		),
 		segments: tasks.NewFetchSegmentsTask(
 			storages.splits.(storage.SplitStorage),
 			storages.segments.(storage.SegmentStorage),
-			api.NewHTTPSegmentFetcher(apikey, cfg, logger),
+			api.NewHTTPSegmentFetcher(apikey, apiCfg, syncLogger),
 			cfg.TaskPeriods.SegmentSync,
 			cfg.Advanced.SegmentWorkers,
 			cfg.Advanced.SegmentQueueSize,
-			logger,
+			syncLogger,
 			readyChannel,
+			segmentSyncStatus,
+			cfg.Advanced.BackoffStrategy,
 		),
 		impressions: tasks.NewRecordImpressionsTask(
 			storages.impressions.(storage.ImpressionStorage),
-			api.NewHTTPImpressionRecorder(apikey, cfg, metadata, logger),
+			impressionRecorder,
 			cfg.TaskPeriods.ImpressionSync,
-			logger,
+			syncLogger,
 			cfg.Advanced.ImpressionsBulkSize,
 		),
-		counters: tasks.NewRecordCountersTask(
+		events: tasks.NewRecordEventsTask(
+			storages.events.(storage.EventsStorage),
+			eventsRecorder,
+			cfg.Advanced.EventsBulkSize,
+			cfg.TaskPeriods.EventsSync,
+			syncLogger,
+		),
+	}
+
+	if cfg.Advanced.MetricsBatching {
+		syncTasks.metrics = tasks.NewRecordMetricsTask(
 			storages.telemetry.(storage.MetricsStorage),
-			api.NewHTTPMetricsRecorder(apikey, cfg, metadata, logger),
+			metricsRecorder,
 			cfg.TaskPeriods.CounterSync,
-			logger,
-		),
-		gauges: tasks.NewRecordGaugesTask(
+			syncLogger,
+		)
+	} else {
+		syncTasks.counters = tasks.NewRecordCountersTask(
+			storages.telemetry.(storage.MetricsStorage),
+			metricsRecorder,
+			cfg.TaskPeriods.CounterSync,
+			syncLogger,
+		)
+		syncTasks.gauges = tasks.NewRecordGaugesTask(
 			storages.telemetry.(storage.MetricsStorage),
-			api.NewHTTPMetricsRecorder(apikey, cfg, metadata, logger),
+			metricsRecorder,
 			cfg.TaskPeriods.GaugeSync,
-			logger,
-		),
-		latencies: tasks.NewRecordLatenciesTask(
+			syncLogger,
+		)
+		syncTasks.latencies = tasks.NewRecordLatenciesTask(
 			storages.telemetry.(storage.MetricsStorage),
-			api.NewHTTPMetricsRecorder(apikey, cfg, metadata, logger),
+			metricsRecorder,
 			cfg.TaskPeriods.LatencySync,
-			logger,
-		),
-		events: tasks.NewRecordEventsTask(
-			storages.events.(storage.EventsStorage),
-			api.NewHTTPEventsRecorder(apikey, cfg, metadata, logger),
-			cfg.Advanced.EventsBulkSize,
-			cfg.TaskPeriods.EventsSync,
-			logger,
-		),
+			syncLogger,
+		)
 	}
 
 	splitFactory := SplitFactory{
@@ -360,14 +782,77 @@ func setupInMemoryFactory(
 		metadata:              *metadata,
 		logger:                logger,
 		operationMode:         "inmemory-standalone",
+		recentErrors:          recentErrors,
+		apikeyTransport:       apikeyTransport,
 		storages:              storages,
 		tasks:                 syncTasks,
+		segmentSyncStatus:     segmentSyncStatus,
 		readinessSubscriptors: make(map[int]chan int),
 	}
 	splitFactory.status.Store(sdkStatusInitializing)
+	// Every queue is flushed even if a previous one failed
+	flushMetrics := func() error {
+		return tasks.FlushMetrics(storages.telemetry.(storage.MetricsStorage), metricsRecorder)
+	}
+	if cfg.Advanced.MetricsBatching {
+		flushMetrics = func() error {
+			return tasks.FlushMetricsBatch(storages.telemetry.(storage.MetricsStorage), metricsRecorder)
+		}
+	}
+	splitFactory.flush = func() error {
+		var first error
+		for _, flush := range []func() error{
+			func() error {
+				return tasks.FlushImpressions(
+					storages.impressions.(storage.ImpressionStorage),
+					impressionRecorder,
+					cfg.Advanced.ImpressionsBulkSize,
+					syncLogger,
+				)
+			},
+			func() error {
+				return tasks.FlushEvents(storages.events.(storage.EventsStorage), eventsRecorder, cfg.Advanced.EventsBulkSize, syncLogger)
+			},
+			flushMetrics,
+		} {
+			if err := flush(); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+
+	if cfg.Advanced.SnapshotFile != "" && cfg.Advanced.SnapshotPersistPeriod > 0 {
+		splitFactory.tasks.snapshot = tasks.NewPersistSnapshotTask(
+			storages.splits.(storage.SplitStorage),
+			storages.segments.(storage.SegmentStorage),
+			cfg.Advanced.SnapshotFile,
+			cfg.Advanced.SnapshotPersistPeriod,
+			syncLogger,
+		)
+	}
+
+	if cfg.Advanced.QueueDepthMetrics {
+		splitFactory.tasks.queueDepths = tasks.NewSampleQueueDepthsTask(
+			storages.telemetry,
+			map[string]tasks.QueueDepthSource{
+				tasks.ImpressionsQueueDepthGauge: impressionsQueue,
+				tasks.EventsQueueDepthGauge:      eventsQueue,
+				tasks.SegmentsQueueDepthGauge:    tasks.QueueDepthFunc(segmentSyncStatus.QueueDepth),
+			},
+			cfg.TaskPeriods.GaugeSync,
+			syncLogger,
+		)
+	}
+
+	splitFactory.tasks.splitsCheck = newSplitsSelfCheckTask(storages.splits, storages.telemetry, cfg, syncLogger)
 
-	go splitFactory.initializationInMemory(readyChannel, &syncTasks)
-	go dataFlusher(&syncTasks, inMememoryFullQueue, logger)
+	if notReadySnapshot := loadNotReadySnapshot(cfg, logger); notReadySnapshot != nil {
+		splitFactory.snapshot = newSnapshotStorages(notReadySnapshot)
+	}
+
+	go splitFactory.initializationInMemory(readyChannel, &splitFactory.tasks)
+	go dataFlusher(&splitFactory.tasks, inMememoryFullQueue, syncLogger)
 
 	return &splitFactory, nil
 }
@@ -378,18 +863,69 @@ func setupRedisFactory(
 	logger logging.LoggerInterface,
 	metadata *splitio.SdkMetadata,
 ) (*SplitFactory, error) {
+	if cfg.Redis.TLSConfig != nil && cfg.Redis.TLSEnabled {
+		logger.Warning("Both redis TLSConfig and TLSEnabled are set, TLSConfig will be used and the rest of TLS fields ignored.")
+	}
+
 	redisClient, err := redisdb.NewPrefixedRedisClient(&cfg.Redis)
 	if err != nil {
 		logger.Error("Failed to instantiate redis client.")
 		return nil, err
 	}
 
+	if cfg.Redis.WarmUpConnections > 0 {
+		warm := redisClient.WarmUp(cfg.Redis.WarmUpConnections, time.Duration(cfg.Redis.WarmUpTimeout)*time.Second)
+		if warm < cfg.Redis.WarmUpConnections {
+			logger.Warning(fmt.Sprintf(
+				"Redis warm-up established %d out of %d connections, the rest will be established on demand.",
+				warm,
+				cfg.Redis.WarmUpConnections,
+			))
+		}
+	}
+
+	recentErrors := diagnostics.NewRecorder(cfg.Advanced.RecentErrorsSize)
+	storageLogger := diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategoryStorage)
+
+	splitStorage := redisdb.NewRedisSplitStorage(redisClient, storageLogger)
+	err = validateRedisPrefix(splitStorage, &cfg.Redis, logger)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Redis.ReconcileTrafficTypes {
+		// Failures are logged, the drifted counters only affect Track's traffic type validation
+		splitStorage.ReconcileTrafficTypes()
+	}
+
+	var segmentStorage storage.SegmentStorage = redisdb.NewRedisSegmentStorage(redisClient, storageLogger)
+	if cfg.Advanced.SegmentCacheSize > 0 {
+		segmentStorage = storage.NewCachedSegmentStorage(
+			redisdb.NewRedisSegmentStorage(redisClient, storageLogger),
+			cfg.Advanced.SegmentCacheSize,
+			time.Duration(cfg.Advanced.SegmentCacheTTL)*time.Second,
+		)
+	}
+	if len(cfg.Advanced.SegmentStorageChain) > 0 {
+		chain := make([]storage.SegmentStorage, 0, len(cfg.Advanced.SegmentStorageChain)+1)
+		chain = append(chain, cfg.Advanced.SegmentStorageChain...)
+		segmentStorage = storage.NewChainedSegmentStorage(append(chain, segmentStorage)...)
+	}
+
+	metricsStorage := redisdb.NewRedisMetricsStorage(redisClient, metadata, storageLogger)
+	impressionStorage := redisdb.NewRedisImpressionStorageWithTTLs(
+		redisClient,
+		metadata,
+		time.Duration(cfg.Advanced.ImpressionsTTL)*time.Second,
+		featureImpressionTTLs(cfg),
+		metricsStorage,
+		storageLogger,
+	)
 	storages := sdkStorages{
-		splits:      redisdb.NewRedisSplitStorage(redisClient, logger),
-		segments:    redisdb.NewRedisSegmentStorage(redisClient, logger),
-		impressions: redisdb.NewRedisImpressionStorage(redisClient, metadata, logger),
-		telemetry:   redisdb.NewRedisMetricsStorage(redisClient, metadata, logger),
-		events:      redisdb.NewRedisEventsStorage(redisClient, metadata, logger),
+		splits:      splitStorage,
+		segments:    segmentStorage,
+		impressions: applyImpressionsMode(withSecondaryImpressionStorage(impressionStorage, cfg, storageLogger), cfg),
+		telemetry:   metricsStorage,
+		events:      redisdb.NewRedisEventsStorage(redisClient, metadata, storageLogger),
 	}
 
 	factory := &SplitFactory{
@@ -398,23 +934,129 @@ func setupRedisFactory(
 		metadata:              *metadata,
 		logger:                logger,
 		operationMode:         "redis-consumer",
+		recentErrors:          recentErrors,
 		storages:              storages,
+		redisClient:           redisClient,
 		readinessSubscriptors: make(map[int]chan int),
 	}
 	factory.status.Store(sdkStatusReady)
+
+	factory.tasks.splitsCheck = newSplitsSelfCheckTask(
+		splitStorage,
+		storages.telemetry,
+		cfg,
+		diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategorySync),
+	)
+	if factory.tasks.splitsCheck != nil {
+		factory.tasks.splitsCheck.Start()
+	}
 	return factory, nil
 }
 
+// newImpressionDeduper returns the deduper of "optimized" mode, along with the task reporting its metrics.
+// If PersistImpressionObserver is set, the combinations deduped before a restart are restored from redis and saved
+// back periodically & on Destroy
+func (f *SplitFactory) newImpressionDeduper() *impressionsutil.ImpressionDeduper {
+	deduper := f.restoreImpressionDeduper()
+	f.tasks.dedup = tasks.NewReportDedupMetricsTask(deduper, f.cfg.TaskPeriods.GaugeSync, f.logger)
+	f.tasks.dedup.Start()
+	return deduper
+}
+
+// restoreImpressionDeduper returns the deduper of "optimized" mode, restored from redis if PersistImpressionObserver
+// is set
+func (f *SplitFactory) restoreImpressionDeduper() *impressionsutil.ImpressionDeduper {
+	size := f.cfg.Advanced.ImpressionObserverSize
+	window := time.Duration(f.cfg.Advanced.ImpressionsDedupWindow) * time.Second
+	if !f.cfg.Advanced.PersistImpressionObserver {
+		return impressionsutil.NewImpressionDeduper(size, window, f.storages.telemetry)
+	}
+	if f.redisClient == nil {
+		f.logger.Warning("PersistImpressionObserver is only supported in redis-consumer mode and will be ignored")
+		return impressionsutil.NewImpressionDeduper(size, window, f.storages.telemetry)
+	}
+
+	f.observerState = redisdb.NewRedisImpressionObserverStorage(f.redisClient, &f.metadata, f.logger)
+	deduper, err := impressionsutil.RestoreImpressionDeduper(size, window, f.storages.telemetry, f.observerState)
+	if err != nil {
+		f.logger.Warning("Could not restore the impression observer, recently deduped impressions may be stored again: ", err.Error())
+	}
+	f.tasks.observer = tasks.NewPersistImpressionObserverTask(deduper, f.observerState, f.cfg.Advanced.ObserverPersistPeriod, f.logger)
+	f.tasks.observer.Start()
+	return deduper
+}
+
+// featureImpressionTTLs returns the impression TTL overrides set in the config as durations
+func featureImpressionTTLs(cfg *conf.SplitSdkConfig) map[string]time.Duration {
+	if len(cfg.Advanced.FeatureImpressionTTLs) == 0 {
+		return nil
+	}
+	ttls := make(map[string]time.Duration, len(cfg.Advanced.FeatureImpressionTTLs))
+	for feature, seconds := range cfg.Advanced.FeatureImpressionTTLs {
+		ttls[feature] = time.Duration(seconds) * time.Second
+	}
+	return ttls
+}
+
+// newSplitsSelfCheckTask returns the task validating stored splits, or nil if it's disabled
+func newSplitsSelfCheckTask(
+	splitStorage storage.SplitStorageConsumer,
+	metricsStorage storage.MetricsStorageProducer,
+	cfg *conf.SplitSdkConfig,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	if cfg.Advanced.SplitsSelfCheckPeriod <= 0 {
+		return nil
+	}
+	return tasks.NewSplitsSelfCheckTask(
+		splitStorage,
+		metricsStorage,
+		cfg.Advanced.SplitsSelfCheckPeriod,
+		cfg.Advanced.SplitsSelfCheckBatchSize,
+		logger,
+	)
+}
+
+// validateRedisPrefix checks that the synchronizer has written data under the configured prefix.
+// A prefix mismatch would otherwise go unnoticed and every evaluation would return CONTROL
+func validateRedisPrefix(splitStorage *redisdb.RedisSplitStorage, cfg *conf.RedisConfig, logger logging.LoggerInterface) error {
+	found, err := splitStorage.HasChangeNumber()
+	if err != nil {
+		logger.Error("Could not validate redis prefix: ", err.Error())
+		return nil
+	}
+
+	if found {
+		return nil
+	}
+
+	message := fmt.Sprintf(
+		"No split data found in redis under prefix \"%s\". Make sure the prefix matches the one used by the synchronizer, "+
+			"otherwise every evaluation will return CONTROL",
+		cfg.Prefix,
+	)
+	logger.Error(message)
+	if cfg.FailOnMissingData {
+		return errors.New(message)
+	}
+	return nil
+}
+
 func setupLocalhostFactory(
 	apikey string,
 	cfg *conf.SplitSdkConfig,
 	logger logging.LoggerInterface,
 	metadata *splitio.SdkMetadata,
 ) (*SplitFactory, error) {
+	recentErrors := diagnostics.NewRecorder(cfg.Advanced.RecentErrorsSize)
+	storageLogger := diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategoryStorage)
+	syncLogger := diagnostics.NewRecordingLogger(logger, recentErrors, diagnostics.CategorySync)
+
 	splitStorage := mutexmap.NewMMSplitStorage()
-	splitFetcher := local.NewFileSplitFetcher(cfg.SplitFile, logger)
+	splitFetcher := local.NewFileSplitFetcher(cfg.SplitFile, syncLogger)
 	splitPeriod := cfg.TaskPeriods.SplitSync
 	readyChannel := make(chan string, 1)
+	impressionsQueue := mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), storageLogger)
 
 	splitFactory := &SplitFactory{
 		apikey:   apikey,
@@ -423,15 +1065,16 @@ func setupLocalhostFactory(
 		logger:   logger,
 		storages: sdkStorages{
 			splits:      splitStorage,
-			impressions: mutexqueue.NewMQImpressionsStorage(cfg.Advanced.ImpressionsQueueSize, make(chan string, 1), logger),
+			impressions: applyImpressionsMode(impressionsQueue, cfg),
 			telemetry:   mutexmap.NewMMMetricsStorage(),
-			events:      mutexqueue.NewMQEventsStorage(cfg.Advanced.EventsQueueSize, make(chan string, 1), logger),
+			events:      mutexqueue.NewMQEventsStorage(cfg.Advanced.EventsQueueSize, make(chan string, 1), storageLogger),
 			segments:    mutexmap.NewMMSegmentStorage(),
 		},
 		tasks: sdkSync{
-			splits: tasks.NewFetchSplitsTask(splitStorage, splitFetcher, splitPeriod, logger, readyChannel),
+			splits: tasks.NewFetchSplitsTask(splitStorage, splitFetcher, tasks.SplitSyncOptions{Period: splitPeriod}, syncLogger, readyChannel),
 		},
 
+		recentErrors:          recentErrors,
 		readinessSubscriptors: make(map[int]chan int),
 	}
 	splitFactory.status.Store(sdkStatusInitializing)
@@ -469,12 +1112,50 @@ func newFactory(apikey string, cfg *conf.SplitSdkConfig, logger logging.LoggerIn
 		return nil, err
 	}
 
+	// Shared by every client, so that evaluations are limited per factory
+	if cfg.Advanced.MaxConcurrentEvaluations > 0 {
+		splitFactory.evaluationSlots = ratelimit.NewSemaphore(cfg.Advanced.MaxConcurrentEvaluations)
+	}
+
 	if cfg.Advanced.ImpressionListener != nil {
-		splitFactory.impressionListener = impressionlistener.NewImpressionListenerWrapper(
-			cfg.Advanced.ImpressionListener,
-			&metadata,
+		if cfg.Advanced.ImpressionListenerQueueSize > 0 {
+			splitFactory.impressionListener = impressionlistener.NewPooledImpressionListenerWrapper(
+				cfg.Advanced.ImpressionListener,
+				&metadata,
+				cfg.Advanced.ImpressionListenerQueueSize,
+				cfg.Advanced.ImpressionListenerWorkers,
+				splitFactory.storages.telemetry,
+				logger,
+			)
+		} else {
+			splitFactory.impressionListener = impressionlistener.NewImpressionListenerWrapper(
+				cfg.Advanced.ImpressionListener,
+				&metadata,
+			)
+		}
+	}
+
+	if cfg.Advanced.ImpressionEnricher != nil {
+		splitFactory.impressionEnricher = impressionenricher.NewImpressionEnricherWrapper(
+			cfg.Advanced.ImpressionEnricher,
+			time.Duration(cfg.Advanced.ImpressionEnricherTimeout)*time.Millisecond,
+			logger,
 		)
 	}
 
+	if cfg.Advanced.AuditSink != nil {
+		splitFactory.auditSink = auditsink.NewAuditSinkWrapper(
+			cfg.Advanced.AuditSink,
+			cfg.Advanced.AuditSinkQueueSize,
+			splitFactory.storages.telemetry,
+			logger,
+		)
+	}
+
+	// Shared by every client, so that impressions are deduped per factory
+	if cfg.Advanced.ImpressionsMode == conf.ImpressionsModeOptimized {
+		splitFactory.impressionDeduper = splitFactory.newImpressionDeduper()
+	}
+
 	return splitFactory, nil
 }
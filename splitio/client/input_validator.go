@@ -7,6 +7,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/splitio/go-client/splitio/engine/evaluator/impressionlabels"
 	"github.com/splitio/go-client/splitio/storage"
@@ -14,6 +16,17 @@ import (
 	"github.com/splitio/go-toolkit/logging"
 )
 
+// truncate returns the longest prefix of value of up to maxLength bytes that doesn't split a multi-byte character
+func truncate(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	for maxLength > 0 && !utf8.RuneStart(value[maxLength]) {
+		maxLength--
+	}
+	return value[:maxLength]
+}
+
 // InputValidation struct is responsible for cheking any input of treatment and
 // track methods.
 
@@ -26,9 +39,35 @@ const MaxEventLength = 32768
 // RegExpEventType constant that EventType must match
 const RegExpEventType = "^[a-zA-Z0-9][-_.:a-zA-Z0-9]{0,79}$"
 
+// RegExpFeatureName constant that feature names must match
+const RegExpFeatureName = "^[a-zA-Z0-9][-_.:a-zA-Z0-9]*$"
+
+var featureNameRegExp = regexp.MustCompile(RegExpFeatureName)
+
 type inputValidation struct {
-	logger       logging.LoggerInterface
-	splitStorage storage.SplitStorageConsumer
+	logger               logging.LoggerInterface
+	splitStorage         storage.SplitStorageConsumer
+	trimKeys             bool
+	maxFeatureNameLength int
+}
+
+// malformedFeatureNameError is returned for feature names that can't exist in Split, ie: names built
+// programmatically with a bug. Unlike other input errors it's logged as a warning
+type malformedFeatureNameError struct {
+	message string
+}
+
+func (e *malformedFeatureNameError) Error() string {
+	return e.message
+}
+
+// logError logs an input validation error, as a warning if it's caused by a malformed feature name
+func (i *inputValidation) logError(err error) {
+	if _, ok := err.(*malformedFeatureNameError); ok {
+		i.logger.Warning(err.Error())
+		return
+	}
+	i.logger.Error(err.Error())
 }
 
 func parseIfNumeric(value interface{}, operation string) (string, error) {
@@ -103,6 +142,18 @@ func checkValidKeyObject(matchingKey string, bucketingKey *string, operation str
 	return matchingKey, bucketingKey, nil
 }
 
+// trimKey removes leading & trailing whitespace from a key if trimming keys is enabled
+func (i *inputValidation) trimKey(key string, operation string) string {
+	if !i.trimKeys {
+		return key
+	}
+	trimmed := strings.TrimSpace(key)
+	if trimmed != key {
+		i.logger.Verbose(fmt.Sprintf(operation+": key '%s' has leading or trailing whitespace, trimming", key))
+	}
+	return trimmed
+}
+
 // ValidateTreatmentKey implements the validation for Treatment call
 func (i *inputValidation) ValidateTreatmentKey(key interface{}, operation string) (string, *string, error) {
 	if key == nil {
@@ -110,7 +161,11 @@ func (i *inputValidation) ValidateTreatmentKey(key interface{}, operation string
 	}
 	okey, ok := key.(*Key)
 	if ok {
-		return checkValidKeyObject(okey.MatchingKey, &okey.BucketingKey, operation)
+		if okey == nil {
+			return "", nil, errors.New(operation + ": you passed a nil key, key must be a non-empty string")
+		}
+		bucketingKey := i.trimKey(okey.BucketingKey, operation)
+		return checkValidKeyObject(i.trimKey(okey.MatchingKey, operation), &bucketingKey, operation)
 	}
 	var sMatchingKey string
 	var err error
@@ -122,6 +177,7 @@ func (i *inputValidation) ValidateTreatmentKey(key interface{}, operation string
 		}
 		i.logger.Warning(fmt.Sprintf(operation+": key %s is not of type string, converting", key))
 	}
+	sMatchingKey = i.trimKey(sMatchingKey, operation)
 	err = checkIsValidString(sMatchingKey, "key", operation)
 	if err != nil {
 		return "", nil, err
@@ -136,7 +192,112 @@ func (i *inputValidation) ValidateFeatureName(featureName string, operation stri
 	if err != nil {
 		return "", err
 	}
-	return i.checkWhitespaces(featureName, operation), nil
+	featureName = i.checkWhitespaces(featureName, operation)
+
+	maxLength := i.maxFeatureNameLength
+	if maxLength <= 0 {
+		maxLength = MaxLength
+	}
+	if len(featureName) > maxLength {
+		return "", &malformedFeatureNameError{message: fmt.Sprintf(
+			"%s: malformed feature name '%s...', feature names must be %d characters or less. Returning CONTROL",
+			operation, truncate(featureName, maxLength), maxLength,
+		)}
+	}
+	if !featureNameRegExp.MatchString(featureName) {
+		return "", &malformedFeatureNameError{message: fmt.Sprintf(
+			"%s: malformed feature name '%s', feature names must adhere to the regular expression %s. Returning CONTROL",
+			operation, featureName, RegExpFeatureName,
+		)}
+	}
+	return featureName, nil
+}
+
+// ValidateAttributes normalizes the attributes passed to an evaluation into the types the matchers expect:
+// integers of every size and integral floats become int64, time.Time becomes a unix timestamp in seconds as
+// datetime matchers expect, and nil values are dropped. Strings, booleans, other floats & slices are kept as they are.
+// Attributes of any other type are kept too, but a warning is logged since no matcher will match them.
+// The attributes map is copied only if some attribute needs to be changed
+func (i *inputValidation) ValidateAttributes(attributes map[string]interface{}, operation string) map[string]interface{} {
+	sanitized := attributes
+	copied := false
+	for name, value := range attributes {
+		normalized, changed := i.normalizeAttribute(name, value, operation)
+		if !changed {
+			continue
+		}
+		if !copied {
+			sanitized = make(map[string]interface{}, len(attributes))
+			for k, v := range attributes {
+				sanitized[k] = v
+			}
+			copied = true
+		}
+		if normalized == nil {
+			delete(sanitized, name)
+		} else {
+			sanitized[name] = normalized
+		}
+	}
+	return sanitized
+}
+
+// normalizeAttribute returns the normalized value of an attribute and whether it differs from the one passed.
+// A nil value means the attribute should be dropped
+func (i *inputValidation) normalizeAttribute(name string, value interface{}, operation string) (interface{}, bool) {
+	switch typed := value.(type) {
+	case nil:
+		return nil, true
+	case string, bool, int64, []string, []interface{}:
+		return value, false
+	case int:
+		return int64(typed), true
+	case int8:
+		return int64(typed), true
+	case int16:
+		return int64(typed), true
+	case int32:
+		return int64(typed), true
+	case uint8:
+		return int64(typed), true
+	case uint16:
+		return int64(typed), true
+	case uint32:
+		return int64(typed), true
+	case uint:
+		if uint64(typed) > math.MaxInt64 {
+			return value, false
+		}
+		return int64(typed), true
+	case uint64:
+		if typed > math.MaxInt64 {
+			return value, false
+		}
+		return int64(typed), true
+	case float32:
+		return normalizeFloat(float64(typed)), true
+	case float64:
+		normalized := normalizeFloat(typed)
+		_, isInt := normalized.(int64)
+		return normalized, isInt
+	case time.Time:
+		return typed.Unix(), true
+	case *time.Time:
+		if typed == nil {
+			return nil, true
+		}
+		return typed.Unix(), true
+	}
+	i.logger.Warning(fmt.Sprintf("%s: attribute '%s' is of unsupported type %T, it won't match any condition", operation, name, value))
+	return value, false
+}
+
+// normalizeFloat returns integral floats as int64, so that they can be compared by numeric matchers
+func normalizeFloat(value float64) interface{} {
+	if value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64 {
+		return int64(value)
+	}
+	return value
 }
 
 func checkEventType(eventType string) error {
@@ -232,7 +393,7 @@ func (i *inputValidation) ValidateFeatureNames(features []string, operation stri
 	for _, feature := range features {
 		f, err := i.ValidateFeatureName(feature, operation)
 		if err != nil {
-			i.logger.Error(err.Error())
+			i.logError(err)
 		} else {
 			featuresSet.Add(f)
 		}
@@ -286,6 +447,9 @@ func (i *inputValidation) validateTrackProperties(properties map[string]interfac
 }
 
 func (i *inputValidation) IsSplitFound(label string, feature string, operation string) bool {
+	if label == impressionlabels.NoSplitsLoaded {
+		return false
+	}
 	if label == impressionlabels.SplitNotFound {
 		i.logger.Error(fmt.Sprintf(operation+": you passed %s that does not exist in this environment, please double check what Splits exist in the web console.", feature))
 		return false
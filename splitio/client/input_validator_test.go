@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/splitio/go-client/splitio/conf"
 	"github.com/splitio/go-client/splitio/service/dtos"
@@ -111,6 +112,10 @@ func TestTreatmentValidatorOnKeys(t *testing.T) {
 	expectedTreatment(client.Treatment(nil, "feature", nil), "control", t)
 	expectedLogMessage("Treatment: you passed a nil key, key must be a non-empty string", t)
 
+	// Nil key object
+	expectedTreatment(client.Treatment((*Key)(nil), "feature", nil), "control", t)
+	expectedLogMessage("Treatment: you passed a nil key, key must be a non-empty string. Feature: 'feature'", t)
+
 	// Boolean
 	expectedTreatment(client.Treatment(true, "feature", nil), "control", t)
 	expectedLogMessage("Treatment: you passed an invalid key, key must be a non-empty string", t)
@@ -199,6 +204,28 @@ func TestTreatmentValidatorOnFeatureName(t *testing.T) {
 	expectedLogMessage("TreatmentWithConfig: you passed feature_non_existent that does not exist in this environment, please double check what Splits exist in the web console", t)
 }
 
+func TestTreatmentValidatorOnMalformedFeatureName(t *testing.T) {
+	// Too long
+	expectedTreatment(client.Treatment("key", strings.Repeat("f", 251), nil), "control", t)
+	expectedLogMessage("Treatment: malformed feature name '"+strings.Repeat("f", 250)+"...', feature names must be 250 characters or less. Returning CONTROL", t)
+
+	// Invalid characters
+	expectedTreatment(client.Treatment("key", "feature/"+"with spaces", nil), "control", t)
+	expectedLogMessage("Treatment: malformed feature name 'feature/with spaces', feature names must adhere to the regular expression "+RegExpFeatureName+". Returning CONTROL", t)
+
+	result := expectedTreatments("key", []string{"feature", "feature?"}, 1, t)
+	expectedTreatment(result["feature"], "TreatmentA", t)
+
+	// Configured limit
+	limited := client
+	limited.validator.maxFeatureNameLength = 5
+	expectedTreatment(limited.Treatment("key", "feature", nil), "control", t)
+	expectedLogMessage("Treatment: malformed feature name 'featu...', feature names must be 5 characters or less. Returning CONTROL", t)
+	// Truncated on a character boundary
+	expectedTreatment(limited.Treatment("key", "featß/ure", nil), "control", t)
+	expectedLogMessage("Treatment: malformed feature name 'feat...', feature names must be 5 characters or less. Returning CONTROL", t)
+}
+
 func expectedTreatments(key interface{}, features []string, length int, t *testing.T) map[string]string {
 	result := client.Treatments(key, features, nil)
 	if len(result) != length {
@@ -215,7 +242,11 @@ func TestTreatmentsValidator(t *testing.T) {
 	// Inf
 	result := expectedTreatments(math.Inf, []string{"feature"}, 1, t)
 	expectedTreatment(result["feature"], "control", t)
-	expectedLogMessage("Treatments: you passed an invalid key, key must be a non-empty string", t)
+	expectedLogMessage("Treatments: you passed an invalid key, key must be a non-empty string. Features: [feature]", t)
+
+	// Whitespace only feature
+	expectedTreatments("key", []string{"   "}, 0, t)
+	expectedLogMessage("Treatments: features must be a non-empty array", t)
 
 	// Float
 	result = expectedTreatments(1.3, []string{"feature"}, 1, t)
@@ -408,3 +439,48 @@ func TestManagerWithEmptySplit(t *testing.T) {
 	manager.Split("non_existent")
 	expectedLogMessage("Split: you passed non_existent that does not exist in this environment, please double check what Splits exist in the web console", t)
 }
+
+func TestValidateAttributes(t *testing.T) {
+	registered := time.Date(2019, 3, 1, 10, 30, 0, 0, time.UTC)
+	attributes := map[string]interface{}{
+		"plan":       "premium",
+		"age":        int32(30),
+		"visits":     uint(7),
+		"score":      float64(12),
+		"ratio":      0.5,
+		"registered": registered,
+		"tags":       []string{"a", "b"},
+		"missing":    nil,
+		"channel":    make(chan int),
+	}
+
+	sanitized := client.validator.ValidateAttributes(attributes, "Treatment")
+	expectedLogMessage("Treatment: attribute 'channel' is of unsupported type chan int", t)
+
+	if sanitized["plan"] != "premium" || sanitized["age"] != int64(30) || sanitized["visits"] != int64(7) ||
+		sanitized["score"] != int64(12) || sanitized["ratio"] != 0.5 {
+		t.Error("Numeric attributes should be coerced to int64 unless they're not integral. Got:", sanitized)
+	}
+	if sanitized["registered"] != registered.Unix() {
+		t.Error("time.Time attributes should be converted to a unix timestamp. Got:", sanitized["registered"])
+	}
+	if tags, ok := sanitized["tags"].([]string); !ok || len(tags) != 2 {
+		t.Error("[]string attributes should be kept. Got:", sanitized["tags"])
+	}
+	if _, found := sanitized["missing"]; found {
+		t.Error("nil attributes should be dropped")
+	}
+	if _, found := sanitized["channel"]; !found {
+		t.Error("Unsupported attributes should be kept")
+	}
+	if attributes["age"] != int32(30) || attributes["missing"] != nil || len(attributes) != 9 {
+		t.Error("The attributes passed should not be modified")
+	}
+
+	untouched := map[string]interface{}{"plan": "premium", "age": int64(30)}
+	sanitized = client.validator.ValidateAttributes(untouched, "Treatment")
+	sanitized["plan"] = "changed"
+	if untouched["plan"] != "changed" {
+		t.Error("The attributes should not be copied if none needs to be changed")
+	}
+}
@@ -24,6 +24,7 @@ type SplitView struct {
 	Treatments   []string          `json:"treatments"`
 	ChangeNumber int64             `json:"changeNumber"`
 	Configs      map[string]string `json:"configs"`
+	Sets         []string          `json:"sets"`
 }
 
 func newSplitView(splitDto *dtos.SplitDTO) *SplitView {
@@ -33,6 +34,8 @@ func newSplitView(splitDto *dtos.SplitDTO) *SplitView {
 			treatments = append(treatments, partition.Treatment)
 		}
 	}
+	sets := make([]string, 0, len(splitDto.Sets))
+	sets = append(sets, splitDto.Sets...)
 	return &SplitView{
 		ChangeNumber: splitDto.ChangeNumber,
 		Killed:       splitDto.Killed,
@@ -40,6 +43,7 @@ func newSplitView(splitDto *dtos.SplitDTO) *SplitView {
 		TrafficType:  splitDto.TrafficTypeName,
 		Treatments:   treatments,
 		Configs:      splitDto.Configurations,
+		Sets:         sets,
 	}
 }
 
@@ -101,6 +105,37 @@ func (m *SplitManager) Split(feature string) *SplitView {
 	return nil
 }
 
+// TreatmentExists returns true if the feature declares the treatment, either in one of its conditions or as
+// its default treatment. It returns false for features that don't exist
+func (m *SplitManager) TreatmentExists(feature string, treatment string) bool {
+	if m.isDestroyed() {
+		m.logger.Error("Client has already been destroyed - no calls possible")
+		return false
+	}
+
+	if !m.isReady() {
+		m.logger.Warning("treatmentExists: the SDK is not ready, results may be incorrect. Make sure to wait for SDK readiness before using this method")
+	}
+
+	split := m.splitStorage.Get(feature)
+	if split == nil {
+		return false
+	}
+
+	if split.DefaultTreatment == treatment {
+		return true
+	}
+
+	for _, condition := range split.Conditions {
+		for _, partition := range condition.Partitions {
+			if partition.Treatment == treatment {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // BlockUntilReady Calls BlockUntilReady on factory to block manager on readiness
 func (m *SplitManager) BlockUntilReady(timer int) error {
 	return m.factory.BlockUntilReady(timer)
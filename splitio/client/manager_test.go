@@ -159,3 +159,83 @@ func TestSplitManagerWithConfigs(t *testing.T) {
 		t.Error("Nonexistent split should return nil")
 	}
 }
+
+func TestSplitManagerWithSets(t *testing.T) {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			ChangeNumber:    123,
+			Name:            "split1",
+			TrafficTypeName: "tt1",
+			Sets:            []string{"set1", "set2"},
+		},
+		{
+			ChangeNumber:    123,
+			Name:            "split2",
+			TrafficTypeName: "tt2",
+		},
+	}, 123)
+
+	logger := logging.NewLogger(nil)
+	factory := SplitFactory{}
+	manager := SplitManager{
+		splitStorage: splitStorage,
+		validator:    inputValidation{logger: logger},
+		logger:       logger,
+		factory:      &factory,
+	}
+
+	factory.status.Store(sdkStatusReady)
+
+	s1 := manager.Split("split1")
+	if len(s1.Sets) != 2 || s1.Sets[0] != "set1" || s1.Sets[1] != "set2" {
+		t.Error("Sets should be set1 and set2. Got:", s1.Sets)
+	}
+
+	s2 := manager.Split("split2")
+	if s2.Sets == nil || len(s2.Sets) != 0 {
+		t.Error("Sets should be an empty slice for a split without sets")
+	}
+}
+
+func TestSplitManagerTreatmentExists(t *testing.T) {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			ChangeNumber:     123,
+			Name:             "split1",
+			DefaultTreatment: "off",
+			Conditions: []dtos.ConditionDTO{
+				{
+					Partitions: []dtos.PartitionDTO{
+						{Treatment: "on", Size: 50},
+						{Treatment: "off", Size: 50},
+					},
+				},
+			},
+		},
+	}, 123)
+
+	logger := logging.NewLogger(nil)
+	factory := SplitFactory{}
+	manager := SplitManager{
+		splitStorage: splitStorage,
+		validator:    inputValidation{logger: logger},
+		logger:       logger,
+		factory:      &factory,
+	}
+
+	factory.status.Store(sdkStatusReady)
+
+	if !manager.TreatmentExists("split1", "on") || !manager.TreatmentExists("split1", "off") {
+		t.Error("Both on and off should exist for split1")
+	}
+
+	if manager.TreatmentExists("split1", "enabled") {
+		t.Error("enabled should not exist for split1")
+	}
+
+	if manager.TreatmentExists("nonexistent", "on") {
+		t.Error("Unknown features should not have any treatment")
+	}
+}
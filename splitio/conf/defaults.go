@@ -1,12 +1,30 @@
 package conf
 
 const (
-	defaultHTTPTimeout        = 30
-	defaultTaskPeriod         = 30
-	defaultRedisHost          = "localhost"
-	defaultRedisPort          = 6379
-	defaultRedisDb            = 0
-	defaultSegmentQueueSize   = 500
-	defaultSegmentWorkers     = 10
-	defaultFeatureRefreshRate = 5
+	defaultHTTPTimeout            = 30
+	defaultTaskPeriod             = 30
+	defaultRedisHost              = "localhost"
+	defaultRedisPort              = 6379
+	defaultRedisDb                = 0
+	defaultSegmentQueueSize       = 500
+	defaultSegmentWorkers         = 10
+	defaultFeatureRefreshRate     = 5
+	defaultMaxConditionsPerSplit  = 1000
+	defaultImpressionObserverSize = 5000
+	defaultSegmentCacheTTL        = 5
+	defaultSnapshotPersistPeriod  = 60
+	defaultInitialSyncTimeout     = 30
+	defaultMaxAttributeLength     = 1024 * 1024
+	defaultMaxAttributeSetSize    = 100000
+	defaultRedisWarmUpTimeout     = 5
+	defaultSplitsSelfCheckBatch   = 100
+	defaultEvaluationSlotTimeout  = 100
+	defaultMaxFeatureNameLength   = 250
+	defaultEnricherTimeout        = 50
+	defaultRecentErrorsSize       = 100
+	defaultAuditSinkQueueSize     = 10000
+	defaultImpressionsDedupWindow = 3600
+	defaultImpressionsTTL         = 3600
+	defaultEvaluationCacheSize    = 10000
+	defaultObserverPersistPeriod  = 60
 )
@@ -5,16 +5,32 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
 	"os/user"
 	"path"
 	"strings"
 
+	auditsink "github.com/splitio/go-client/splitio/auditSink"
+	impressionenricher "github.com/splitio/go-client/splitio/impressionEnricher"
 	impressionlistener "github.com/splitio/go-client/splitio/impressionListener"
+	metricssink "github.com/splitio/go-client/splitio/metricsSink"
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/util/backoff"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
 	"github.com/splitio/go-toolkit/nethelpers"
 )
 
+const (
+	// ImpressionsModeDebug stores every impression
+	ImpressionsModeDebug = "debug"
+	// ImpressionsModeOptimized only stores the first impression of each key/feature/treatment/changeNumber per window
+	ImpressionsModeOptimized = "optimized"
+	// ImpressionsModeNone doesn't store impressions at all, disabling impression-based analytics
+	ImpressionsModeNone = "none"
+)
+
 // SplitSdkConfig struct ...
 // struct used to setup a Split.io SDK client.
 //
@@ -23,27 +39,29 @@ import (
 // - InstanceName (Optional) Name to be used when submitting metrics & impressions to split servers
 // - IPAddress (Optional) Address to be used when submitting metrics & impressions to split servers
 // - BlockUntilReady (Optional) How much to wait until the sdk is ready
-// - SplitFile (Optional) File with splits to use when running in localhost mode
+// - SplitFile (Optional) File with splits to use when running in localhost mode. May be an http(s) URL, fetched every TaskPeriods.SplitSync seconds
 // - LabelsEnabled (Optional) Can be used to disable labels if the user does not want to send that info to split servers.
 // - Logger: (Optional) Custom logger complying with logging.LoggerInterface
 // - LoggerConfig: (Optional) Options to setup the sdk's own logger
 // - TaskPeriods: (Optional) How often should each task run
 // - Redis: (Required for "redis-consumer" & "redis-standalone" operation modes. Sets up Redis config
 // - Advanced: (Optional) Sets up various advanced options for the sdk
+// - AllowedOperationModes: (Optional) Operation modes accepted by this build. Every mode is accepted if empty
 type SplitSdkConfig struct {
-	OperationMode      string
-	InstanceName       string
-	IPAddress          string
-	IPAddressesEnabled bool
-	BlockUntilReady    int
-	SplitFile          string
-	LabelsEnabled      bool
-	SplitSyncProxyURL  string
-	Logger             logging.LoggerInterface
-	LoggerConfig       logging.LoggerOptions
-	TaskPeriods        TaskPeriods
-	Advanced           AdvancedConfig
-	Redis              RedisConfig
+	OperationMode         string
+	InstanceName          string
+	IPAddress             string
+	IPAddressesEnabled    bool
+	BlockUntilReady       int
+	SplitFile             string
+	LabelsEnabled         bool
+	SplitSyncProxyURL     string
+	Logger                logging.LoggerInterface
+	LoggerConfig          logging.LoggerOptions
+	TaskPeriods           TaskPeriods
+	Advanced              AdvancedConfig
+	Redis                 RedisConfig
+	AllowedOperationModes []string
 }
 
 // TaskPeriods struct is used to configure the period for each synchronization task
@@ -58,13 +76,91 @@ type TaskPeriods struct {
 }
 
 // RedisConfig struct is used to cofigure the redis parameters
+// - PrefixSeparator - String placed between Prefix and every key, "." if empty. The synchronizer must use the same one.
+// - TLSConfig - TLS settings used to connect to redis. Takes precedence over TLSEnabled and the fields that go along with it.
+// - TLSEnabled - Connect to redis over TLS, configured by CertificateAuthorities, ClientCertificate, ClientKey & ServerName.
+// - CertificateAuthorities - Paths of PEM files with the CAs trusted to sign the server certificate. Empty trusts the system ones.
+// - ClientCertificate - Path of the PEM certificate presented to the server, along with ClientKey.
+// - ClientKey - Path of the PEM private key of ClientCertificate.
+// - ServerName - Name the server certificate is verified against, instead of the host connected to.
+// - Sentinel - Connect to the master monitored by these sentinels instead of Host & Port, which must be left empty.
+// - Cluster - Connect to this redis cluster instead of Host & Port, which must be left empty.
+// - FailOnMissingData - Fail factory instantiation in "redis-consumer" mode if no split data is found under Prefix.
+// - ReconcileTrafficTypes - Recompute the traffic type counters from the stored splits during factory instantiation, fixing drifted ones.
+// - WarmUpConnections - Number of pool connections established during factory instantiation. 0 disables warm-up.
+// - WarmUpTimeout - Maximum number of seconds factory instantiation waits for the warm-up connections.
+// - PoolSize - Maximum number of connections in the pool. 0 keeps the redis library default.
+// - DialTimeout - Milliseconds to wait for new connections to be established. 0 keeps the redis library default.
+// - ReadTimeout - Milliseconds to wait for replies. 0 keeps the redis library default.
+// - WriteTimeout - Milliseconds to wait for commands to be written. 0 keeps the redis library default.
+// - VerifyConnection - Ping redis when the client is created so that an unreachable server fails factory instantiation.
+// - ReadReplica - Replica split & segment reads are sent to, falling back to the primary if it fails. Not supported along with Cluster.
 type RedisConfig struct {
-	Host      string
-	Port      int
-	Database  int
-	Password  string
-	Prefix    string
-	TLSConfig *tls.Config
+	Host                   string
+	Port                   int
+	Database               int
+	Password               string
+	Prefix                 string
+	PrefixSeparator        string
+	TLSConfig              *tls.Config
+	TLSEnabled             bool
+	CertificateAuthorities []string
+	ClientCertificate      string
+	ClientKey              string
+	ServerName             string
+	Sentinel               SentinelConfig
+	Cluster                ClusterConfig
+	FailOnMissingData      bool
+	ReconcileTrafficTypes  bool
+	WarmUpConnections      int
+	WarmUpTimeout          int
+	PoolSize               int
+	DialTimeout            int
+	ReadTimeout            int
+	WriteTimeout           int
+	VerifyConnection       bool
+	ReadReplica            ReadReplicaConfig
+}
+
+// ReadReplicaConfig struct is used to configure a read-only replica of the redis primary
+// - Host - Host of the replica. The replica is only used if it's set.
+// - Port - Port of the replica.
+// - Password - Password of the replica.
+type ReadReplicaConfig struct {
+	Host     string
+	Port     int
+	Password string
+}
+
+// Enabled returns true if the replica host is set
+func (r *ReadReplicaConfig) Enabled() bool {
+	return r.Host != ""
+}
+
+// SentinelConfig struct is used to configure the redis sentinels the master is discovered through
+// - MasterName - Name of the master, as monitored by the sentinels.
+// - SentinelAddrs - host:port addresses of the sentinels.
+type SentinelConfig struct {
+	MasterName    string
+	SentinelAddrs []string
+}
+
+// Enabled returns true if any sentinel setting is set
+func (s *SentinelConfig) Enabled() bool {
+	return s.MasterName != "" || len(s.SentinelAddrs) > 0
+}
+
+// ClusterConfig struct is used to configure the nodes of a redis cluster
+// - Addrs - host:port addresses of some of the cluster nodes, the rest are discovered from them.
+// - KeyHashTag - If set, every key is prefixed with "{KeyHashTag}" so that they all hash to the same slot, which allows a single MGET when fetching many splits, at the cost of keeping all the data in one shard. The synchronizer must use the same hash tag. If empty, splits are fetched with pipelined GETs.
+type ClusterConfig struct {
+	Addrs      []string
+	KeyHashTag string
+}
+
+// Enabled returns true if any cluster node is set
+func (c *ClusterConfig) Enabled() bool {
+	return len(c.Addrs) > 0
 }
 
 // AdvancedConfig exposes more configurable parameters that can be used to further tailor the sdk to the user's needs
@@ -72,17 +168,117 @@ type RedisConfig struct {
 // - HTTPTimeout - Timeout for HTTP requests when doing synchronization
 // - SegmentQueueSize - How many segments can be queued for updating (should be >= # segments the user has)
 // - SegmentWorkers - How many workers will be used when performing segments sync.
+// - MaxConditionsPerSplit - Maximum number of conditions evaluated per split before returning the default treatment.
+// - ImpressionListenerQueueSize - If > 0, impressions are sent to the ImpressionListener asynchronously through a queue of this size.
+// - ImpressionListenerWorkers - Number of goroutines sending queued impressions to the ImpressionListener. Values < 1 use a single one.
+// - StrictAttributeTypes - Return CONTROL with a "type mismatch" label when a numeric or datetime matcher receives a non integer attribute.
+// - ImpressionObserverSize - Maximum number of key/feature/treatment/changeNumber combinations tracked to detect repeated impressions.
+// - SegmentCacheSize - Maximum number of segment memberships cached in redis-consumer mode. 0 disables the cache.
+// - SegmentCacheTTL - How many seconds a cached segment membership is considered valid.
+// - NotReadySnapshot - Splits & segments used to serve treatments in "inmemory-standalone" mode until the SDK is ready.
+// - SnapshotFile - File where splits & segments are persisted in "inmemory-standalone" mode and loaded from at startup.
+// - SnapshotPersistPeriod - How often (in seconds) the snapshot is written to SnapshotFile. 0 disables persistence.
+// - InitialSyncTimeout - For how many seconds transient errors in the initial splits synchronization are retried. 0 disables retries.
+// - MetricsBatching - Submit latencies, counters & gauges from a single task every TaskPeriods.CounterSync seconds.
+// - MetricsCompression - Gzip the body of the requests used to submit metrics.
+// - BackoffStrategy - Decides how long splits & segments synchronization waits before retrying. Exponential with jitter if nil. Impressions, events & metrics recorders don't retry, dropping batches that fail to be posted.
+// - MaxAttributeLength - Conditions evaluated against a string attribute longer than this don't match. 0 disables the limit.
+// - MaxAttributeSetSize - Conditions evaluated against a set attribute with more elements than this don't match. 0 disables the limit.
+// - TrackRateLimit - Maximum number of events per second accepted by Track across every client of the factory. Excess events are dropped. 0 disables the limit.
+// - MetricsSink - Receives evaluation latencies, treatment counts & exceptions, ie: to expose them as Prometheus collectors.
+// - SecondaryImpressionStorage - Impressions are also written here, ie: to verify parity while migrating storages. Failures are only logged.
+// - StrictFeatureNames - Log a warning and increment a counter each time an unknown feature is evaluated once the SDK is ready.
+// - SegmentStorageChain - Segment storages asked in order, before the SDK's own, in "redis-consumer" mode. Only errors move on to the next one.
+// - StrictPartitions - Reject splits whose condition partitions don't add up to 100, removing their previous version, instead of only logging a warning.
+// - QueueDepthMetrics - Store the depth of the impressions, events & segments queues as gauges every TaskPeriods.GaugeSync seconds.
+// - AllowlistOnlyFeatures - Features evaluated against their whitelists & segments only. Other keys get the default treatment.
+// - CaseInsensitiveStrings - Ignore case in whitelist, starts with, ends with & contains matchers. The backend is case sensitive.
+// - SplitsSelfCheckPeriod - How often (in seconds) stored splits are validated, reporting invalid ones as a gauge. 0 disables it.
+// - SplitsSelfCheckBatchSize - Maximum number of splits validated by each self-check run. 0 validates every split.
+// - MaxConcurrentEvaluations - Maximum number of evaluations run concurrently per factory, ie: to protect the redis pool. 0 disables the limit.
+// - EvaluationSlotTimeout - Milliseconds an evaluation waits for a slot before returning CONTROL with an "overloaded" label.
+// - TrimKeys - Remove leading & trailing whitespace from matching & bucketing keys before evaluating them.
+// - MaxFeatureNameLength - Feature names longer than this are rejected as malformed, returning CONTROL.
+// - ImpressionEnricher - Computes properties added to the impressions of each evaluation, ie: the region of an IP address.
+// - ImpressionEnricherTimeout - Milliseconds the ImpressionEnricher has to return before impressions are stored without properties.
+// - HTTPTransport - Transport used for requests to Split servers. nil uses the default one.
+// - MaxInFlightRequests - Maximum number of requests to Split servers in flight at once across all tasks. 0 disables the limit.
+// - CompiledEvaluation - Evaluate against an index of pre-parsed splits rebuilt within a second of each change, lowering evaluation latency. Evaluations bound to a context & TreatmentForKeys parse splits as usual.
+// - RecentErrorsSize - Number of recent internal errors kept for SplitClient.RecentErrors. 0 disables keeping them.
+// - ControlImpressions - Store a "control" impression labeled "definition not found", "no splits loaded", "cancelled" or "exception" when a valid key & feature name get CONTROL because the feature is not found, the evaluation is cancelled or panics. "not ready", "overloaded" & "type mismatch" impressions are stored anyway. Invalid keys & feature names and destroyed clients store nothing.
+// - PinBatchSegments - Features evaluated together for a key see the same segment memberships, even if segments change meanwhile.
+// - FeatureImpressionTTLs - Seconds the impressions of each feature are kept in redis, overriding ImpressionsTTL. Stored in a list per feature.
+// - AuditSink - Receives every decision taken by Treatment & its variants, asynchronously, to keep an audit trail apart from impressions.
+// - AuditSinkQueueSize - Number of decisions queued for the AuditSink. Decisions are dropped while the queue is full.
+// - ImpressionsMode - "debug" stores every impression. "optimized" drops repeated ones, tracking up to ImpressionObserverSize key/feature/treatment combinations. "none" stores no impressions, disabling impression-based analytics.
+// - ImpressionsDedupWindow - Seconds during which repeated impressions are dropped in "optimized" mode.
+// - ImpressionsTTL - Seconds the impressions list is kept in redis after each write, unless drained by the synchronizer.
+// - EvaluationCacheTTL - Milliseconds the evaluation of a feature for a key without attributes is reused, by every client reading the same storages. 0 disables the cache.
+// - EvaluationCacheSize - Maximum number of evaluations cached for the clients reading the same storages, evicting the least recently used ones.
+// - PersistImpressionObserver - Save the combinations deduped in "optimized" mode to redis and restore them on startup. "redis-consumer" mode only.
+// - ObserverPersistPeriod - How often (in seconds) the deduped combinations are saved with PersistImpressionObserver, besides on Destroy.
+// - StorageResolver - Resolves the split & segment storages of the tenant passed to SplitFactory.TenantClient. Every tenant uses the SDK's own storages if nil.
 type AdvancedConfig struct {
-	ImpressionListener   impressionlistener.ImpressionListener
-	HTTPTimeout          int
-	SegmentQueueSize     int
-	SegmentWorkers       int
-	SdkURL               string
-	EventsURL            string
-	EventsBulkSize       int64
-	EventsQueueSize      int
-	ImpressionsQueueSize int
-	ImpressionsBulkSize  int64
+	ImpressionListener          impressionlistener.ImpressionListener
+	HTTPTimeout                 int
+	SegmentQueueSize            int
+	SegmentWorkers              int
+	SdkURL                      string
+	EventsURL                   string
+	EventsBulkSize              int64
+	EventsQueueSize             int
+	ImpressionsQueueSize        int
+	ImpressionsBulkSize         int64
+	MaxConditionsPerSplit       int
+	ImpressionListenerQueueSize int
+	ImpressionListenerWorkers   int
+	StrictAttributeTypes        bool
+	ImpressionObserverSize      int
+	SegmentCacheSize            int
+	SegmentCacheTTL             int
+	NotReadySnapshot            *dtos.SnapshotDTO
+	SnapshotFile                string
+	SnapshotPersistPeriod       int
+	InitialSyncTimeout          int
+	MetricsBatching             bool
+	MetricsCompression          bool
+	BackoffStrategy             backoff.Strategy
+	MaxAttributeLength          int
+	MaxAttributeSetSize         int
+	TrackRateLimit              int
+	MetricsSink                 metricssink.MetricsSink
+	SecondaryImpressionStorage  storage.ImpressionStorageProducer
+	StrictFeatureNames          bool
+	SegmentStorageChain         []storage.SegmentStorage
+	StrictPartitions            bool
+	QueueDepthMetrics           bool
+	AllowlistOnlyFeatures       map[string]bool
+	CaseInsensitiveStrings      bool
+	SplitsSelfCheckPeriod       int
+	SplitsSelfCheckBatchSize    int
+	MaxConcurrentEvaluations    int
+	EvaluationSlotTimeout       int
+	TrimKeys                    bool
+	MaxFeatureNameLength        int
+	ImpressionEnricher          impressionenricher.ImpressionEnricher
+	ImpressionEnricherTimeout   int
+	HTTPTransport               http.RoundTripper
+	MaxInFlightRequests         int
+	CompiledEvaluation          bool
+	RecentErrorsSize            int
+	ControlImpressions          bool
+	PinBatchSegments            bool
+	FeatureImpressionTTLs       map[string]int
+	AuditSink                   auditsink.AuditSink
+	AuditSinkQueueSize          int
+	ImpressionsMode             string
+	ImpressionsDedupWindow      int
+	ImpressionsTTL              int
+	EvaluationCacheTTL          int
+	EvaluationCacheSize         int
+	PersistImpressionObserver   bool
+	ObserverPersistPeriod       int
+	StorageResolver             storage.StorageResolver
 }
 
 // Default returns a config struct with all the default values
@@ -114,12 +310,30 @@ func Default() *SplitSdkConfig {
 		LoggerConfig:       logging.LoggerOptions{},
 		SplitFile:          splitFile,
 		Redis: RedisConfig{
-			Database:  0,
-			Host:      "localhost",
-			Password:  "",
-			Port:      6379,
-			Prefix:    "",
-			TLSConfig: nil,
+			Database:               0,
+			Host:                   "localhost",
+			Password:               "",
+			Port:                   6379,
+			Prefix:                 "",
+			PrefixSeparator:        ".",
+			TLSConfig:              nil,
+			TLSEnabled:             false,
+			CertificateAuthorities: nil,
+			ClientCertificate:      "",
+			ClientKey:              "",
+			ServerName:             "",
+			Sentinel:               SentinelConfig{},
+			Cluster:                ClusterConfig{},
+			FailOnMissingData:      false,
+			ReconcileTrafficTypes:  false,
+			WarmUpConnections:      0,
+			WarmUpTimeout:          defaultRedisWarmUpTimeout,
+			PoolSize:               0,
+			DialTimeout:            0,
+			ReadTimeout:            0,
+			WriteTimeout:           0,
+			VerifyConnection:       true,
+			ReadReplica:            ReadReplicaConfig{},
 		},
 		TaskPeriods: TaskPeriods{
 			CounterSync:    defaultTaskPeriod,
@@ -131,16 +345,65 @@ func Default() *SplitSdkConfig {
 			EventsSync:     defaultTaskPeriod,
 		},
 		Advanced: AdvancedConfig{
-			EventsURL:            "",
-			SdkURL:               "",
-			HTTPTimeout:          0,
-			ImpressionListener:   nil,
-			SegmentQueueSize:     500,
-			SegmentWorkers:       10,
-			EventsBulkSize:       5000,
-			EventsQueueSize:      10000,
-			ImpressionsQueueSize: 10000,
-			ImpressionsBulkSize:  5000,
+			EventsURL:                   "",
+			SdkURL:                      "",
+			HTTPTimeout:                 0,
+			ImpressionListener:          nil,
+			SegmentQueueSize:            500,
+			SegmentWorkers:              10,
+			EventsBulkSize:              5000,
+			EventsQueueSize:             10000,
+			ImpressionsQueueSize:        10000,
+			ImpressionsBulkSize:         5000,
+			MaxConditionsPerSplit:       defaultMaxConditionsPerSplit,
+			ImpressionListenerQueueSize: 0,
+			ImpressionListenerWorkers:   1,
+			StrictAttributeTypes:        false,
+			ImpressionObserverSize:      defaultImpressionObserverSize,
+			SegmentCacheSize:            0,
+			SegmentCacheTTL:             defaultSegmentCacheTTL,
+			NotReadySnapshot:            nil,
+			SnapshotFile:                "",
+			SnapshotPersistPeriod:       defaultSnapshotPersistPeriod,
+			InitialSyncTimeout:          defaultInitialSyncTimeout,
+			MetricsBatching:             false,
+			MetricsCompression:          false,
+			BackoffStrategy:             nil,
+			MaxAttributeLength:          defaultMaxAttributeLength,
+			MaxAttributeSetSize:         defaultMaxAttributeSetSize,
+			TrackRateLimit:              0,
+			MetricsSink:                 nil,
+			SecondaryImpressionStorage:  nil,
+			StrictFeatureNames:          false,
+			SegmentStorageChain:         nil,
+			StrictPartitions:            false,
+			QueueDepthMetrics:           false,
+			AllowlistOnlyFeatures:       nil,
+			CaseInsensitiveStrings:      false,
+			SplitsSelfCheckPeriod:       0,
+			SplitsSelfCheckBatchSize:    defaultSplitsSelfCheckBatch,
+			MaxConcurrentEvaluations:    0,
+			EvaluationSlotTimeout:       defaultEvaluationSlotTimeout,
+			TrimKeys:                    false,
+			MaxFeatureNameLength:        defaultMaxFeatureNameLength,
+			ImpressionEnricher:          nil,
+			ImpressionEnricherTimeout:   defaultEnricherTimeout,
+			HTTPTransport:               nil,
+			MaxInFlightRequests:         0,
+			CompiledEvaluation:          false,
+			RecentErrorsSize:            defaultRecentErrorsSize,
+			ControlImpressions:          false,
+			PinBatchSegments:            false,
+			FeatureImpressionTTLs:       nil,
+			AuditSink:                   nil,
+			AuditSinkQueueSize:          defaultAuditSinkQueueSize,
+			ImpressionsMode:             ImpressionsModeDebug,
+			ImpressionsDedupWindow:      defaultImpressionsDedupWindow,
+			ImpressionsTTL:              defaultImpressionsTTL,
+			EvaluationCacheTTL:          0,
+			EvaluationCacheSize:         defaultEvaluationCacheSize,
+			PersistImpressionObserver:   false,
+			ObserverPersistPeriod:       defaultObserverPersistPeriod,
 		},
 	}
 }
@@ -171,11 +434,65 @@ func Normalize(apikey string, cfg *SplitSdkConfig) error {
 		return fmt.Errorf("OperationMode parameter must be one of: %v", operationModes.List())
 	}
 
+	// Fail if the operation-mode is valid but has been excluded from this build
+	if len(cfg.AllowedOperationModes) > 0 && !isOperationModeAllowed(cfg.OperationMode, cfg.AllowedOperationModes) {
+		return fmt.Errorf(
+			"OperationMode %s is not allowed, allowed operation modes are: %v",
+			cfg.OperationMode,
+			cfg.AllowedOperationModes,
+		)
+	}
+
 	if cfg.SplitSyncProxyURL != "" {
 		cfg.Advanced.SdkURL = cfg.SplitSyncProxyURL
 		cfg.Advanced.EventsURL = cfg.SplitSyncProxyURL
 	}
 
+	if cfg.Advanced.ImpressionObserverSize < 0 {
+		return errors.New("ImpressionObserverSize must be a positive number")
+	}
+	if cfg.Advanced.ImpressionObserverSize == 0 {
+		cfg.Advanced.ImpressionObserverSize = defaultImpressionObserverSize
+	}
+
+	if cfg.Advanced.ImpressionsTTL < 0 {
+		return errors.New("ImpressionsTTL must be a positive number")
+	}
+	if cfg.Advanced.ImpressionsTTL == 0 {
+		cfg.Advanced.ImpressionsTTL = defaultImpressionsTTL
+	}
+
+	if cfg.Advanced.ObserverPersistPeriod < 0 {
+		return errors.New("ObserverPersistPeriod must be a positive number")
+	}
+	if cfg.Advanced.ObserverPersistPeriod == 0 {
+		cfg.Advanced.ObserverPersistPeriod = defaultObserverPersistPeriod
+	}
+
+	if cfg.Advanced.EvaluationCacheTTL < 0 {
+		return errors.New("EvaluationCacheTTL must be a positive number")
+	}
+	if cfg.Advanced.EvaluationCacheTTL > 0 && cfg.Advanced.EvaluationCacheSize <= 0 {
+		return errors.New("EvaluationCacheSize must be a positive number when EvaluationCacheTTL is set")
+	}
+
+	if cfg.Advanced.SegmentCacheTTL < 0 {
+		return errors.New("SegmentCacheTTL must be a positive number")
+	}
+
+	switch cfg.Advanced.ImpressionsMode {
+	case "":
+		cfg.Advanced.ImpressionsMode = ImpressionsModeDebug
+	case ImpressionsModeDebug, ImpressionsModeOptimized, ImpressionsModeNone:
+	default:
+		return fmt.Errorf(
+			"ImpressionsMode must be one of: [%s %s %s]",
+			ImpressionsModeDebug,
+			ImpressionsModeOptimized,
+			ImpressionsModeNone,
+		)
+	}
+
 	if !cfg.IPAddressesEnabled {
 		cfg.IPAddress = "NA"
 		cfg.InstanceName = "NA"
@@ -183,3 +500,12 @@ func Normalize(apikey string, cfg *SplitSdkConfig) error {
 
 	return nil
 }
+
+func isOperationModeAllowed(operationMode string, allowedOperationModes []string) bool {
+	for _, allowed := range allowedOperationModes {
+		if allowed == operationMode {
+			return true
+		}
+	}
+	return false
+}
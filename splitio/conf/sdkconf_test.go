@@ -42,3 +42,96 @@ func TestSdkConfNormalization(t *testing.T) {
 		t.Error("Should not be NA")
 	}
 }
+
+func TestImpressionObserverSizeNormalization(t *testing.T) {
+	cfg := Default()
+	cfg.Advanced.ImpressionObserverSize = -1
+	if err := Normalize("asd", cfg); err == nil {
+		t.Error("Should throw an error when ImpressionObserverSize is negative")
+	}
+
+	cfg = Default()
+	cfg.Advanced.ImpressionObserverSize = 0
+	if err := Normalize("asd", cfg); err != nil || cfg.Advanced.ImpressionObserverSize != defaultImpressionObserverSize {
+		t.Error("Default ImpressionObserverSize should be used when not set")
+	}
+}
+
+func TestImpressionsModeNormalization(t *testing.T) {
+	cfg := Default()
+	cfg.Advanced.ImpressionsMode = "verbose"
+	if err := Normalize("asd", cfg); err == nil {
+		t.Error("Should throw an error when ImpressionsMode is unknown")
+	}
+
+	cfg = Default()
+	cfg.Advanced.ImpressionsMode = ImpressionsModeNone
+	if err := Normalize("asd", cfg); err != nil || cfg.Advanced.ImpressionsMode != ImpressionsModeNone {
+		t.Error("None mode should be accepted")
+	}
+
+	cfg = Default()
+	cfg.Advanced.ImpressionsMode = ""
+	if err := Normalize("asd", cfg); err != nil || cfg.Advanced.ImpressionsMode != ImpressionsModeDebug {
+		t.Error("Debug mode should be used when not set")
+	}
+}
+
+func TestImpressionsTTLNormalization(t *testing.T) {
+	cfg := Default()
+	cfg.Advanced.ImpressionsTTL = -1
+	if err := Normalize("asd", cfg); err == nil {
+		t.Error("Should throw an error when ImpressionsTTL is negative")
+	}
+
+	cfg = Default()
+	cfg.Advanced.ImpressionsTTL = 0
+	if err := Normalize("asd", cfg); err != nil || cfg.Advanced.ImpressionsTTL != defaultImpressionsTTL {
+		t.Error("The default TTL should be used when not set. Got:", cfg.Advanced.ImpressionsTTL)
+	}
+}
+
+func TestSegmentCacheTTLNormalization(t *testing.T) {
+	cfg := Default()
+	cfg.Advanced.SegmentCacheTTL = -1
+	if err := Normalize("asd", cfg); err == nil {
+		t.Error("Should throw an error when SegmentCacheTTL is negative")
+	}
+
+	cfg = Default()
+	if err := Normalize("asd", cfg); err != nil || cfg.Advanced.SegmentCacheTTL != defaultSegmentCacheTTL {
+		t.Error("The default SegmentCacheTTL should be kept. Got:", cfg.Advanced.SegmentCacheTTL)
+	}
+}
+
+func TestAllowedOperationModes(t *testing.T) {
+	for _, mode := range []string{"localhost", "inmemory-standalone", "redis-consumer", "redis-standalone"} {
+		cfg := Default()
+		cfg.OperationMode = mode
+		if err := Normalize("asd", cfg); err != nil {
+			t.Error("Every operation mode should be allowed by default", mode, err)
+		}
+	}
+
+	for _, mode := range []string{"localhost", "inmemory-standalone", "redis-standalone"} {
+		cfg := Default()
+		cfg.OperationMode = mode
+		cfg.AllowedOperationModes = []string{"redis-consumer"}
+		if err := Normalize("asd", cfg); err == nil {
+			t.Error("Operation mode should have been rejected", mode)
+		}
+	}
+
+	cfg := Default()
+	cfg.OperationMode = "redis-consumer"
+	cfg.AllowedOperationModes = []string{"redis-consumer"}
+	if err := Normalize("asd", cfg); err != nil {
+		t.Error("An allowed operation mode should be accepted", err)
+	}
+
+	cfg = Default()
+	cfg.AllowedOperationModes = []string{"redis-consumer"}
+	if err := Normalize("localhost", cfg); err == nil {
+		t.Error("The localhost apikey should not bypass the allowed operation modes")
+	}
+}
@@ -3,6 +3,8 @@ package engine
 import (
 	"fmt"
 	"math"
+	"sync"
+	"time"
 
 	"github.com/splitio/go-client/splitio/engine/evaluator/impressionlabels"
 	"github.com/splitio/go-client/splitio/engine/grammar"
@@ -13,9 +15,30 @@ import (
 // Engine struct is responsible for cheking if any of the conditions of the split matches,
 // performing traffic allocation, calculating the bucket and returning the appropriate treatment
 type Engine struct {
-	logger logging.LoggerInterface
+	logger               logging.LoggerInterface
+	maxConditions        int
+	strictAttributeTypes bool
+	attributeLimits      AttributeLimits
+	allowlistOnly        map[string]bool
+	caseInsensitive      bool
+	warnedFeatures       sync.Map
+	warnedPartitions     sync.Map
+	warnedAttributes     sync.Map
 }
 
+// AttributeLimits sets the maximum length of string attributes and the maximum number of elements of set attributes
+// that matchers are evaluated against. Limits <= 0 are not enforced
+type AttributeLimits struct {
+	MaxLength  int
+	MaxSetSize int
+}
+
+// NoConditionIndex is the condition index reported when the treatment didn't come from a condition
+const NoConditionIndex = -1
+
+// oversizedAttributeWarningInterval is the minimum time between warnings about the same oversized attribute
+const oversizedAttributeWarningInterval = time.Minute
+
 // DoEvaluation performs the main evaluation against each condition
 func (e *Engine) DoEvaluation(
 	split *grammar.Split,
@@ -23,9 +46,42 @@ func (e *Engine) DoEvaluation(
 	bucketingKey string,
 	attributes map[string]interface{},
 ) (*string, string) {
+	treatment, label, _ := e.DoEvaluationWithIndex(split, key, bucketingKey, attributes)
+	return treatment, label
+}
+
+// DoEvaluationWithIndex performs the main evaluation against each condition and additionally returns
+// the zero-based index of the condition that matched, or NoConditionIndex if none did
+func (e *Engine) DoEvaluationWithIndex(
+	split *grammar.Split,
+	key string,
+	bucketingKey string,
+	attributes map[string]interface{},
+) (*string, string, int) {
+	var oversized []string
+	if e != nil {
+		oversized = split.OversizedAttributes(attributes, e.attributeLimits.MaxLength, e.attributeLimits.MaxSetSize)
+		e.warnOversizedAttributes(split.Name(), oversized)
+	}
+
+	allowlistOnly := e != nil && e.allowlistOnly[split.Name()]
+	rolloutSkipped := false
+
 	inRollOut := false
-	for _, condition := range split.Conditions() {
-		if !inRollOut && condition.ConditionType() == grammar.ConditionTypeRollout {
+	skipped := false
+	for index, condition := range split.Conditions() {
+		if e.exceedsConditionsLimit(index) {
+			e.warnConditionsLimitExceeded(split.Name())
+			defaultTreatment := split.DefaultTreatment()
+			return &defaultTreatment, impressionlabels.ConditionsLimitExceeded, NoConditionIndex
+		}
+
+		if allowlistOnly && condition.ConditionType() == grammar.ConditionTypeRollout && !condition.TargetsKeys() {
+			rolloutSkipped = true
+			continue
+		}
+
+		if !allowlistOnly && !inRollOut && condition.ConditionType() == grammar.ConditionTypeRollout {
 			if split.TrafficAllocation() < 100 {
 				bucket := e.calculateBucket(split.Algo(), bucketingKey, split.TrafficAllocationSeed())
 				if bucket > split.TrafficAllocation() {
@@ -34,19 +90,46 @@ func (e *Engine) DoEvaluation(
 							" Returning default treatment", split.Name(), key,
 					))
 					defaultTreatment := split.DefaultTreatment()
-					return &defaultTreatment, impressionlabels.NotInSplit
+					return &defaultTreatment, impressionlabels.NotInSplit, NoConditionIndex
 				}
 				inRollOut = true
 			}
 		}
 
+		if len(oversized) > 0 && condition.UsesAnyAttribute(oversized) {
+			skipped = true
+			continue
+		}
+
+		if e != nil && e.strictAttributeTypes {
+			if attribute, mismatch := condition.AttributeTypeMismatch(attributes); mismatch {
+				e.logger.Warning(fmt.Sprintf(
+					"Feature %s: attribute %s should be an integer, returning control.", split.Name(), attribute,
+				))
+				return nil, impressionlabels.TypeMismatch, NoConditionIndex
+			}
+		}
+
 		if condition.Matches(key, &bucketingKey, attributes) {
 			bucket := e.calculateBucket(split.Algo(), bucketingKey, split.Seed())
 			treatment := condition.CalculateTreatment(bucket)
-			return treatment, condition.Label()
+			if treatment == nil {
+				e.warnPartitionsIncomplete(split.Name(), index)
+				return nil, impressionlabels.PartitionsIncomplete, NoConditionIndex
+			}
+			if skipped {
+				return treatment, impressionlabels.AttributeTooLarge, index
+			}
+			return treatment, condition.Label(), index
 		}
 	}
-	return nil, impressionlabels.NoConditionMatched
+	if skipped {
+		return nil, impressionlabels.AttributeTooLarge, NoConditionIndex
+	}
+	if rolloutSkipped {
+		return nil, impressionlabels.RolloutDisabled, NoConditionIndex
+	}
+	return nil, impressionlabels.NoConditionMatched, NoConditionIndex
 }
 
 func (e *Engine) calculateBucket(algo int, bucketingKey string, seed int64) int {
@@ -64,7 +147,81 @@ func (e *Engine) calculateBucket(algo int, bucketingKey string, seed int64) int
 
 }
 
-// NewEngine instantiates and returns a new engine
-func NewEngine(logger logging.LoggerInterface) *Engine {
-	return &Engine{logger: logger}
+// exceedsConditionsLimit returns true if the condition at the given index is beyond the configured limit
+func (e *Engine) exceedsConditionsLimit(index int) bool {
+	return e != nil && e.maxConditions > 0 && index >= e.maxConditions
+}
+
+// warnPartitionsIncomplete logs a warning the first time a key of a feature falls beyond the partitions of a condition
+func (e *Engine) warnPartitionsIncomplete(feature string, index int) {
+	if e == nil || e.logger == nil {
+		return
+	}
+	if _, warned := e.warnedPartitions.LoadOrStore(feature, true); warned {
+		return
+	}
+	e.logger.Warning(fmt.Sprintf(
+		"Feature %s: keys fall beyond the partitions of condition %d, which don't add up to 100. "+
+			"The default treatment will be returned for them. Please review the split definition.",
+		feature, index,
+	))
+}
+
+// warnConditionsLimitExceeded logs a warning the first time a feature exceeds the conditions limit
+func (e *Engine) warnConditionsLimitExceeded(feature string) {
+	if _, warned := e.warnedFeatures.LoadOrStore(feature, true); warned {
+		return
+	}
+	e.logger.Warning(fmt.Sprintf(
+		"Feature %s has more than %d conditions. Only the first %d will be evaluated before "+
+			"returning the default treatment. Please review the split definition.",
+		feature, e.maxConditions, e.maxConditions,
+	))
+}
+
+// warnOversizedAttributes logs a warning for each oversized attribute, at most once a minute per attribute
+func (e *Engine) warnOversizedAttributes(feature string, attributes []string) {
+	now := time.Now()
+	for _, attribute := range attributes {
+		if last, ok := e.warnedAttributes.Load(attribute); ok && now.Sub(last.(time.Time)) < oversizedAttributeWarningInterval {
+			continue
+		}
+		e.warnedAttributes.Store(attribute, now)
+		e.logger.Warning(fmt.Sprintf(
+			"Feature %s: attribute %s exceeds the maximum allowed size, conditions using it will not match.",
+			feature, attribute,
+		))
+	}
+}
+
+// Options sets the evaluation limits & behaviors of an engine. The zero value evaluates every condition of a split
+// without any limit
+// - MaxConditions - Maximum number of conditions evaluated per split. A value <= 0 means no limit.
+// - StrictAttributeTypes - Numeric & datetime matchers evaluated against an attribute of another type return no treatment and a TypeMismatch label.
+// - AttributeLimits - Conditions using attributes that exceed them don't match and the evaluation gets an AttributeTooLarge label.
+// - AllowlistOnly - Features evaluated against the conditions targeting segments & whitelists only, without traffic allocation. The rest of keys get the default treatment.
+// - CaseInsensitiveStrings - Whitelist, starts with, ends with & contains matchers ignore case.
+type Options struct {
+	MaxConditions          int
+	StrictAttributeTypes   bool
+	AttributeLimits        AttributeLimits
+	AllowlistOnly          map[string]bool
+	CaseInsensitiveStrings bool
+}
+
+// NewEngine instantiates and returns a new engine configured by options
+func NewEngine(logger logging.LoggerInterface, options Options) *Engine {
+	return &Engine{
+		logger:               logger,
+		maxConditions:        options.MaxConditions,
+		strictAttributeTypes: options.StrictAttributeTypes,
+		attributeLimits:      options.AttributeLimits,
+		allowlistOnly:        options.AllowlistOnly,
+		caseInsensitive:      options.CaseInsensitiveStrings,
+	}
+}
+
+// CaseInsensitiveStrings returns whether string matchers evaluated by this engine ignore case
+func (e *Engine) CaseInsensitiveStrings() bool {
+	return e != nil && e.caseInsensitive
 }
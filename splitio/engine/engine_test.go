@@ -5,11 +5,17 @@ import (
 	"io"
 	"math"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/splitio/go-client/splitio/engine/evaluator/impressionlabels"
 	"github.com/splitio/go-client/splitio/engine/grammar"
 	"github.com/splitio/go-client/splitio/engine/hash"
 	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/datastructures/set"
+	"github.com/splitio/go-toolkit/injection"
 	"github.com/splitio/go-toolkit/logging"
 )
 
@@ -247,3 +253,306 @@ func TestTest(t *testing.T) {
 		}
 	}
 }
+
+type warningsWriter struct {
+	warnings []string
+}
+
+func (w *warningsWriter) Write(p []byte) (n int, err error) {
+	w.warnings = append(w.warnings, string(p))
+	return len(p), nil
+}
+
+func TestConditionsLimitExceeded(t *testing.T) {
+	writer := &warningsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer})
+
+	conditions := make([]dtos.ConditionDTO, 0)
+	for i := 0; i < 5; i++ {
+		conditions = append(conditions, dtos.ConditionDTO{
+			ConditionType: "WHITELIST",
+			Label:         "whitelisted",
+			MatcherGroup: dtos.MatcherGroupDTO{
+				Combiner: "AND",
+				Matchers: []dtos.MatcherDTO{
+					{
+						MatcherType: "WHITELIST",
+						Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"other_key"}},
+					},
+				},
+			},
+			Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+		})
+	}
+
+	splitDTO := dtos.SplitDTO{
+		Algo:              2,
+		DefaultTreatment:  "default",
+		Name:              "huge_split",
+		Seed:              1234,
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions:        conditions,
+	}
+	split := grammar.NewSplit(&splitDTO, nil, logger)
+
+	eng := NewEngine(logger, Options{MaxConditions: 2})
+	treatment, label := eng.DoEvaluation(split, "some_key", "some_key", nil)
+	if treatment == nil || *treatment != "default" {
+		t.Error("Default treatment should be returned when conditions limit is exceeded")
+	}
+	if label != impressionlabels.ConditionsLimitExceeded {
+		t.Error("Unexpected label", label)
+	}
+
+	eng.DoEvaluation(split, "some_key", "some_key", nil)
+	if len(writer.warnings) != 1 || !strings.Contains(writer.warnings[0], "huge_split") {
+		t.Error("A single warning naming the feature should have been logged", writer.warnings)
+	}
+
+	treatment, label = eng.DoEvaluation(split, "other_key", "other_key", nil)
+	if treatment == nil || *treatment != "on" || label != "whitelisted" {
+		t.Error("Conditions within the limit should still be evaluated")
+	}
+
+	unlimited := NewEngine(logger, Options{})
+	_, label = unlimited.DoEvaluation(split, "some_key", "some_key", nil)
+	if label != impressionlabels.NoConditionMatched {
+		t.Error("No limit should be applied when maxConditions is 0")
+	}
+}
+
+func TestOversizedAttributes(t *testing.T) {
+	writer := &warningsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer})
+
+	attribute := "bio"
+	splitDTO := dtos.SplitDTO{
+		Algo:              2,
+		DefaultTreatment:  "off",
+		Name:              "bio_split",
+		Seed:              1234,
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{
+			{
+				ConditionType: "WHITELIST",
+				Label:         "bio doesn't start with x",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{
+						{
+							KeySelector: &dtos.KeySelectorDTO{TrafficType: "user", Attribute: &attribute},
+							MatcherType: "STARTS_WITH",
+							Negate:      true,
+							Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"x"}},
+						},
+					},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+			},
+		},
+	}
+	split := grammar.NewSplit(&splitDTO, nil, logger)
+	eng := NewEngine(logger, Options{AttributeLimits: AttributeLimits{MaxLength: 10, MaxSetSize: 2}})
+
+	treatment, label := eng.DoEvaluation(split, "key", "key", map[string]interface{}{attribute: "short"})
+	if treatment == nil || *treatment != "on" || label != "bio doesn't start with x" {
+		t.Error("Attributes within the limits should be matched as usual", label)
+	}
+
+	oversized := map[string]interface{}{attribute: strings.Repeat("a", 11)}
+	treatment, label = eng.DoEvaluation(split, "key", "key", oversized)
+	if treatment != nil || label != impressionlabels.AttributeTooLarge {
+		t.Error("Conditions using an oversized attribute should not match, even if negated", label)
+	}
+
+	eng.DoEvaluation(split, "key", "key", oversized)
+	if len(writer.warnings) != 1 || !strings.Contains(writer.warnings[0], "attribute bio") {
+		t.Error("A single warning naming the attribute should have been logged", writer.warnings)
+	}
+
+	_, label = eng.DoEvaluation(split, "key", "key", map[string]interface{}{attribute: []string{"a", "b", "c"}})
+	if label != impressionlabels.AttributeTooLarge {
+		t.Error("Sets with more elements than allowed should not match", label)
+	}
+
+	_, label = eng.DoEvaluation(split, "key", "key", map[string]interface{}{"unused": strings.Repeat("a", 11)})
+	if label != "bio doesn't start with x" {
+		t.Error("Attributes not used by the split should be ignored", label)
+	}
+}
+
+func TestPartitionsIncomplete(t *testing.T) {
+	writer := &warningsWriter{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: writer})
+
+	splitDTO := dtos.SplitDTO{
+		Algo:              2,
+		DefaultTreatment:  "off",
+		Name:              "incomplete_split",
+		Seed:              1234,
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{
+			{
+				ConditionType: "ROLLOUT",
+				Label:         "default rule",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 99, Treatment: "on"}},
+			},
+		},
+	}
+	split := grammar.NewSplit(&splitDTO, nil, logger)
+	eng := NewEngine(logger, Options{})
+
+	var outside, inside string
+	for i := 0; outside == "" || inside == ""; i++ {
+		key := "key" + strconv.Itoa(i)
+		if eng.calculateBucket(split.Algo(), key, split.Seed()) == 100 {
+			outside = key
+		} else {
+			inside = key
+		}
+	}
+
+	treatment, label, index := eng.DoEvaluationWithIndex(split, outside, outside, nil)
+	if treatment != nil || label != impressionlabels.PartitionsIncomplete || index != NoConditionIndex {
+		t.Error("Keys beyond the last partition should go to the default rule with a distinct label", label, index)
+	}
+	if len(writer.warnings) != 1 || !strings.Contains(writer.warnings[0], "incomplete_split") {
+		t.Error("A warning naming the feature should have been logged", writer.warnings)
+	}
+
+	treatment, label, _ = eng.DoEvaluationWithIndex(split, inside, inside, nil)
+	if treatment == nil || *treatment != "on" || label != "default rule" {
+		t.Error("Keys within the partitions should get their treatment", label)
+	}
+
+	eng.DoEvaluationWithIndex(split, outside, outside, nil)
+	if len(writer.warnings) != 1 {
+		t.Error("The warning should only be logged once per feature", writer.warnings)
+	}
+}
+
+func TestAllowlistOnlyFeatures(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitDTO := dtos.SplitDTO{
+		Algo:              2,
+		DefaultTreatment:  "off",
+		Name:              "launch",
+		Seed:              1234,
+		Status:            "ACTIVE",
+		TrafficAllocation: 100,
+		Conditions: []dtos.ConditionDTO{
+			{
+				ConditionType: "WHITELIST",
+				Label:         "whitelisted",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{
+						{MatcherType: "WHITELIST", Whitelist: &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"vip"}}},
+					},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+			},
+			{
+				ConditionType: "ROLLOUT",
+				Label:         "default rule",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 50, Treatment: "on"}, {Size: 50, Treatment: "off"}},
+			},
+		},
+	}
+	split := grammar.NewSplit(&splitDTO, nil, logger)
+
+	rollout := NewEngine(logger, Options{})
+	allowlistOnly := NewEngine(logger, Options{AllowlistOnly: map[string]bool{"launch": true}})
+
+	rolledOut := 0
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		if treatment, _ := rollout.DoEvaluation(split, key, key, nil); *treatment == "on" {
+			rolledOut++
+		}
+
+		treatment, label := allowlistOnly.DoEvaluation(split, key, key, nil)
+		if treatment != nil || label != impressionlabels.RolloutDisabled {
+			t.Error("Keys not whitelisted should get the default treatment without bucketing", key, label)
+		}
+	}
+	if rolledOut == 0 {
+		t.Error("Some keys should get the rolled out treatment when the override is not set")
+	}
+
+	treatment, label := allowlistOnly.DoEvaluation(split, "vip", "vip", nil)
+	if treatment == nil || *treatment != "on" || label != "whitelisted" {
+		t.Error("Whitelisted keys should still get their treatment", label)
+	}
+}
+
+func TestAllowlistOnlyFeaturesMatchSegments(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	segmentKeys := set.NewSet()
+	segmentKeys.Add("beta1", "beta2")
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	segmentStorage.Put("beta", segmentKeys, 123)
+	ctx := injection.NewContext()
+	ctx.AddDependency("segmentStorage", segmentStorage)
+
+	age := "age"
+	splitDTO := dtos.SplitDTO{
+		Algo:              2,
+		DefaultTreatment:  "off",
+		Name:              "launch",
+		Seed:              1234,
+		Status:            "ACTIVE",
+		TrafficAllocation: 1,
+		Conditions: []dtos.ConditionDTO{
+			{
+				ConditionType: "ROLLOUT",
+				Label:         "in segment beta",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{
+						{MatcherType: "IN_SEGMENT", UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "beta"}},
+					},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+			},
+			{
+				ConditionType: "ROLLOUT",
+				Label:         "adults",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{{
+						MatcherType:  "GREATER_THAN_OR_EQUAL_TO",
+						KeySelector:  &dtos.KeySelectorDTO{Attribute: &age},
+						UnaryNumeric: &dtos.UnaryNumericMatcherDataDTO{DataType: "NUMBER", Value: 18},
+					}},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+			},
+		},
+	}
+	split := grammar.NewSplit(&splitDTO, ctx, logger)
+	allowlistOnly := NewEngine(logger, Options{AllowlistOnly: map[string]bool{"launch": true}})
+
+	for _, key := range []string{"beta1", "beta2"} {
+		treatment, label := allowlistOnly.DoEvaluation(split, key, key, nil)
+		if treatment == nil || *treatment != "on" || label != "in segment beta" {
+			t.Error("Keys in a targeted segment should get their treatment regardless of the traffic allocation", key, label)
+		}
+	}
+
+	treatment, label := allowlistOnly.DoEvaluation(split, "key1", "key1", map[string]interface{}{"age": 30})
+	if treatment != nil || label != impressionlabels.RolloutDisabled {
+		t.Error("Conditions not targeting segments or whitelists should be skipped", label)
+	}
+}
@@ -0,0 +1,231 @@
+package evaluator
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage"
+)
+
+// evaluationCacheTillCheckInterval is the minimum time between checks of the changeNumber of the split storage
+const evaluationCacheTillCheckInterval = time.Second
+
+type evaluationCacheKey struct {
+	matchingKey  string
+	bucketingKey string
+	feature      string
+	till         int64
+}
+
+type cachedEvaluation struct {
+	key       evaluationCacheKey
+	result    Result
+	expiresAt time.Time
+}
+
+// CachedEvaluator memoizes the evaluations of another evaluator for the configured TTL, keyed by matching key,
+// bucketing key, feature & changeNumber of the split storage. The changeNumber is checked at most once per second and,
+// if it has advanced, every cached evaluation is purged. Evaluations with attributes, CONTROL results and results
+// computed while a segment couldn't be read, ie: because the context of the evaluation was cancelled, are never
+// cached. When the cache is full, the least recently used evaluation is evicted
+type CachedEvaluator struct {
+	inner Interface
+	cache *evaluationCache
+}
+
+// evaluationCache holds the cached evaluations, shared by a CachedEvaluator and the copies rebound to other storages
+type evaluationCache struct {
+	hits          int64 // accessed atomically, kept first for 64-bit alignment
+	splitStorage  storage.SplitStorageConsumer
+	size          int
+	ttl           time.Duration
+	tillTTL       time.Duration
+	till          int64
+	tillCheckedAt time.Time
+	entries       map[evaluationCacheKey]*list.Element
+	lru           *list.List
+	mutex         *sync.Mutex
+}
+
+// NewCachedEvaluator instantiates a new CachedEvaluator holding up to size evaluations for ttl
+func NewCachedEvaluator(
+	inner Interface,
+	splitStorage storage.SplitStorageConsumer,
+	size int,
+	ttl time.Duration,
+) *CachedEvaluator {
+	return &CachedEvaluator{
+		inner: inner,
+		cache: &evaluationCache{
+			splitStorage: splitStorage,
+			size:         size,
+			ttl:          ttl,
+			tillTTL:      evaluationCacheTillCheckInterval,
+			entries:      make(map[evaluationCacheKey]*list.Element),
+			lru:          list.New(),
+			mutex:        &sync.Mutex{},
+		},
+	}
+}
+
+// SplitStorage returns the storage the wrapped evaluator reads splits from, or nil if it can't tell
+func (c *CachedEvaluator) SplitStorage() storage.SplitStorageConsumer {
+	if rebindable, ok := c.inner.(Rebindable); ok {
+		return rebindable.SplitStorage()
+	}
+	return nil
+}
+
+// SegmentStorage returns the storage the wrapped evaluator reads segments from, or nil if it can't tell
+func (c *CachedEvaluator) SegmentStorage() storage.SegmentStorageConsumer {
+	if rebindable, ok := c.inner.(Rebindable); ok {
+		return rebindable.SegmentStorage()
+	}
+	return nil
+}
+
+// Rebind returns a CachedEvaluator sharing the cache with the current one whose misses are evaluated reading from the
+// supplied storages. Those must hold the same data as the original ones, otherwise the cache would serve stale results
+func (c *CachedEvaluator) Rebind(splitStorage storage.SplitStorageConsumer, segmentStorage storage.SegmentStorageConsumer) Interface {
+	rebindable, ok := c.inner.(Rebindable)
+	if !ok {
+		return c
+	}
+	return &CachedEvaluator{inner: rebindable.Rebind(splitStorage, segmentStorage), cache: c.cache}
+}
+
+// Unwrap returns the evaluator whose evaluations are cached
+func (c *CachedEvaluator) Unwrap() Interface {
+	return c.inner
+}
+
+// EvaluateFeature returns the cached evaluation of the feature for the key if there's one, evaluating it otherwise
+func (c *CachedEvaluator) EvaluateFeature(key string, bucketingKey *string, feature string, attributes map[string]interface{}) *Result {
+	if len(attributes) > 0 {
+		return c.inner.EvaluateFeature(key, bucketingKey, feature, attributes)
+	}
+
+	before := time.Now()
+	evaluationKey := cacheKey(key, bucketingKey, feature, c.cache.refreshTill(before))
+	if result, ok := c.cache.get(evaluationKey, before); ok {
+		result.EvaluationTimeNs = time.Since(before).Nanoseconds()
+		return &result
+	}
+
+	result := c.inner.EvaluateFeature(key, bucketingKey, feature, attributes)
+	c.cache.add(evaluationKey, result, before)
+	return result
+}
+
+// EvaluateFeatures serves the features cached for the key, evaluating the rest at once
+func (c *CachedEvaluator) EvaluateFeatures(key string, bucketingKey *string, features []string, attributes map[string]interface{}) Results {
+	if len(attributes) > 0 {
+		return c.inner.EvaluateFeatures(key, bucketingKey, features, attributes)
+	}
+
+	before := time.Now()
+	till := c.cache.refreshTill(before)
+	results := Results{Evaluations: make(map[string]Result, len(features))}
+	missing := make([]string, 0, len(features))
+	for _, feature := range features {
+		if result, ok := c.cache.get(cacheKey(key, bucketingKey, feature, till), before); ok {
+			results.Evaluations[feature] = result
+			continue
+		}
+		missing = append(missing, feature)
+	}
+
+	if len(missing) > 0 {
+		evaluated := c.inner.EvaluateFeatures(key, bucketingKey, missing, attributes)
+		for feature, result := range evaluated.Evaluations {
+			c.cache.add(cacheKey(key, bucketingKey, feature, till), &result, before)
+			results.Evaluations[feature] = result
+		}
+	}
+
+	results.EvaluationTimeNs = time.Since(before).Nanoseconds()
+	return results
+}
+
+// Hits returns the amount of evaluations served from cache
+func (c *CachedEvaluator) Hits() int64 {
+	return atomic.LoadInt64(&c.cache.hits)
+}
+
+func cacheKey(key string, bucketingKey *string, feature string, till int64) evaluationCacheKey {
+	evaluationKey := evaluationCacheKey{matchingKey: key, bucketingKey: key, feature: feature, till: till}
+	if bucketingKey != nil {
+		evaluationKey.bucketingKey = *bucketingKey
+	}
+	return evaluationKey
+}
+
+// refreshTill returns the changeNumber of the split storage, purging every cached evaluation if it has advanced.
+// Storages that can't tell their changeNumber are only checked once
+func (c *evaluationCache) refreshTill(now time.Time) int64 {
+	c.mutex.Lock()
+	till, checkedAt := c.till, c.tillCheckedAt
+	c.mutex.Unlock()
+	if !checkedAt.IsZero() && now.Sub(checkedAt) < c.tillTTL {
+		return till
+	}
+
+	current := till
+	if tills, ok := c.splitStorage.(interface{ Till() int64 }); ok {
+		current = tills.Till()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if current != c.till {
+		c.entries = make(map[evaluationCacheKey]*list.Element)
+		c.lru.Init()
+	}
+	c.till, c.tillCheckedAt = current, now
+	return current
+}
+
+// get returns a copy of the evaluation cached for the key if it hasn't expired
+func (c *evaluationCache) get(key evaluationCacheKey, now time.Time) (Result, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return Result{}, false
+	}
+
+	entry := element.Value.(*cachedEvaluation)
+	if !now.Before(entry.expiresAt) {
+		c.removeElement(element)
+		return Result{}, false
+	}
+	c.lru.MoveToFront(element)
+	atomic.AddInt64(&c.hits, 1)
+	return entry.result, true
+}
+
+// add caches an evaluation evicting the least recently used one if the cache is full
+func (c *evaluationCache) add(key evaluationCacheKey, result *Result, now time.Time) {
+	if result == nil || result.Treatment == Control || result.SegmentReadFailed {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+	c.entries[key] = c.lru.PushFront(&cachedEvaluation{key: key, result: *result, expiresAt: now.Add(c.ttl)})
+
+	if c.lru.Len() > c.size {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// removeElement removes a cached evaluation. Must be called with the lock held
+func (c *evaluationCache) removeElement(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cachedEvaluation).key)
+}
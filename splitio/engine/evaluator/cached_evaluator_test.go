@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/splitio/go-client/splitio/engine"
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-toolkit/datastructures/set"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type countingEvaluator struct {
+	inner       Interface
+	evaluations int
+}
+
+func (c *countingEvaluator) EvaluateFeature(key string, bucketingKey *string, feature string, attributes map[string]interface{}) *Result {
+	c.evaluations++
+	return c.inner.EvaluateFeature(key, bucketingKey, feature, attributes)
+}
+
+func (c *countingEvaluator) EvaluateFeatures(key string, bucketingKey *string, features []string, attributes map[string]interface{}) Results {
+	c.evaluations += len(features)
+	return c.inner.EvaluateFeatures(key, bucketingKey, features, attributes)
+}
+
+func TestCachedEvaluator(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage, segmentStorage := compiledEvaluatorFixtures()
+	counting := &countingEvaluator{inner: NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger)}
+	cached := NewCachedEvaluator(counting, splitStorage, 2, time.Hour)
+	cached.cache.tillTTL = 0
+
+	first := cached.EvaluateFeature("key3", nil, "rollout", nil)
+	second := cached.EvaluateFeature("key3", nil, "rollout", nil)
+	if counting.evaluations != 1 || cached.Hits() != 1 {
+		t.Error("The second evaluation should be served from cache", counting.evaluations, cached.Hits())
+	}
+	if second.Treatment != first.Treatment || second.Label != first.Label || second.SplitChangeNumber != first.SplitChangeNumber {
+		t.Error("The cached evaluation should match the original one", first, second)
+	}
+
+	bucketingKey := "other"
+	cached.EvaluateFeature("key3", &bucketingKey, "rollout", nil)
+	cached.EvaluateFeature("key3", nil, "rollout", map[string]interface{}{"age": 20})
+	cached.EvaluateFeature("key3", nil, "nonexistent", nil)
+	cached.EvaluateFeature("key3", nil, "nonexistent", nil)
+	if counting.evaluations != 5 {
+		t.Error("Other bucketing keys, attributes & CONTROL results should not be served from cache", counting.evaluations)
+	}
+
+	results := cached.EvaluateFeatures("key3", nil, []string{"rollout", "segmented"}, nil)
+	if counting.evaluations != 6 || len(results.Evaluations) != 2 || results.Evaluations["rollout"].Treatment != first.Treatment {
+		t.Error("Only features not cached should be evaluated", counting.evaluations, results)
+	}
+
+	// "key3/other" was the least recently used evaluation, so it should have been evicted
+	cached.EvaluateFeature("key3", &bucketingKey, "rollout", nil)
+	if counting.evaluations != 7 {
+		t.Error("The least recently used evaluation should be evicted", counting.evaluations)
+	}
+
+	splitStorage.PutMany([]dtos.SplitDTO{}, splitStorage.Till()+1)
+	cached.EvaluateFeature("key3", nil, "rollout", nil)
+	if counting.evaluations != 8 {
+		t.Error("Evaluations should be invalidated when the changeNumber advances", counting.evaluations)
+	}
+
+	expiring := NewCachedEvaluator(counting, splitStorage, 10, time.Millisecond)
+	expiring.EvaluateFeature("key3", nil, "rollout", nil)
+	time.Sleep(5 * time.Millisecond)
+	expiring.EvaluateFeature("key3", nil, "rollout", nil)
+	if counting.evaluations != 10 || expiring.Hits() != 0 {
+		t.Error("Expired evaluations should not be served from cache", counting.evaluations)
+	}
+}
+
+type failingSegmentStorage struct{}
+
+func (f *failingSegmentStorage) Get(segmentName string) *set.ThreadUnsafeSet { return nil }
+func (f *failingSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	return false, errors.New("context canceled")
+}
+
+func TestCachedEvaluatorSkipsFailedSegmentReads(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage, _ := compiledEvaluatorFixtures()
+	counting := &countingEvaluator{inner: NewEvaluator(splitStorage, &failingSegmentStorage{}, engine.NewEngine(logger, engine.Options{}), logger)}
+	cached := NewCachedEvaluator(counting, splitStorage, 10, time.Hour)
+
+	// key3 is a member of beta, so "off" is only returned because the segment couldn't be read
+	result := cached.EvaluateFeature("key3", nil, "segmented", nil)
+	if result.Treatment != "off" || !result.SegmentReadFailed {
+		t.Error("The failed segment read should be flagged in the result", result)
+	}
+	cached.EvaluateFeature("key3", nil, "segmented", nil)
+	cached.EvaluateFeatures("key3", nil, []string{"segmented"}, nil)
+	if counting.evaluations != 3 || cached.Hits() != 0 {
+		t.Error("Results computed from a failed segment read should not be cached", counting.evaluations, cached.Hits())
+	}
+
+	cached.EvaluateFeature("key3", nil, "rollout", nil)
+	cached.EvaluateFeature("key3", nil, "rollout", nil)
+	if counting.evaluations != 4 || cached.Hits() != 1 {
+		t.Error("Results not reading segments should still be cached", counting.evaluations, cached.Hits())
+	}
+}
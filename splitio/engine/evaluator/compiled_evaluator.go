@@ -0,0 +1,219 @@
+package evaluator
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/splitio/go-client/splitio/engine"
+	"github.com/splitio/go-client/splitio/engine/grammar"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// compiledIndexTillCheckInterval is the minimum time between checks of the changeNumber of the split storage
+const compiledIndexTillCheckInterval = time.Second
+
+// compiledIndex holds the splits in storage at a given change number, parsed and indexed by feature ID.
+// Feature IDs are never reassigned, so the splits of features no longer in storage are nil
+type compiledIndex struct {
+	till   int64
+	ids    map[string]int
+	splits []*grammar.Split
+}
+
+// split returns the parsed split of a feature ID, or nil if it's unknown or not in storage
+func (i *compiledIndex) split(id int) *grammar.Split {
+	if id < 0 || id >= len(i.splits) {
+		return nil
+	}
+	return i.splits[id]
+}
+
+// CompiledEvaluator evaluates features against an index of the splits in storage whose conditions, matchers,
+// whitelists & sets have been built beforehand, instead of parsing each split on every evaluation as the Evaluator does.
+// The index is rebuilt when the change number of the split storage moves, ie: after a synchronization brought changes.
+// The change number is checked at most once per second. Features are interned into IDs that never change, so that
+// callers evaluating a fixed set of features can skip looking them up by name
+type CompiledEvaluator struct {
+	tillCheckedAt int64 // unix nanoseconds, accessed atomically, kept first for 64-bit alignment
+	tillTTL       time.Duration
+	base          *Evaluator
+	index         atomic.Value
+	mutex         sync.Mutex
+}
+
+// NewCompiledEvaluator instantiates a CompiledEvaluator struct and returns a reference to it
+func NewCompiledEvaluator(
+	splitStorage storage.SplitStorageConsumer,
+	segmentStorage storage.SegmentStorageConsumer,
+	eng *engine.Engine,
+	logger logging.LoggerInterface,
+) *CompiledEvaluator {
+	return &CompiledEvaluator{
+		tillTTL: compiledIndexTillCheckInterval,
+		base:    NewEvaluator(splitStorage, segmentStorage, eng, logger),
+	}
+}
+
+// SplitStorage returns the storage splits are read from
+func (c *CompiledEvaluator) SplitStorage() storage.SplitStorageConsumer {
+	return c.base.SplitStorage()
+}
+
+// SegmentStorage returns the storage segments are read from
+func (c *CompiledEvaluator) SegmentStorage() storage.SegmentStorageConsumer {
+	return c.base.SegmentStorage()
+}
+
+// Rebind returns an Evaluator reading splits & segments from the supplied storages. Matchers of the index are bound
+// to the original storages, so the returned evaluator parses splits on every evaluation
+func (c *CompiledEvaluator) Rebind(splitStorage storage.SplitStorageConsumer, segmentStorage storage.SegmentStorageConsumer) Interface {
+	return c.base.Rebind(splitStorage, segmentStorage)
+}
+
+// Unwrap returns the Evaluator used to evaluate the compiled splits
+func (c *CompiledEvaluator) Unwrap() Interface {
+	return c.base
+}
+
+// loadIndex returns the last index built, or nil if none was
+func (c *CompiledEvaluator) loadIndex() *compiledIndex {
+	index, _ := c.index.Load().(*compiledIndex)
+	return index
+}
+
+// currentIndex returns the index of the splits in storage, rebuilding it if the storage changed since it was built.
+// Within a second of the last check, the last index built is returned without checking the storage
+func (c *CompiledEvaluator) currentIndex() *compiledIndex {
+	index := c.loadIndex()
+	now := time.Now().UnixNano()
+	if index != nil && now-atomic.LoadInt64(&c.tillCheckedAt) < int64(c.tillTTL) {
+		return index
+	}
+
+	atomic.StoreInt64(&c.tillCheckedAt, now)
+	if index != nil && index.till == c.till() {
+		return index
+	}
+	return c.compile()
+}
+
+// till returns the change number of the split storage, or 0 if it can't tell, in which case the index is only built once
+func (c *CompiledEvaluator) till() int64 {
+	tills, ok := c.base.splitStorage.(interface{ Till() int64 })
+	if !ok {
+		return 0
+	}
+	return tills.Till()
+}
+
+// Compile rebuilds the index if the splits in storage changed since it was built. Evaluations rebuild it automatically
+// within a second of a change, so calling it is only useful to pick changes up right away or to avoid paying for it
+// on the first evaluation after a change
+func (c *CompiledEvaluator) Compile() {
+	c.compile()
+}
+
+func (c *CompiledEvaluator) compile() *compiledIndex {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// The change number is read first so that changes stored while building trigger another rebuild
+	till := c.till()
+	previous := c.loadIndex()
+	if previous != nil && previous.till == till {
+		return previous
+	}
+
+	index := &compiledIndex{till: till, ids: make(map[string]int)}
+	if previous != nil {
+		for feature, id := range previous.ids {
+			index.ids[feature] = id
+		}
+	}
+
+	ctx := c.base.newContext(c)
+	splitDTOs := c.base.splitStorage.GetAll()
+	for idx := range splitDTOs {
+		if _, ok := index.ids[splitDTOs[idx].Name]; !ok {
+			index.ids[splitDTOs[idx].Name] = len(index.ids)
+		}
+	}
+	index.splits = make([]*grammar.Split, len(index.ids))
+	for idx := range splitDTOs {
+		index.splits[index.ids[splitDTOs[idx].Name]] = grammar.NewSplit(&splitDTOs[idx], ctx, c.base.logger)
+	}
+
+	c.index.Store(index)
+	return index
+}
+
+// FeatureID returns the ID a feature has been interned with. IDs are assigned to the features in storage when the
+// index is built, so unknown features get one once they're synchronized
+func (c *CompiledEvaluator) FeatureID(feature string) (int, bool) {
+	id, ok := c.currentIndex().ids[feature]
+	return id, ok
+}
+
+// EvaluateFeature returns a struct with the resulting treatment and extra information for the impression
+func (c *CompiledEvaluator) EvaluateFeature(key string, bucketingKey *string, feature string, attributes map[string]interface{}) *Result {
+	before := time.Now()
+	index := c.currentIndex()
+	id, ok := index.ids[feature]
+	if !ok {
+		id = -1
+	}
+	result := c.evaluate(index, key, bucketingKey, feature, id, attributes)
+	result.EvaluationTimeNs = time.Since(before).Nanoseconds()
+	return result
+}
+
+// EvaluateFeatureByID evaluates a feature by the ID returned by FeatureID, skipping the lookup by name
+func (c *CompiledEvaluator) EvaluateFeatureByID(key string, bucketingKey *string, id int, attributes map[string]interface{}) *Result {
+	before := time.Now()
+	index := c.currentIndex()
+	var feature string
+	if split := index.split(id); split != nil {
+		feature = split.Name()
+	}
+	result := c.evaluate(index, key, bucketingKey, feature, id, attributes)
+	result.EvaluationTimeNs = time.Since(before).Nanoseconds()
+	return result
+}
+
+// EvaluateFeatures returns a struct with the resulting treatment and extra information for the impression
+func (c *CompiledEvaluator) EvaluateFeatures(key string, bucketingKey *string, features []string, attributes map[string]interface{}) Results {
+	before := time.Now()
+	results := Results{Evaluations: make(map[string]Result, len(features))}
+	index := c.currentIndex()
+	for _, feature := range features {
+		id, ok := index.ids[feature]
+		if !ok {
+			id = -1
+		}
+		results.Evaluations[feature] = *c.evaluate(index, key, bucketingKey, feature, id, attributes)
+	}
+	results.EvaluationTimeNs = time.Since(before).Nanoseconds()
+	return results
+}
+
+// EvaluateDependency SHOULD ONLY BE USED by DependencyMatcher.
+// It's used to break the dependency cycle between matchers and evaluators.
+func (c *CompiledEvaluator) EvaluateDependency(key string, bucketingKey *string, feature string, attributes map[string]interface{}) string {
+	return c.EvaluateFeature(key, bucketingKey, feature, attributes).Treatment
+}
+
+func (c *CompiledEvaluator) evaluate(
+	index *compiledIndex,
+	key string,
+	bucketingKey *string,
+	feature string,
+	id int,
+	attributes map[string]interface{},
+) *Result {
+	if bucketingKey == nil {
+		bucketingKey = &key
+	}
+	return c.base.evaluateSplit(key, *bucketingKey, feature, index.split(id), attributes)
+}
@@ -0,0 +1,162 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/splitio/go-client/splitio/engine"
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/datastructures/set"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func compiledEvaluatorFixtures() (*mutexmap.MMSplitStorage, *mutexmap.MMSegmentStorage) {
+	condition := func(matcher dtos.MatcherDTO, label string, partitions ...dtos.PartitionDTO) dtos.ConditionDTO {
+		return dtos.ConditionDTO{
+			ConditionType: "ROLLOUT",
+			Label:         label,
+			MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{matcher}},
+			Partitions:    partitions,
+		}
+	}
+	allKeys := dtos.MatcherDTO{MatcherType: "ALL_KEYS"}
+	age := "age"
+	split := func(name string, seed int64, conditions ...dtos.ConditionDTO) dtos.SplitDTO {
+		return dtos.SplitDTO{
+			Name:              name,
+			Status:            "ACTIVE",
+			Algo:              2,
+			Seed:              seed,
+			DefaultTreatment:  "off",
+			TrafficAllocation: 100,
+			ChangeNumber:      1,
+			Configurations:    map[string]string{"on": `{"color":"blue"}`},
+			Conditions:        conditions,
+		}
+	}
+
+	splits := []dtos.SplitDTO{
+		split("rollout", 11,
+			condition(dtos.MatcherDTO{MatcherType: "WHITELIST", Whitelist: &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"key3", "key7"}}},
+				"whitelisted", dtos.PartitionDTO{Treatment: "vip", Size: 100}),
+			condition(allKeys, "default rule", dtos.PartitionDTO{Treatment: "on", Size: 50}, dtos.PartitionDTO{Treatment: "off", Size: 50}),
+		),
+		split("segmented", 22,
+			condition(dtos.MatcherDTO{MatcherType: "IN_SEGMENT", UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "beta"}},
+				"in segment beta", dtos.PartitionDTO{Treatment: "on", Size: 100}),
+		),
+		split("adults", 33,
+			condition(dtos.MatcherDTO{
+				MatcherType:  "GREATER_THAN_OR_EQUAL_TO",
+				KeySelector:  &dtos.KeySelectorDTO{Attribute: &age},
+				UnaryNumeric: &dtos.UnaryNumericMatcherDataDTO{DataType: "NUMBER", Value: 18},
+			}, "adult", dtos.PartitionDTO{Treatment: "on", Size: 70}, dtos.PartitionDTO{Treatment: "off", Size: 30}),
+		),
+		split("dependent", 44,
+			condition(dtos.MatcherDTO{MatcherType: "IN_SPLIT_TREATMENT", Dependency: &dtos.DependencyMatcherDataDTO{Split: "rollout", Treatments: []string{"on"}}},
+				"rollout on", dtos.PartitionDTO{Treatment: "on", Size: 100}),
+		),
+	}
+	killed := split("killed", 55, condition(allKeys, "default rule", dtos.PartitionDTO{Treatment: "on", Size: 100}))
+	killed.Killed = true
+	splits = append(splits, killed)
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany(splits, 1)
+
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	beta := set.NewSet()
+	for i := 0; i < 1000; i += 3 {
+		beta.Add(fmt.Sprintf("key%d", i))
+	}
+	segmentStorage.Put("beta", beta, 1)
+	return splitStorage, segmentStorage
+}
+
+func TestCompiledEvaluatorMatchesEvaluator(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage, segmentStorage := compiledEvaluatorFixtures()
+	eng := engine.NewEngine(logger, engine.Options{})
+	standard := NewEvaluator(splitStorage, segmentStorage, eng, logger)
+	compiled := NewCompiledEvaluator(splitStorage, segmentStorage, eng, logger)
+
+	features := []string{"rollout", "segmented", "adults", "dependent", "killed", "missing"}
+	compare := func() {
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("key%d", i)
+			attributes := map[string]interface{}{"age": i % 40}
+			expected := standard.EvaluateFeatures(key, nil, features, attributes).Evaluations
+			actual := compiled.EvaluateFeatures(key, nil, features, attributes).Evaluations
+			for _, feature := range features {
+				single := compiled.EvaluateFeature(key, nil, feature, attributes)
+				e, a := expected[feature], actual[feature]
+				if e.Treatment != a.Treatment || e.Label != a.Label || e.SplitChangeNumber != a.SplitChangeNumber ||
+					(e.Config == nil) != (a.Config == nil) || single.Treatment != e.Treatment || single.Label != e.Label {
+					t.Error("Compiled evaluation differs for", feature, key, e, a, single)
+					return
+				}
+			}
+		}
+	}
+	compare()
+
+	// Changes in storage are picked up once the change number moves and it's checked
+	id, ok := compiled.FeatureID("segmented")
+	if !ok {
+		t.Error("Features in storage should be interned")
+	}
+	changed := *splitStorage.Get("rollout")
+	changed.Conditions = changed.Conditions[1:]
+	changed.ChangeNumber = 2
+	splitStorage.Remove("segmented")
+	splitStorage.PutMany([]dtos.SplitDTO{changed}, 2)
+	compiled.EvaluateFeature("key0", nil, "rollout", nil)
+	if compiled.loadIndex().till != 1 {
+		t.Error("The change number should be checked at most once per second")
+	}
+	compiled.Compile()
+	compare()
+
+	if result := compiled.EvaluateFeatureByID("key0", nil, id, nil); result.Treatment != Control {
+		t.Error("Features removed from storage should return CONTROL", result)
+	}
+	if newID, _ := compiled.FeatureID("segmented"); newID != id {
+		t.Error("Feature IDs should never be reassigned")
+	}
+	rolloutID, _ := compiled.FeatureID("rollout")
+	if byID, byName := compiled.EvaluateFeatureByID("key3", nil, rolloutID, nil), standard.EvaluateFeature("key3", nil, "rollout", nil); byID.Treatment != byName.Treatment {
+		t.Error("Evaluating by ID should match evaluating by name", byID, byName)
+	}
+	prefetched := mutexmap.NewMMSegmentStorage()
+	if rebound, ok := compiled.Rebind(splitStorage, prefetched).(*Evaluator); !ok || rebound.SegmentStorage() != prefetched {
+		t.Error("Rebound evaluations should read from the supplied storages", rebound)
+	}
+}
+
+func benchmarkEvaluator(b *testing.B, evaluator Interface) {
+	attributes := map[string]interface{}{"age": 30}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evaluator.EvaluateFeature(keys[i%len(keys)], nil, "adults", attributes)
+	}
+}
+
+func BenchmarkEvaluator(b *testing.B) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage, segmentStorage := compiledEvaluatorFixtures()
+	eng := engine.NewEngine(logger, engine.Options{})
+	benchmarkEvaluator(b, NewEvaluator(splitStorage, segmentStorage, eng, logger))
+}
+
+func BenchmarkCompiledEvaluator(b *testing.B) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage, segmentStorage := compiledEvaluatorFixtures()
+	eng := engine.NewEngine(logger, engine.Options{})
+	benchmarkEvaluator(b, NewCompiledEvaluator(splitStorage, segmentStorage, eng, logger))
+}
@@ -2,6 +2,7 @@ package evaluator
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/splitio/go-client/splitio/engine"
@@ -20,13 +21,17 @@ const (
 )
 
 // Result represents the result of an evaluation, including the resulting treatment, the label for the impression,
-// the latency and error if any
+// the latency and error if any. SegmentReadFailed is set when a segment couldn't be read while evaluating, in which
+// case the key was taken as not being a member and the treatment may not be the right one
 type Result struct {
-	Treatment         string
-	Label             string
-	EvaluationTimeNs  int64
-	SplitChangeNumber int64
-	Config            *string
+	Treatment             string
+	Label                 string
+	EvaluationTimeNs      int64
+	SplitChangeNumber     int64
+	Config                *string
+	MatchedConditionIndex int
+	ImpressionsDisabled   bool
+	SegmentReadFailed     bool
 }
 
 // Results represents the result of multiple evaluations at once
@@ -40,7 +45,10 @@ type Evaluator struct {
 	splitStorage   storage.SplitStorageConsumer
 	segmentStorage storage.SegmentStorageConsumer
 	eng            *engine.Engine
+	pinSegments    bool
 	logger         logging.LoggerInterface
+	// segmentFailures counts the failed segment reads of matchers, shared by the copies of the evaluator
+	segmentFailures *int64
 }
 
 // NewEvaluator instantiates an Evaluator struct and returns a reference to it
@@ -51,48 +59,135 @@ func NewEvaluator(
 	logger logging.LoggerInterface,
 ) *Evaluator {
 	return &Evaluator{
-		splitStorage:   splitStorage,
-		segmentStorage: segmentStorage,
-		eng:            eng,
-		logger:         logger,
+		splitStorage:    splitStorage,
+		segmentStorage:  segmentStorage,
+		eng:             eng,
+		logger:          logger,
+		segmentFailures: new(int64),
 	}
 }
 
+// WithSegmentStorage returns a new Evaluator that shares splits, engine & logger with the current one
+// but reads segments from the supplied storage, ie: a snapshot of a past segment state
+func (e *Evaluator) WithSegmentStorage(segmentStorage storage.SegmentStorageConsumer) *Evaluator {
+	evaluator := *e
+	evaluator.segmentStorage = segmentStorage
+	return &evaluator
+}
+
+// WithSplitStorage returns a new Evaluator that shares segments, engine & logger with the current one
+// but reads splits from the supplied storage, ie: proposed split definitions
+func (e *Evaluator) WithSplitStorage(splitStorage storage.SplitStorageConsumer) *Evaluator {
+	evaluator := *e
+	evaluator.splitStorage = splitStorage
+	return &evaluator
+}
+
+// Rebind returns a new Evaluator that shares engine & logger with the current one but reads splits & segments
+// from the supplied storages
+func (e *Evaluator) Rebind(splitStorage storage.SplitStorageConsumer, segmentStorage storage.SegmentStorageConsumer) Interface {
+	return e.WithSplitStorage(splitStorage).WithSegmentStorage(segmentStorage)
+}
+
+// WithPinnedSegments returns a new Evaluator that, if pin is true, pins the segment memberships read while evaluating
+// a batch of features, so that every feature in the batch sees the same memberships even if segments are updated
+// in the middle of it
+func (e *Evaluator) WithPinnedSegments(pin bool) *Evaluator {
+	evaluator := *e
+	evaluator.pinSegments = pin
+	return &evaluator
+}
+
+// SplitStorage returns the storage splits are read from
+func (e *Evaluator) SplitStorage() storage.SplitStorageConsumer {
+	return e.splitStorage
+}
+
+// SegmentStorage returns the storage segments are read from
+func (e *Evaluator) SegmentStorage() storage.SegmentStorageConsumer {
+	return e.segmentStorage
+}
+
+// newContext returns the context matchers are built with. Dependency matchers evaluate the features they depend on
+// through dependencyEvaluator
+func (e *Evaluator) newContext(dependencyEvaluator interface{}) *injection.Context {
+	ctx := injection.NewContext()
+	ctx.AddDependency("segmentStorage", e.segmentStorage)
+	ctx.AddDependency("evaluator", dependencyEvaluator)
+	ctx.AddDependency("caseInsensitiveStrings", e.eng.CaseInsensitiveStrings())
+	ctx.AddDependency("segmentFailures", e.segmentFailures)
+	return ctx
+}
+
 func (e *Evaluator) evaluateTreatment(key string, bucketingKey string, feature string, splitDto *dtos.SplitDTO, attributes map[string]interface{}) *Result {
-	var config *string
 	if splitDto == nil {
-		e.logger.Warning(fmt.Sprintf("Feature %s not found, returning control.", feature))
-		return &Result{Treatment: Control, Label: impressionlabels.SplitNotFound, Config: config}
+		return e.evaluateSplit(key, bucketingKey, feature, nil, attributes)
+	}
+	if splitDto.Killed {
+		// Killed splits always return their default treatment, so their conditions aren't even parsed
+		return e.killedResult(feature, splitDto.DefaultTreatment, splitDto.Configurations, splitDto.ChangeNumber, splitDto.ImpressionsTracked())
 	}
+	return e.evaluateSplit(key, bucketingKey, feature, grammar.NewSplit(splitDto, e.newContext(e), e.logger), attributes)
+}
 
-	ctx := injection.NewContext()
-	ctx.AddDependency("segmentStorage", e.segmentStorage)
-	ctx.AddDependency("evaluator", e)
+// killedResult returns the default treatment of a killed split along with its config, labeled as killed
+func (e *Evaluator) killedResult(
+	feature string,
+	defaultTreatment string,
+	configurations map[string]string,
+	changeNumber int64,
+	trackImpressions bool,
+) *Result {
+	e.logger.Warning(fmt.Sprintf(
+		"Feature %s has been killed, returning default treatment: %s",
+		feature,
+		defaultTreatment,
+	))
 
-	split := grammar.NewSplit(splitDto, ctx, e.logger)
+	var config *string
+	if treatmentConfig, ok := configurations[defaultTreatment]; ok {
+		config = &treatmentConfig
+	}
+
+	return &Result{
+		Treatment:             defaultTreatment,
+		Label:                 impressionlabels.Killed,
+		SplitChangeNumber:     changeNumber,
+		Config:                config,
+		MatchedConditionIndex: engine.NoConditionIndex,
+		ImpressionsDisabled:   !trackImpressions,
+	}
+}
+
+// evaluateSplit evaluates an already parsed split, or returns CONTROL if it's nil
+func (e *Evaluator) evaluateSplit(key string, bucketingKey string, feature string, split *grammar.Split, attributes map[string]interface{}) *Result {
+	var config *string
+	if split == nil {
+		e.logger.Warning(fmt.Sprintf("Feature %s not found, returning control.", feature))
+		return &Result{Treatment: Control, Label: impressionlabels.SplitNotFound, Config: config, MatchedConditionIndex: engine.NoConditionIndex}
+	}
 
 	if split.Killed() {
-		e.logger.Warning(fmt.Sprintf(
-			"Feature %s has been killed, returning default treatment: %s",
-			feature,
-			split.DefaultTreatment(),
-		))
+		return e.killedResult(feature, split.DefaultTreatment(), split.Configurations(), split.ChangeNumber(), split.TrackImpressions())
+	}
 
-		if _, ok := split.Configurations()[split.DefaultTreatment()]; ok {
-			treatmentConfig := split.Configurations()[split.DefaultTreatment()]
-			config = &treatmentConfig
-		}
+	failuresBefore := atomic.LoadInt64(e.segmentFailures)
+	treatment, label, conditionIndex := e.eng.DoEvaluationWithIndex(split, key, bucketingKey, attributes)
+	// Failures of concurrent evaluations are counted too, which only errs on the side of flagging a result
+	segmentReadFailed := atomic.LoadInt64(e.segmentFailures) != failuresBefore
 
+	if label == impressionlabels.TypeMismatch {
 		return &Result{
-			Treatment:         split.DefaultTreatment(),
-			Label:             impressionlabels.Killed,
-			SplitChangeNumber: split.ChangeNumber(),
-			Config:            config,
+			Treatment:             Control,
+			Label:                 label,
+			SplitChangeNumber:     split.ChangeNumber(),
+			Config:                config,
+			MatchedConditionIndex: conditionIndex,
+			ImpressionsDisabled:   !split.TrackImpressions(),
+			SegmentReadFailed:     segmentReadFailed,
 		}
 	}
 
-	treatment, label := e.eng.DoEvaluation(split, key, bucketingKey, attributes)
-
 	if treatment == nil {
 		e.logger.Warning(fmt.Sprintf(
 			"No condition matched, returning default treatment: %s",
@@ -100,7 +195,9 @@ func (e *Evaluator) evaluateTreatment(key string, bucketingKey string, feature s
 		))
 		defaultTreatment := split.DefaultTreatment()
 		treatment = &defaultTreatment
-		label = impressionlabels.NoConditionMatched
+		if label == "" {
+			label = impressionlabels.NoConditionMatched
+		}
 	}
 
 	if _, ok := split.Configurations()[*treatment]; ok {
@@ -109,10 +206,13 @@ func (e *Evaluator) evaluateTreatment(key string, bucketingKey string, feature s
 	}
 
 	return &Result{
-		Treatment:         *treatment,
-		Label:             label,
-		SplitChangeNumber: split.ChangeNumber(),
-		Config:            config,
+		Treatment:             *treatment,
+		Label:                 label,
+		SplitChangeNumber:     split.ChangeNumber(),
+		Config:                config,
+		MatchedConditionIndex: conditionIndex,
+		ImpressionsDisabled:   !split.TrackImpressions(),
+		SegmentReadFailed:     segmentReadFailed,
 	}
 }
 
@@ -140,11 +240,16 @@ func (e *Evaluator) EvaluateFeatures(key string, bucketingKey *string, features
 	before := time.Now()
 	splits := e.splitStorage.FetchMany(features)
 
+	evaluating := e
+	if e.pinSegments {
+		evaluating = e.WithSegmentStorage(storage.NewPinnedSegmentStorage(e.segmentStorage))
+	}
+
 	if bucketingKey == nil {
 		bucketingKey = &key
 	}
 	for _, feature := range features {
-		results.Evaluations[feature] = *e.evaluateTreatment(key, *bucketingKey, feature, splits[feature], attributes)
+		results.Evaluations[feature] = *evaluating.evaluateTreatment(key, *bucketingKey, feature, splits[feature], attributes)
 	}
 
 	after := time.Now()
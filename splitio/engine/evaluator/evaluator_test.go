@@ -1,10 +1,15 @@
 package evaluator
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/splitio/go-client/splitio/conf"
+	"github.com/splitio/go-client/splitio/engine"
+	"github.com/splitio/go-client/splitio/engine/evaluator/impressionlabels"
 	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
 )
@@ -364,3 +369,273 @@ func TestMultipleEvaluations(t *testing.T) {
 		t.Error("It should be greater than 0")
 	}
 }
+
+func TestEvaluationAgainstSegmentSnapshots(t *testing.T) {
+	logger := logging.NewLogger(nil)
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:                  2,
+			ChangeNumber:          123,
+			DefaultTreatment:      "off",
+			Name:                  "segment_split",
+			Seed:                  -1992295819,
+			Status:                "ACTIVE",
+			TrafficAllocation:     100,
+			TrafficAllocationSeed: -285565213,
+			TrafficTypeName:       "user",
+			Conditions: []dtos.ConditionDTO{
+				{
+					ConditionType: "WHITELIST",
+					Label:         "in segment beta",
+					MatcherGroup: dtos.MatcherGroupDTO{
+						Combiner: "AND",
+						Matchers: []dtos.MatcherDTO{
+							{
+								KeySelector:        &dtos.KeySelectorDTO{TrafficType: "user"},
+								MatcherType:        "IN_SEGMENT",
+								UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "beta"},
+							},
+						},
+					},
+					Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+				},
+			},
+		},
+	}, 123)
+
+	evaluator := NewEvaluator(splitStorage, mutexmap.NewMMSegmentStorage(), nil, logger)
+
+	lastWeek := evaluator.WithSegmentStorage(mutexmap.NewMMSegmentStorageFromSnapshot([]dtos.SegmentSnapshotDTO{
+		{Name: "beta", Keys: []string{"user1", "user2"}, Till: 100},
+	}))
+	today := evaluator.WithSegmentStorage(mutexmap.NewMMSegmentStorageFromSnapshot([]dtos.SegmentSnapshotDTO{
+		{Name: "beta", Keys: []string{"user2"}, Till: 200},
+	}))
+
+	key := "user1"
+	if result := lastWeek.EvaluateFeature(key, nil, "segment_split", nil); result.Treatment != "on" {
+		t.Error("user1 was part of the beta segment in the first snapshot. Got:", result.Treatment)
+	}
+
+	if result := today.EvaluateFeature(key, nil, "segment_split", nil); result.Treatment != "off" {
+		t.Error("user1 is not part of the beta segment in the second snapshot. Got:", result.Treatment)
+	}
+}
+
+func TestAttributeTypeMismatch(t *testing.T) {
+	logger := logging.NewLogger(nil)
+	attribute := "age"
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:              2,
+			ChangeNumber:      123,
+			DefaultTreatment:  "off",
+			Name:              "numeric_split",
+			Status:            "ACTIVE",
+			TrafficAllocation: 100,
+			TrafficTypeName:   "user",
+			Conditions: []dtos.ConditionDTO{
+				{
+					ConditionType: "WHITELIST",
+					Label:         "whitelisted",
+					MatcherGroup: dtos.MatcherGroupDTO{
+						Combiner: "AND",
+						Matchers: []dtos.MatcherDTO{
+							{MatcherType: "WHITELIST", Whitelist: &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"vip"}}},
+						},
+					},
+					Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+				},
+				{
+					ConditionType: "ROLLOUT",
+					Label:         "adults",
+					MatcherGroup: dtos.MatcherGroupDTO{
+						Combiner: "AND",
+						Matchers: []dtos.MatcherDTO{
+							{
+								KeySelector:  &dtos.KeySelectorDTO{TrafficType: "user", Attribute: &attribute},
+								MatcherType:  "GREATER_THAN_OR_EQUAL_TO",
+								UnaryNumeric: &dtos.UnaryNumericMatcherDataDTO{DataType: "NUMBER", Value: 18},
+							},
+						},
+					},
+					Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+				},
+			},
+		},
+	}, 123)
+
+	lenient := NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger)
+	result := lenient.EvaluateFeature("key", nil, "numeric_split", map[string]interface{}{"age": "42"})
+	if result.Treatment != "off" || result.Label != impressionlabels.NoConditionMatched {
+		t.Error("A string attribute should not match a numeric matcher by default. Got:", result.Treatment, result.Label)
+	}
+
+	strict := NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{StrictAttributeTypes: true}), logger)
+	result = strict.EvaluateFeature("key", nil, "numeric_split", map[string]interface{}{"age": "42"})
+	if result.Treatment != Control || result.Label != impressionlabels.TypeMismatch {
+		t.Error("A string attribute should return control on strict mode. Got:", result.Treatment, result.Label)
+	}
+
+	result = strict.EvaluateFeature("key", nil, "numeric_split", map[string]interface{}{"age": 42})
+	if result.Treatment != "on" {
+		t.Error("Integer attributes should be evaluated normally on strict mode. Got:", result.Treatment)
+	}
+
+	result = strict.EvaluateFeature("key", nil, "numeric_split", map[string]interface{}{"age": int64(42)})
+	if result.Treatment != "on" {
+		t.Error("Normalized integer attributes should be evaluated normally on strict mode. Got:", result.Treatment)
+	}
+
+	result = strict.EvaluateFeature("vip", nil, "numeric_split", map[string]interface{}{"age": "42"})
+	if result.Treatment != "on" || result.Label != "whitelisted" {
+		t.Error("Conditions not reached should not be type checked. Got:", result.Treatment, result.Label)
+	}
+}
+
+func TestSplitWithoutConditions(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:              2,
+			ChangeNumber:      123,
+			DefaultTreatment:  "off",
+			Name:              "nil_conditions",
+			Status:            "ACTIVE",
+			TrafficAllocation: 100,
+			TrafficTypeName:   "user",
+			Configurations:    map[string]string{"off": "{\"color\": \"gray\"}"},
+		},
+		{
+			Algo:              2,
+			ChangeNumber:      124,
+			DefaultTreatment:  "disabled",
+			Name:              "empty_conditions",
+			Status:            "ACTIVE",
+			TrafficAllocation: 50,
+			TrafficTypeName:   "user",
+			Conditions:        []dtos.ConditionDTO{},
+		},
+	}, 124)
+
+	evaluator := NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger)
+	for _, key := range []string{"key1", "key2", "some_other_key", "a"} {
+		result := evaluator.EvaluateFeature(key, nil, "nil_conditions", nil)
+		if result.Treatment != "off" || result.Label != impressionlabels.NoConditionMatched || result.SplitChangeNumber != 123 {
+			t.Error("Splits without conditions should return the default treatment. Got:", result.Treatment, result.Label)
+		}
+		if result.Config == nil || *result.Config != "{\"color\": \"gray\"}" {
+			t.Error("The config of the default treatment should be returned", result.Config)
+		}
+
+		result = evaluator.EvaluateFeature(key, nil, "empty_conditions", map[string]interface{}{"age": 42})
+		if result.Treatment != "disabled" || result.Label != impressionlabels.NoConditionMatched {
+			t.Error("Splits with an empty conditions array should return the default treatment. Got:", result.Treatment, result.Label)
+		}
+	}
+
+	results := evaluator.EvaluateFeatures("key1", nil, []string{"nil_conditions", "empty_conditions"}, nil)
+	if results.Evaluations["nil_conditions"].Treatment != "off" || results.Evaluations["empty_conditions"].Treatment != "disabled" {
+		t.Error("Splits without conditions should return the default treatment when evaluated together", results.Evaluations)
+	}
+}
+
+func TestSplitWithIncompletePartitions(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Algo:              2,
+			ChangeNumber:      123,
+			DefaultTreatment:  "off",
+			Name:              "no_partitions",
+			Status:            "ACTIVE",
+			TrafficAllocation: 100,
+			Conditions: []dtos.ConditionDTO{
+				{
+					ConditionType: "ROLLOUT",
+					Label:         "default rule",
+					MatcherGroup:  dtos.MatcherGroupDTO{Combiner: "AND", Matchers: []dtos.MatcherDTO{{MatcherType: "ALL_KEYS"}}},
+				},
+			},
+		},
+	}, 123)
+
+	evaluator := NewEvaluator(splitStorage, nil, engine.NewEngine(logger, engine.Options{}), logger)
+	result := evaluator.EvaluateFeature("key", nil, "no_partitions", nil)
+	if result.Treatment != "off" || result.Label != impressionlabels.PartitionsIncomplete || result.MatchedConditionIndex != engine.NoConditionIndex {
+		t.Error("Keys beyond the last partition should get the default treatment with a distinct label. Got:", result.Treatment, result.Label)
+	}
+}
+
+// shrinkingSegmentStorage removes the key from the segment right after its membership is first checked,
+// as a segment update arriving in the middle of a batch would
+type shrinkingSegmentStorage struct {
+	*mutexmap.MMSegmentStorage
+}
+
+func (s *shrinkingSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	isMember, err := s.MMSegmentStorage.SegmentContainsKey(segmentName, key)
+	s.Put(segmentName, set.NewSet(), 2)
+	return isMember, err
+}
+
+func TestBatchEvaluationWithPinnedSegments(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	inBeta := func(name string) dtos.SplitDTO {
+		return dtos.SplitDTO{
+			Name:             name,
+			DefaultTreatment: "off",
+			Status:           "ACTIVE",
+			Conditions: []dtos.ConditionDTO{{
+				ConditionType: "WHITELIST",
+				MatcherGroup: dtos.MatcherGroupDTO{
+					Combiner: "AND",
+					Matchers: []dtos.MatcherDTO{{
+						MatcherType:        "IN_SEGMENT",
+						UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "beta"},
+					}},
+				},
+				Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}},
+			}},
+		}
+	}
+	splitStorage.PutMany([]dtos.SplitDTO{inBeta("feature1"), inBeta("feature2")}, 1)
+
+	evaluate := func(pin bool) map[string]string {
+		segmentStorage := &shrinkingSegmentStorage{MMSegmentStorage: mutexmap.NewMMSegmentStorage()}
+		segmentStorage.Put("beta", set.NewSet("user1"), 1)
+		evaluator := NewEvaluator(splitStorage, segmentStorage, engine.NewEngine(logger, engine.Options{}), logger)
+		results := evaluator.WithPinnedSegments(pin).EvaluateFeatures("user1", nil, []string{"feature1", "feature2"}, nil)
+		return map[string]string{"feature1": results.Evaluations["feature1"].Treatment, "feature2": results.Evaluations["feature2"].Treatment}
+	}
+
+	if treatments := evaluate(false); treatments["feature1"] == treatments["feature2"] {
+		t.Error("Without pinning, the second feature should see the updated segment", treatments)
+	}
+
+	if treatments := evaluate(true); treatments["feature1"] != "on" || treatments["feature2"] != "on" {
+		t.Error("With pinning, both features should see the membership read first", treatments)
+	}
+}
+
+func TestKilledSplitIsNotParsed(t *testing.T) {
+	debug := &bytes.Buffer{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelDebug, DebugWriter: debug})
+	evaluator := NewEvaluator(&mockStorage{}, nil, nil, logger)
+
+	result := evaluator.EvaluateFeature("test", nil, "mysplittest4", nil)
+	if result.Treatment != "killed" || result.Label != impressionlabels.Killed || result.SplitChangeNumber != mysplittest4.ChangeNumber {
+		t.Error("The default treatment should be returned with the killed label. Got:", result)
+	}
+	if result.Config == nil || *result.Config != mysplittest4.Configurations["killed"] {
+		t.Error("The config of the default treatment should be returned. Got:", result.Config)
+	}
+	if strings.Contains(debug.String(), "Building") {
+		t.Error("The conditions of killed splits should not be parsed")
+	}
+}
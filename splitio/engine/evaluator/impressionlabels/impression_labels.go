@@ -20,3 +20,40 @@ const Exception = "exception"
 
 // ClientNotReady label will be returned when the client is not ready
 const ClientNotReady = "not ready"
+
+// ConditionsLimitExceeded label will be returned when the split has more conditions than the configured limit
+const ConditionsLimitExceeded = "conditions limit exceeded"
+
+// TypeMismatch label will be returned when an attribute doesn't have the type expected by a matcher
+// and strict attribute types are enabled
+const TypeMismatch = "type mismatch"
+
+// NotReadyFromSnapshot label will be returned when the client is not ready and the treatment was computed from a snapshot
+const NotReadyFromSnapshot = "not ready - from snapshot"
+
+// AttributeTooLarge label will be returned when conditions were treated as non-matching because an attribute
+// they use exceeds the configured size limits
+const AttributeTooLarge = "attribute too large"
+
+// PartitionsIncomplete label will be returned when the key matched a condition whose partitions add up to less
+// than 100 and fell beyond the last one, so the default treatment was returned
+const PartitionsIncomplete = "partitions incomplete"
+
+// RolloutDisabled label will be returned when the feature is set to be evaluated against its whitelists & segments only
+// and the key didn't match any of them
+const RolloutDisabled = "rollout disabled"
+
+// Overloaded label will be returned when the evaluation couldn't get a slot within the configured timeout because
+// too many evaluations were running concurrently
+const Overloaded = "overloaded"
+
+// SegmentOverride label will be appended to the label of evaluations that relied on segment memberships supplied
+// by the caller instead of the ones in storage
+const SegmentOverride = "segment override"
+
+// NoSplitsLoaded label will be returned instead of SplitNotFound when the SDK is ready but no split is stored at all,
+// which usually means it's connected to the wrong environment or redis prefix
+const NoSplitsLoaded = "no splits loaded"
+
+// Cancelled label will be returned when the context an evaluation was bound to was cancelled before it completed
+const Cancelled = "cancelled"
@@ -1,7 +1,23 @@
 package evaluator
 
+import "github.com/splitio/go-client/splitio/storage"
+
 // Interface should be implemented by concrete treatment evaluator structs
 type Interface interface {
 	EvaluateFeature(key string, bucketingKey *string, feature string, attributes map[string]interface{}) *Result
 	EvaluateFeatures(key string, bucketingKey *string, features []string, attributes map[string]interface{}) Results
 }
+
+// Rebindable should be implemented by evaluators able to read the same splits & segments through other storages,
+// ie: storages bound to a context or with segment memberships prefetched
+type Rebindable interface {
+	Interface
+	SplitStorage() storage.SplitStorageConsumer
+	SegmentStorage() storage.SegmentStorageConsumer
+	Rebind(splitStorage storage.SplitStorageConsumer, segmentStorage storage.SegmentStorageConsumer) Interface
+}
+
+// Wrapper should be implemented by evaluators adding behavior on top of another evaluator
+type Wrapper interface {
+	Unwrap() Interface
+}
@@ -14,6 +14,17 @@ type Condition struct {
 	partitions    []Partition
 	label         string
 	conditionType string
+	attributes    map[string]struct{}
+	numeric       []string
+	targetsKeys   bool
+}
+
+// numericMatcherTypes holds the matchers that can only be evaluated against integer attributes
+var numericMatcherTypes = map[string]struct{}{
+	matchers.MatcherTypeEqualTo:              {},
+	matchers.MatcherTypeGreaterThanOrEqualTo: {},
+	matchers.MatcherTypeLessThanOrEqualTo:    {},
+	matchers.MatcherTypeBetween:              {},
 }
 
 // NewCondition instantiates a new Condition struct with appropriate wrappers around dtos and returns it.
@@ -23,7 +34,20 @@ func NewCondition(cond *dtos.ConditionDTO, ctx *injection.Context, logger loggin
 		partitions = append(partitions, Partition{partitionData: part})
 	}
 	matcherObjs := make([]matchers.MatcherInterface, 0)
+	attributes := make(map[string]struct{})
+	var numeric []string
+	targetsKeys := false
 	for _, matcher := range cond.MatcherGroup.Matchers {
+		if matcher.KeySelector != nil && matcher.KeySelector.Attribute != nil {
+			attributes[*matcher.KeySelector.Attribute] = struct{}{}
+			if _, ok := numericMatcherTypes[matcher.MatcherType]; ok {
+				numeric = append(numeric, *matcher.KeySelector.Attribute)
+			}
+		}
+		switch matcher.MatcherType {
+		case matchers.MatcherTypeInSegment, matchers.MatcherTypeWhitelist:
+			targetsKeys = targetsKeys || !matcher.Negate
+		}
 		m, err := matchers.BuildMatcher(&matcher, ctx, logger)
 		if err == nil {
 			matcherObjs = append(matcherObjs, m)
@@ -36,6 +60,9 @@ func NewCondition(cond *dtos.ConditionDTO, ctx *injection.Context, logger loggin
 		partitions:    partitions,
 		label:         cond.Label,
 		conditionType: cond.ConditionType,
+		attributes:    attributes,
+		numeric:       numeric,
+		targetsKeys:   targetsKeys,
 	}
 }
 
@@ -61,6 +88,39 @@ func (c *Condition) Label() string {
 	return c.label
 }
 
+// UsesAnyAttribute returns true if any of the condition's matchers is evaluated against one of the given attributes
+func (c *Condition) UsesAnyAttribute(attributes []string) bool {
+	for _, attribute := range attributes {
+		if _, ok := c.attributes[attribute]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeTypeMismatch returns the name of the first attribute used by a numeric or datetime matcher of the condition
+// whose value is present but is not an integer. The second value is false when all attributes are valid
+func (c *Condition) AttributeTypeMismatch(attributes map[string]interface{}) (string, bool) {
+	for _, name := range c.numeric {
+		value, present := attributes[name]
+		if !present || value == nil {
+			continue
+		}
+
+		switch value.(type) {
+		case int, int64:
+		default:
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// TargetsKeys returns true if the condition has a non negated segment or whitelist matcher
+func (c *Condition) TargetsKeys() bool {
+	return c.targetsKeys
+}
+
 // Matches returns true if the condition matches for a specific key and/or set of attributes
 func (c *Condition) Matches(key string, bucketingKey *string, attributes map[string]interface{}) bool {
 	partial := make([]bool, len(c.matchers))
@@ -1,6 +1,7 @@
 package grammar
 
 import (
+	"github.com/splitio/go-client/splitio/engine/grammar/matchers"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-toolkit/logging"
 	"testing"
@@ -139,3 +140,34 @@ func TestAnotherWrapper(t *testing.T) {
 		t.Error("CalculateTreatment returned incorrect treatment")
 	}
 }
+
+func TestSetMatchersNegation(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	attrName := "setdata"
+
+	for _, matcherType := range []string{
+		matchers.MatcherTypeEqualToSet, matchers.MatcherTypePartOfSet, matchers.MatcherTypeContainsAllOfSet, matchers.MatcherTypeContainsAnyOfSet,
+	} {
+		condition := NewCondition(&dtos.ConditionDTO{
+			ConditionType: ConditionTypeWhitelist,
+			MatcherGroup: dtos.MatcherGroupDTO{
+				Combiner: "AND",
+				Matchers: []dtos.MatcherDTO{{
+					MatcherType: matcherType,
+					Negate:      true,
+					Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"one", "two"}},
+					KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+				}},
+			},
+			Partitions: []dtos.PartitionDTO{{Treatment: "on", Size: 100}},
+		}, nil, logger)
+
+		if condition.Matches("key", nil, map[string]interface{}{attrName: []string{"one", "two"}}) {
+			t.Errorf("Negated %s should not match an equal set", matcherType)
+		}
+
+		if !condition.Matches("key", nil, map[string]interface{}{attrName: []string{"three"}}) {
+			t.Errorf("Negated %s should match a disjoint set", matcherType)
+		}
+	}
+}
@@ -20,7 +20,7 @@ func (m *ContainsAllOfSetMatcher) Match(key string, attributes map[string]interf
 		return false
 	}
 
-	conv, ok := matchingKey.([]string)
+	matchingSet, ok := attributeSet(matchingKey)
 	if !ok {
 		m.logger.Error(
 			"AllOfSetMatcher: Attribute passed is not a slice of strings. ",
@@ -29,14 +29,8 @@ func (m *ContainsAllOfSetMatcher) Match(key string, attributes map[string]interf
 		return false
 	}
 
-	matchingSet := set.NewSet()
-	for _, x := range conv {
-		matchingSet.Add(x)
-	}
-
-	res := m.comparisonSet.IsSuperset(matchingSet)
-	return res
-
+	// Every item in the split's set must be present in the supplied one
+	return matchingSet.HasAll(m.comparisonSet.List()...)
 }
 
 // NewContainsAllOfSetMatcher returns a pointer to a new instance of ContainsAllOfSetMatcher
@@ -4,13 +4,13 @@ import (
 	"github.com/splitio/go-toolkit/datastructures/set"
 )
 
-// ContainsAnyOfSetMatcher matches if the set supplied to the getTreatment is a superset of the one in the split
+// ContainsAnyOfSetMatcher matches if the set supplied to the getTreatment shares at least one item with the one in the split
 type ContainsAnyOfSetMatcher struct {
 	Matcher
 	comparisonSet *set.ThreadUnsafeSet
 }
 
-// Match returns true if the set provided is a superset of the one in the split
+// Match returns true if the set provided and the one in the split intersect
 func (m *ContainsAnyOfSetMatcher) Match(key string, attributes map[string]interface{}, bucketingKey *string) bool {
 	matchingKey, err := m.matchingKey(key, attributes)
 	if err != nil {
@@ -18,20 +18,14 @@ func (m *ContainsAnyOfSetMatcher) Match(key string, attributes map[string]interf
 		return false
 	}
 
-	conv, ok := matchingKey.([]string)
+	matchingSet, ok := attributeSet(matchingKey)
 	if !ok {
 		m.logger.Error("AnyOfSetMatcher: Failed to parse the key as a []string")
 		return false
 	}
 
-	matchingSet := set.NewSet()
-	for _, x := range conv {
-		matchingSet.Add(x)
-	}
-
-	intersection := set.Intersection(matchingSet, m.comparisonSet)
-
-	return intersection.Size() > 0
+	// At least one item must be present in both sets
+	return set.Intersection(matchingSet, m.comparisonSet).Size() > 0
 }
 
 // NewContainsAnyOfSetMatcher returns a pointer to a new instance of ContainsAnyOfSetMatcher
@@ -0,0 +1,117 @@
+package matchers
+
+import (
+	"testing"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestAttributePresence(t *testing.T) {
+	if _, found := attributeValue(nil, "value"); found {
+		t.Error("Attribute should not be present in a nil map")
+	}
+
+	if _, found := attributeValue(map[string]interface{}{"other": "aaa"}, "value"); found {
+		t.Error("Absent key should not be present")
+	}
+
+	if _, found := attributeValue(map[string]interface{}{"value": nil}, "value"); found {
+		t.Error("Key holding a nil value should not be present")
+	}
+
+	if value, found := attributeValue(map[string]interface{}{"value": ""}, "value"); !found || value != "" {
+		t.Error("Empty string should be present")
+	}
+
+	if value, found := attributeValue(map[string]interface{}{"value": []string{}}, "value"); !found || len(value.([]string)) != 0 {
+		t.Error("Empty slice should be present")
+	}
+}
+
+func TestWhitelistMatcherEmptyVsAbsentAttribute(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{})
+	attrName := "value"
+	matcher, _ := BuildMatcher(&dtos.MatcherDTO{
+		MatcherType: "WHITELIST",
+		Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{""}},
+		KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+	}, nil, logger)
+
+	if !matcher.Match("asd", map[string]interface{}{"value": ""}, nil) {
+		t.Error("Empty string attribute should be compared against the whitelist")
+	}
+
+	if matcher.Match("asd", map[string]interface{}{}, nil) {
+		t.Error("Absent attribute should not match a whitelist containing an empty string")
+	}
+
+	if matcher.Match("asd", map[string]interface{}{"value": nil}, nil) {
+		t.Error("Nil attribute should not match a whitelist containing an empty string")
+	}
+}
+
+func TestSetMatchersEmptyVsAbsentAttribute(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{})
+	attrName := "value"
+	equalToSet, _ := BuildMatcher(&dtos.MatcherDTO{
+		MatcherType: "EQUAL_TO_SET",
+		Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{}},
+		KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+	}, nil, logger)
+
+	if !equalToSet.Match("asd", map[string]interface{}{"value": []string{}}, nil) {
+		t.Error("Empty slice attribute should be equal to an empty set")
+	}
+
+	if equalToSet.Match("asd", map[string]interface{}{}, nil) {
+		t.Error("Absent attribute should not be equal to an empty set")
+	}
+
+	anyOfSet, _ := BuildMatcher(&dtos.MatcherDTO{
+		MatcherType: "CONTAINS_ANY_OF_SET",
+		Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{"one"}},
+		KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+	}, nil, logger)
+
+	if anyOfSet.Match("asd", map[string]interface{}{"value": []string{}}, nil) {
+		t.Error("Empty slice attribute should not contain any of the set")
+	}
+
+	if anyOfSet.Match("asd", map[string]interface{}{"value": nil}, nil) {
+		t.Error("Nil attribute should not contain any of the set")
+	}
+}
+
+func TestStringMatchersEmptyVsAbsentAttribute(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{})
+	attrName := "value"
+	startsWith, _ := BuildMatcher(&dtos.MatcherDTO{
+		MatcherType: "STARTS_WITH",
+		Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: []string{""}},
+		KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+	}, nil, logger)
+
+	if !startsWith.Match("asd", map[string]interface{}{"value": ""}, nil) {
+		t.Error("Empty string attribute should be compared")
+	}
+
+	if startsWith.Match("asd", map[string]interface{}{}, nil) {
+		t.Error("Absent attribute should not match")
+	}
+
+	boolean := true
+	booleanMatcher, _ := BuildMatcher(&dtos.MatcherDTO{
+		MatcherType: "EQUAL_TO_BOOLEAN",
+		Boolean:     &boolean,
+		KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+	}, nil, logger)
+
+	if booleanMatcher.Match("asd", map[string]interface{}{"value": nil}, nil) {
+		t.Error("Nil attribute should be treated as absent")
+	}
+
+	if booleanMatcher.Match("asd", map[string]interface{}{"value": ""}, nil) {
+		t.Error("Empty string is present but is not a valid boolean")
+	}
+}
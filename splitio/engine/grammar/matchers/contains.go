@@ -8,6 +8,8 @@ import (
 type ContainsStringMatcher struct {
 	Matcher
 	substrings []string
+	// foldedSubstrings holds the case folded substrings when the matcher ignores case, nil otherwise
+	foldedSubstrings []string
 }
 
 // Match returns true if the key contains one of the substrings in the split
@@ -24,7 +26,13 @@ func (m *ContainsStringMatcher) Match(key string, attributes map[string]interfac
 		return false
 	}
 
-	for _, substring := range m.substrings {
+	substrings := m.substrings
+	if m.foldedSubstrings != nil {
+		asString = foldCase(asString)
+		substrings = m.foldedSubstrings
+	}
+
+	for _, substring := range substrings {
 		if strings.Contains(asString, substring) {
 			return true
 		}
@@ -33,6 +41,15 @@ func (m *ContainsStringMatcher) Match(key string, attributes map[string]interfac
 	return false
 }
 
+// foldSubstrings builds the case folded substrings used by Match to ignore case
+func (m *ContainsStringMatcher) foldSubstrings() {
+	folded := make([]string, 0, len(m.substrings))
+	for _, substring := range m.substrings {
+		folded = append(folded, foldCase(substring))
+	}
+	m.foldedSubstrings = folded
+}
+
 // NewContainsStringMatcher returns a new instance of ContainsStringMatcher
 func NewContainsStringMatcher(negate bool, substrings []string, attributeName *string) *ContainsStringMatcher {
 	return &ContainsStringMatcher{
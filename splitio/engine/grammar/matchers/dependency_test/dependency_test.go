@@ -121,7 +121,7 @@ func TestDependencyMatcher(t *testing.T) {
 		evaluator.NewEvaluator(
 			splitStorage,
 			segmentStorage,
-			engine.NewEngine(logger),
+			engine.NewEngine(logger, engine.Options{}),
 			logger,
 		),
 	)
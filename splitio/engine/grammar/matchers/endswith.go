@@ -8,6 +8,8 @@ import (
 type EndsWithMatcher struct {
 	Matcher
 	suffixes []string
+	// foldedSuffixes holds the case folded suffixes when the matcher ignores case, nil otherwise
+	foldedSuffixes []string
 }
 
 // Match returns true if the key provided ends with one of the suffixes in the split.
@@ -24,7 +26,13 @@ func (m *EndsWithMatcher) Match(key string, attributes map[string]interface{}, b
 		return false
 	}
 
-	for _, suffix := range m.suffixes {
+	suffixes := m.suffixes
+	if m.foldedSuffixes != nil {
+		asString = foldCase(asString)
+		suffixes = m.foldedSuffixes
+	}
+
+	for _, suffix := range suffixes {
 		if strings.HasSuffix(asString, suffix) {
 			return true
 		}
@@ -33,6 +41,15 @@ func (m *EndsWithMatcher) Match(key string, attributes map[string]interface{}, b
 	return false
 }
 
+// foldSuffixes builds the case folded suffixes used by Match to ignore case
+func (m *EndsWithMatcher) foldSuffixes() {
+	folded := make([]string, 0, len(m.suffixes))
+	for _, suffix := range m.suffixes {
+		folded = append(folded, foldCase(suffix))
+	}
+	m.foldedSuffixes = folded
+}
+
 // NewEndsWithMatcher returns a new instance of EndsWithMatcher
 func NewEndsWithMatcher(negate bool, suffixes []string, attributeName *string) *EndsWithMatcher {
 	return &EndsWithMatcher{
@@ -18,19 +18,13 @@ func (m *EqualToSetMatcher) Match(key string, attributes map[string]interface{},
 		return false
 	}
 
-	conv, ok := matchingKey.([]string)
+	matchingSet, ok := attributeSet(matchingKey)
 	if !ok {
 		m.logger.Error("EqualToSetMatcher: Cannot type assert to []string")
 		return false
 	}
 
-	matchingSet := set.NewSet()
-	for _, x := range conv {
-		matchingSet.Add(x)
-	}
-
 	return matchingSet.IsEqual(m.comparisonSet)
-
 }
 
 // NewEqualToSetMatcher returns a pointer to a new instance of EqualToSetMatcher
@@ -2,6 +2,7 @@ package matchers
 
 import (
 	"fmt"
+
 	"github.com/splitio/go-client/splitio/storage"
 )
 
@@ -21,7 +22,8 @@ func (m *InSegmentMatcher) Match(key string, attributes map[string]interface{},
 
 	isInSegment, err := segmentStorage.SegmentContainsKey(m.segmentName, key)
 	if err != nil {
-		m.logger.Error(fmt.Printf("InSegmentMatcher: Segment %s not found", m.segmentName))
+		m.logger.Error(fmt.Sprintf("InSegmentMatcher: Segment %s could not be read: %s", m.segmentName, err.Error()))
+		m.recordSegmentFailure()
 	}
 	return isInSegment
 }
@@ -93,3 +93,61 @@ func TestMatcherConstruction(t *testing.T) {
 		t.Error("Recovered string doesn't match stored one")
 	}
 }
+
+func TestCaseInsensitiveStringMatchers(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	attrName := "email"
+	build := func(matcherType string, values []string, insensitive bool) MatcherInterface {
+		ctx := injection.NewContext()
+		ctx.AddDependency("caseInsensitiveStrings", insensitive)
+		matcher, err := BuildMatcher(&dtos.MatcherDTO{
+			MatcherType: matcherType,
+			Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: values},
+			KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+		}, ctx, logger)
+		if err != nil {
+			t.Error(err)
+		}
+		return matcher
+	}
+
+	cases := []struct {
+		matcherType string
+		values      []string
+		value       string
+	}{
+		{MatcherTypeWhitelist, []string{"Bob@Corp.com"}, "bob@corp.COM"},
+		{MatcherTypeStartsWith, []string{"Admin"}, "aDMIN@corp.com"},
+		{MatcherTypeEndsWith, []string{"@Corp.com"}, "bob@corp.com"},
+		{MatcherTypeContainsString, []string{"CORP"}, "bob@corp.com"},
+		{MatcherTypeEndsWith, []string{"ΣΑΣ"}, "ελληνικάσας"},
+		{MatcherTypeStartsWith, []string{"k"}, "\u212Aelvin"}, // Kelvin sign
+		{MatcherTypeWhitelist, []string{"\u212Aelvin"}, "kelvin"},
+	}
+
+	for _, c := range cases {
+		attributes := map[string]interface{}{"email": c.value}
+		if build(c.matcherType, c.values, false).Match("key", attributes, nil) {
+			t.Error("Case sensitive matchers should not match", c.matcherType, c.values, c.value)
+		}
+		if !build(c.matcherType, c.values, true).Match("key", attributes, nil) {
+			t.Error("Case insensitive matchers should match", c.matcherType, c.values, c.value)
+		}
+	}
+
+	if build(MatcherTypeWhitelist, []string{"bob@corp.com"}, true).Match("key", map[string]interface{}{"email": "alice@corp.com"}, nil) {
+		t.Error("Case insensitive matchers should not match different strings")
+	}
+
+	startsWith := build(MatcherTypeStartsWith, []string{"Admin"}, true).(*StartsWithMatcher)
+	endsWith := build(MatcherTypeEndsWith, []string{"@Corp.com"}, true).(*EndsWithMatcher)
+	contains := build(MatcherTypeContainsString, []string{"CORP"}, true).(*ContainsStringMatcher)
+	if len(startsWith.foldedPrefixes) != 1 || startsWith.foldedPrefixes[0] != foldCase("Admin") ||
+		len(endsWith.foldedSuffixes) != 1 || endsWith.foldedSuffixes[0] != foldCase("@Corp.com") ||
+		len(contains.foldedSubstrings) != 1 || contains.foldedSubstrings[0] != foldCase("CORP") {
+		t.Error("Case insensitive matchers should fold the split values when built")
+	}
+	if build(MatcherTypeStartsWith, []string{"Admin"}, false).(*StartsWithMatcher).foldedPrefixes != nil {
+		t.Error("Case sensitive matchers shouldn't fold the split values")
+	}
+}
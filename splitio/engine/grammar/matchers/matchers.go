@@ -3,8 +3,12 @@ package matchers
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
 
 	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/injection"
 	"github.com/splitio/go-toolkit/logging"
 )
@@ -77,7 +81,7 @@ func (m *Matcher) matchingKey(key string, attributes map[string]interface{}) (in
 		return nil, errors.New("Attribute required but no attributes provided")
 	}
 
-	attrValue, found := attributes[*m.attributeName]
+	attrValue, found := attributeValue(attributes, *m.attributeName)
 	if !found {
 		return nil, fmt.Errorf(
 			"Attribute \"%s\" required but not present in provided attribute map",
@@ -88,6 +92,76 @@ func (m *Matcher) matchingKey(key string, attributes map[string]interface{}) (in
 	return attrValue, nil
 }
 
+// caseInsensitive returns whether string matchers should ignore case, as set by the "caseInsensitiveStrings" dependency
+func (m *Matcher) caseInsensitive() bool {
+	if m.Context == nil {
+		return false
+	}
+	insensitive, _ := m.Context.Dependency("caseInsensitiveStrings").(bool)
+	return insensitive
+}
+
+// recordSegmentFailure counts a failed segment read on the "segmentFailures" dependency, if any, so that evaluators
+// can tell results computed from a failed read apart
+func (m *Matcher) recordSegmentFailure() {
+	if m.Context == nil {
+		return
+	}
+	if failures, ok := m.Context.Dependency("segmentFailures").(*int64); ok {
+		atomic.AddInt64(failures, 1)
+	}
+}
+
+// foldCase maps every rune to a canonical representative of its Unicode simple case folding orbit, so that two strings
+// are equal under strings.EqualFold if and only if their folded versions are equal. Unlike strings.ToLower this also
+// handles runes such as the Kelvin sign or the long s, which don't round-trip through lower case
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < folded {
+				folded = f
+			}
+		}
+		return folded
+	}, s)
+}
+
+// attributeValue returns the value of an attribute and whether it's present or not.
+// An attribute is present when its key exists in the map and holds a non-nil value. Empty strings and
+// empty slices are considered present and will be compared by the matchers as any other value.
+func attributeValue(attributes map[string]interface{}, name string) (interface{}, bool) {
+	value, found := attributes[name]
+	if !found || value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// attributeSet builds a set from an attribute holding a []string or a []interface{} made only of strings.
+// It returns false if the attribute cannot be coerced into a set of strings
+func attributeSet(value interface{}) (*set.ThreadUnsafeSet, bool) {
+	switch items := value.(type) {
+	case []string:
+		result := set.NewSet()
+		for _, item := range items {
+			result.Add(item)
+		}
+		return result, true
+	case []interface{}:
+		result := set.NewSet()
+		for _, item := range items {
+			asString, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			result.Add(asString)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
 // matcher returns the matcher instance embbeded in structs
 func (m *Matcher) base() *Matcher {
 	return m
@@ -344,6 +418,19 @@ func BuildMatcher(dto *dtos.MatcherDTO, ctx *injection.Context, logger logging.L
 		ctx.Inject(matcher.base())
 	}
 
+	if matcher.base().caseInsensitive() {
+		switch typed := matcher.(type) {
+		case *WhitelistMatcher:
+			typed.foldWhitelist()
+		case *StartsWithMatcher:
+			typed.foldPrefixes()
+		case *EndsWithMatcher:
+			typed.foldSuffixes()
+		case *ContainsStringMatcher:
+			typed.foldSubstrings()
+		}
+	}
+
 	matcher.base().logger = logger
 
 	return matcher, nil
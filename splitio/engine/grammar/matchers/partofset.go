@@ -18,21 +18,18 @@ func (m *PartOfSetMatcher) Match(key string, attributes map[string]interface{},
 		return false
 	}
 
-	conv, ok := matchingKey.([]string)
+	matchingSet, ok := attributeSet(matchingKey)
 	if !ok {
 		m.logger.Error("Unable to type-assert key to []string")
 		return false
 	}
 
-	matchingSet := set.NewSet()
-	for _, x := range conv {
-		matchingSet.Add(x)
-	}
-
 	if matchingSet.IsEmpty() {
 		return false
 	}
-	return m.comparisonSet.IsSubset(matchingSet)
+
+	// Every item in the supplied set must be present in the split's one
+	return m.comparisonSet.HasAll(matchingSet.List()...)
 }
 
 // NewPartOfSetMatcher returns a pointer to a new instance of PartOfSetMatcher
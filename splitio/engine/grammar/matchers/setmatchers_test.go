@@ -0,0 +1,70 @@
+package matchers
+
+import (
+	"testing"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestSetMatchersSemantics(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	attrName := "setdata"
+	whitelist := []string{"one", "two", "three"}
+
+	inputs := map[string][]string{
+		"equal":       {"three", "two", "one"},
+		"subset":      {"one", "two"},
+		"superset":    {"one", "two", "three", "four"},
+		"overlapping": {"one", "four"},
+		"disjoint":    {"four", "five"},
+		"empty":       {},
+	}
+
+	expected := map[string]map[string]bool{
+		MatcherTypeEqualToSet: {
+			"equal": true, "subset": false, "superset": false, "overlapping": false, "disjoint": false, "empty": false,
+		},
+		MatcherTypePartOfSet: {
+			"equal": true, "subset": true, "superset": false, "overlapping": false, "disjoint": false, "empty": false,
+		},
+		MatcherTypeContainsAllOfSet: {
+			"equal": true, "subset": false, "superset": true, "overlapping": false, "disjoint": false, "empty": false,
+		},
+		MatcherTypeContainsAnyOfSet: {
+			"equal": true, "subset": true, "superset": true, "overlapping": true, "disjoint": false, "empty": false,
+		},
+	}
+
+	for matcherType, cases := range expected {
+		matcher, err := BuildMatcher(&dtos.MatcherDTO{
+			MatcherType: matcherType,
+			Whitelist:   &dtos.WhitelistMatcherDataDTO{Whitelist: whitelist},
+			KeySelector: &dtos.KeySelectorDTO{Attribute: &attrName},
+		}, nil, logger)
+		if err != nil {
+			t.Error("There should be no errors when building the matcher", matcherType, err)
+			continue
+		}
+
+		for input, shouldMatch := range cases {
+			asStrings := inputs[input]
+			asInterfaces := make([]interface{}, 0, len(asStrings))
+			for _, item := range asStrings {
+				asInterfaces = append(asInterfaces, item)
+			}
+
+			if matcher.Match("key", map[string]interface{}{attrName: asStrings}, nil) != shouldMatch {
+				t.Errorf("%s with %s []string input should return %t", matcherType, input, shouldMatch)
+			}
+
+			if matcher.Match("key", map[string]interface{}{attrName: asInterfaces}, nil) != shouldMatch {
+				t.Errorf("%s with %s []interface{} input should return %t", matcherType, input, shouldMatch)
+			}
+		}
+
+		if matcher.Match("key", map[string]interface{}{attrName: []interface{}{"one", 2}}, nil) {
+			t.Errorf("%s should not match a slice holding non-string items", matcherType)
+		}
+	}
+}
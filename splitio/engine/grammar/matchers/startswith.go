@@ -8,6 +8,8 @@ import (
 type StartsWithMatcher struct {
 	Matcher
 	prefixes []string
+	// foldedPrefixes holds the case folded prefixes when the matcher ignores case, nil otherwise
+	foldedPrefixes []string
 }
 
 // Match returns true if the key provided starts with one of the prefixes in the split.
@@ -24,7 +26,13 @@ func (m *StartsWithMatcher) Match(key string, attributes map[string]interface{},
 		return false
 	}
 
-	for _, prefix := range m.prefixes {
+	prefixes := m.prefixes
+	if m.foldedPrefixes != nil {
+		asString = foldCase(asString)
+		prefixes = m.foldedPrefixes
+	}
+
+	for _, prefix := range prefixes {
 		if strings.HasPrefix(asString, prefix) {
 			return true
 		}
@@ -33,6 +41,15 @@ func (m *StartsWithMatcher) Match(key string, attributes map[string]interface{},
 	return false
 }
 
+// foldPrefixes builds the case folded prefixes used by Match to ignore case
+func (m *StartsWithMatcher) foldPrefixes() {
+	folded := make([]string, 0, len(m.prefixes))
+	for _, prefix := range m.prefixes {
+		folded = append(folded, foldCase(prefix))
+	}
+	m.foldedPrefixes = folded
+}
+
 // NewStartsWithMatcher returns a new instance of StartsWithMatcher
 func NewStartsWithMatcher(negate bool, prefixes []string, attributeName *string) *StartsWithMatcher {
 	return &StartsWithMatcher{
@@ -8,6 +8,8 @@ import (
 type WhitelistMatcher struct {
 	Matcher
 	whitelist *set.ThreadUnsafeSet
+	// foldedWhitelist holds the case folded whitelist when the matcher ignores case, nil otherwise
+	foldedWhitelist *set.ThreadUnsafeSet
 }
 
 // Match returns true if the key is present in the whitelist.
@@ -24,9 +26,22 @@ func (m *WhitelistMatcher) Match(key string, attributes map[string]interface{},
 		return false
 	}
 
+	if m.foldedWhitelist != nil {
+		return m.foldedWhitelist.Has(foldCase(stringMatchingKey))
+	}
+
 	return m.whitelist.Has(stringMatchingKey)
 }
 
+// foldWhitelist builds the case folded whitelist used by Match to ignore case
+func (m *WhitelistMatcher) foldWhitelist() {
+	folded := set.NewSet()
+	for _, item := range m.whitelist.List() {
+		folded.Add(foldCase(item.(string)))
+	}
+	m.foldedWhitelist = folded
+}
+
 // NewWhitelistMatcher returns a new WhitelistMatcher
 func NewWhitelistMatcher(negate bool, whitelist []string, attributeName *string) *WhitelistMatcher {
 	wlSet := set.NewSet()
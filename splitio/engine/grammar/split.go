@@ -1,6 +1,8 @@
 package grammar
 
 import (
+	"fmt"
+
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-toolkit/injection"
 	"github.com/splitio/go-toolkit/logging"
@@ -88,7 +90,65 @@ func (s *Split) ChangeNumber() int64 {
 	return s.splitData.ChangeNumber
 }
 
+// TrackImpressions returns whether impressions should be stored for this split
+func (s *Split) TrackImpressions() bool {
+	return s.splitData.ImpressionsTracked()
+}
+
 // Configurations returns the configurations for this split
 func (s *Split) Configurations() map[string]string {
 	return s.splitData.Configurations
 }
+
+// OversizedAttributes returns the names of the attributes used by the split's matchers whose value is a string
+// longer than maxLength or a set with more than maxSetSize elements. Limits <= 0 are not enforced. The matchers
+// are only looked up for attributes exceeding a limit, so that evaluations within them remain cheap
+func (s *Split) OversizedAttributes(attributes map[string]interface{}, maxLength int, maxSetSize int) []string {
+	if len(attributes) == 0 || (maxLength <= 0 && maxSetSize <= 0) {
+		return nil
+	}
+
+	var oversized []string
+	for name, value := range attributes {
+		size, limit := 0, 0
+		switch typed := value.(type) {
+		case string:
+			size, limit = len(typed), maxLength
+		case []string:
+			size, limit = len(typed), maxSetSize
+		case []interface{}:
+			size, limit = len(typed), maxSetSize
+		}
+		if limit > 0 && size > limit && s.usesAttribute(name) {
+			oversized = append(oversized, name)
+		}
+	}
+	return oversized
+}
+
+// usesAttribute returns true if any condition of the split has a matcher evaluated against the attribute
+func (s *Split) usesAttribute(name string) bool {
+	for _, condition := range s.conditions {
+		if condition.UsesAnyAttribute([]string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSplit checks that the partitions of each condition of the split add up to 100.
+// Otherwise, keys falling beyond the last partition won't get a treatment from that condition
+func ValidateSplit(split *dtos.SplitDTO) error {
+	for index, condition := range split.Conditions {
+		total := 0
+		for _, partition := range condition.Partitions {
+			total += partition.Size
+		}
+		if total != 100 {
+			return fmt.Errorf(
+				"split %s: partitions of condition %d (%s) add up to %d instead of 100", split.Name, index, condition.Label, total,
+			)
+		}
+	}
+	return nil
+}
@@ -55,3 +55,24 @@ func TestSplitCreation(t *testing.T) {
 		t.Error("Traffic allocation should be 100")
 	}
 }
+
+func TestValidateSplit(t *testing.T) {
+	dto := dtos.SplitDTO{
+		Name: "split1",
+		Conditions: []dtos.ConditionDTO{
+			{Label: "first", Partitions: []dtos.PartitionDTO{{Size: 50, Treatment: "on"}, {Size: 50, Treatment: "off"}}},
+		},
+	}
+	if err := ValidateSplit(&dto); err != nil {
+		t.Error("Partitions adding up to 100 should be valid", err)
+	}
+
+	dto.Conditions = append(dto.Conditions, dtos.ConditionDTO{
+		Label:      "second",
+		Partitions: []dtos.PartitionDTO{{Size: 49, Treatment: "on"}, {Size: 50, Treatment: "off"}},
+	})
+	err := ValidateSplit(&dto)
+	if err == nil || err.Error() != "split split1: partitions of condition 1 (second) add up to 99 instead of 100" {
+		t.Error("Partitions adding up to 99 should be reported", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Package impressionenricher declares the hook used to add derived properties to impressions, such as the region
+// of an IP address or the class of a device, computed once per evaluation instead of by each caller.
+package impressionenricher
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// ImpressionEnricher computes the properties to add to the impressions of a key from the attributes it was
+// evaluated with. The returned map must not be modified afterwards
+type ImpressionEnricher interface {
+	Enrich(key string, attributes map[string]interface{}) map[string]interface{}
+}
+
+// WrapperImpressionEnricher runs an ImpressionEnricher recovering from its panics and giving up on it once its time
+// budget elapses, so that a faulty or slow enricher can't break or stall evaluations
+type WrapperImpressionEnricher struct {
+	ImpressionEnricher ImpressionEnricher
+	timeout            time.Duration
+	logger             logging.LoggerInterface
+}
+
+// NewImpressionEnricherWrapper instantiates a new WrapperImpressionEnricher
+func NewImpressionEnricherWrapper(
+	impressionEnricher ImpressionEnricher,
+	timeout time.Duration,
+	logger logging.LoggerInterface,
+) *WrapperImpressionEnricher {
+	return &WrapperImpressionEnricher{
+		ImpressionEnricher: impressionEnricher,
+		timeout:            timeout,
+		logger:             logger,
+	}
+}
+
+// Properties returns the properties computed by the enricher for the key, or nil if it panicked or didn't return
+// within the time budget. In the latter case the enricher is left running in the background and its result is discarded
+func (w *WrapperImpressionEnricher) Properties(key string, attributes map[string]interface{}) map[string]interface{} {
+	result := make(chan map[string]interface{}, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.Error("Impression enricher is panicking with the following error", r, "\n", string(debug.Stack()))
+				result <- nil
+			}
+		}()
+		result <- w.ImpressionEnricher.Enrich(key, attributes)
+	}()
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case properties := <-result:
+		return properties
+	case <-timer.C:
+		w.logger.Warning(fmt.Sprintf(
+			"Impression enricher didn't return within %s, storing impressions of key %s without its properties", w.timeout, key,
+		))
+		return nil
+	}
+}
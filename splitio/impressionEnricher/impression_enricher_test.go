@@ -0,0 +1,41 @@
+package impressionenricher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type enricherFunc func(key string, attributes map[string]interface{}) map[string]interface{}
+
+func (f enricherFunc) Enrich(key string, attributes map[string]interface{}) map[string]interface{} {
+	return f(key, attributes)
+}
+
+func TestImpressionEnricherWrapper(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+
+	device := NewImpressionEnricherWrapper(enricherFunc(func(key string, attributes map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"device": attributes["userAgent"]}
+	}), time.Second, logger)
+	if properties := device.Properties("user1", map[string]interface{}{"userAgent": "mobile"}); properties["device"] != "mobile" {
+		t.Error("The enricher properties should be returned", properties)
+	}
+
+	panicking := NewImpressionEnricherWrapper(enricherFunc(func(key string, attributes map[string]interface{}) map[string]interface{} {
+		panic("something went wrong")
+	}), time.Second, logger)
+	if properties := panicking.Properties("user1", nil); properties != nil {
+		t.Error("No properties should be returned if the enricher panics", properties)
+	}
+
+	slow := NewImpressionEnricherWrapper(enricherFunc(func(key string, attributes map[string]interface{}) map[string]interface{} {
+		time.Sleep(time.Second)
+		return map[string]interface{}{"device": "desktop"}
+	}), 10*time.Millisecond, logger)
+	before := time.Now()
+	if properties := slow.Properties("user1", nil); properties != nil || time.Since(before) > 500*time.Millisecond {
+		t.Error("The enricher should be given up on once its time budget elapses", properties)
+	}
+}
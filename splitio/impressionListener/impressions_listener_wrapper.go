@@ -1,22 +1,42 @@
 package impressionlistener
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/splitio/go-client/splitio"
 	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/logging"
 )
 
+// DroppedCounter is the counter incremented each time an impression can't be queued for the listener
+const DroppedCounter = "sdk.impressionListener.dropped"
+
+// droppedWarningInterval is the minimum time between warnings about dropped impressions
+const droppedWarningInterval = time.Minute
+
 // ILObject struct to map entire data for listener
 type ILObject struct {
 	Impression         storage.Impression
 	Attributes         map[string]interface{}
 	InstanceID         string
+	InstanceIP         string
 	SDKLanguageVersion string
 }
 
 // WrapperImpressionListener struct
 type WrapperImpressionListener struct {
+	dropped            int64 // accessed atomically, kept first for 64-bit alignment
+	lastWarning        int64
 	ImpressionListener ImpressionListener
 	metadata           *splitio.SdkMetadata
+	queue              chan ILObject
+	metrics            storage.MetricsStorageProducer
+	logger             logging.LoggerInterface
+	stopped            bool
+	mutex              *sync.RWMutex
 }
 
 // NewImpressionListenerWrapper instantiates a new ImpressionListenerWrapper
@@ -24,19 +44,120 @@ func NewImpressionListenerWrapper(impressionListener ImpressionListener, metadat
 	return &WrapperImpressionListener{
 		ImpressionListener: impressionListener,
 		metadata:           metadata,
+		mutex:              &sync.RWMutex{},
+	}
+}
+
+// NewAsyncImpressionListenerWrapper instantiates a new ImpressionListenerWrapper that dispatches impressions
+// to the listener from a background goroutine through a queue of queueSize elements. When the queue is full,
+// impressions are dropped for the listener and the DroppedCounter is incremented
+func NewAsyncImpressionListenerWrapper(
+	impressionListener ImpressionListener,
+	metadata *splitio.SdkMetadata,
+	queueSize int,
+	metrics storage.MetricsStorageProducer,
+	logger logging.LoggerInterface,
+) *WrapperImpressionListener {
+	return NewPooledImpressionListenerWrapper(impressionListener, metadata, queueSize, 1, metrics, logger)
+}
+
+// NewPooledImpressionListenerWrapper instantiates a new ImpressionListenerWrapper like NewAsyncImpressionListenerWrapper
+// does, but dispatching impressions from a pool of workers goroutines, at least one, so that a slow listener can
+// handle several impressions at once
+func NewPooledImpressionListenerWrapper(
+	impressionListener ImpressionListener,
+	metadata *splitio.SdkMetadata,
+	queueSize int,
+	workers int,
+	metrics storage.MetricsStorageProducer,
+	logger logging.LoggerInterface,
+) *WrapperImpressionListener {
+	wrapper := &WrapperImpressionListener{
+		ImpressionListener: impressionListener,
+		metadata:           metadata,
+		queue:              make(chan ILObject, queueSize),
+		metrics:            metrics,
+		logger:             logger,
+		mutex:              &sync.RWMutex{},
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go wrapper.dispatch()
+	}
+	return wrapper
+}
+
+// dispatch forwards queued impressions to the listener until the queue is closed
+func (i *WrapperImpressionListener) dispatch() {
+	for data := range i.queue {
+		i.ImpressionListener.LogImpression(data)
 	}
 }
 
 // SendDataToClient sends the data to client
 func (i *WrapperImpressionListener) SendDataToClient(impressions []storage.Impression, attributes map[string]interface{}) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.stopped {
+		return
+	}
+
 	for _, impression := range impressions {
 		datToSend := ILObject{
 			Impression:         impression,
 			Attributes:         attributes,
 			InstanceID:         i.metadata.MachineName,
+			InstanceIP:         i.metadata.MachineIP,
 			SDKLanguageVersion: i.metadata.SDKVersion,
 		}
 
-		i.ImpressionListener.LogImpression(datToSend)
+		if i.queue == nil {
+			i.ImpressionListener.LogImpression(datToSend)
+			continue
+		}
+
+		select {
+		case i.queue <- datToSend:
+		default:
+			i.drop(impression)
+		}
+	}
+}
+
+// drop accounts for an impression that didn't fit in the listener queue. The warning is throttled so that
+// a stalled listener doesn't flood the logs on every evaluation
+func (i *WrapperImpressionListener) drop(impression storage.Impression) {
+	if i.metrics != nil {
+		i.metrics.IncCounter(DroppedCounter)
+	}
+	atomic.AddInt64(&i.dropped, 1)
+	if i.logger == nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&i.lastWarning)
+	if now-last < int64(droppedWarningInterval) || !atomic.CompareAndSwapInt64(&i.lastWarning, last, now) {
+		return
+	}
+	i.logger.Warning(fmt.Sprintf(
+		"Impression listener queue is full, %d impressions dropped since the last warning, the latest for feature %s",
+		atomic.SwapInt64(&i.dropped, 0),
+		impression.FeatureName,
+	))
+}
+
+// Stop stops accepting impressions for the listener. Impressions already queued are still dispatched
+func (i *WrapperImpressionListener) Stop() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.stopped {
+		return
+	}
+	i.stopped = true
+	if i.queue != nil {
+		close(i.queue)
 	}
 }
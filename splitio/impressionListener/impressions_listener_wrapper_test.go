@@ -0,0 +1,104 @@
+package impressionlistener
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/splitio/go-client/splitio"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type blockingListener struct {
+	release  chan struct{}
+	inFlight int64
+	received chan ILObject
+}
+
+func (l *blockingListener) LogImpression(data ILObject) {
+	atomic.AddInt64(&l.inFlight, 1)
+	<-l.release
+	l.received <- data
+}
+
+func TestPooledImpressionListenerWrapper(t *testing.T) {
+	listener := &blockingListener{release: make(chan struct{}), received: make(chan ILObject, 10)}
+	wrapper := NewPooledImpressionListenerWrapper(
+		listener,
+		&splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123", MachineIP: "10.0.0.1"},
+		10,
+		3,
+		nil,
+		nil,
+	)
+
+	wrapper.SendDataToClient([]storage.Impression{
+		{FeatureName: "feature1"},
+		{FeatureName: "feature2"},
+		{FeatureName: "feature3"},
+		{FeatureName: "feature4"},
+	}, map[string]interface{}{"one": 1})
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&listener.inFlight) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if inFlight := atomic.LoadInt64(&listener.inFlight); inFlight != 3 {
+		t.Error("Each worker should be handling an impression. In flight:", inFlight)
+	}
+
+	close(listener.release)
+	wrapper.Stop()
+	for i := 0; i < 4; i++ {
+		select {
+		case data := <-listener.received:
+			if data.InstanceID != "instance123" || data.InstanceIP != "10.0.0.1" || data.SDKLanguageVersion != "go-test" || data.Attributes["one"] != 1 {
+				t.Error("The impression should carry the SDK metadata and the attributes. Got:", data)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Every queued impression should be dispatched")
+		}
+	}
+}
+
+func TestImpressionListenerWrapperThrottlesDropWarnings(t *testing.T) {
+	listener := &blockingListener{release: make(chan struct{}), received: make(chan ILObject, 10)}
+	defer close(listener.release)
+	warnings := &bytes.Buffer{}
+	metrics := mutexmap.NewMMMetricsStorage()
+	wrapper := NewAsyncImpressionListenerWrapper(
+		listener,
+		&splitio.SdkMetadata{SDKVersion: "go-test"},
+		1,
+		metrics,
+		logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: warnings}),
+	)
+
+	// The first impression is taken by the worker, which blocks on the listener, and the next one fills the queue
+	wrapper.SendDataToClient([]storage.Impression{{FeatureName: "feature1"}}, nil)
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&listener.inFlight) < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	wrapper.SendDataToClient([]storage.Impression{{FeatureName: "feature2"}}, nil)
+	for i := 0; i < 5; i++ {
+		wrapper.SendDataToClient([]storage.Impression{{FeatureName: "dropped"}}, nil)
+	}
+
+	if dropped := metrics.PopCounters(); len(dropped) != 1 || dropped[0].Count != 5 {
+		t.Error("Every dropped impression should be counted", dropped)
+	}
+	if lines := strings.Count(warnings.String(), "\n"); lines != 1 {
+		t.Error("A single warning should be logged per interval. Got:", warnings.String())
+	}
+
+	atomic.StoreInt64(&wrapper.lastWarning, 0)
+	wrapper.SendDataToClient([]storage.Impression{{FeatureName: "dropped"}}, nil)
+	if !strings.Contains(warnings.String(), "5 impressions dropped since the last warning") {
+		t.Error("The warning should report the impressions dropped since the previous one. Got:", warnings.String())
+	}
+}
@@ -0,0 +1,80 @@
+package impressionlistener
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/splitio/go-toolkit/logging"
+)
+
+const (
+	// WriterFormatJSON writes each impression as a JSON document in a single line
+	WriterFormatJSON = "json"
+	// WriterFormatLine writes each impression as a compact line of space separated name=value pairs, with the values
+	// quoted as Go strings so that spaces, '=' or newlines in them can't break the line
+	WriterFormatLine = "line"
+)
+
+// WriterImpressionListener is an ImpressionListener that writes every impression to an io.Writer.
+// It's intended to be used for local debugging, ie: by passing os.Stdout as the writer.
+type WriterImpressionListener struct {
+	writer io.Writer
+	format string
+	logger logging.LoggerInterface
+	mutex  *sync.Mutex
+}
+
+// NewWriterImpressionListener instantiates a new WriterImpressionListener. If an unknown format
+// is supplied, WriterFormatLine will be used. Impressions that can't be serialized or written are reported
+// through the logger, if any
+func NewWriterImpressionListener(w io.Writer, format string, logger logging.LoggerInterface) *WriterImpressionListener {
+	if format != WriterFormatJSON {
+		format = WriterFormatLine
+	}
+	return &WriterImpressionListener{
+		writer: w,
+		format: format,
+		logger: logger,
+		mutex:  &sync.Mutex{},
+	}
+}
+
+// LogImpression serializes the impression and writes it to the underlying writer
+func (l *WriterImpressionListener) LogImpression(data ILObject) {
+	var line string
+	switch l.format {
+	case WriterFormatJSON:
+		raw, err := json.Marshal(data)
+		if err != nil {
+			l.logError(fmt.Sprintf("Impression for feature %s couldn't be serialized: %s", data.Impression.FeatureName, err.Error()))
+			return
+		}
+		line = string(raw)
+	default:
+		line = fmt.Sprintf(
+			"feature=%q key=%q bucketingKey=%q treatment=%q label=%q changeNumber=%d time=%d",
+			data.Impression.FeatureName,
+			data.Impression.KeyName,
+			data.Impression.BucketingKey,
+			data.Impression.Treatment,
+			data.Impression.Label,
+			data.Impression.ChangeNumber,
+			data.Impression.Time,
+		)
+	}
+
+	// Writes are serialized so that concurrent evaluations don't interleave their output
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, err := fmt.Fprintln(l.writer, line); err != nil {
+		l.logError(fmt.Sprintf("Impression for feature %s couldn't be written: %s", data.Impression.FeatureName, err.Error()))
+	}
+}
+
+func (l *WriterImpressionListener) logError(message string) {
+	if l.logger != nil {
+		l.logger.Error(message)
+	}
+}
@@ -0,0 +1,133 @@
+package impressionlistener
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/splitio/go-client/splitio"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type failingWriter struct{}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("some write error")
+}
+
+func TestWriterImpressionListenerLineFormat(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	listener := NewImpressionListenerWrapper(
+		NewWriterImpressionListener(buffer, WriterFormatLine, nil),
+		&splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "some_machine"},
+	)
+
+	listener.SendDataToClient([]storage.Impression{
+		{FeatureName: "feature1", KeyName: "key1", Treatment: "on", Label: "some label", ChangeNumber: 123, Time: 456},
+		{FeatureName: "feature2", KeyName: "key2", Treatment: "off", Label: "other label", ChangeNumber: 789, Time: 456},
+	}, nil)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Error("There should be one line per impression")
+	}
+
+	if !strings.Contains(lines[0], `feature="feature1"`) || !strings.Contains(lines[0], `treatment="on"`) {
+		t.Error("Unexpected line written for first impression", lines[0])
+	}
+
+	if !strings.Contains(lines[1], `feature="feature2"`) || !strings.Contains(lines[1], "changeNumber=789") {
+		t.Error("Unexpected line written for second impression", lines[1])
+	}
+}
+
+func TestWriterImpressionListenerLineFormatQuotesValues(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	listener := NewWriterImpressionListener(buffer, WriterFormatLine, nil)
+
+	listener.LogImpression(ILObject{Impression: storage.Impression{
+		FeatureName: "feature1",
+		KeyName:     "some key=value\nfeature=fake",
+		Treatment:   "on",
+		Label:       "some label",
+	}})
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatal("A key with a newline shouldn't break the impression line", lines)
+	}
+	if !strings.Contains(lines[0], `key="some key=value\nfeature=fake"`) || !strings.Contains(lines[0], `label="some label"`) {
+		t.Error("Values should be quoted", lines[0])
+	}
+	if strings.Count(lines[0], "feature=") != 2 || !strings.HasPrefix(lines[0], `feature="feature1" `) {
+		t.Error("The key shouldn't be confused with other fields", lines[0])
+	}
+}
+
+func TestWriterImpressionListenerJSONFormat(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	listener := NewWriterImpressionListener(buffer, WriterFormatJSON, nil)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listener.LogImpression(ILObject{
+				Impression:         storage.Impression{FeatureName: "feature1", KeyName: "key1", Treatment: "on"},
+				Attributes:         map[string]interface{}{"one": "test"},
+				InstanceID:         "some_machine",
+				SDKLanguageVersion: "go-test",
+			})
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 50 {
+		t.Error("There should be one line per impression")
+	}
+
+	for _, line := range lines {
+		var parsed ILObject
+		err := json.Unmarshal([]byte(line), &parsed)
+		if err != nil {
+			t.Error("Each line should be a valid JSON document", err)
+			continue
+		}
+
+		if parsed.Impression.FeatureName != "feature1" || parsed.Attributes["one"] != "test" || parsed.InstanceID != "some_machine" {
+			t.Error("Unexpected impression written", line)
+		}
+	}
+}
+
+func TestWriterImpressionListenerLogsErrors(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logs := &bytes.Buffer{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelError, ErrorWriter: logs})
+
+	listener := NewWriterImpressionListener(buffer, WriterFormatJSON, logger)
+	listener.LogImpression(ILObject{
+		Impression: storage.Impression{FeatureName: "feature1", KeyName: "key1", Treatment: "on"},
+		Attributes: map[string]interface{}{"one": func() {}},
+	})
+
+	if buffer.Len() != 0 {
+		t.Error("Nothing should be written for an impression that can't be serialized")
+	}
+	if !strings.Contains(logs.String(), "feature1 couldn't be serialized") {
+		t.Error("Serialization errors should be logged", logs.String())
+	}
+
+	logs.Reset()
+	listener = NewWriterImpressionListener(&failingWriter{}, WriterFormatLine, logger)
+	listener.LogImpression(ILObject{Impression: storage.Impression{FeatureName: "feature2", KeyName: "key1", Treatment: "on"}})
+	if !strings.Contains(logs.String(), "feature2 couldn't be written: some write error") {
+		t.Error("Write errors should be logged", logs.String())
+	}
+}
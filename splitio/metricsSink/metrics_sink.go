@@ -0,0 +1,26 @@
+// Package metricssink declares the interface used to feed the SDK's evaluation metrics to an external
+// monitoring system, such as Prometheus, without adding it as a dependency of the SDK.
+//
+// A Prometheus adapter would typically wrap a HistogramVec for latencies and CounterVecs for treatments and
+// exceptions, and register them in the user's registry:
+//
+//	type prometheusSink struct {
+//		latencies  *prometheus.HistogramVec // labels: operation
+//		treatments *prometheus.CounterVec   // labels: feature, treatment
+//		exceptions *prometheus.CounterVec   // labels: operation
+//	}
+//
+//	func (p *prometheusSink) ObserveLatency(operation string, latency time.Duration) {
+//		p.latencies.WithLabelValues(operation).Observe(latency.Seconds())
+//	}
+package metricssink
+
+import "time"
+
+// MetricsSink receives the metrics recorded by the SDK each time a treatment is evaluated or an event is tracked.
+// Implementations must be safe for concurrent use and should not block, since they're called inline
+type MetricsSink interface {
+	ObserveLatency(operation string, latency time.Duration)
+	IncTreatment(feature string, treatment string)
+	IncException(operation string)
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/splitio/go-client/splitio/conf"
@@ -33,6 +35,87 @@ func getUrls(cfg *conf.AdvancedConfig) (sdkURL string, eventsURL string) {
 	return sdkURL, eventsURL
 }
 
+// limitedTransport is a http.RoundTripper that allows at most a fixed number of requests in flight at once.
+// Requests beyond the limit wait for a slot until their context is done
+type limitedTransport struct {
+	base  http.RoundTripper
+	slots chan struct{}
+}
+
+// NewLimitedTransport returns a http.RoundTripper that sends requests through base, or the default transport if nil,
+// allowing at most maxInFlight of them in flight at once. Share it among clients to limit their requests as a whole
+func NewLimitedTransport(base http.RoundTripper, maxInFlight int) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limitedTransport{base: base, slots: make(chan struct{}, maxInFlight)}
+}
+
+// RoundTrip waits for a slot and sends the request. The slot is released once the response body is closed
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-t.slots
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { <-t.slots }}
+	return resp, nil
+}
+
+// releasingBody releases the slot of a request once its response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// APIKeyTransport is a http.RoundTripper that authenticates every request with the current apikey, which can be
+// replaced at any time, ie: to rotate it without recreating the fetchers & recorders sending requests through it
+type APIKeyTransport struct {
+	base   http.RoundTripper
+	apikey atomic.Value
+}
+
+// NewAPIKeyTransport returns a http.RoundTripper that sends requests through base, or the default transport if nil,
+// authenticated with apikey until it's replaced
+func NewAPIKeyTransport(base http.RoundTripper, apikey string) *APIKeyTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport := &APIKeyTransport{base: base}
+	transport.apikey.Store(apikey)
+	return transport
+}
+
+// Apikey returns the apikey requests are currently authenticated with
+func (t *APIKeyTransport) Apikey() string {
+	return t.apikey.Load().(string)
+}
+
+// SetApikey replaces the apikey requests are authenticated with, starting with the next request sent
+func (t *APIKeyTransport) SetApikey(apikey string) {
+	t.apikey.Store(apikey)
+}
+
+// RoundTrip sends a copy of the request authenticated with the current apikey
+func (t *APIKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("Authorization", "Bearer "+t.Apikey())
+	return t.base.RoundTrip(authenticated)
+}
+
 // HTTPClient structure to wrap up the net/http.Client
 type HTTPClient struct {
 	url        string
@@ -57,7 +140,7 @@ func NewHTTPClient(
 	} else {
 		timeout = defaultHTTPTimeout
 	}
-	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second, Transport: cfg.Advanced.HTTPTransport}
 	return &HTTPClient{
 		url:        endpoint,
 		httpClient: client,
@@ -67,6 +150,23 @@ func NewHTTPClient(
 	}
 }
 
+// HTTPError represents a response with a non successful status code from Split servers
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+// Error returns the message of the error
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// IsPermanent returns true if retrying the request won't make it succeed, as it happens
+// with client errors such as an invalid apikey. Timeouts and throttling are retryable
+func (e *HTTPError) IsPermanent() bool {
+	return e.Code >= 400 && e.Code < 500 && e.Code != http.StatusRequestTimeout && e.Code != http.StatusTooManyRequests
+}
+
 // Get method is a get call to an url
 func (c *HTTPClient) Get(service string) ([]byte, error) {
 
@@ -112,7 +212,10 @@ func (c *HTTPClient) Get(service string) ([]byte, error) {
 		return body, nil
 	}
 
-	return nil, fmt.Errorf("GET method: Status Code: %d - %s", resp.StatusCode, resp.Status)
+	return nil, &HTTPError{
+		Code:    resp.StatusCode,
+		Message: fmt.Sprintf("GET method: Status Code: %d - %s", resp.StatusCode, resp.Status),
+	}
 }
 
 // Post performs a HTTP POST request
@@ -159,19 +262,27 @@ func (c *HTTPClient) Post(service string, body []byte, headers map[string]string
 		return nil
 	}
 
-	return fmt.Errorf("POST method: Status Code: %d - %s", resp.StatusCode, resp.Status)
+	return &HTTPError{
+		Code:    resp.StatusCode,
+		Message: fmt.Sprintf("POST method: Status Code: %d - %s", resp.StatusCode, resp.Status),
+	}
 }
 
-// ValidateApikey validates apikey
-func ValidateApikey(apikey string, config conf.AdvancedConfig) error {
+// testApikey sends a test request to Split servers authenticated with apikey
+func testApikey(apikey string, config conf.AdvancedConfig) (*http.Response, error) {
 	sdkURL, _ := getUrls(&config)
-	client := &http.Client{}
+	client := &http.Client{Transport: config.HTTPTransport}
 
 	req, _ := http.NewRequest("GET", sdkURL+"/segmentChanges/___TEST___?since=-1", nil)
 	req.Header.Add("Accept-Encoding", "gzip")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+apikey)
-	resp, err := client.Do(req)
+	return client.Do(req)
+}
+
+// ValidateApikey validates apikey
+func ValidateApikey(apikey string, config conf.AdvancedConfig) error {
+	resp, err := testApikey(apikey, config)
 	if err != nil {
 		return err
 	}
@@ -183,3 +294,25 @@ func ValidateApikey(apikey string, config conf.AdvancedConfig) error {
 
 	return nil
 }
+
+// VerifyApikey checks that Split servers accept apikey. Unlike ValidateApikey, which only rejects browser apikeys,
+// any unsuccessful response is returned as an error, ie: an unknown or revoked apikey
+func VerifyApikey(apikey string, config conf.AdvancedConfig) error {
+	resp, err := testApikey(apikey, config)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 403 {
+		return errors.New("you passed a browser type apikey, please grab an apikey from the Split console that is of type sdk")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return &HTTPError{
+			Code:    resp.StatusCode,
+			Message: fmt.Sprintf("apikey rejected: Status Code: %d - %s", resp.StatusCode, resp.Status),
+		}
+	}
+
+	return nil
+}
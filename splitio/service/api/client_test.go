@@ -4,9 +4,14 @@ package api
 import (
 	"compress/gzip"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/splitio/go-client/splitio"
 	"github.com/splitio/go-client/splitio/conf"
@@ -66,3 +71,66 @@ func TestPost(t *testing.T) {
 		t.Error(errp)
 	}
 }
+
+type concurrencyTrackingTransport struct {
+	inFlight    int64
+	maxInFlight int64
+}
+
+func (t *concurrencyTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	current := atomic.AddInt64(&t.inFlight, 1)
+	defer atomic.AddInt64(&t.inFlight, -1)
+	for {
+		max := atomic.LoadInt64(&t.maxInFlight)
+		if current <= max || atomic.CompareAndSwapInt64(&t.maxInFlight, max, current) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestLimitedTransport(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	tracking := &concurrencyTrackingTransport{}
+	cfg := &conf.SplitSdkConfig{}
+	cfg.Advanced.HTTPTransport = NewLimitedTransport(tracking, 3)
+
+	// Clients created from the same config share the limit
+	fetcher := NewHTTPClient("", cfg, "http://sdk", splitio.Version, logger)
+	recorder := NewHTTPClient("", cfg, "http://events", splitio.Version, logger)
+
+	var wg sync.WaitGroup
+	var failures int64
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := fetcher.Get("/splitChanges"); err != nil {
+				atomic.AddInt64(&failures, 1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := recorder.Post("/events/bulk", []byte("[]"), nil); err != nil {
+				atomic.AddInt64(&failures, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failures != 0 {
+		t.Error("Requests beyond the limit should wait instead of failing", failures)
+	}
+	if tracking.maxInFlight > 3 {
+		t.Error("There should never be more than 3 requests in flight", tracking.maxInFlight)
+	}
+	if tracking.inFlight != 0 || len(cfg.Advanced.HTTPTransport.(*limitedTransport).slots) != 0 {
+		t.Error("Every slot should be released once responses are read")
+	}
+}
@@ -84,6 +84,15 @@ func TestSpitChangesFetchHTTPError(t *testing.T) {
 	if err == nil {
 		t.Error("Error expected but not found")
 	}
+
+	httpErr, ok := err.(*HTTPError)
+	if !ok || httpErr.Code != http.StatusInternalServerError {
+		t.Error("An HTTPError with the response status code should be returned", err)
+	}
+
+	if ok && httpErr.IsPermanent() {
+		t.Error("Server errors should be retryable")
+	}
 }
 
 func TestSegmentChangesFetch(t *testing.T) {
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 
 	"github.com/splitio/go-client/splitio/storage"
 
@@ -15,6 +18,7 @@ type httpRecorderBase struct {
 	client   *HTTPClient
 	logger   logging.LoggerInterface
 	metadata *splitio.SdkMetadata
+	compress bool
 }
 
 func (h *httpRecorderBase) recordRaw(url string, data []byte) error {
@@ -26,9 +30,29 @@ func (h *httpRecorderBase) recordRaw(url string, data []byte) error {
 	if h.metadata.MachineIP != "NA" && h.metadata.MachineIP != "unknown" {
 		headers["SplitSDKMachineIP"] = h.metadata.MachineIP
 	}
+	if h.compress {
+		compressed, err := gzipBody(data)
+		if err != nil {
+			return err
+		}
+		headers["Content-Encoding"] = "gzip"
+		data = compressed
+	}
 	return h.client.Post(url, data, headers)
 }
 
+func gzipBody(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
 // HTTPImpressionRecorder is a struct responsible for submitting impression bulks to the backend
 type HTTPImpressionRecorder struct {
 	httpRecorderBase
@@ -41,6 +65,8 @@ type impressionRecord struct {
 	ChangeNumber int64  `json:"changeNumber"`
 	Label        string `json:"label"`
 	BucketingKey string `json:"bucketingKey,omitempty"`
+	PreviousTime int64  `json:"pt,omitempty"`
+	Properties   string `json:"properties,omitempty"`
 }
 
 type impressionsRecord struct {
@@ -59,6 +85,8 @@ func (i *HTTPImpressionRecorder) Record(impressions []storage.Impression) error
 			ChangeNumber: impression.ChangeNumber,
 			Label:        impression.Label,
 			BucketingKey: impression.BucketingKey,
+			PreviousTime: impression.PreviousTime,
+			Properties:   impression.Properties,
 		}
 		v, ok := impressionsToPost[impression.FeatureName]
 		if ok {
@@ -166,7 +194,28 @@ func (m *HTTPMetricsRecorder) RecordGauge(gauge dtos.GaugeDTO) error {
 	return nil
 }
 
-// NewHTTPMetricsRecorder instantiates an HTTPMetricsRecorder
+// RecordMetricsBatch submits latencies, counters & gauges in a single pass. Each type of metric is posted to
+// its own endpoint, and types without metrics are skipped
+func (m *HTTPMetricsRecorder) RecordMetricsBatch(batch dtos.MetricsBatchDTO) error {
+	failed := false
+	if len(batch.Latencies) > 0 && m.RecordLatencies(batch.Latencies) != nil {
+		failed = true
+	}
+	if len(batch.Counters) > 0 && m.RecordCounters(batch.Counters) != nil {
+		failed = true
+	}
+	for _, gauge := range batch.Gauges {
+		if m.RecordGauge(gauge) != nil {
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("Some metrics could not be posted")
+	}
+	return nil
+}
+
+// NewHTTPMetricsRecorder instantiates an HTTPMetricsRecorder. Request bodies are gzipped if MetricsCompression is enabled
 func NewHTTPMetricsRecorder(
 	apikey string,
 	cfg *conf.SplitSdkConfig,
@@ -180,6 +229,7 @@ func NewHTTPMetricsRecorder(
 			client:   client,
 			metadata: metadata,
 			logger:   logger,
+			compress: cfg.Advanced.MetricsCompression,
 		},
 	}
 }
@@ -2,11 +2,13 @@
 package api
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/splitio/go-client/splitio"
@@ -72,6 +74,11 @@ func TestPostImpressions(t *testing.T) {
 			t.Error("Posted impressions arrived mal-formed")
 		}
 
+		if impressionsInPost[0].KeyImpressions[0].Properties != `{"region":"us-east"}` ||
+			impressionsInPost[0].KeyImpressions[1].Properties != "" {
+			t.Error("Impression properties should be posted when present", impressionsInPost[0].KeyImpressions)
+		}
+
 		fmt.Fprintln(w, "ok")
 	}))
 	defer ts.Close()
@@ -84,6 +91,7 @@ func TestPostImpressions(t *testing.T) {
 		ChangeNumber: 9876543210,
 		Label:        "some_label_1",
 		BucketingKey: "some_bucket_key_1",
+		Properties:   `{"region":"us-east"}`,
 	}
 	imp2 := storage.Impression{
 		FeatureName:  "some_test",
@@ -318,3 +326,69 @@ func TestPostMetricsGauge(t *testing.T) {
 	}
 
 }
+
+func TestPostMetricsBatchCompressed(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{})
+
+	received := make(map[string][]byte)
+	var mutex sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Error("Body should be gzipped")
+		}
+
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		body, _ := ioutil.ReadAll(reader)
+
+		mutex.Lock()
+		received[r.URL.Path] = body
+		mutex.Unlock()
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	metricsRecorder := NewHTTPMetricsRecorder(
+		"",
+		&conf.SplitSdkConfig{
+			Advanced: conf.AdvancedConfig{
+				EventsURL:          ts.URL,
+				SdkURL:             ts.URL,
+				MetricsCompression: true,
+			},
+		},
+		&splitio.SdkMetadata{SDKVersion: "go-" + splitio.Version},
+		logger,
+	)
+
+	err := metricsRecorder.RecordMetricsBatch(dtos.MetricsBatchDTO{
+		Latencies: []dtos.LatenciesDTO{{MetricName: "latency_1", Latencies: []int64{1, 2}}},
+		Counters:  []dtos.CounterDTO{{MetricName: "counter_1", Count: 111}},
+		Gauges:    []dtos.GaugeDTO{{MetricName: "gauge_1", Gauge: 111.1}},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(received) != 3 {
+		t.Error("Each type of metric should be posted to its own endpoint. Got:", len(received))
+	}
+
+	var latencies []dtos.LatenciesDTO
+	if json.Unmarshal(received["/metrics/times"], &latencies) != nil || latencies[0].MetricName != "latency_1" {
+		t.Error("Latencies arrived mal-formed")
+	}
+
+	var counters []dtos.CounterDTO
+	if json.Unmarshal(received["/metrics/counters"], &counters) != nil || counters[0].Count != 111 {
+		t.Error("Counters arrived mal-formed")
+	}
+
+	var gauge dtos.GaugeDTO
+	if json.Unmarshal(received["/metrics/gauge"], &gauge) != nil || gauge.MetricName != "gauge_1" {
+		t.Error("Gauges arrived mal-formed")
+	}
+}
@@ -17,3 +17,15 @@ type GaugeDTO struct {
 	MetricName string  `json:"name"`
 	Gauge      float64 `json:"value"`
 }
+
+// MetricsBatchDTO groups every type of metric to be submitted in a single synchronization
+type MetricsBatchDTO struct {
+	Latencies []LatenciesDTO `json:"latencies"`
+	Counters  []CounterDTO   `json:"counters"`
+	Gauges    []GaugeDTO     `json:"gauges"`
+}
+
+// IsEmpty returns true if the batch has no metrics
+func (b *MetricsBatchDTO) IsEmpty() bool {
+	return len(b.Latencies) == 0 && len(b.Counters) == 0 && len(b.Gauges) == 0
+}
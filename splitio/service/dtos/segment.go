@@ -8,3 +8,17 @@ type SegmentChangesDTO struct {
 	Since   int64    `json:"since"`
 	Till    int64    `json:"till"`
 }
+
+// SegmentSnapshotDTO struct to map the exported state of a segment at a given changeNumber
+type SegmentSnapshotDTO struct {
+	Name string   `json:"name"`
+	Keys []string `json:"keys"`
+	Till int64    `json:"till"`
+}
+
+// SnapshotDTO struct to map an exported state of splits and segments
+type SnapshotDTO struct {
+	Till     int64                `json:"till"`
+	Splits   []SplitDTO           `json:"splits"`
+	Segments []SegmentSnapshotDTO `json:"segments"`
+}
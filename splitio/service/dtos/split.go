@@ -26,6 +26,14 @@ type SplitDTO struct {
 	Algo                  int               `json:"algo"`
 	Conditions            []ConditionDTO    `json:"conditions"`
 	Configurations        map[string]string `json:"configurations"`
+	Sets                  []string          `json:"sets"`
+	TrackImpressions      *bool             `json:"trackImpressions,omitempty"`
+}
+
+// ImpressionsTracked returns whether impressions should be stored for this split, which is the case unless
+// trackImpressions is explicitly false
+func (s *SplitDTO) ImpressionsTracked() bool {
+	return s.TrackImpressions == nil || *s.TrackImpressions
 }
 
 // MarshalBinary exports SplitDTO to JSON string
@@ -62,3 +62,13 @@ func TestSplitDTO(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitDTOTrackImpressions(t *testing.T) {
+	var split SplitDTO
+	if err := json.Unmarshal([]byte(`{"name":"feature"}`), &split); err != nil || !split.ImpressionsTracked() {
+		t.Error("Impressions should be tracked by default", err)
+	}
+	if err := json.Unmarshal([]byte(`{"name":"feature","trackImpressions":false}`), &split); err != nil || split.ImpressionsTracked() {
+		t.Error("Impressions should not be tracked if trackImpressions is false", err)
+	}
+}
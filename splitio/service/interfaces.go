@@ -27,6 +27,11 @@ type MetricsRecorder interface {
 	RecordGauge(gauge dtos.GaugeDTO) error
 }
 
+// MetricsBatchRecorder interface to be implemented by Metrics loggers able to submit every type of metric at once
+type MetricsBatchRecorder interface {
+	RecordMetricsBatch(batch dtos.MetricsBatchDTO) error
+}
+
 // EventsRecorder interface to post events
 type EventsRecorder interface {
 	Record(events []dtos.EventDTO) error
@@ -4,11 +4,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
 	"regexp"
 	"runtime/debug"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/splitio/go-client/splitio/engine/evaluator"
+	"github.com/splitio/go-client/splitio/engine/grammar"
+	"github.com/splitio/go-client/splitio/engine/hash"
 	"github.com/splitio/go-toolkit/logging"
 
 	"github.com/splitio/go-client/splitio/service/dtos"
@@ -24,27 +30,60 @@ const (
 	SplitFileFormatYAML
 )
 
-// FileSplitFetcher struct fetches splits from a file
+// splitFileURLTimeout bounds each request made to fetch a split file served over http(s)
+const splitFileURLTimeout = 10 * time.Second
+
+// FileSplitFetcher struct fetches splits from a file, or from an http(s) URL serving one
 type FileSplitFetcher struct {
 	splitFile        string
 	fileFormat       int
 	lastChangeNumber int64
+	isURL            bool
+	httpClient       *http.Client
+	logger           logging.LoggerInterface
 }
 
-// NewFileSplitFetcher returns a new instance of LocalFileSplitFetcher
+// NewFileSplitFetcher returns a new instance of LocalFileSplitFetcher. If splitFile is an http(s) URL the file is
+// downloaded on every fetch instead, and its format is told by the extension of the URL path
 func NewFileSplitFetcher(splitFile string, logger logging.LoggerInterface) *FileSplitFetcher {
+	fetcher := &FileSplitFetcher{
+		splitFile:  splitFile,
+		fileFormat: SplitFileFormatClassic,
+		logger:     logger,
+	}
+
+	path := splitFile
+	if parsed, err := url.Parse(splitFile); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		fetcher.isURL = true
+		fetcher.httpClient = &http.Client{Timeout: splitFileURLTimeout}
+		path = parsed.Path
+	}
+
 	var r = regexp.MustCompile("(?i)(.yml$|.yaml$)")
-	if r.MatchString(splitFile) {
-		return &FileSplitFetcher{
-			splitFile:  splitFile,
-			fileFormat: SplitFileFormatYAML,
-		}
+	if r.MatchString(path) {
+		fetcher.fileFormat = SplitFileFormatYAML
+		return fetcher
 	}
 	logger.Warning("Localhost mode: .split mocks will be deprecated soon in favor of YAML files, which provide more targeting power. Take a look in our documentation.")
-	return &FileSplitFetcher{
-		splitFile:  splitFile,
-		fileFormat: SplitFileFormatClassic,
+	return fetcher
+}
+
+// readSplitFile returns the contents of the split file, downloading it if it's an URL
+func (s *FileSplitFetcher) readSplitFile() ([]byte, error) {
+	if !s.isURL {
+		return ioutil.ReadFile(s.splitFile)
+	}
+
+	response, err := s.httpClient.Get(s.splitFile)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("fetching %s returned status code %d", s.splitFile, response.StatusCode)
 	}
+	return ioutil.ReadAll(response.Body)
 }
 
 func parseSplitsClassic(data string) []dtos.SplitDTO {
@@ -69,14 +108,22 @@ func parseSplitsClassic(data string) []dtos.SplitDTO {
 	return splits
 }
 
+// featureSeed derives the seed a feature buckets keys with from its name, so that distributions of different
+// features aren't correlated
+func featureSeed(splitName string) int64 {
+	return int64(int32(hash.Murmur3_32([]byte(splitName), 0)))
+}
+
 func createSplit(splitName string, treatment string, condition dtos.ConditionDTO, configurations map[string]string) dtos.SplitDTO {
 	split := dtos.SplitDTO{
 		Name:              splitName,
+		Seed:              featureSeed(splitName),
 		TrafficAllocation: 100,
 		Conditions:        []dtos.ConditionDTO{condition},
 		Status:            "ACTIVE",
 		DefaultTreatment:  evaluator.Control,
 		Configurations:    configurations,
+		Algo:              grammar.SplitAlgoMurmur,
 	}
 	return split
 }
@@ -149,6 +196,40 @@ func createRolloutCondition(treatment string) dtos.ConditionDTO {
 	}
 }
 
+// parseDistribution builds the partitions of a treatment distribution such as {"on": 30, "off": 70}. Partitions
+// are sorted by treatment so that keys always fall in the same bucket. Percentages must add up to 100
+func parseDistribution(distribution map[interface{}]interface{}) ([]dtos.PartitionDTO, error) {
+	partitions := make([]dtos.PartitionDTO, 0, len(distribution))
+	total := 0
+	for rawTreatment, rawSize := range distribution {
+		treatment, ok := rawTreatment.(string)
+		if !ok {
+			return nil, fmt.Errorf("treatment %v must be a string, quote it if it's a YAML boolean such as on or off", rawTreatment)
+		}
+		size, ok := rawSize.(int)
+		if !ok || size < 0 {
+			return nil, fmt.Errorf("percentage of treatment %s must be a non negative integer", treatment)
+		}
+		partitions = append(partitions, dtos.PartitionDTO{Size: size, Treatment: treatment})
+		total += size
+	}
+
+	if total != 100 {
+		return nil, fmt.Errorf("treatment percentages add up to %d instead of 100", total)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Treatment < partitions[j].Treatment })
+	return partitions, nil
+}
+
+// createDistributionCondition creates a condition that buckets keys into the partitions supplied, restricted to
+// the keys supplied if any
+func createDistributionCondition(keys interface{}, partitions []dtos.PartitionDTO) dtos.ConditionDTO {
+	condition := createCondition(keys, "")
+	condition.Partitions = partitions
+	return condition
+}
+
 func createCondition(keys interface{}, treatment string) dtos.ConditionDTO {
 	if keys != nil {
 		return createWhitelistedCondition(treatment, keys)
@@ -156,7 +237,7 @@ func createCondition(keys interface{}, treatment string) dtos.ConditionDTO {
 	return createRolloutCondition(treatment)
 }
 
-func parseSplitsYAML(data string) (d []dtos.SplitDTO) {
+func parseSplitsYAML(data string) (d []dtos.SplitDTO, e error) {
 	// Set up a guard deferred function to recover if some error occurs during parsing
 	defer func() {
 		if r := recover(); r != nil {
@@ -164,6 +245,7 @@ func parseSplitsYAML(data string) (d []dtos.SplitDTO) {
 			// that the logger isn't panicking
 			log.Fatalf("Localhost Parsing: %v", string(debug.Stack()))
 			d = make([]dtos.SplitDTO, 0)
+			e = nil
 		}
 	}()
 
@@ -173,7 +255,7 @@ func parseSplitsYAML(data string) (d []dtos.SplitDTO) {
 	err := yaml.Unmarshal([]byte(data), &splitsFromYAML)
 	if err != nil {
 		log.Fatalf("error: %v", err)
-		return splits
+		return splits, nil
 	}
 
 	splitsToParse := make(map[string]dtos.SplitDTO, 0)
@@ -182,30 +264,40 @@ func parseSplitsYAML(data string) (d []dtos.SplitDTO) {
 		for splitName, splitParsed := range splitMap {
 			split, ok := splitsToParse[splitName]
 			treatment, isString := splitParsed["treatment"].(string)
-			if !isString {
-				break
+			var newCondition dtos.ConditionDTO
+			if isString {
+				newCondition = createCondition(splitParsed["keys"], treatment)
+			} else {
+				distribution, isMap := splitParsed["treatment"].(map[interface{}]interface{})
+				if !isMap {
+					break
+				}
+				partitions, err := parseDistribution(distribution)
+				if err != nil {
+					return nil, fmt.Errorf("Localhost Parsing: feature %s: %s", splitName, err.Error())
+				}
+				newCondition = createDistributionCondition(splitParsed["keys"], partitions)
 			}
 			config, isValidConfig := splitParsed["config"].(string)
 			if !ok {
 				configurations := make(map[string]string)
-				if isValidConfig {
+				if isValidConfig && isString {
 					configurations[treatment] = config
 				}
 				splitsToParse[splitName] = createSplit(
 					splitName,
 					treatment,
-					createCondition(splitParsed["keys"], treatment),
+					newCondition,
 					configurations,
 				)
 			} else {
-				newCondition := createCondition(splitParsed["keys"], treatment)
 				if newCondition.ConditionType == "ROLLOUT" {
 					split.Conditions = append(split.Conditions, newCondition)
 				} else {
 					split.Conditions = append([]dtos.ConditionDTO{newCondition}, split.Conditions...)
 				}
 				configurations := split.Configurations
-				if isValidConfig {
+				if isValidConfig && isString {
 					configurations[treatment] = config
 				}
 				split.Configurations = configurations
@@ -219,13 +311,22 @@ func parseSplitsYAML(data string) (d []dtos.SplitDTO) {
 		splits = append(splits, split)
 	}
 
-	return splits
+	return splits, nil
 }
 
-// Fetch parses the file and returns the appropriate structures
+// Fetch parses the file and returns the appropriate structures. Once a split file served over http(s) has been
+// fetched, failing to fetch it again is logged and no changes are returned, keeping the splits last fetched
 func (s *FileSplitFetcher) Fetch(changeNumber int64) (*dtos.SplitChangesDTO, error) {
-	fileContents, err := ioutil.ReadFile(s.splitFile)
+	fileContents, err := s.readSplitFile()
 	if err != nil {
+		if s.isURL && s.lastChangeNumber != 0 {
+			s.logger.Warning("Localhost mode: keeping the last splits fetched, as the split file could not be fetched: ", err.Error())
+			return &dtos.SplitChangesDTO{
+				Splits: []dtos.SplitDTO{},
+				Since:  s.lastChangeNumber,
+				Till:   s.lastChangeNumber,
+			}, nil
+		}
 		return nil, err
 	}
 
@@ -242,7 +343,10 @@ func (s *FileSplitFetcher) Fetch(changeNumber int64) (*dtos.SplitChangesDTO, err
 	case SplitFileFormatClassic:
 		splits = parseSplitsClassic(data)
 	case SplitFileFormatYAML:
-		splits = parseSplitsYAML(data)
+		splits, err = parseSplitsYAML(data)
+		if err != nil {
+			return nil, err
+		}
 	case SplitFileFormatJSON:
 		return nil, fmt.Errorf("JSON is not yet supported")
 	default:
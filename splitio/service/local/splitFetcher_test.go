@@ -0,0 +1,105 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/splitio/go-client/splitio/engine"
+	"github.com/splitio/go-client/splitio/engine/evaluator"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestParseSplitsYAMLDistribution(t *testing.T) {
+	splits, err := parseSplitsYAML("- feature:\n    treatment:\n      \"on\": 30\n      \"off\": 70\n")
+	if err != nil || len(splits) != 1 || len(splits[0].Conditions) != 1 {
+		t.Error("A valid distribution should be parsed", splits, err)
+		return
+	}
+
+	partitions := splits[0].Conditions[0].Partitions
+	if len(partitions) != 2 || partitions[0].Treatment != "off" || partitions[0].Size != 70 ||
+		partitions[1].Treatment != "on" || partitions[1].Size != 30 {
+		t.Error("Partitions should be sorted by treatment", partitions)
+	}
+
+	if _, err = parseSplitsYAML("- feature:\n    treatment:\n      \"on\": 30\n      \"off\": 60\n"); err == nil {
+		t.Error("Distributions not adding up to 100 should fail")
+	}
+
+	if _, err = parseSplitsYAML("- feature:\n    treatment:\n      on: 50\n      \"off\": 50\n"); err == nil {
+		t.Error("Non string treatments should fail")
+	}
+}
+
+func TestParseSplitsYAMLDistributionsNotCorrelated(t *testing.T) {
+	splits, err := parseSplitsYAML(
+		"- first:\n    treatment:\n      \"on\": 50\n      \"off\": 50\n" +
+			"- second:\n    treatment:\n      \"on\": 50\n      \"off\": 50\n",
+	)
+	if err != nil || len(splits) != 2 {
+		t.Fatal("Both distributions should be parsed", splits, err)
+	}
+	if splits[0].Seed == splits[1].Seed {
+		t.Error("Each feature should get its own seed", splits[0].Seed)
+	}
+
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany(splits, 1)
+	eval := evaluator.NewEvaluator(splitStorage, mutexmap.NewMMSegmentStorage(), engine.NewEngine(logger, engine.Options{}), logger)
+	differing := 0
+	for i := 0; i < 1000; i++ {
+		results := eval.EvaluateFeatures(fmt.Sprintf("key%d", i), nil, []string{"first", "second"}, nil).Evaluations
+		if results["first"].Treatment != results["second"].Treatment {
+			differing++
+		}
+	}
+	if differing < 400 || differing > 600 {
+		t.Error("Keys should be bucketed independently for each feature", differing)
+	}
+}
+
+func TestFileSplitFetcherURL(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("- feature:\n    treatment: \"on\"\n"))
+	}))
+	defer server.Close()
+
+	warnings := &bytes.Buffer{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelWarning, WarningWriter: warnings})
+
+	atomic.StoreInt32(&failing, 1)
+	if _, err := NewFileSplitFetcher(server.URL+"/splits.yaml", logger).Fetch(-1); err == nil {
+		t.Error("Failing to fetch the split file for the first time should return an error")
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	fetcher := NewFileSplitFetcher(server.URL+"/splits.yaml?fixture=shared", logger)
+	if fetcher.fileFormat != SplitFileFormatYAML {
+		t.Error("The format should be told by the extension of the URL path")
+	}
+	changes, err := fetcher.Fetch(-1)
+	if err != nil || len(changes.Splits) != 1 || changes.Splits[0].Name != "feature" {
+		t.Error("The split file should be fetched and parsed", changes, err)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	changes, err = fetcher.Fetch(0)
+	if err != nil || len(changes.Splits) != 0 || changes.Since != changes.Till {
+		t.Error("Failing to fetch the split file again should keep the splits last fetched", changes, err)
+	}
+	if !strings.Contains(warnings.String(), "keeping the last splits fetched") {
+		t.Error("Failing to fetch the split file again should be logged as a warning", warnings.String())
+	}
+}
@@ -2,7 +2,9 @@ package storage
 
 import "github.com/splitio/go-client/splitio/service/dtos"
 
-// Impression struct to map an impression
+// Impression struct to map an impression. Evaluation attributes are never part of it, so they're not
+// sent to Split nor written to Redis. They're only forwarded to the impression listener, if any.
+// Properties holds the JSON encoded properties added by the impression enricher, if any
 type Impression struct {
 	KeyName      string `json:"k"`
 	BucketingKey string `json:"b"`
@@ -11,6 +13,8 @@ type Impression struct {
 	Label        string `json:"r"`
 	ChangeNumber int64  `json:"c"`
 	Time         int64  `json:"m"`
+	Properties   string `json:"p,omitempty"`
+	PreviousTime int64  `json:"pt,omitempty"`
 }
 
 // ImpressionQueueObject struct mapping impressions
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// DualImpressionStorage writes impressions to a primary and a secondary storage, ie: while migrating from one
+// to the other. Impressions are popped from the primary only, and failures writing to the secondary are logged
+// without affecting the primary
+type DualImpressionStorage struct {
+	primary   ImpressionStorage
+	secondary ImpressionStorageProducer
+	logger    logging.LoggerInterface
+}
+
+// NewDualImpressionStorage instantiates a new DualImpressionStorage
+func NewDualImpressionStorage(
+	primary ImpressionStorage,
+	secondary ImpressionStorageProducer,
+	logger logging.LoggerInterface,
+) *DualImpressionStorage {
+	return &DualImpressionStorage{
+		primary:   primary,
+		secondary: secondary,
+		logger:    logger,
+	}
+}
+
+// LogImpressions writes the impressions to both storages and returns the result of writing to the primary one
+func (d *DualImpressionStorage) LogImpressions(impressions []Impression) error {
+	err := d.primary.LogImpressions(impressions)
+	if secondaryErr := d.secondary.LogImpressions(impressions); secondaryErr != nil {
+		d.logger.Warning("Error writing impressions to the secondary storage: ", secondaryErr.Error())
+	}
+	return err
+}
+
+// PopN pops impressions from the primary storage
+func (d *DualImpressionStorage) PopN(n int64) ([]Impression, error) {
+	return d.primary.PopN(n)
+}
+
+// Purge discards the impressions of the primary storage if it supports it
+func (d *DualImpressionStorage) Purge() error {
+	if purger, ok := d.primary.(TelemetryPurger); ok {
+		return purger.Purge()
+	}
+	return nil
+}
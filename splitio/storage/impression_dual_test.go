@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type impressionsQueue struct {
+	impressions []Impression
+	err         error
+}
+
+func (q *impressionsQueue) LogImpressions(impressions []Impression) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.impressions = append(q.impressions, impressions...)
+	return nil
+}
+
+func (q *impressionsQueue) PopN(n int64) ([]Impression, error) {
+	if int64(len(q.impressions)) < n {
+		n = int64(len(q.impressions))
+	}
+	popped := q.impressions[:n]
+	q.impressions = q.impressions[n:]
+	return popped, nil
+}
+
+func TestDualImpressionStorage(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	primary := &impressionsQueue{}
+	secondary := &impressionsQueue{}
+	dual := NewDualImpressionStorage(primary, secondary, logger)
+
+	impression := Impression{FeatureName: "feature", KeyName: "key", Treatment: "on"}
+	if err := dual.LogImpressions([]Impression{impression}); err != nil {
+		t.Error("No error was expected", err)
+	}
+	if len(primary.impressions) != 1 || len(secondary.impressions) != 1 || secondary.impressions[0] != impression {
+		t.Error("The impression should land in both storages", primary.impressions, secondary.impressions)
+	}
+
+	popped, _ := dual.PopN(10)
+	if len(popped) != 1 || len(primary.impressions) != 0 || len(secondary.impressions) != 1 {
+		t.Error("Impressions should be popped from the primary storage only")
+	}
+
+	secondary.err = errors.New("secondary unavailable")
+	if err := dual.LogImpressions([]Impression{impression}); err != nil {
+		t.Error("A secondary write error should not fail the primary write", err)
+	}
+	if len(primary.impressions) != 1 {
+		t.Error("The impression should have been written to the primary storage", primary.impressions)
+	}
+
+	primary.err = errors.New("primary unavailable")
+	if err := dual.LogImpressions([]Impression{impression}); err == nil {
+		t.Error("Primary write errors should be returned")
+	}
+}
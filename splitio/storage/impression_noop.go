@@ -0,0 +1,20 @@
+package storage
+
+// NoopImpressionStorage discards every impression, for usages that only need treatments. Impression-based
+// analytics aren't available for the features evaluated with it
+type NoopImpressionStorage struct{}
+
+// NewNoopImpressionStorage instantiates a new NoopImpressionStorage
+func NewNoopImpressionStorage() *NoopImpressionStorage {
+	return &NoopImpressionStorage{}
+}
+
+// LogImpressions discards the impressions
+func (n *NoopImpressionStorage) LogImpressions(impressions []Impression) error {
+	return nil
+}
+
+// PopN never returns impressions
+func (n *NoopImpressionStorage) PopN(count int64) ([]Impression, error) {
+	return nil, nil
+}
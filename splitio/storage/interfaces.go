@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-toolkit/datastructures/set"
 )
@@ -37,6 +40,55 @@ type SegmentStorageConsumer interface {
 	SegmentContainsKey(segmentName string, key string) (bool, error)
 }
 
+// SegmentStorageStats interface should be implemented by segment storages able to report the size of many segments at once
+type SegmentStorageStats interface {
+	SegmentSizes(segmentNames []string) (map[string]int64, error)
+}
+
+// SegmentStorageContextBinder interface should be implemented by segment storages whose reads can be bound to a
+// context, so that they're abandoned when it's cancelled. Storages wrapping others should bind the wrapped ones
+type SegmentStorageContextBinder interface {
+	WithContext(ctx context.Context) SegmentStorage
+}
+
+// SegmentStorageBulkConsumer interface should be implemented by segment storages able to check the membership
+// of many keys in a segment at once
+type SegmentStorageBulkConsumer interface {
+	SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error)
+}
+
+// SplitStorageChangesConsumer interface should be implemented by split storages able to list the splits that changed
+// after a given change number, ie: to mirror them incrementally
+type SplitStorageChangesConsumer interface {
+	SplitsChangedSince(sinceChangeNumber int64) []dtos.SplitDTO
+}
+
+// SplitStorageFlagSetConsumer interface should be implemented by split storages able to list the splits that
+// belong to a flag set
+type SplitStorageFlagSetConsumer interface {
+	SplitNamesByFlagSet(flagSet string) []string
+}
+
+// SplitStorageFreshness interface should be implemented by split storages able to tell when splits were last
+// synchronized, whether they changed or not
+type SplitStorageFreshness interface {
+	LastUpdated() time.Time
+}
+
+// ImpressionObserverStateStorage interface should be implemented by storages able to persist the combinations
+// tracked by an impression observer, indexed by hash and holding the last time they were seen, so that
+// they survive a restart. Saved state should expire after ttl
+type ImpressionObserverStateStorage interface {
+	SaveObserverState(state map[string]int64, ttl time.Duration) error
+	LoadObserverState() (map[string]int64, error)
+}
+
+// TelemetryPurger interface should be implemented by impression & metrics storages able to discard
+// everything recorded by this SDK instance without touching other instances' data
+type TelemetryPurger interface {
+	Purge() error
+}
+
 // ImpressionStorageProducer interface should be impemented by structs that accept incoming impressions
 type ImpressionStorageProducer interface {
 	LogImpressions(impressions []Impression) error
@@ -54,6 +106,18 @@ type MetricsStorageProducer interface {
 	IncCounter(key string)
 }
 
+// MetricsCounterBatchProducer interface should be implemented by metrics storages able to increment a counter
+// by many at once
+type MetricsCounterBatchProducer interface {
+	IncCounterBy(key string, delta int64)
+}
+
+// MetricsExceptionProducer interface should be implemented by metrics storages able to count the unexpected
+// failures of each SDK method
+type MetricsExceptionProducer interface {
+	IncException(method string)
+}
+
 // MetricsStorageConsumer interface should be implemented by structs that offer popping metrics
 type MetricsStorageConsumer interface {
 	PopGauges() []dtos.GaugeDTO
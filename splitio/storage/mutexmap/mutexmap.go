@@ -2,7 +2,9 @@ package mutexmap
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-toolkit/datastructures/set"
@@ -13,8 +15,10 @@ import (
 // MMSplitStorage struct contains is an in-memory implementation of split storage
 type MMSplitStorage struct {
 	data         map[string]dtos.SplitDTO
+	flagSets     map[string]map[string]struct{}
 	trafficTypes map[string]int64
 	till         int64
+	lastUpdated  time.Time
 	mutex        *sync.RWMutex
 	ttMutex      *sync.RWMutex
 	tillMutex    *sync.RWMutex
@@ -24,6 +28,7 @@ type MMSplitStorage struct {
 func NewMMSplitStorage() *MMSplitStorage {
 	return &MMSplitStorage{
 		data:         make(map[string]dtos.SplitDTO),
+		flagSets:     make(map[string]map[string]struct{}),
 		trafficTypes: make(map[string]int64),
 		till:         0,
 		mutex:        &sync.RWMutex{},
@@ -65,6 +70,7 @@ func (m *MMSplitStorage) _updateTill(till int64) {
 	m.tillMutex.Lock()
 	defer m.tillMutex.Unlock()
 	m.till = till
+	m.lastUpdated = time.Now()
 }
 
 // PutMany bulk inserts splits into the in-memory storage
@@ -77,9 +83,11 @@ func (m *MMSplitStorage) PutMany(splits []dtos.SplitDTO, till int64) {
 			// If it's an update, we decrement the traffic type count of the existing split,
 			// and then add the updated one (as part of the normal flow), in case it's different.
 			m.decreaseTrafficTypeCount(existing.TrafficTypeName)
+			m.removeFromFlagSets(&existing)
 		}
 		m.data[split.Name] = split
 		m.increaseTrafficTypeCount(split.TrafficTypeName)
+		m.addToFlagSets(&split)
 	}
 	m._updateTill(till)
 }
@@ -92,9 +100,48 @@ func (m *MMSplitStorage) Remove(splitName string) {
 	if exists {
 		delete(m.data, splitName)
 		m.decreaseTrafficTypeCount(split.TrafficTypeName)
+		m.removeFromFlagSets(&split)
 	}
 }
 
+// addToFlagSets indexes a split under each of its flag sets. The split mutex must be held
+func (m *MMSplitStorage) addToFlagSets(split *dtos.SplitDTO) {
+	for _, flagSet := range split.Sets {
+		names, ok := m.flagSets[flagSet]
+		if !ok {
+			names = make(map[string]struct{})
+			m.flagSets[flagSet] = names
+		}
+		names[split.Name] = struct{}{}
+	}
+}
+
+// removeFromFlagSets removes a split from the index of each of its flag sets. The split mutex must be held
+func (m *MMSplitStorage) removeFromFlagSets(split *dtos.SplitDTO) {
+	for _, flagSet := range split.Sets {
+		names, ok := m.flagSets[flagSet]
+		if !ok {
+			continue
+		}
+		delete(names, split.Name)
+		if len(names) == 0 {
+			delete(m.flagSets, flagSet)
+		}
+	}
+}
+
+// SplitNamesByFlagSet returns the names of the splits in a flag set, sorted. Empty if the flag set is unknown
+func (m *MMSplitStorage) SplitNamesByFlagSet(flagSet string) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	splitNames := make([]string, 0, len(m.flagSets[flagSet]))
+	for name := range m.flagSets[flagSet] {
+		splitNames = append(splitNames, name)
+	}
+	sort.Strings(splitNames)
+	return splitNames
+}
+
 // Till returns the last timestamp the split was fetched
 func (m *MMSplitStorage) Till() int64 {
 	m.tillMutex.RLock()
@@ -102,6 +149,13 @@ func (m *MMSplitStorage) Till() int64 {
 	return m.till
 }
 
+// LastUpdated returns the last time splits were stored, even if none changed. Zero if they never were
+func (m *MMSplitStorage) LastUpdated() time.Time {
+	m.tillMutex.RLock()
+	defer m.tillMutex.RUnlock()
+	return m.lastUpdated
+}
+
 // SplitNames returns a slice with the names of all the current splits
 func (m *MMSplitStorage) SplitNames() []string {
 	m.mutex.RLock()
@@ -143,11 +197,27 @@ func (m *MMSplitStorage) GetAll() []dtos.SplitDTO {
 	return splitList
 }
 
+// SplitsChangedSince returns a copy of the splits whose change number is greater than sinceChangeNumber,
+// sorted by change number
+func (m *MMSplitStorage) SplitsChangedSince(sinceChangeNumber int64) []dtos.SplitDTO {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	splitList := make([]dtos.SplitDTO, 0)
+	for _, split := range m.data {
+		if split.ChangeNumber > sinceChangeNumber {
+			splitList = append(splitList, split)
+		}
+	}
+	sort.Slice(splitList, func(i, j int) bool { return splitList[i].ChangeNumber < splitList[j].ChangeNumber })
+	return splitList
+}
+
 // Clear replaces the split storage with an empty one.
 func (m *MMSplitStorage) Clear() {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.data = make(map[string]dtos.SplitDTO)
+	m.flagSets = make(map[string]map[string]struct{})
 }
 
 // increaseTrafficTypeCount increases value for a traffic type
@@ -205,6 +275,20 @@ func NewMMSegmentStorage() *MMSegmentStorage {
 	}
 }
 
+// NewMMSegmentStorageFromSnapshot instantiates a new MMSegmentStorage populated with previously exported
+// segment snapshots. It can be used to evaluate against a past state of the segments
+func NewMMSegmentStorageFromSnapshot(snapshots []dtos.SegmentSnapshotDTO) *MMSegmentStorage {
+	segmentStorage := NewMMSegmentStorage()
+	for _, snapshot := range snapshots {
+		keys := set.NewSet()
+		for _, key := range snapshot.Keys {
+			keys.Add(key)
+		}
+		segmentStorage.Put(snapshot.Name, keys, snapshot.Till)
+	}
+	return segmentStorage
+}
+
 // Get retrieves a segment from the in-memory storage
 // NOTE: A pointer TO A COPY is returned, in order to avoid race conditions between
 // evaluations and sdk <-> backend sync
@@ -230,6 +314,35 @@ func (m *MMSegmentStorage) SegmentContainsKey(segmentName string, key string) (b
 	return item.Has(key), nil
 }
 
+// SegmentContainsKeys returns whether the segment contains each one of the keys
+func (m *MMSegmentStorage) SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	item, exists := m.data[segmentName]
+	if !exists {
+		return nil, fmt.Errorf("segment %s not found in storage", segmentName)
+	}
+	memberships := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		memberships[key] = item.Has(key)
+	}
+	return memberships, nil
+}
+
+// SegmentSizes returns the amount of keys of each segment. Segments that are not present
+// in storage are not included in the result
+func (m *MMSegmentStorage) SegmentSizes(segmentNames []string) (map[string]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	sizes := make(map[string]int64)
+	for _, segmentName := range segmentNames {
+		if item, exists := m.data[segmentName]; exists {
+			sizes[segmentName] = int64(item.Size())
+		}
+	}
+	return sizes, nil
+}
+
 func (m *MMSegmentStorage) _updateTill(name string, till int64) {
 	m.tillMutex.Lock()
 	defer m.tillMutex.Unlock()
@@ -334,6 +447,13 @@ func (m *MMMetricsStorage) IncCounter(key string) {
 	}
 }
 
+// IncCounterBy increments the counter of a key by delta
+func (m *MMMetricsStorage) IncCounterBy(key string, delta int64) {
+	m.countersMutex.Lock()
+	defer m.countersMutex.Unlock()
+	m.counterData[key] += delta
+}
+
 // PopCounters returns and deletes all the counters stored
 func (m *MMMetricsStorage) PopCounters() []dtos.CounterDTO {
 	m.countersMutex.Lock()
@@ -386,3 +506,11 @@ func (m *MMMetricsStorage) PopLatencies() []dtos.LatenciesDTO {
 	}
 	return latencies
 }
+
+// Purge discards every gauge, counter & latency stored
+func (m *MMMetricsStorage) Purge() error {
+	m.PopGauges()
+	m.PopCounters()
+	m.PopLatencies()
+	return nil
+}
@@ -425,3 +425,54 @@ func TestMMSplitStorageObjectLivesAfterDeletion(t *testing.T) {
 		t.Error("Wrong algo")
 	}
 }
+
+func TestMMSplitStorageChangedSince(t *testing.T) {
+	splitStorage := NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "old", ChangeNumber: 10},
+		{Name: "current", ChangeNumber: 20},
+		{Name: "newest", ChangeNumber: 40},
+		{Name: "newer", ChangeNumber: 30},
+	}, 40)
+
+	changed := splitStorage.SplitsChangedSince(20)
+	if len(changed) != 2 || changed[0].Name != "newer" || changed[1].Name != "newest" {
+		t.Error("Only splits newer than the change number should be returned, sorted by change number", changed)
+	}
+
+	if changed = splitStorage.SplitsChangedSince(40); len(changed) != 0 {
+		t.Error("Nothing should be returned if nothing changed", changed)
+	}
+}
+
+func TestMMSplitStorageFlagSets(t *testing.T) {
+	splitStorage := NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "split1", Sets: []string{"backend", "frontend"}},
+		{Name: "split2", Sets: []string{"backend"}},
+		{Name: "split3"},
+	}, 1)
+
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 2 || names[0] != "split1" || names[1] != "split2" {
+		t.Error("Splits should be indexed by flag set. Got:", names)
+	}
+	if names := splitStorage.SplitNamesByFlagSet("unknown"); names == nil || len(names) != 0 {
+		t.Error("Unknown flag sets should have no splits. Got:", names)
+	}
+
+	// Updates move splits between flag sets
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split1", Sets: []string{"frontend"}}}, 2)
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 1 || names[0] != "split2" {
+		t.Error("Updated splits should be removed from the flag sets they left. Got:", names)
+	}
+
+	splitStorage.Remove("split1")
+	if names := splitStorage.SplitNamesByFlagSet("frontend"); len(names) != 0 {
+		t.Error("Removed splits should be removed from their flag sets. Got:", names)
+	}
+
+	splitStorage.Clear()
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 0 {
+		t.Error("Flag sets should be cleared along with the splits. Got:", names)
+	}
+}
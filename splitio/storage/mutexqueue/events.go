@@ -4,6 +4,7 @@ import (
 	"container/list"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-toolkit/logging"
@@ -30,6 +31,7 @@ type eventWrapper struct {
 
 // MQEventsStorage in memory events storage
 type MQEventsStorage struct {
+	depth            int64 // accessed atomically, kept first for 64-bit alignment
 	queue            *list.List
 	size             int
 	accumulatedBytes int
@@ -53,6 +55,7 @@ func (s *MQEventsStorage) sendSignalIsFull() {
 func (s *MQEventsStorage) Push(event dtos.EventDTO, size int) error {
 	s.mutexQueue.Lock()
 	defer s.mutexQueue.Unlock()
+	defer s.updateDepth()
 
 	if s.queue.Len()+1 > s.size {
 		s.sendSignalIsFull()
@@ -77,6 +80,7 @@ func (s *MQEventsStorage) PopN(n int64) ([]dtos.EventDTO, error) {
 	// Mutexing queue
 	s.mutexQueue.Lock()
 	defer s.mutexQueue.Unlock()
+	defer s.updateDepth()
 
 	if int64(s.queue.Len()) >= n {
 		totalItems = int(n)
@@ -124,3 +128,13 @@ func (s *MQEventsStorage) Count() int64 {
 
 	return int64(s.queue.Len())
 }
+
+// updateDepth publishes the current length of the queue. Must be called with the lock held
+func (s *MQEventsStorage) updateDepth() {
+	atomic.StoreInt64(&s.depth, int64(s.queue.Len()))
+}
+
+// Depth returns the number of elements in the queue without locking it, so that it can be sampled cheaply
+func (s *MQEventsStorage) Depth() int64 {
+	return atomic.LoadInt64(&s.depth)
+}
@@ -3,6 +3,7 @@ package mutexqueue
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 
 	"github.com/splitio/go-client/splitio/storage"
 	"github.com/splitio/go-toolkit/logging"
@@ -21,6 +22,7 @@ func NewMQImpressionsStorage(queueSize int, isFull chan<- string, logger logging
 
 // MQImpressionsStorage in memory events storage
 type MQImpressionsStorage struct {
+	depth      int64 // accessed atomically, kept first for 64-bit alignment
 	queue      *list.List
 	size       int
 	mutexQueue *sync.Mutex
@@ -44,6 +46,7 @@ func (s *MQImpressionsStorage) sendSignalIsFull() {
 func (s *MQImpressionsStorage) LogImpressions(impressions []storage.Impression) error {
 	s.mutexQueue.Lock()
 	defer s.mutexQueue.Unlock()
+	defer s.updateDepth()
 
 	for _, impression := range impressions {
 		if s.queue.Len()+1 > s.size {
@@ -68,6 +71,7 @@ func (s *MQImpressionsStorage) PopN(n int64) ([]storage.Impression, error) {
 	// Mutexing queue
 	s.mutexQueue.Lock()
 	defer s.mutexQueue.Unlock()
+	defer s.updateDepth()
 
 	if int64(s.queue.Len()) >= n {
 		totalItems = int(n)
@@ -98,3 +102,23 @@ func (s *MQImpressionsStorage) Count() int64 {
 
 	return int64(s.queue.Len())
 }
+
+// Purge removes every impression in the queue
+func (s *MQImpressionsStorage) Purge() error {
+	s.mutexQueue.Lock()
+	defer s.mutexQueue.Unlock()
+	defer s.updateDepth()
+
+	s.queue.Init()
+	return nil
+}
+
+// updateDepth publishes the current length of the queue. Must be called with the lock held
+func (s *MQImpressionsStorage) updateDepth() {
+	atomic.StoreInt64(&s.depth, int64(s.queue.Len()))
+}
+
+// Depth returns the number of elements in the queue without locking it, so that it can be sampled cheaply
+func (s *MQImpressionsStorage) Depth() int64 {
+	return atomic.LoadInt64(&s.depth)
+}
@@ -1,5 +1,7 @@
 package redisdb
 
+import "time"
+
 const (
 	redisSplit            = "SPLITIO.split.{split}"                                              // split object
 	redisSplitTill        = "SPLITIO.splits.till"                                                // last split fetch
@@ -9,11 +11,22 @@ const (
 	redisImpressions      = "SPLITIO/{sdkVersion}/{instanceId}/impressions.{feature}"            // impressions for a feature
 	redisLatency          = "SPLITIO/{sdkVersion}/{instanceId}/latency.{metric}.bucket.{bucket}" // latency bucket
 	redisCount            = "SPLITIO/{sdkVersion}/{instanceId}/count.{metric}"                   // counter
+	redisException        = "SPLITIO/{sdkVersion}/{instanceId}/exception.{metric}"               // exception counter
 	redisGauge            = "SPLITIO/{sdkVersion}/{instanceId}/gauge.{metric}"                   // gauge
 	redisEvents           = "SPLITIO.events"                                                     // events LIST key
 	redisImpressionsQueue = "SPLITIO.impressions"                                                // impressions LIST key
+	redisFeatureQueue     = "SPLITIO.impressions.{feature}"                                      // impressions LIST key of a feature with its own TTL
 	redisImpressionsTTL   = 60                                                                   // impressions default TTL
 	redisTrafficType      = "SPLITIO.trafficType.{trafficType}"                                  // traffic Type fetch
+	redisFlagSet          = "SPLITIO.flagSet.{flagSet}"                                          // names of the splits in a flag set
+	redisObserverState    = "SPLITIO/{sdkVersion}/{instanceId}/impressionObserver"               // impression observer state HASH key
+)
+
+const (
+	defaultPrefixSeparator      = "."              // separator between the prefix & the keys if none is configured
+	redisDroppedWarningInterval = time.Minute      // minimum time between warnings for impressions dropped by redis
+	replicaRetryInterval        = 30 * time.Second // time the read replica is skipped for after it fails
+	purgeBatchSize              = 1000             // impressions read or metric keys scanned by each step of a purge
 )
 
 const (
@@ -2,7 +2,6 @@ package redisdb
 
 import (
 	"encoding/json"
-	"sync"
 
 	"github.com/splitio/go-client/splitio"
 	"github.com/splitio/go-client/splitio/service/dtos"
@@ -12,7 +11,6 @@ import (
 // RedisEventsStorage redis implementation of EventsStorage interface
 type RedisEventsStorage struct {
 	client          *PrefixedRedisClient
-	mutex           *sync.Mutex
 	logger          logging.LoggerInterface
 	redisKey        string
 	metadataMessage dtos.QueueStoredMachineMetadataDTO
@@ -24,7 +22,6 @@ func NewRedisEventsStorage(redisClient *PrefixedRedisClient, metadata *splitio.S
 		client:   redisClient,
 		logger:   logger,
 		redisKey: redisEvents,
-		mutex:    &sync.Mutex{},
 		metadataMessage: dtos.QueueStoredMachineMetadataDTO{
 			SDKVersion:  metadata.SDKVersion,
 			MachineIP:   metadata.MachineIP,
@@ -59,30 +56,14 @@ func (r *RedisEventsStorage) Push(event dtos.EventDTO, _ int) error {
 func (r *RedisEventsStorage) PopN(n int64) ([]dtos.EventDTO, error) {
 	toReturn := make([]dtos.EventDTO, 0)
 
-	r.mutex.Lock()
-	lrange := r.client.LRange(r.redisKey, 0, n-1)
-	if lrange.Err() != nil {
-		r.logger.Error("Fetching events", lrange.Err().Error())
-		r.mutex.Unlock()
-		return nil, lrange.Err()
-	}
-	totalFetchedEvents := int64(len(lrange.Val()))
-
-	idxFrom := n
-	if totalFetchedEvents < n {
-		idxFrom = totalFetchedEvents
-	}
-
-	res := r.client.LTrim(r.redisKey, idxFrom, -1)
-	if res.Err() != nil {
-		r.logger.Error("Trim events", res.Err().Error())
-		r.mutex.Unlock()
-		return nil, res.Err()
+	// Popped atomically, so that events are handed to a single consumer even across processes
+	listOfEvents, err := r.client.LPopN(r.redisKey, n)
+	if err != nil {
+		r.logger.Error("Popping events", err.Error())
+		return nil, err
 	}
-	r.mutex.Unlock()
 
 	//JSON unmarshal
-	listOfEvents := lrange.Val()
 	for _, se := range listOfEvents {
 		storedEventDTO := dtos.QueueStoredEventDTO{}
 		err := json.Unmarshal([]byte(se), &storedEventDTO)
@@ -2,7 +2,10 @@ package redisdb
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/splitio/go-client/splitio"
@@ -11,24 +14,52 @@ import (
 	"github.com/splitio/go-toolkit/logging"
 )
 
+// DroppedCounter is the counter incremented by the amount of impressions that couldn't be written to redis
+const DroppedCounter = "sdk.impressions.droppedByRedis"
+
 // RedisImpressionStorage is a redis-based implementation of split storage
 type RedisImpressionStorage struct {
+	dropped         int64 // accessed atomically, kept first for 64-bit alignment
+	lastWarning     int64
 	client          *PrefixedRedisClient
 	mutex           *sync.Mutex
 	logger          logging.LoggerInterface
 	redisKey        string
 	impressionsTTL  time.Duration
+	featureTTLs     map[string]time.Duration
+	metrics         storage.MetricsStorageProducer
 	metadataMessage dtos.QueueStoredMachineMetadataDTO
 }
 
 // NewRedisImpressionStorage creates a new RedisSplitStorage and returns a reference to it
 func NewRedisImpressionStorage(client *PrefixedRedisClient, metadata *splitio.SdkMetadata, logger logging.LoggerInterface) *RedisImpressionStorage {
+	return NewRedisImpressionStorageWithTTLs(client, metadata, 0, nil, nil, logger)
+}
+
+// NewRedisImpressionStorageWithTTLs creates a new RedisImpressionStorage that expires the impressions list after
+// impressionsTTL, or the default TTL if it's not positive, and the impressions of the features in featureTTLs after
+// their own TTL. Since a redis list can only have one TTL, those impressions are pushed to a list of their own,
+// SPLITIO.impressions.{feature}, which PopN drains along with the shared one. Impressions that can't be written
+// are added to the DroppedCounter of metrics, if any
+func NewRedisImpressionStorageWithTTLs(
+	client *PrefixedRedisClient,
+	metadata *splitio.SdkMetadata,
+	impressionsTTL time.Duration,
+	featureTTLs map[string]time.Duration,
+	metrics storage.MetricsStorageProducer,
+	logger logging.LoggerInterface,
+) *RedisImpressionStorage {
+	if impressionsTTL <= 0 {
+		impressionsTTL = time.Duration(redisImpressionsTTL) * time.Minute
+	}
 	return &RedisImpressionStorage{
 		client:         client,
 		mutex:          &sync.Mutex{},
 		logger:         logger,
 		redisKey:       redisImpressionsQueue,
-		impressionsTTL: redisImpressionsTTL,
+		impressionsTTL: impressionsTTL,
+		featureTTLs:    featureTTLs,
+		metrics:        metrics,
 		metadataMessage: dtos.QueueStoredMachineMetadataDTO{
 			SDKVersion:  metadata.SDKVersion,
 			MachineIP:   metadata.MachineIP,
@@ -40,19 +71,40 @@ func NewRedisImpressionStorage(client *PrefixedRedisClient, metadata *splitio.Sd
 // LogImpressions stores impressions in redis as Queue
 func (r *RedisImpressionStorage) LogImpressions(impressions []storage.Impression) error {
 	var impressionsToStore []storage.ImpressionQueueObject
+	var byFeature map[string][]storage.ImpressionQueueObject
 	for _, i := range impressions {
 		var impression = storage.ImpressionQueueObject{Metadata: r.metadataMessage, Impression: i}
+		if _, ok := r.featureTTLs[i.FeatureName]; ok {
+			if byFeature == nil {
+				byFeature = make(map[string][]storage.ImpressionQueueObject)
+			}
+			byFeature[i.FeatureName] = append(byFeature[i.FeatureName], impression)
+			continue
+		}
 		impressionsToStore = append(impressionsToStore, impression)
 	}
 
-	if len(impressionsToStore) > 0 {
-		return r.Push(impressionsToStore)
+	err := r.Push(impressionsToStore)
+	for feature, featureImpressions := range byFeature {
+		if errPush := r.push(featureQueueKey(feature), r.featureTTLs[feature], featureImpressions); errPush != nil {
+			err = errPush
+		}
 	}
-	return nil
+	return err
+}
+
+// featureQueueKey returns the key of the list holding the impressions of a feature with its own TTL
+func featureQueueKey(feature string) string {
+	return strings.Replace(redisFeatureQueue, "{feature}", feature, 1)
 }
 
 // Push stores impressions in redis
 func (r *RedisImpressionStorage) Push(impressions []storage.ImpressionQueueObject) error {
+	return r.push(r.redisKey, r.impressionsTTL, impressions)
+}
+
+// push stores impressions in the list under key, setting it to expire after ttl if it was just created
+func (r *RedisImpressionStorage) push(key string, ttl time.Duration, impressions []storage.ImpressionQueueObject) error {
 	var impressionsJSON []interface{}
 	for _, impression := range impressions {
 		iJSON, err := json.Marshal(impression)
@@ -63,19 +115,22 @@ func (r *RedisImpressionStorage) Push(impressions []storage.ImpressionQueueObjec
 			impressionsJSON = append(impressionsJSON, string(iJSON))
 		}
 	}
+	if len(impressionsJSON) == 0 {
+		return nil
+	}
 
-	r.logger.Debug("Pushing impressions to: ", r.redisKey, len(impressionsJSON))
+	r.logger.Debug("Pushing impressions to: ", key, len(impressionsJSON))
 
-	inserted, errPush := r.client.RPush(r.redisKey, impressionsJSON...)
+	inserted, errPush := r.client.RPush(key, impressionsJSON...)
 	if errPush != nil {
-		r.logger.Error("Something were wrong pushing impressions to redis", errPush)
+		r.dropImpressions(int64(len(impressionsJSON)), errPush)
 		return errPush
 	}
 
 	// Checks if expiration needs to be set
 	if inserted == int64(len(impressionsJSON)) {
-		r.logger.Debug("Proceeding to set expiration for: ", r.redisKey)
-		result := r.client.Expire(r.redisKey, time.Duration(r.impressionsTTL)*time.Minute).Val()
+		r.logger.Debug("Proceeding to set expiration for: ", key)
+		result := r.client.Expire(key, ttl).Val()
 		if result == false {
 			r.logger.Error("Something were wrong setting expiration", errPush)
 		}
@@ -83,34 +138,128 @@ func (r *RedisImpressionStorage) Push(impressions []storage.ImpressionQueueObjec
 	return nil
 }
 
-// PopN return N elements from 0 to N
-func (r *RedisImpressionStorage) PopN(n int64) ([]storage.Impression, error) {
+// dropImpressions accounts for impressions that could not be written to redis. The warning is throttled
+// so that a redis instance under memory pressure doesn't flood the logs on every evaluation
+func (r *RedisImpressionStorage) dropImpressions(count int64, err error) {
+	total := atomic.AddInt64(&r.dropped, count)
+	if batch, ok := r.metrics.(storage.MetricsCounterBatchProducer); ok {
+		batch.IncCounterBy(DroppedCounter, count)
+	} else if r.metrics != nil {
+		for i := int64(0); i < count; i++ {
+			r.metrics.IncCounter(DroppedCounter)
+		}
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&r.lastWarning)
+	if now-last < int64(redisDroppedWarningInterval) || !atomic.CompareAndSwapInt64(&r.lastWarning, last, now) {
+		return
+	}
+
+	reason := "write failed"
+	if strings.HasPrefix(err.Error(), "OOM") {
+		reason = "redis is out of memory"
+	}
+	r.logger.Warning(fmt.Sprintf(
+		"Could not push impressions to redis (%s): %s. %d impressions dropped so far",
+		reason,
+		err.Error(),
+		total,
+	))
+}
+
+// Dropped returns the amount of impressions that could not be written to redis
+func (r *RedisImpressionStorage) Dropped() int64 {
+	return atomic.LoadInt64(&r.dropped)
+}
+
+// Purge removes from the shared impressions queue, and from the lists of the features with their own TTL, every
+// impression stored by this SDK instance, identified by its metadata. Impressions pushed by other instances are left
+// untouched. Each list is read once in batches, up to its length when the purge starts, and the impressions of each
+// batch are removed in a single round trip
+func (r *RedisImpressionStorage) Purge() error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	toReturn := make([]storage.Impression, 0)
 
-	lrange := r.client.LRange(r.redisKey, 0, n-1)
-	if lrange.Err() != nil {
-		r.logger.Error("Fetching impressions", lrange.Err().Error())
-		r.mutex.Unlock()
-		return nil, lrange.Err()
+	if err := r.purge(r.redisKey); err != nil {
+		return err
+	}
+	for feature := range r.featureTTLs {
+		if err := r.purge(featureQueueKey(feature)); err != nil {
+			return err
+		}
 	}
-	totalFetchedImpressions := int64(len(lrange.Val()))
+	return nil
+}
 
-	idxFrom := n
-	if totalFetchedImpressions < n {
-		idxFrom = totalFetchedImpressions
+// purge removes the impressions stored by this SDK instance from the list under key
+func (r *RedisImpressionStorage) purge(key string) error {
+	end, err := r.client.LLen(key).Result()
+	if err != nil {
+		r.logger.Error("Error reading impressions to purge: ", err.Error())
+		return err
 	}
 
-	res := r.client.LTrim(r.redisKey, idxFrom, -1)
-	if res.Err() != nil {
-		r.logger.Error("Trim impressions", res.Err().Error())
-		r.mutex.Unlock()
-		return nil, res.Err()
+	for start := int64(0); start < end; {
+		rawImpressions, err := r.client.LRange(key, start, start+purgeBatchSize-1).Result()
+		if err != nil {
+			r.logger.Error("Error reading impressions to purge: ", err.Error())
+			return err
+		}
+		if len(rawImpressions) == 0 {
+			return nil
+		}
+
+		own := make([]string, 0)
+		for _, rawImpression := range rawImpressions {
+			var impression storage.ImpressionQueueObject
+			if json.Unmarshal([]byte(rawImpression), &impression) == nil && impression.Metadata == r.metadataMessage {
+				own = append(own, rawImpression)
+			}
+		}
+		if len(own) > 0 {
+			err = r.client.TxPipelined(func(p *prefixedPipe) {
+				for _, rawImpression := range own {
+					p.LRem(key, 1, rawImpression)
+				}
+			})
+			if err != nil {
+				r.logger.Error("Error purging impressions: ", err.Error())
+				return err
+			}
+		}
+
+		// The impressions after the ones removed have moved back that many positions
+		start += int64(len(rawImpressions) - len(own))
+		end -= int64(len(own))
+	}
+	return nil
+}
+
+// PopN return N elements from 0 to N, taken from the shared impressions queue first and then from the lists
+// of the features with their own TTL
+func (r *RedisImpressionStorage) PopN(n int64) ([]storage.Impression, error) {
+	// Popped atomically, so that impressions are handed to a single consumer even across processes
+	listOfImpressions, err := r.client.LPopN(r.redisKey, n)
+	if err != nil {
+		r.logger.Error("Popping impressions", err.Error())
+		return nil, err
+	}
+	for feature := range r.featureTTLs {
+		remaining := n - int64(len(listOfImpressions))
+		if remaining <= 0 {
+			break
+		}
+		featureImpressions, err := r.client.LPopN(featureQueueKey(feature), remaining)
+		if err != nil {
+			r.logger.Error("Popping impressions of ", feature, err.Error())
+			continue
+		}
+		listOfImpressions = append(listOfImpressions, featureImpressions...)
 	}
 
 	//JSON unmarshal
-	listOfImpressions := lrange.Val()
+	toReturn := make([]storage.Impression, 0)
 	for _, se := range listOfImpressions {
 		storedImpression := storage.ImpressionQueueObject{}
 		err := json.Unmarshal([]byte(se), &storedImpression)
@@ -10,12 +10,16 @@ import (
 	"strings"
 )
 
+// popKeysBatchSize is the number of keys popped with each pipeline
+const popKeysBatchSize = 100
+
 // RedisMetricsStorage is a redis-based implementation of split storage
 type RedisMetricsStorage struct {
 	client            PrefixedRedisClient
 	logger            logging.LoggerInterface
 	gaugeTemplate     string
 	countersTemplate  string
+	exceptionTemplate string
 	latenciesTemplate string
 	latenciesRegexp   *regexp.Regexp
 }
@@ -26,6 +30,8 @@ func NewRedisMetricsStorage(redisClient *PrefixedRedisClient, metadata *splitio.
 	gaugeTemplate = strings.Replace(gaugeTemplate, "{instanceId}", metadata.MachineName, 1)
 	countersTemplate := strings.Replace(redisCount, "{sdkVersion}", metadata.SDKVersion, 1)
 	countersTemplate = strings.Replace(countersTemplate, "{instanceId}", metadata.MachineName, 1)
+	exceptionTemplate := strings.Replace(redisException, "{sdkVersion}", metadata.SDKVersion, 1)
+	exceptionTemplate = strings.Replace(exceptionTemplate, "{instanceId}", metadata.MachineName, 1)
 	latenciesTemplate := strings.Replace(redisLatency, "{sdkVersion}", metadata.SDKVersion, 1)
 	latenciesTemplate = strings.Replace(latenciesTemplate, "{instanceId}", metadata.MachineName, 1)
 	latencyRegex := regexp.MustCompile(redisLatencyRegex)
@@ -34,11 +40,42 @@ func NewRedisMetricsStorage(redisClient *PrefixedRedisClient, metadata *splitio.
 		logger:            logger,
 		gaugeTemplate:     gaugeTemplate,
 		countersTemplate:  countersTemplate,
+		exceptionTemplate: exceptionTemplate,
 		latenciesTemplate: latenciesTemplate,
 		latenciesRegexp:   latencyRegex,
 	}
 }
 
+// popKeys reads and deletes every key matching pattern, pipelining popKeysBatchSize keys at a time. Each key is
+// still read and deleted atomically, so a key that fails is left in redis for the next pop and no value is ever
+// returned twice
+func (r *RedisMetricsStorage) popKeys(pattern string) (map[string]string, error) {
+	keys, err := r.client.Keys(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += popKeysBatchSize {
+		end := start + popKeysBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+		popped, errs := r.client.GetDelMany(batch)
+		for index, key := range batch {
+			if errs[index] != nil {
+				r.logger.Error(fmt.Sprintf("Could not pop metric key %s: %s", key, errs[index].Error()))
+				continue
+			}
+			if value, ok := popped[index].(string); ok {
+				values[key] = value
+			}
+		}
+	}
+	return values, nil
+}
+
 // PutGauge stores a gauge in redis
 func (r *RedisMetricsStorage) PutGauge(key string, gauge float64) {
 	keyToStore := strings.Replace(r.gaugeTemplate, "{metric}", key, 1)
@@ -52,34 +89,20 @@ func (r *RedisMetricsStorage) PutGauge(key string, gauge float64) {
 func (r *RedisMetricsStorage) PopGauges() []dtos.GaugeDTO {
 	toRemove := strings.Replace(r.gaugeTemplate, "{metric}", "", 1) // String that will be removed from every key
 	rawGauges := make(map[string]float64)
-	err := r.client.WrapTransaction(func(t *prefixedTx) error {
-		keys, err := t.Keys(strings.Replace(r.gaugeTemplate, "{metric}", "*", 1))
+	values, err := r.popKeys(strings.Replace(r.gaugeTemplate, "{metric}", "*", 1))
+	if err != nil {
+		r.logger.Error("Could not retrieve gauge keys from redis")
+		return nil
+	}
+
+	for key, gauge := range values {
+		asFloat, err := strconv.ParseFloat(gauge, 64)
 		if err != nil {
-			r.logger.Error("Could not retrieve gauge keys from redis")
-			return err
+			r.logger.Error("Error parsing gauge as float")
+			continue
 		}
 
-		for _, key := range keys {
-			gauge, err := t.Get(key)
-			if err != nil {
-				r.logger.Error(fmt.Sprintf("Could not retrieve gauges for key %s: %s", key, err))
-				continue
-			}
-
-			asFloat, err := strconv.ParseFloat(gauge, 64)
-			if err != nil {
-				r.logger.Error("Error parsing gauge as float")
-				continue
-			}
-
-			rawGauges[strings.Replace(key, toRemove, "", 1)] = asFloat
-			t.Del(key)
-		}
-		return nil
-	})
-	if err != nil {
-		r.logger.Error(err)
-		return nil
+		rawGauges[strings.Replace(key, toRemove, "", 1)] = asFloat
 	}
 
 	all := make([]dtos.GaugeDTO, len(rawGauges))
@@ -109,52 +132,39 @@ func (r *RedisMetricsStorage) IncLatency(metric string, index int) {
 // PopLatencies returns and clears all gauges in redis.
 func (r *RedisMetricsStorage) PopLatencies() []dtos.LatenciesDTO {
 	latencies := make(map[string][]int64)
-	err := r.client.WrapTransaction(func(t *prefixedTx) error {
-		pattern := strings.Replace(r.latenciesTemplate, "{metric}", "*", 1)
-		pattern = strings.Replace(pattern, "{bucket}", "*", 1)
-		keys, err := t.Keys(pattern)
+	pattern := strings.Replace(r.latenciesTemplate, "{metric}", "*", 1)
+	pattern = strings.Replace(pattern, "{bucket}", "*", 1)
+	values, err := r.popKeys(pattern)
+	if err != nil {
+		r.logger.Error("Could not retrieve latency keys from redis")
+		return nil
+	}
+
+	for key, latency := range values {
+		asInt64, err := strconv.ParseInt(latency, 10, 64)
 		if err != nil {
-			r.logger.Error("Could not retrieve latency keys from redis")
-			return err
+			r.logger.Error("Error parsing latency to int")
+			continue
 		}
 
-		for _, key := range keys {
-			latency, err := t.Get(key)
-			if err != nil {
-				r.logger.Error(fmt.Sprintf("Could not retrieve latency for key %s: %s", key, err.Error()))
-				continue
-			}
-
-			asInt64, err := strconv.ParseInt(latency, 10, 64)
-			if err != nil {
-				r.logger.Error("Error parsing latency to int")
-				continue
-			}
-
-			// We use a regular expression to parse the key and retrieve the
-			// metric name and bucket
-			matches := r.latenciesRegexp.FindStringSubmatch(key)
-			if len(matches) != 3 {
-				r.logger.Error(fmt.Sprintf("Error parsing latency key %s", key))
-				continue
-			}
-			metricName := matches[1]
-			bucket, converr := strconv.ParseInt(matches[2], 10, 64)
-			if converr != nil || bucket > 22 { // TODO: Change 22 to a constant!
-				r.logger.Error(fmt.Sprintf("Invalid bucket %s in key %s", matches[2], key))
-			}
+		// We use a regular expression to parse the key and retrieve the
+		// metric name and bucket
+		matches := r.latenciesRegexp.FindStringSubmatch(key)
+		if len(matches) != 3 {
+			r.logger.Error(fmt.Sprintf("Error parsing latency key %s", key))
+			continue
+		}
+		metricName := matches[1]
+		bucket, converr := strconv.ParseInt(matches[2], 10, 64)
+		if converr != nil || bucket > 22 { // TODO: Change 22 to a constant!
+			r.logger.Error(fmt.Sprintf("Invalid bucket %s in key %s", matches[2], key))
+			continue
+		}
 
-			if _, has := latencies[metricName]; !has {
-				latencies[metricName] = make([]int64, 23) // TODO move 23 to a constant!
-			}
-			latencies[string(metricName)][bucket] = asInt64
-			t.Del(key)
+		if _, has := latencies[metricName]; !has {
+			latencies[metricName] = make([]int64, 23) // TODO move 23 to a constant!
 		}
-		return nil
-	})
-	if err != nil {
-		r.logger.Error(err)
-		return nil
+		latencies[string(metricName)][bucket] = asInt64
 	}
 
 	all := make([]dtos.LatenciesDTO, len(latencies))
@@ -178,40 +188,54 @@ func (r *RedisMetricsStorage) IncCounter(metric string) {
 	}
 }
 
+// IncCounterBy increments the counter of a metric by delta with a single command
+func (r *RedisMetricsStorage) IncCounterBy(metric string, delta int64) {
+	keyToIncr := strings.Replace(r.countersTemplate, "{metric}", metric, 1)
+	err := r.client.IncrBy(keyToIncr, delta)
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Error incrementing counter for metric \"%s\" in redis: %s", metric, err.Error()))
+	}
+}
+
 // PopCounters returns and clears all counters in redis.
 func (r *RedisMetricsStorage) PopCounters() []dtos.CounterDTO {
-	toRemove := strings.Replace(r.countersTemplate, "{metric}", "", 1) // String that will be removed from every key
-	rawCounters := make(map[string]int64)
-	err := r.client.WrapTransaction(func(t *prefixedTx) error {
-		keys, err := t.Keys(strings.Replace(r.countersTemplate, "{metric}", "*", 1))
-		if err != nil {
-			r.logger.Error("Could not retrieve counter keys from redis")
-			return err
-		}
+	return r.popCounters(r.countersTemplate)
+}
 
-		for _, key := range keys {
-			counter, err := t.Get(key)
-			if err != nil {
-				r.logger.Error(fmt.Sprintf("Could not retrieve counters for key %s: %s", key, err.Error()))
-				continue
-			}
+// IncException increases the count of unexpected failures of a specific method
+func (r *RedisMetricsStorage) IncException(method string) {
+	keyToIncr := strings.Replace(r.exceptionTemplate, "{metric}", method, 1)
+	err := r.client.Incr(keyToIncr)
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Error incrementing exceptions for method \"%s\" in redis: %s", method, err.Error()))
+	}
+}
 
-			asInt, err := strconv.ParseInt(counter, 10, 64)
-			if err != nil {
-				r.logger.Error("Error parsing counter as int")
-				continue
-			}
+// PopExceptions returns and clears all exception counters in redis, named after the method that failed.
+func (r *RedisMetricsStorage) PopExceptions() []dtos.CounterDTO {
+	return r.popCounters(r.exceptionTemplate)
+}
 
-			rawCounters[strings.Replace(key, toRemove, "", 1)] = asInt
-			t.Del(key)
-		}
-		return nil
-	})
+// popCounters returns and clears all counters stored with the given template
+func (r *RedisMetricsStorage) popCounters(template string) []dtos.CounterDTO {
+	toRemove := strings.Replace(template, "{metric}", "", 1) // String that will be removed from every key
+	rawCounters := make(map[string]int64)
+	values, err := r.popKeys(strings.Replace(template, "{metric}", "*", 1))
 	if err != nil {
-		r.logger.Error(err)
+		r.logger.Error("Could not retrieve counter keys from redis")
 		return nil
 	}
 
+	for key, counter := range values {
+		asInt, err := strconv.ParseInt(counter, 10, 64)
+		if err != nil {
+			r.logger.Error("Error parsing counter as int")
+			continue
+		}
+
+		rawCounters[strings.Replace(key, toRemove, "", 1)] = asInt
+	}
+
 	all := make([]dtos.CounterDTO, len(rawCounters))
 	allIndex := 0
 	for metric, counter := range rawCounters {
@@ -223,3 +247,25 @@ func (r *RedisMetricsStorage) PopCounters() []dtos.CounterDTO {
 	}
 	return all
 }
+
+// Purge removes every gauge, counter, exception & latency stored by this SDK instance
+func (r *RedisMetricsStorage) Purge() error {
+	patterns := []string{
+		strings.Replace(r.gaugeTemplate, "{metric}", "*", 1),
+		strings.Replace(r.countersTemplate, "{metric}", "*", 1),
+		strings.Replace(r.exceptionTemplate, "{metric}", "*", 1),
+		strings.Replace(strings.Replace(r.latenciesTemplate, "{metric}", "*", 1), "{bucket}", "*", 1),
+	}
+
+	for _, pattern := range patterns {
+		err := r.client.ScanKeys(pattern, purgeBatchSize, func(keys []string) error {
+			_, err := r.client.Del(keys...)
+			return err
+		})
+		if err != nil {
+			r.logger.Error("Error purging metrics: ", err.Error())
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package redisdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/splitio/go-client/splitio"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// RedisImpressionObserverStorage persists the state of an impression observer in a redis hash namespaced
+// by the SDK version & instance name, so that a restarted instance picks up where it left
+type RedisImpressionObserverStorage struct {
+	client   *PrefixedRedisClient
+	logger   logging.LoggerInterface
+	redisKey string
+}
+
+// NewRedisImpressionObserverStorage creates a new RedisImpressionObserverStorage and returns a reference to it
+func NewRedisImpressionObserverStorage(
+	client *PrefixedRedisClient,
+	metadata *splitio.SdkMetadata,
+	logger logging.LoggerInterface,
+) *RedisImpressionObserverStorage {
+	redisKey := strings.Replace(redisObserverState, "{sdkVersion}", metadata.SDKVersion, 1)
+	redisKey = strings.Replace(redisKey, "{instanceId}", metadata.MachineName, 1)
+	return &RedisImpressionObserverStorage{
+		client:   client,
+		logger:   logger,
+		redisKey: redisKey,
+	}
+}
+
+// SaveObserverState replaces the persisted state with the one supplied, expiring it after ttl
+func (r *RedisImpressionObserverStorage) SaveObserverState(state map[string]int64, ttl time.Duration) error {
+	fields := make(map[string]interface{}, len(state))
+	for hash, lastSeen := range state {
+		fields[hash] = lastSeen
+	}
+
+	err := r.client.ReplaceHash(r.redisKey, fields, ttl)
+	if err != nil {
+		r.logger.Error("Error saving impression observer state in redis: ", err.Error())
+	}
+	return err
+}
+
+// LoadObserverState returns the persisted state, which is empty if it has expired or was never saved
+func (r *RedisImpressionObserverStorage) LoadObserverState() (map[string]int64, error) {
+	raw, err := r.client.HGetAll(r.redisKey)
+	if err != nil {
+		r.logger.Error("Error loading impression observer state from redis: ", err.Error())
+		return nil, err
+	}
+
+	state := make(map[string]int64, len(raw))
+	for hash, rawLastSeen := range raw {
+		lastSeen, err := strconv.ParseInt(rawLastSeen, 10, 64)
+		if err != nil {
+			r.logger.Warning(fmt.Sprintf("Skipping impression observer entry %s with invalid time %s", hash, rawLastSeen))
+			continue
+		}
+		state[hash] = lastSeen
+	}
+	return state, nil
+}
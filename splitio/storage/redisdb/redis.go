@@ -1,8 +1,15 @@
 package redisdb
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -11,29 +18,47 @@ import (
 
 // prefixable is a struct intended to be embedded in anything that can have a prefix added.
 // this currently includes a redis client and a redis transaction.
+// If a hash tag is set, it's prepended to every key within braces so that they all map to the same cluster slot
 type prefixable struct {
-	prefix string
+	prefix    string
+	separator string
+	hashTag   string
+}
+
+// newPrefixable returns a prefixable joining the prefix & the keys with the given separator, or "." if it's empty
+func newPrefixable(prefix string, separator string, hashTag string) prefixable {
+	if separator == "" {
+		separator = defaultPrefixSeparator
+	}
+	return prefixable{prefix: prefix, separator: separator, hashTag: hashTag}
 }
 
 // withPrefix adds a prefix to the key if the prefix supplied has a length greater than 0
 func (p *prefixable) withPrefix(key string) string {
 	if len(p.prefix) > 0 {
-		return fmt.Sprintf("%s.%s", p.prefix, key)
+		key = p.prefix + p.separator + key
+	}
+	if len(p.hashTag) > 0 {
+		key = fmt.Sprintf("{%s}%s", p.hashTag, key)
 	}
 	return key
 }
 
 // withoutPrefix removes the prefix from a key if the prefix has a length greater than 0
 func (p *prefixable) withoutPrefix(key string) string {
+	if len(p.hashTag) > 0 {
+		key = strings.TrimPrefix(key, fmt.Sprintf("{%s}", p.hashTag))
+	}
 	if len(p.prefix) > 0 {
-		return strings.Replace(key, fmt.Sprintf("%s.", p.prefix), "", 1)
+		return strings.Replace(key, p.prefix+p.separator, "", 1)
 	}
 	return key
 }
 
 type prefixedTx struct {
 	prefixable
-	tx *redis.Tx
+	tx        *redis.Tx
+	multiSlot bool
 }
 
 // wrap redis "set" operation with a prefix inside a transaction
@@ -46,14 +71,23 @@ func (t *prefixedTx) SAdd(key string, members ...interface{}) error {
 	return t.tx.SAdd(t.withPrefix(key), members...).Err()
 }
 
-// wrap redis "del" operation with a prefix inside a transaction
+// wrap redis "del" operation with a prefix inside a transaction. Keys are deleted one by one if they may map
+// to different cluster slots
 func (t *prefixedTx) Del(keys ...string) error {
 	prefixed := make([]string, 0)
 	for _, key := range keys {
 		prefixed = append(prefixed, t.withPrefix(key))
 	}
 
-	return t.tx.Del(prefixed...).Err()
+	if !t.multiSlot {
+		return t.tx.Del(prefixed...).Err()
+	}
+	for _, key := range prefixed {
+		if err := t.tx.Del(key).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Wraps redis "smembers" operation with a prefix inside a transaction
@@ -77,41 +111,341 @@ func (t *prefixedTx) Get(key string) (string, error) {
 	return res.Val(), res.Err()
 }
 
+// Mget wraps redis "mget" operation with a prefix inside a transaction. Missing keys are returned as nil
+func (t *prefixedTx) Mget(keys []string) ([]interface{}, error) {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, t.withPrefix(key))
+	}
+	return t.tx.MGet(prefixed...).Result()
+}
+
+// Pipelined queues the operations of f to be executed atomically with MULTI/EXEC. If keys are watched by
+// the transaction and any of them was modified, nothing is executed and redis.TxFailedErr is returned
+func (t *prefixedTx) Pipelined(f func(p *prefixedPipe)) error {
+	_, err := t.tx.Pipelined(func(pipe redis.Pipeliner) error {
+		f(&prefixedPipe{prefixable: t.prefixable, pipe: pipe, multiSlot: t.multiSlot})
+		return nil
+	})
+	return err
+}
+
 // newPrefixedPipe instantiates a new pipewrapper and returns a reference
-func newPrefixedTx(tx *redis.Tx, prefix string) *prefixedTx {
+func newPrefixedTx(tx *redis.Tx, prefix prefixable, multiSlot bool) *prefixedTx {
 	return &prefixedTx{
-		prefixable: prefixable{prefix: prefix},
+		prefixable: prefix,
 		tx:         tx,
+		multiSlot:  multiSlot,
 	}
 }
 
+// prefixedPipe queues prefixed operations to be sent in a single MULTI/EXEC round-trip
+type prefixedPipe struct {
+	prefixable
+	pipe      redis.Pipeliner
+	multiSlot bool
+}
+
+// Del queues a redis "del" operation with a prefix. A "del" is queued per key if they may map to different
+// cluster slots
+func (p *prefixedPipe) Del(keys ...string) {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, p.withPrefix(key))
+	}
+	if !p.multiSlot {
+		p.pipe.Del(prefixed...)
+		return
+	}
+	for _, key := range prefixed {
+		p.pipe.Del(key)
+	}
+}
+
+// Set queues a redis "set" operation with a prefix
+func (p *prefixedPipe) Set(key string, value interface{}, expiration time.Duration) {
+	p.pipe.Set(p.withPrefix(key), value, expiration)
+}
+
+// DecrBy queues a redis "decrby" operation with a prefix
+func (p *prefixedPipe) DecrBy(key string, decrement int64) {
+	p.pipe.DecrBy(p.withPrefix(key), decrement)
+}
+
+// LRem queues a redis "lrem" operation with a prefix
+func (p *prefixedPipe) LRem(key string, count int64, value interface{}) {
+	p.pipe.LRem(p.withPrefix(key), count, value)
+}
+
+// SAdd queues a redis "sadd" operation with a prefix
+func (p *prefixedPipe) SAdd(key string, members ...interface{}) {
+	p.pipe.SAdd(p.withPrefix(key), members...)
+}
+
+// SRem queues a redis "srem" operation with a prefix
+func (p *prefixedPipe) SRem(key string, members ...interface{}) {
+	p.pipe.SRem(p.withPrefix(key), members...)
+}
+
 // ---------
 
+// universalClient is implemented by both the single node & the cluster redis clients
+type universalClient interface {
+	redis.UniversalClient
+	PoolStats() *redis.PoolStats
+}
+
 // PrefixedRedisClient is a redis client that adds/remove prefixes in every operation where needed
-// it also uses prefixedPipe for redis trasactions (serialized atomic operations)
+// it also uses prefixedPipe for redis trasactions (serialized atomic operations).
+// multiSlot is set when keys may map to different cluster slots, so that multi-key commands are split.
+// replica is set when reads run through onReplica should be sent to a read replica, and replicaFailedAt holds the
+// time it last failed at. It's shared with the copies bound to a context, so that they all skip a failing replica
 type PrefixedRedisClient struct {
 	prefixable
-	client *redis.Client
+	client          universalClient
+	multiSlot       bool
+	replica         *PrefixedRedisClient
+	replicaFailedAt *int64 // unix nanoseconds, accessed atomically
+}
+
+// newTLSConfig returns the TLSConfig set in the config if any or, if TLSEnabled is set, one built from the
+// CertificateAuthorities, ClientCertificate, ClientKey & ServerName set in the config. Returns nil if TLS is disabled
+func newTLSConfig(config *conf.RedisConfig) (*tls.Config, error) {
+	if config.TLSConfig != nil || !config.TLSEnabled {
+		return config.TLSConfig, nil
+	}
+
+	tlsConfig := &tls.Config{ServerName: config.ServerName}
+	if len(config.CertificateAuthorities) > 0 {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		for _, path := range config.CertificateAuthorities {
+			pem, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("could not read redis certificate authority %s: %s", path, err.Error())
+			}
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no PEM certificates found in redis certificate authority %s", path)
+			}
+		}
+	}
+
+	if config.ClientCertificate != "" || config.ClientKey != "" {
+		if config.ClientCertificate == "" || config.ClientKey == "" {
+			return nil, errors.New("redis ClientCertificate and ClientKey must be set together")
+		}
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertificate, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load redis client certificate: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// newRedisClient returns a client connected to the configured cluster, to the master monitored by the configured
+// sentinels or to the configured host, in that order
+func newRedisClient(config *conf.RedisConfig, tlsConfig *tls.Config) (universalClient, error) {
+	if config.PoolSize < 0 {
+		return nil, errors.New("redis PoolSize must be a non-negative number")
+	}
+
+	if config.Cluster.Enabled() {
+		if config.Host != "" || config.Sentinel.Enabled() {
+			return nil, errors.New("redis Cluster can't be set along with Host or Sentinel, leave them empty to connect to the cluster")
+		}
+		if config.Database != 0 {
+			return nil, errors.New("redis Cluster only supports Database 0")
+		}
+		if config.ReadReplica.Enabled() {
+			return nil, errors.New("redis ReadReplica can't be set along with Cluster")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.Cluster.Addrs,
+			Password:     config.Password,
+			TLSConfig:    tlsConfig,
+			MinIdleConns: config.WarmUpConnections,
+			PoolSize:     config.PoolSize,
+			DialTimeout:  milliseconds(config.DialTimeout),
+			ReadTimeout:  milliseconds(config.ReadTimeout),
+			WriteTimeout: milliseconds(config.WriteTimeout),
+		}), nil
+	}
+
+	if !config.Sentinel.Enabled() {
+		return redis.NewClient(&redis.Options{
+			Addr:      fmt.Sprintf("%s:%d", config.Host, config.Port),
+			Password:  config.Password,
+			DB:        config.Database,
+			TLSConfig: tlsConfig,
+			// The pool dials these connections in the background, WarmUp waits for them
+			MinIdleConns: config.WarmUpConnections,
+			PoolSize:     config.PoolSize,
+			DialTimeout:  milliseconds(config.DialTimeout),
+			ReadTimeout:  milliseconds(config.ReadTimeout),
+			WriteTimeout: milliseconds(config.WriteTimeout),
+		}), nil
+	}
+
+	if config.Host != "" {
+		return nil, errors.New("redis Host and Sentinel can't be set at once, leave Host empty to connect through the sentinels")
+	}
+	if config.Sentinel.MasterName == "" || len(config.Sentinel.SentinelAddrs) == 0 {
+		return nil, errors.New("redis Sentinel requires both a MasterName and at least one address in SentinelAddrs")
+	}
+	return redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    config.Sentinel.MasterName,
+		SentinelAddrs: config.Sentinel.SentinelAddrs,
+		Password:      config.Password,
+		DB:            config.Database,
+		TLSConfig:     tlsConfig,
+		MinIdleConns:  config.WarmUpConnections,
+		PoolSize:      config.PoolSize,
+		DialTimeout:   milliseconds(config.DialTimeout),
+		ReadTimeout:   milliseconds(config.ReadTimeout),
+		WriteTimeout:  milliseconds(config.WriteTimeout),
+	}), nil
+}
+
+// milliseconds converts a timeout set in milliseconds to a duration. 0 is kept so that the library default is used
+func milliseconds(timeout int) time.Duration {
+	return time.Duration(timeout) * time.Millisecond
 }
 
 // NewPrefixedRedisClient returns a new Prefixed Redis Client
 func NewPrefixedRedisClient(config *conf.RedisConfig) (*PrefixedRedisClient, error) {
-	rClient := redis.NewClient(&redis.Options{
-		Addr:      fmt.Sprintf("%s:%d", config.Host, config.Port),
-		Password:  config.Password,
-		DB:        config.Database,
-		TLSConfig: config.TLSConfig,
-	})
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
 
-	err := rClient.Ping().Err()
+	rClient, err := newRedisClient(config, tlsConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return &PrefixedRedisClient{
+	client := &PrefixedRedisClient{
 		client:     rClient,
-		prefixable: prefixable{prefix: config.Prefix},
-	}, nil
+		prefixable: newPrefixable(config.Prefix, config.PrefixSeparator, config.Cluster.KeyHashTag),
+		multiSlot:  config.Cluster.Enabled() && config.Cluster.KeyHashTag == "",
+	}
+
+	if config.ReadReplica.Enabled() {
+		client.replica = &PrefixedRedisClient{
+			client: redis.NewClient(&redis.Options{
+				Addr:         fmt.Sprintf("%s:%d", config.ReadReplica.Host, config.ReadReplica.Port),
+				Password:     config.ReadReplica.Password,
+				DB:           config.Database,
+				TLSConfig:    tlsConfig,
+				PoolSize:     config.PoolSize,
+				DialTimeout:  milliseconds(config.DialTimeout),
+				ReadTimeout:  milliseconds(config.ReadTimeout),
+				WriteTimeout: milliseconds(config.WriteTimeout),
+			}),
+			prefixable: client.prefixable,
+		}
+		client.replicaFailedAt = new(int64)
+	}
+
+	if config.VerifyConnection {
+		err = client.Ping()
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("could not connect to redis at %s: %s", redisAddress(config), err.Error())
+		}
+	}
+
+	return client, nil
+}
+
+// redisAddress describes the server(s) the client connects to, for error messages
+func redisAddress(config *conf.RedisConfig) string {
+	if config.Cluster.Enabled() {
+		return fmt.Sprintf("cluster nodes %s", strings.Join(config.Cluster.Addrs, ", "))
+	}
+	if config.Sentinel.Enabled() {
+		return fmt.Sprintf("master %q through sentinels %s", config.Sentinel.MasterName, strings.Join(config.Sentinel.SentinelAddrs, ", "))
+	}
+	return fmt.Sprintf("%s:%d", config.Host, config.Port)
+}
+
+// Ping checks that redis is reachable, returning the error otherwise
+func (r *PrefixedRedisClient) Ping() error {
+	return r.client.Ping().Err()
+}
+
+// WarmUp waits up to timeout for the pool to hold the given number of idle connections, pinging redis
+// through them as they become available. Returns how many idle connections the pool has afterwards
+func (r *PrefixedRedisClient) WarmUp(connections int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for r.IdleConnections() < connections && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if idle := r.IdleConnections(); idle > 0 {
+		// Concurrent pings check out every idle connection so that broken ones are discarded now
+		errs := make(chan error, idle)
+		for i := 0; i < idle; i++ {
+			go func() { errs <- r.client.Ping().Err() }()
+		}
+		for i := 0; i < idle; i++ {
+			<-errs
+		}
+	}
+	return r.IdleConnections()
+}
+
+// IdleConnections returns the number of idle connections currently held by the pool
+func (r *PrefixedRedisClient) IdleConnections() int {
+	return int(r.client.PoolStats().IdleConns)
+}
+
+// Close closes the connections to redis & the read replica. The client can't be used afterwards
+func (r *PrefixedRedisClient) Close() error {
+	if r.replica != nil {
+		r.replica.Close()
+	}
+	return r.client.Close()
+}
+
+// WithContext returns a copy of the client, and of its read replica, whose commands are bound to ctx. Failures of the
+// read replica seen by the copy are shared with the client
+func (r *PrefixedRedisClient) WithContext(ctx context.Context) *PrefixedRedisClient {
+	client := *r
+	switch underlying := r.client.(type) {
+	case *redis.Client:
+		client.client = underlying.WithContext(ctx)
+	case *redis.ClusterClient:
+		client.client = underlying.WithContext(ctx)
+	}
+	if r.replica != nil {
+		client.replica = r.replica.WithContext(ctx)
+	}
+	return &client
+}
+
+// Context returns the context the commands of the client are bound to
+func (r *PrefixedRedisClient) Context() context.Context {
+	switch underlying := r.client.(type) {
+	case *redis.Client:
+		return underlying.Context()
+	case *redis.ClusterClient:
+		return underlying.Context()
+	}
+	return context.Background()
+}
+
+// onReplica runs a read against the read replica if there's one, falling back to the primary if the replica fails.
+// After a failure the replica is skipped for replicaRetryInterval so that reads don't keep waiting for it
+func (r *PrefixedRedisClient) onReplica(read func(c *PrefixedRedisClient) error) error {
+	if r.replica == nil || time.Since(time.Unix(0, atomic.LoadInt64(r.replicaFailedAt))) < replicaRetryInterval {
+		return read(r)
+	}
+
+	err := read(r.replica)
+	if err == nil || err == redis.Nil {
+		return err
+	}
+	atomic.StoreInt64(r.replicaFailedAt, time.Now().UnixNano())
+	return read(r)
 }
 
 // Get wraps aound redis get method by adding prefix and returning string and error directly
@@ -124,9 +458,10 @@ func (r *PrefixedRedisClient) Set(key string, value interface{}, expiration time
 	return r.client.Set(r.withPrefix(key), value, expiration).Err()
 }
 
-// Keys wraps around redis keys method by adding prefix and returning []string and error directly
+// Keys wraps around redis keys method by adding prefix and returning []string and error directly.
+// In a cluster every master is asked for its keys
 func (r *PrefixedRedisClient) Keys(pattern string) ([]string, error) {
-	keys, err := r.client.Keys(r.withPrefix(pattern)).Result()
+	keys, err := r.keys(r.withPrefix(pattern))
 	if err != nil {
 		return nil, err
 	}
@@ -139,13 +474,94 @@ func (r *PrefixedRedisClient) Keys(pattern string) ([]string, error) {
 
 }
 
-// Del wraps around redis del method by adding prefix and returning int64 and error directly
+func (r *PrefixedRedisClient) keys(pattern string) ([]string, error) {
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return r.client.Keys(pattern).Result()
+	}
+
+	var keys []string
+	var mutex sync.Mutex
+	err := cluster.ForEachMaster(func(master *redis.Client) error {
+		masterKeys, err := master.Keys(pattern).Result()
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		keys = append(keys, masterKeys...)
+		mutex.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
+// ScanKeys enumerates the keys matching pattern with SCAN, passing them to f without prefix in batches of roughly
+// count. In a cluster every master is scanned. A key may be passed more than once if keys are added or removed
+// while they're being enumerated. Enumeration stops at the first error returned by f
+func (r *PrefixedRedisClient) ScanKeys(pattern string, count int64, f func(keys []string) error) error {
+	scan := func(client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(cursor, r.withPrefix(pattern), count).Result()
+			if err != nil {
+				return err
+			}
+
+			if len(keys) > 0 {
+				woPrefix := make([]string, len(keys))
+				for index, key := range keys {
+					woPrefix[index] = r.withoutPrefix(key)
+				}
+				if err = f(woPrefix); err != nil {
+					return err
+				}
+			}
+
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return scan(r.client)
+	}
+	var mutex sync.Mutex
+	return cluster.ForEachMaster(func(master *redis.Client) error {
+		// f isn't required to be safe for concurrent use
+		mutex.Lock()
+		defer mutex.Unlock()
+		return scan(master)
+	})
+}
+
+// Del wraps around redis del method by adding prefix and returning int64 and error directly. If the keys may map
+// to different cluster slots, where DEL fails, they're deleted with a pipelined DEL each instead
 func (r *PrefixedRedisClient) Del(keys ...string) (int64, error) {
 	prefixedKeys := make([]string, len(keys))
 	for i, k := range keys {
 		prefixedKeys[i] = r.withPrefix(k)
 	}
-	return r.client.Del(prefixedKeys...).Result()
+	if !r.multiSlot {
+		return r.client.Del(prefixedKeys...).Result()
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(prefixedKeys))
+	for _, key := range prefixedKeys {
+		cmds = append(cmds, pipe.Del(key))
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return deleted, nil
 }
 
 // SMembers returns a slice with all the members of a set
@@ -153,6 +569,11 @@ func (r *PrefixedRedisClient) SMembers(key string) ([]string, error) {
 	return r.client.SMembers(r.withPrefix(key)).Result()
 }
 
+// SCard returns the number of members of a set
+func (r *PrefixedRedisClient) SCard(key string) (int64, error) {
+	return r.client.SCard(r.withPrefix(key)).Result()
+}
+
 // SIsMember returns a slice with all the members of a set
 func (r *PrefixedRedisClient) SIsMember(key string, item interface{}) (bool, error) {
 	return r.client.SIsMember(r.withPrefix(key), item).Result()
@@ -168,6 +589,11 @@ func (r *PrefixedRedisClient) SRem(key string, members ...string) (int64, error)
 	return r.client.SRem(r.withPrefix(key), members).Result()
 }
 
+// SScan iterates over the members of the set stored at key, returning a batch of members and the next cursor
+func (r *PrefixedRedisClient) SScan(key string, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	return r.client.SScan(r.withPrefix(key), cursor, match, count).Result()
+}
+
 // Exists returns true if a key exists in redis
 func (r *PrefixedRedisClient) Exists(key string) (bool, error) {
 	val, err := r.client.Exists(r.withPrefix(key)).Result()
@@ -179,12 +605,34 @@ func (r *PrefixedRedisClient) Incr(key string) error {
 	return r.client.Incr(r.withPrefix(key)).Err()
 }
 
+// IncrBy increments a key by delta. Sets it in delta if it doesn't exist
+func (r *PrefixedRedisClient) IncrBy(key string, delta int64) error {
+	return r.client.IncrBy(r.withPrefix(key), delta).Err()
+}
+
 // WrapTransaction accepts a function that performs a set of operations that will
-// be serialized and executed atomically. The function passed will recive a prefixedPipe
-func (r *PrefixedRedisClient) WrapTransaction(f func(t *prefixedTx) error) error {
+// be serialized and executed atomically. The function passed will recive a prefixedPipe.
+// If keys are given they're watched, so that the writes queued through prefixedTx.Pipelined
+// fail with redis.TxFailedErr if any of them is modified before they're executed.
+// Keys watched together must map to the same cluster slot, so none should be given if multiSlot is set
+func (r *PrefixedRedisClient) WrapTransaction(f func(t *prefixedTx) error, keys ...string) error {
+	prefixedKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixedKeys = append(prefixedKeys, r.withPrefix(key))
+	}
 	return r.client.Watch(func(tx *redis.Tx) error {
-		return f(newPrefixedTx(tx, r.prefix))
+		return f(newPrefixedTx(tx, r.prefixable, r.multiSlot))
+	}, prefixedKeys...)
+}
+
+// TxPipelined accepts a function that queues a set of operations that will be sent in a single
+// round-trip and executed atomically
+func (r *PrefixedRedisClient) TxPipelined(f func(p *prefixedPipe)) error {
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		f(&prefixedPipe{prefixable: r.prefixable, pipe: pipe, multiSlot: r.multiSlot})
+		return nil
 	})
+	return err
 }
 
 // RPush insert all the specified values at the tail of the list stored at key
@@ -197,6 +645,25 @@ func (r *PrefixedRedisClient) LRange(key string, start, stop int64) *redis.Strin
 	return r.client.LRange(r.withPrefix(key), start, stop)
 }
 
+// LPopN atomically removes and returns up to n elements from the head of the list stored at key. Both the LRANGE
+// and the LTRIM are sent within a MULTI/EXEC transaction, so that concurrent callers never get the same elements
+func (r *PrefixedRedisClient) LPopN(key string, n int64) ([]string, error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	prefixed := r.withPrefix(key)
+	var lrange *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		lrange = pipe.LRange(prefixed, 0, n-1)
+		pipe.LTrim(prefixed, n, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lrange.Val(), nil
+}
+
 // LTrim Trim an existing list so that it will contain only the specified range of elements specified
 func (r *PrefixedRedisClient) LTrim(key string, start, stop int64) *redis.StatusCmd {
 	return r.client.LTrim(r.withPrefix(key), start, stop)
@@ -217,11 +684,125 @@ func (r *PrefixedRedisClient) TTL(key string) *redis.DurationCmd {
 	return r.client.TTL(r.withPrefix(key))
 }
 
-// Mget fetchs multiple results
+// Mget fetchs multiple results. If the keys may map to different cluster slots, where MGET fails, they're
+// fetched with a pipelined GET each instead. Missing keys are returned as nil either way
 func (r *PrefixedRedisClient) Mget(keys []string) ([]interface{}, error) {
 	keysWithPrefix := make([]string, 0)
 	for _, key := range keys {
 		keysWithPrefix = append(keysWithPrefix, r.withPrefix(key))
 	}
-	return r.client.MGet(keysWithPrefix...).Result()
+	if !r.multiSlot {
+		return r.client.MGet(keysWithPrefix...).Result()
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(keysWithPrefix))
+	for _, key := range keysWithPrefix {
+		cmds = append(cmds, pipe.Get(key))
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, len(cmds))
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, cmd.Val())
+	}
+	return values, nil
+}
+
+// getDelScript reads and deletes a key atomically. GETDEL isn't available before redis 6.2
+var getDelScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+redis.call('DEL', KEYS[1])
+return value
+`)
+
+// GetDel atomically returns the value of a key and deletes it. If an error is returned the key is left untouched
+func (r *PrefixedRedisClient) GetDel(key string) (string, error) {
+	return getDelScript.Run(r.client, []string{r.withPrefix(key)}).String()
+}
+
+// GetDelMany pipelines a GetDel for each key, so that every key is still read and deleted atomically, and returns
+// the values & errors in the same order as the keys. Missing keys get a nil value and a nil error
+func (r *PrefixedRedisClient) GetDelMany(keys []string) ([]interface{}, []error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, getDelScript.Eval(pipe, []string{r.withPrefix(key)}))
+	}
+	pipe.Exec()
+
+	values := make([]interface{}, 0, len(cmds))
+	errs := make([]error, 0, len(cmds))
+	for _, cmd := range cmds {
+		value, err := cmd.Result()
+		if err == redis.Nil {
+			value, err = nil, nil
+		}
+		values = append(values, value)
+		errs = append(errs, err)
+	}
+	return values, errs
+}
+
+// HGetAll wraps around redis hgetall method by adding prefix and returning the hash and error directly
+func (r *PrefixedRedisClient) HGetAll(key string) (map[string]string, error) {
+	return r.client.HGetAll(r.withPrefix(key)).Result()
+}
+
+// ReplaceHash atomically replaces the contents of a hash and sets it to expire after ttl
+func (r *PrefixedRedisClient) ReplaceHash(key string, fields map[string]interface{}, ttl time.Duration) error {
+	prefixed := r.withPrefix(key)
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(prefixed)
+		if len(fields) > 0 {
+			pipe.HMSet(prefixed, fields)
+			pipe.Expire(prefixed, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// SCardMany pipelines a SCARD for each key and returns the cardinalities in the same order as the keys
+func (r *PrefixedRedisClient) SCardMany(keys []string) ([]int64, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipe.SCard(r.withPrefix(key)))
+	}
+	_, err := pipe.Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, 0, len(cmds))
+	for _, cmd := range cmds {
+		counts = append(counts, cmd.Val())
+	}
+	return counts, nil
+}
+
+// SIsMemberMany pipelines a SISMEMBER for each member and returns the results in the same order as the members
+func (r *PrefixedRedisClient) SIsMemberMany(key string, members []string) ([]bool, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.BoolCmd, 0, len(members))
+	for _, member := range members {
+		cmds = append(cmds, pipe.SIsMember(r.withPrefix(key), member))
+	}
+	_, err := pipe.Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, 0, len(cmds))
+	for _, cmd := range cmds {
+		results = append(results, cmd.Val())
+	}
+	return results, nil
 }
@@ -1,7 +1,9 @@
 package redisdb
 
 import (
+	"context"
 	"fmt"
+	"github.com/splitio/go-client/splitio/storage"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
 	"strconv"
@@ -10,41 +12,148 @@ import (
 
 // RedisSegmentStorage is a redis implementation of a storage for segments
 type RedisSegmentStorage struct {
-	client PrefixedRedisClient
+	client *PrefixedRedisClient
 	logger logging.LoggerInterface
 }
 
 // NewRedisSegmentStorage creates a new RedisSegmentStorage and returns a reference to it
 func NewRedisSegmentStorage(redisClient *PrefixedRedisClient, logger logging.LoggerInterface) *RedisSegmentStorage {
 	return &RedisSegmentStorage{
-		client: *redisClient,
+		client: redisClient,
 		logger: logger,
 	}
 }
 
-// Get returns a segment wrapped in a set
+// WithContext returns a copy of the storage whose redis commands are bound to ctx, so that they're abandoned
+// when it's cancelled
+func (r *RedisSegmentStorage) WithContext(ctx context.Context) storage.SegmentStorage {
+	return &RedisSegmentStorage{client: r.client.WithContext(ctx), logger: r.logger}
+}
+
+// Get returns a segment wrapped in a set. Every member is fetched with a single SMEMBERS and held in memory,
+// which is unsafe for very large segments. Use SegmentContainsKey to check membership or StreamKeys to enumerate them
 func (r *RedisSegmentStorage) Get(segmentName string) *set.ThreadUnsafeSet {
-	keyToFetch := strings.Replace(redisSegment, "{segment}", segmentName, 1)
-	segmentKeys, err := r.client.SMembers(keyToFetch)
-	if len(segmentKeys) <= 0 {
+	segment, err := r.Keys(segmentName)
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Error retrieving memebers from set %s", segmentName))
+		return nil
+	}
+	if segment.IsEmpty() {
 		r.logger.Warning(fmt.Sprintf("Nonexsitant segment requested: \"%s\"", segmentName))
 		return nil
 	}
+	return segment
+}
+
+// Keys returns the members of a segment wrapped in a set, like Get, but returns the error if they can't be
+// fetched. Segments that are not present in storage are returned empty
+func (r *RedisSegmentStorage) Keys(segmentName string) (*set.ThreadUnsafeSet, error) {
+	keyToFetch := strings.Replace(redisSegment, "{segment}", segmentName, 1)
+	segmentKeys, err := r.client.SMembers(keyToFetch)
 	if err != nil {
-		r.logger.Error(fmt.Sprintf("Error retrieving memebers from set %s", segmentName))
-		return nil
+		return nil, err
 	}
 	segment := set.NewSet()
 	for _, member := range segmentKeys {
 		segment.Add(member)
 	}
-	return segment
+	return segment, nil
+}
+
+// SegmentKeysCount returns the amount of keys in a segment with a single SCARD, without fetching them.
+// Segments that are not present in storage have 0 keys
+func (r *RedisSegmentStorage) SegmentKeysCount(segmentName string) (int64, error) {
+	segmentKey := strings.Replace(redisSegment, "{segment}", segmentName, 1)
+	return r.client.SCard(segmentKey)
 }
 
-// SegmentContainsKey returns true if the segment contains a specific key
+// SegmentContainsKey returns true if the segment contains a specific key, reading it from the read replica if any
 func (r *RedisSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
 	segmentKey := strings.Replace(redisSegment, "{segment}", segmentName, 1)
-	return r.client.SIsMember(segmentKey, key)
+	var contains bool
+	err := r.client.onReplica(func(c *PrefixedRedisClient) (err error) {
+		contains, err = c.SIsMember(segmentKey, key)
+		return err
+	})
+	return contains, err
+}
+
+// SegmentContainsKeys returns whether the segment contains each one of the keys, checked in a single round trip
+func (r *RedisSegmentStorage) SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error) {
+	segmentKey := strings.Replace(redisSegment, "{segment}", segmentName, 1)
+	var members []bool
+	err := r.client.onReplica(func(c *PrefixedRedisClient) (err error) {
+		members, err = c.SIsMemberMany(segmentKey, keys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := make(map[string]bool, len(keys))
+	for index, key := range keys {
+		memberships[key] = members[index]
+	}
+	return memberships, nil
+}
+
+// StreamKeys enumerates the members of a segment with SSCAN, passing them to f in batches of roughly batchSize,
+// so that large segments are never fully loaded in memory. A member may be passed more than once if the segment
+// is modified while it's being enumerated. Enumeration stops at the first error returned by f
+func (r *RedisSegmentStorage) StreamKeys(segmentName string, batchSize int64, f func(keys []string) error) error {
+	segmentKey := strings.Replace(redisSegment, "{segment}", segmentName, 1)
+	var cursor uint64
+	for {
+		keys, next, err := r.client.SScan(segmentKey, cursor, "", batchSize)
+		if err != nil {
+			r.logger.Error(fmt.Sprintf("Error scanning members of segment %s: %s", segmentName, err.Error()))
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err = f(keys); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// SegmentSizes returns the amount of keys of each segment, fetched with a single round trip for the counts
+// and another one for the changeNumbers. Segments that are not present in storage are not included in the result
+func (r *RedisSegmentStorage) SegmentSizes(segmentNames []string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	if len(segmentNames) == 0 {
+		return sizes, nil
+	}
+
+	segmentKeys := make([]string, 0, len(segmentNames))
+	tillKeys := make([]string, 0, len(segmentNames))
+	for _, segmentName := range segmentNames {
+		segmentKeys = append(segmentKeys, strings.Replace(redisSegment, "{segment}", segmentName, 1))
+		tillKeys = append(tillKeys, strings.Replace(redisSegmentTill, "{segment}", segmentName, 1))
+	}
+
+	tills, err := r.client.Mget(tillKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := r.client.SCardMany(segmentKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for index, segmentName := range segmentNames {
+		if tills[index] != nil {
+			sizes[segmentName] = counts[index]
+		}
+	}
+	return sizes, nil
 }
 
 // Put (over)writes a segment in redis with the one passed to this function
@@ -1,11 +1,15 @@
 package redisdb
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/go-redis/redis"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
@@ -25,47 +29,94 @@ func NewRedisSplitStorage(redisClient *PrefixedRedisClient, logger logging.Logge
 	}
 }
 
-// Get fetches a feature in redis and returns a pointer to a split dto
+// WithContext returns a copy of the storage whose redis commands are bound to ctx, so that they're abandoned
+// when it's cancelled
+func (r *RedisSplitStorage) WithContext(ctx context.Context) *RedisSplitStorage {
+	return &RedisSplitStorage{client: r.client.WithContext(ctx), logger: r.logger}
+}
+
+// Get fetches a feature in redis, or its read replica, and returns a pointer to a split dto. It returns nil both
+// when the feature is missing and when it can't be fetched, logging the error in the latter case
 func (r *RedisSplitStorage) Get(feature string) *dtos.SplitDTO {
+	split, err := r.GetWithError(feature)
+	if err != nil {
+		r.logger.Error(err.Error())
+		return nil
+	}
+	return split
+}
+
+// GetWithError fetches a feature in redis, or its read replica. A missing feature returns nil without an error,
+// whereas failing to fetch it or to parse it returns the error
+func (r *RedisSplitStorage) GetWithError(feature string) (*dtos.SplitDTO, error) {
 	keyToFetch := strings.Replace(redisSplit, "{split}", feature, 1)
-	val, err := r.client.Get(keyToFetch)
+	var val string
+	err := r.client.onReplica(func(c *PrefixedRedisClient) (err error) {
+		val, err = c.Get(keyToFetch)
+		return err
+	})
 
+	if err == redis.Nil {
+		return nil, nil
+	}
 	if err != nil {
-		r.logger.Error(fmt.Sprintf("Could not fetch feature \"%s\" from redis: %s", feature, err.Error()))
-		return nil
+		return nil, fmt.Errorf("could not fetch feature \"%s\" from redis: %s", feature, err.Error())
 	}
 
 	var split dtos.SplitDTO
 	err = json.Unmarshal([]byte(val), &split)
 	if err != nil {
-		r.logger.Error(fmt.Sprintf("Could not parse feature \"%s\" fetched from redis", feature))
-		return nil
+		return nil, fmt.Errorf("could not parse feature \"%s\" fetched from redis: %s", feature, err.Error())
 	}
 
-	return &split
+	return &split, nil
 }
 
-// FetchMany retrieves features from redis storage
+// FetchMany retrieves features from redis storage, or its read replica
 func (r *RedisSplitStorage) FetchMany(features []string) map[string]*dtos.SplitDTO {
-	keysToFetch := make([]string, 0)
-	for _, feature := range features {
-		keysToFetch = append(keysToFetch, strings.Replace(redisSplit, "{split}", feature, 1))
+	var rawSplits []interface{}
+	err := r.client.onReplica(func(c *PrefixedRedisClient) (err error) {
+		rawSplits, err = c.Mget(splitKeys(features))
+		return err
+	})
+
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Could not fetch features from redis: %s", err.Error()))
+		return nil
 	}
-	rawSplits, err := r.client.Mget(keysToFetch)
+	return r.parseSplits(features, rawSplits)
+}
 
+// fetchManyFromPrimary retrieves features from the primary, never from the read replica, for updates that must
+// not be based on the view of a lagging replica
+func (r *RedisSplitStorage) fetchManyFromPrimary(features []string) map[string]*dtos.SplitDTO {
+	rawSplits, err := r.client.Mget(splitKeys(features))
 	if err != nil {
 		r.logger.Error(fmt.Sprintf("Could not fetch features from redis: %s", err.Error()))
 		return nil
 	}
+	return r.parseSplits(features, rawSplits)
+}
 
+// splitKeys returns the keys the features are stored under
+func splitKeys(features []string) []string {
+	keys := make([]string, 0, len(features))
+	for _, feature := range features {
+		keys = append(keys, strings.Replace(redisSplit, "{split}", feature, 1))
+	}
+	return keys
+}
+
+// parseSplits parses the raw splits fetched for the features, in the same order. Missing features are nil
+func (r *RedisSplitStorage) parseSplits(features []string, rawSplits []interface{}) map[string]*dtos.SplitDTO {
 	splits := make(map[string]*dtos.SplitDTO)
 	for idx, feature := range features {
 		var split *dtos.SplitDTO
 		rawSplit, ok := rawSplits[idx].(string)
 		if ok {
-			err = json.Unmarshal([]byte(rawSplit), &split)
+			err := json.Unmarshal([]byte(rawSplit), &split)
 			if err != nil {
-				r.logger.Error("Could not parse feature \"%s\" fetched from redis", feature)
+				r.logger.Error(fmt.Sprintf("Could not parse feature \"%s\" fetched from redis", feature))
 				return nil
 			}
 		}
@@ -75,8 +126,17 @@ func (r *RedisSplitStorage) FetchMany(features []string) map[string]*dtos.SplitD
 	return splits
 }
 
-// PutMany bulk stores splits in redis
+// PutMany bulk stores splits in redis, moving them to the flag sets they now belong to
 func (r *RedisSplitStorage) PutMany(splits []dtos.SplitDTO, changeNumber int64) {
+	var previous map[string]*dtos.SplitDTO
+	if len(splits) > 0 {
+		names := make([]string, 0, len(splits))
+		for _, split := range splits {
+			names = append(names, split.Name)
+		}
+		previous = r.fetchManyFromPrimary(names)
+	}
+
 	for _, split := range splits {
 		keyToStore := strings.Replace(redisSplit, "{split}", split.Name, 1)
 		raw, err := json.Marshal(split)
@@ -90,19 +150,95 @@ func (r *RedisSplitStorage) PutMany(splits []dtos.SplitDTO, changeNumber int64)
 			r.logger.Error(fmt.Sprintf("Could not store split \"%s\" in redis: %s", split.Name, err.Error()))
 		}
 	}
+	r.updateFlagSets(previous, splits)
 	err := r.client.Set(redisSplitTill, changeNumber, 0)
 	if err != nil {
 		r.logger.Error("Could not update split changenumber")
 	}
 }
 
-// Remove revemoves a split from redis
+// updateFlagSets removes the stored splits from the flag sets they belonged to & adds them to their current ones
+func (r *RedisSplitStorage) updateFlagSets(previous map[string]*dtos.SplitDTO, splits []dtos.SplitDTO) {
+	removed := make(map[string][]interface{})
+	added := make(map[string][]interface{})
+	for _, split := range splits {
+		if existing := previous[split.Name]; existing != nil {
+			for _, flagSet := range existing.Sets {
+				removed[flagSet] = append(removed[flagSet], split.Name)
+			}
+		}
+		for _, flagSet := range split.Sets {
+			added[flagSet] = append(added[flagSet], split.Name)
+		}
+	}
+	if len(removed) == 0 && len(added) == 0 {
+		return
+	}
+
+	err := r.client.TxPipelined(func(p *prefixedPipe) {
+		for flagSet, names := range removed {
+			p.SRem(strings.Replace(redisFlagSet, "{flagSet}", flagSet, 1), names...)
+		}
+		for flagSet, names := range added {
+			p.SAdd(strings.Replace(redisFlagSet, "{flagSet}", flagSet, 1), names...)
+		}
+	})
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Could not update the flag sets of %d splits: %s", len(splits), err.Error()))
+	}
+}
+
+// Remove revemoves a split from redis, along with its flag sets membership, decrementing the counter of its
+// traffic type
 func (r *RedisSplitStorage) Remove(splitName string) {
-	keyToDelete := strings.Replace(redisSplit, "{split}", splitName, 1)
-	_, err := r.client.Del(keyToDelete)
+	r.RemoveMany([]string{splitName})
+}
+
+// RemoveMany removes several splits in a single transaction, along with their flag sets membership, decrementing
+// the counters of their traffic types. Only the splits removed are read. Names of splits that aren't stored are skipped
+func (r *RedisSplitStorage) RemoveMany(splitNames []string) error {
+	if len(splitNames) == 0 {
+		return nil
+	}
+	splits := r.fetchManyFromPrimary(splitNames)
+	if splits == nil {
+		r.logger.Error("Could not fetch the splits to remove, none removed")
+		return errors.New("could not fetch the splits to remove")
+	}
+
+	keys := make([]string, 0, len(splitNames))
+	trafficTypes := make(map[string]int64)
+	flagSets := make(map[string][]interface{})
+	for name, split := range splits {
+		if split == nil {
+			continue
+		}
+		keys = append(keys, strings.Replace(redisSplit, "{split}", name, 1))
+		if split.TrafficTypeName != "" {
+			trafficTypes[split.TrafficTypeName]++
+		}
+		for _, flagSet := range split.Sets {
+			flagSets[flagSet] = append(flagSets[flagSet], name)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := r.client.TxPipelined(func(p *prefixedPipe) {
+		p.Del(keys...)
+		for trafficType, count := range trafficTypes {
+			p.DecrBy(strings.Replace(redisTrafficType, "{trafficType}", trafficType, 1), count)
+		}
+		for flagSet, names := range flagSets {
+			p.SRem(strings.Replace(redisFlagSet, "{flagSet}", flagSet, 1), names...)
+		}
+	})
 	if err != nil {
-		r.logger.Error(fmt.Sprintf("Error deleting split \"%s\".", splitName))
+		r.logger.Error(fmt.Sprintf("Error removing %d splits: %s", len(keys), err.Error()))
 	}
+	return err
 }
 
 // Till returns the latest split changeNumber
@@ -119,6 +255,12 @@ func (r *RedisSplitStorage) Till() int64 {
 	return asInt
 }
 
+// HasChangeNumber returns true if the split changeNumber written by the synchronizer
+// exists under the configured prefix
+func (r *RedisSplitStorage) HasChangeNumber() (bool, error) {
+	return r.client.Exists(redisSplitTill)
+}
+
 // SplitNames returns a slice of strings with all the split names
 func (r *RedisSplitStorage) SplitNames() []string {
 	splitNames := make([]string, 0)
@@ -195,13 +337,30 @@ func (r *RedisSplitStorage) GetAll() []dtos.SplitDTO {
 	return splits
 }
 
-// Clear removes all splits from storage
+// SplitsChangedSince returns the splits whose change number is greater than sinceChangeNumber, sorted by change number
+func (r *RedisSplitStorage) SplitsChangedSince(sinceChangeNumber int64) []dtos.SplitDTO {
+	changed := make([]dtos.SplitDTO, 0)
+	for _, split := range r.GetAll() {
+		if split.ChangeNumber > sinceChangeNumber {
+			changed = append(changed, split)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].ChangeNumber < changed[j].ChangeNumber })
+	return changed
+}
+
+// Clear removes all splits & flag sets from storage
 func (r *RedisSplitStorage) Clear() {
 	r.client.WrapTransaction(func(t *prefixedTx) error {
 		keys, err := t.Keys(strings.Replace(redisSplit, "{split}", "*", 1))
 		if err != nil {
 			return err
 		}
+		flagSetKeys, err := t.Keys(strings.Replace(redisFlagSet, "{flagSet}", "*", 1))
+		if err != nil {
+			return err
+		}
+		keys = append(keys, flagSetKeys...)
 
 		if len(keys) > 0 {
 			err = t.Del(keys...)
@@ -211,6 +370,17 @@ func (r *RedisSplitStorage) Clear() {
 	})
 }
 
+// SplitNamesByFlagSet returns the names of the splits in a flag set, sorted. Empty if the flag set is unknown
+func (r *RedisSplitStorage) SplitNamesByFlagSet(flagSet string) []string {
+	splitNames, err := r.client.SMembers(strings.Replace(redisFlagSet, "{flagSet}", flagSet, 1))
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("Could not fetch flag set \"%s\" from redis: %s", flagSet, err.Error()))
+		return []string{}
+	}
+	sort.Strings(splitNames)
+	return splitNames
+}
+
 // TrafficTypeExists returns true or false depending on existence and counter
 // of trafficType
 func (r *RedisSplitStorage) TrafficTypeExists(trafficType string) bool {
@@ -229,3 +399,129 @@ func (r *RedisSplitStorage) TrafficTypeExists(trafficType string) bool {
 	}
 	return val > 0
 }
+
+// TrafficTypeCount returns the counter of splits of a traffic type, 0 if there's none
+func (r *RedisSplitStorage) TrafficTypeCount(trafficType string) (int64, error) {
+	res, err := r.client.Get(strings.Replace(redisTrafficType, "{trafficType}", trafficType, 1))
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(res, 10, 64)
+}
+
+// ReconcileTrafficTypes recomputes the counter of each traffic type from the splits stored, correcting the ones
+// that drifted, ie: because a split moved to another traffic type without decrementing the old counter.
+// Counters of traffic types no split belongs to are removed. Splits & counters are read from the primary and watched
+// along with the split changeNumber, so that nothing is corrected if the synchronizer updates them meanwhile, unless
+// they're spread over several cluster slots
+func (r *RedisSplitStorage) ReconcileTrafficTypes() error {
+	storedKeys, err := r.client.Keys(strings.Replace(redisSplit, "{split}", "*", 1))
+	if err != nil {
+		r.logger.Error("Could not fetch split keys to reconcile traffic types: ", err.Error())
+		return err
+	}
+	counterKeys, err := r.client.Keys(strings.Replace(redisTrafficType, "{trafficType}", "*", 1))
+	if err != nil {
+		r.logger.Error("Could not fetch traffic type keys to reconcile them: ", err.Error())
+		return err
+	}
+
+	var stale []string
+	var drifted, current map[string]int64
+	correct := func(mget func(keys []string) ([]interface{}, error), pipelined func(f func(p *prefixedPipe)) error) error {
+		stale, drifted, current, err = trafficTypeCorrections(storedKeys, counterKeys, mget)
+		if err != nil || (len(stale) == 0 && len(drifted) == 0) {
+			return err
+		}
+		return pipelined(func(p *prefixedPipe) {
+			if len(stale) > 0 {
+				p.Del(stale...)
+			}
+			for key, count := range drifted {
+				p.Set(key, count, 0)
+			}
+		})
+	}
+	if r.client.multiSlot {
+		// Keys spread over several cluster slots can't be watched together, so they're corrected without watching them
+		err = correct(r.client.Mget, r.client.TxPipelined)
+	} else {
+		watched := append(append([]string{redisSplitTill}, storedKeys...), counterKeys...)
+		err = r.client.WrapTransaction(func(t *prefixedTx) error {
+			return correct(t.Mget, t.Pipelined)
+		}, watched...)
+	}
+	if err == redis.TxFailedErr {
+		r.logger.Warning("Splits changed while reconciling traffic types, counters will be reconciled next time")
+		return err
+	}
+	if err != nil {
+		r.logger.Error("Could not correct traffic type counters: ", err.Error())
+		return err
+	}
+
+	for _, key := range stale {
+		r.logger.Warning(fmt.Sprintf("Removed counter %s, as no split belongs to its traffic type (was %d)", key, current[key]))
+	}
+	for key, count := range drifted {
+		r.logger.Warning(fmt.Sprintf("Corrected counter %s from %d to %d", key, current[key], count))
+	}
+	return nil
+}
+
+// trafficTypeCorrections compares the traffic type counters stored under counterKeys with the number of splits
+// stored under storedKeys that belong to each traffic type, returning the counters no split belongs to, the ones
+// that drifted with the count they should have, and the current value of every counter
+func trafficTypeCorrections(
+	storedKeys []string,
+	counterKeys []string,
+	mget func(keys []string) ([]interface{}, error),
+) ([]string, map[string]int64, map[string]int64, error) {
+	expected := make(map[string]int64)
+	if len(storedKeys) > 0 {
+		rawSplits, err := mget(storedKeys)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, raw := range rawSplits {
+			rawSplit, ok := raw.(string)
+			if !ok {
+				continue
+			}
+			var split dtos.SplitDTO
+			if json.Unmarshal([]byte(rawSplit), &split) == nil && split.TrafficTypeName != "" {
+				expected[strings.Replace(redisTrafficType, "{trafficType}", split.TrafficTypeName, 1)]++
+			}
+		}
+	}
+
+	current := make(map[string]int64, len(counterKeys))
+	if len(counterKeys) > 0 {
+		counters, err := mget(counterKeys)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for index, key := range counterKeys {
+			if raw, ok := counters[index].(string); ok {
+				current[key], _ = strconv.ParseInt(raw, 10, 64)
+			}
+		}
+	}
+
+	stale := make([]string, 0)
+	for key := range current {
+		if _, ok := expected[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	drifted := make(map[string]int64)
+	for key, count := range expected {
+		if previous, ok := current[key]; !ok || previous != count {
+			drifted[key] = count
+		}
+	}
+	return stale, drifted, current, nil
+}
@@ -1,14 +1,32 @@
 package redisdb
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-redis/redis"
 	"github.com/splitio/go-client/splitio"
 	"github.com/splitio/go-client/splitio/conf"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
 )
@@ -294,6 +312,18 @@ func TestSegmentStorage(t *testing.T) {
 		}
 	}
 
+	memberships, err := segmentStorage.SegmentContainsKeys("segment2", []string{"item4", "item1", "item6"})
+	if err != nil || len(memberships) != 3 || !memberships["item4"] || memberships["item1"] || !memberships["item6"] {
+		t.Error("Incorrect memberships for segment2", memberships, err)
+	}
+	memberships, err = segmentStorage.SegmentContainsKeys("unknownSegment", []string{"item1", "item4"})
+	if err != nil || len(memberships) != 2 || memberships["item1"] || memberships["item4"] {
+		t.Error("No key should be contained in an unknown segment", memberships, err)
+	}
+	if contained, err := segmentStorage.SegmentContainsKey("unknownSegment", "item1"); err != nil || contained {
+		t.Error("No key should be contained in an unknown segment", err)
+	}
+
 	if segmentStorage.Till("segment1") != 123 || segmentStorage.Till("segment2") != 124 {
 		t.Error("Incorrect till stored")
 	}
@@ -315,6 +345,19 @@ func TestSegmentStorage(t *testing.T) {
 		t.Error(segmentStorage.Till("segment1"))
 	}
 
+	segmentStorage.Put("emptySegment", set.NewSet(), 333)
+	sizes, err := segmentStorage.SegmentSizes([]string{"segment1", "segment2", "emptySegment"})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(sizes) != 2 || sizes["segment2"] != 3 || sizes["emptySegment"] != 0 {
+		t.Error("Incorrect segment sizes", sizes)
+	}
+	if _, ok := sizes["segment1"]; ok {
+		t.Error("Removed segments should not be reported")
+	}
+	segmentStorage.Remove("emptySegment")
+
 	// To test the .Clear() method we add a couple of segments and random keys
 	// we check that the segments are the deleted but the other keys remain intact
 	segmentStorage.Put("segment1", set.NewSet("item1", "item2"), 222)
@@ -408,6 +451,35 @@ func TestImpressionStorage(t *testing.T) {
 	}
 }
 
+func TestImpressionStorageWriteFailure(t *testing.T) {
+	// A client pointing to a closed port makes every write fail
+	failingClient := &PrefixedRedisClient{
+		client:     redis.NewClient(&redis.Options{Addr: "localhost:1", MaxRetries: 0}),
+		prefixable: newPrefixable("testPrefix", "", ""),
+	}
+	metadata := &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}
+	metrics := mutexmap.NewMMMetricsStorage()
+	impressionStorage := NewRedisImpressionStorageWithTTLs(failingClient, metadata, 0, nil, metrics, logging.NewLogger(&logging.LoggerOptions{}))
+
+	impression := storage.Impression{FeatureName: "feature1", KeyName: "key1", Treatment: "on"}
+	err := impressionStorage.LogImpressions([]storage.Impression{impression, impression})
+	if err == nil {
+		t.Error("An error should be returned when the push fails")
+	}
+
+	err = impressionStorage.LogImpressions([]storage.Impression{impression})
+	if err == nil {
+		t.Error("An error should be returned when the push fails")
+	}
+
+	if impressionStorage.Dropped() != 3 {
+		t.Error("Dropped impressions should be counted. Got:", impressionStorage.Dropped())
+	}
+	if counters := metrics.PopCounters(); len(counters) != 1 || counters[0].MetricName != DroppedCounter || counters[0].Count != 3 {
+		t.Error("Dropped impressions should be reported as a counter. Got:", counters)
+	}
+}
+
 func TestMetricsStorage(t *testing.T) {
 	logger := logging.NewLogger(&logging.LoggerOptions{})
 	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
@@ -560,6 +632,36 @@ func TestMetricsStorage(t *testing.T) {
 	).Val() != 0 {
 		t.Error("Counter keys should have been removed after PopAll()")
 	}
+
+	// Exceptions
+	metricsStorage.IncException("sdk.getTreatment")
+	metricsStorage.IncException("sdk.getTreatment")
+	metricsStorage.IncException("sdk.getTreatments")
+	metricsStorage.IncCounter("count1")
+
+	if metricsStorage.client.client.Exists(
+		"testPrefix.SPLITIO/go-test/instance123/exception.sdk.getTreatment",
+		"testPrefix.SPLITIO/go-test/instance123/exception.sdk.getTreatments",
+	).Val() != 2 {
+		t.Error("Incorrect exception keys stored in redis")
+	}
+
+	exceptions := metricsStorage.PopExceptions()
+	byMethod := make(map[string]int64)
+	for _, exception := range exceptions {
+		byMethod[exception.MetricName] = exception.Count
+	}
+	if len(exceptions) != 2 || byMethod["sdk.getTreatment"] != 2 || byMethod["sdk.getTreatments"] != 1 {
+		t.Error("Incorrect exceptions fetched", exceptions)
+	}
+	if len(metricsStorage.PopExceptions()) != 0 {
+		t.Error("Exception keys should have been removed after PopExceptions()")
+	}
+
+	counters = metricsStorage.PopCounters()
+	if len(counters) != 1 || counters[0].MetricName != "count1" || counters[0].Count != 1 {
+		t.Error("Exceptions should not be popped along with counters", counters)
+	}
 }
 
 func TestTrafficTypeStorage(t *testing.T) {
@@ -586,3 +688,1032 @@ func TestTrafficTypeStorage(t *testing.T) {
 
 	ttStorage.client.client.Del("testPrefix.SPLITIO.trafficType.mytraffictype")
 }
+
+func TestLargeSegmentStorage(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Password: "",
+		Prefix:   "testPrefix",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	segmentStorage := NewRedisSegmentStorage(prefixedClient, logger)
+	defer segmentStorage.Remove("large")
+
+	members := set.NewSet()
+	for i := 0; i < 50000; i++ {
+		members.Add(fmt.Sprintf("key%d", i))
+	}
+	segmentStorage.Put("large", members, 123)
+
+	commands := make([]string, 0)
+	prefixedClient.client.WrapProcess(func(old func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			commands = append(commands, strings.ToLower(cmd.Name()))
+			return old(cmd)
+		}
+	})
+
+	for _, key := range []string{"key0", "key49999", "key50000"} {
+		contained, err := segmentStorage.SegmentContainsKey("large", key)
+		if err != nil || contained != (key != "key50000") {
+			t.Error("Wrong membership for", key, contained, err)
+		}
+	}
+
+	for _, command := range commands {
+		if command != "sismember" {
+			t.Error("Membership checks should only use SISMEMBER, got", command)
+		}
+	}
+
+	commands = commands[:0]
+	streamed := set.NewSet()
+	largestBatch := 0
+	err = segmentStorage.StreamKeys("large", 1000, func(keys []string) error {
+		if len(keys) > largestBatch {
+			largestBatch = len(keys)
+		}
+		for _, key := range keys {
+			streamed.Add(key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error("Streaming should not fail", err)
+	}
+
+	if streamed.Size() != 50000 {
+		t.Error("Every member should have been streamed, got", streamed.Size())
+	}
+
+	if largestBatch >= 50000 {
+		t.Error("Members should have been streamed in batches")
+	}
+
+	for _, command := range commands {
+		if command != "sscan" {
+			t.Error("Streaming should only use SSCAN, got", command)
+		}
+	}
+}
+
+func TestRedisWarmUp(t *testing.T) {
+	client, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:              "localhost",
+		Port:              6379,
+		WarmUpConnections: 4,
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer client.client.Close()
+
+	if idle := client.WarmUp(4, 5*time.Second); idle < 4 {
+		t.Error("The pool should hold the warmed up connections", idle)
+	}
+
+	if idle := client.IdleConnections(); idle < 4 {
+		t.Error("Warmed up connections should remain idle in the pool", idle)
+	}
+}
+
+func TestMetricsPopFailure(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testPopFailure",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	metricsStorage := NewRedisMetricsStorage(prefixedClient, &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}, logger)
+	defer metricsStorage.Purge()
+
+	metricsStorage.IncCounter("c1")
+	metricsStorage.IncCounter("c1")
+	metricsStorage.IncCounter("c2")
+	// A key that can't be read with GET makes popping it fail without affecting the rest
+	broken := "testPopFailure.SPLITIO/go-test/instance123/count.broken"
+	prefixedClient.client.SAdd(broken, "value")
+
+	counters := make(map[string]int64)
+	for _, counter := range metricsStorage.PopCounters() {
+		counters[counter.MetricName] = counter.Count
+	}
+	if len(counters) != 2 || counters["c1"] != 2 || counters["c2"] != 1 {
+		t.Error("Counters that could be popped should be returned", counters)
+	}
+
+	if prefixedClient.client.Exists(
+		"testPopFailure.SPLITIO/go-test/instance123/count.c1",
+		"testPopFailure.SPLITIO/go-test/instance123/count.c2",
+	).Val() != 0 {
+		t.Error("Popped counters should have been removed")
+	}
+	if prefixedClient.client.Exists(broken).Val() != 1 {
+		t.Error("A key that failed to be popped should be left untouched")
+	}
+
+	metricsStorage.IncCounter("c1")
+	counters = make(map[string]int64)
+	for _, counter := range metricsStorage.PopCounters() {
+		counters[counter.MetricName] = counter.Count
+	}
+	if len(counters) != 1 || counters["c1"] != 1 {
+		t.Error("Counters popped before should not be counted twice", counters)
+	}
+}
+
+func TestMetricsPopManyKeys(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testPopMany",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	metricsStorage := NewRedisMetricsStorage(prefixedClient, &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}, logger)
+	defer metricsStorage.Purge()
+
+	total := 2*popKeysBatchSize + 1
+	for i := 0; i < total; i++ {
+		metricsStorage.IncCounterBy(fmt.Sprintf("c%d", i), int64(i+1))
+	}
+	counters := metricsStorage.PopCounters()
+	if len(counters) != total {
+		t.Error("Every counter should be popped across batches. Got:", len(counters))
+	}
+	for _, counter := range counters {
+		var index int64
+		fmt.Sscanf(counter.MetricName, "c%d", &index)
+		if counter.Count != index+1 {
+			t.Error("Unexpected count for", counter.MetricName, counter.Count)
+		}
+	}
+	if keys, _ := prefixedClient.Keys("SPLITIO/go-test/instance123/count.*"); len(keys) != 0 {
+		t.Error("Every popped counter should have been removed. Got:", len(keys))
+	}
+
+	prefixedClient.Set("popMany.value", "1", 0)
+	prefixedClient.client.SAdd("testPopMany.popMany.broken", "value")
+	defer prefixedClient.Del("popMany.broken")
+	values, errs := prefixedClient.GetDelMany([]string{"popMany.value", "popMany.missing", "popMany.broken"})
+	if len(values) != 3 || values[0] != "1" || values[1] != nil || values[2] != nil {
+		t.Error("Values should be returned in order with nil for missing & failed keys. Got:", values)
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[1] != nil || errs[2] == nil {
+		t.Error("Only the key that couldn't be popped should get an error. Got:", errs)
+	}
+	if exists, _ := prefixedClient.Exists("popMany.value"); exists {
+		t.Error("Popped keys should be deleted")
+	}
+}
+
+func TestRedisSplitStorageChangedSince(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testChangedSince",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	splitStorage := NewRedisSplitStorage(prefixedClient, logger)
+	defer splitStorage.Clear()
+
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "old", ChangeNumber: 10},
+		{Name: "newest", ChangeNumber: 40},
+		{Name: "newer", ChangeNumber: 30},
+	}, 40)
+
+	changed := splitStorage.SplitsChangedSince(20)
+	if len(changed) != 2 || changed[0].Name != "newer" || changed[1].Name != "newest" {
+		t.Error("Only splits newer than the change number should be returned, sorted by change number", changed)
+	}
+
+	if changed = splitStorage.SplitsChangedSince(40); len(changed) != 0 {
+		t.Error("Nothing should be returned if nothing changed", changed)
+	}
+}
+
+func TestRedisSplitStorageRemoveMany(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testRemoveMany",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	splitStorage := NewRedisSplitStorage(prefixedClient, logger)
+	defer splitStorage.Clear()
+
+	inSegment := func(segment string) []dtos.ConditionDTO {
+		return []dtos.ConditionDTO{{MatcherGroup: dtos.MatcherGroupDTO{Matchers: []dtos.MatcherDTO{
+			{UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: segment}},
+		}}}}
+	}
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "split1", TrafficTypeName: "user", Conditions: inSegment("employees"), Sets: []string{"backend"}},
+		{Name: "split2", TrafficTypeName: "user", Conditions: inSegment("beta"), Sets: []string{"backend"}},
+		{Name: "split3", TrafficTypeName: "user", Conditions: inSegment("beta"), Sets: []string{"backend"}},
+		{Name: "split4", TrafficTypeName: "account"},
+		{Name: "split5", TrafficTypeName: "account", Sets: []string{"frontend"}},
+	}, 10)
+	prefixedClient.Set("SPLITIO.trafficType.user", 3, 0)
+	prefixedClient.Set("SPLITIO.trafficType.account", 2, 0)
+	defer prefixedClient.Del("SPLITIO.trafficType.user", "SPLITIO.trafficType.account")
+
+	if err := splitStorage.RemoveMany([]string{"split1", "split2", "split4", "nonexistent"}); err != nil {
+		t.Error("Removing splits should not fail", err)
+	}
+
+	names := splitStorage.SplitNames()
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "split3" || names[1] != "split5" {
+		t.Error("Only split3 & split5 should remain", names)
+	}
+	if count, _ := prefixedClient.Get("SPLITIO.trafficType.user"); count != "1" {
+		t.Error("Only removed splits should decrement the counter", count)
+	}
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 1 || names[0] != "split3" {
+		t.Error("Removed splits should leave their flag sets", names)
+	}
+
+	// Removing a single split keeps the counters consistent as well
+	splitStorage.Remove("split5")
+	if splitStorage.TrafficTypeExists("account") {
+		t.Error("Traffic type counters should be decremented by the number of removed splits")
+	}
+	if names := splitStorage.SplitNamesByFlagSet("frontend"); len(names) != 0 {
+		t.Error("Removed splits should leave their flag sets", names)
+	}
+
+	if err := splitStorage.RemoveMany([]string{"nonexistent"}); err != nil {
+		t.Error("Removing nonexistent splits should be a no-op", err)
+	}
+}
+
+func TestImpressionStoragePurge(t *testing.T) {
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "purge",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer prefixedClient.Del(redisImpressionsQueue)
+
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	own := NewRedisImpressionStorage(prefixedClient, &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "own"}, logger)
+	other := NewRedisImpressionStorage(prefixedClient, &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "other"}, logger)
+
+	// Interleaved over several batches, so that removals shift the impressions still to be read
+	for i := 0; i < 2*purgeBatchSize+10; i++ {
+		impressions := []storage.Impression{{FeatureName: "feature", KeyName: fmt.Sprintf("key%d", i), Treatment: "on"}}
+		if i%3 == 0 {
+			other.LogImpressions(impressions)
+		} else {
+			own.LogImpressions(impressions)
+		}
+	}
+
+	if err := own.Purge(); err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	rawImpressions, _ := prefixedClient.LRange(redisImpressionsQueue, 0, -1).Result()
+	if len(rawImpressions) != (2*purgeBatchSize+10+2)/3 {
+		t.Error("Only the impressions of other instances should be kept. Got:", len(rawImpressions))
+	}
+	for _, rawImpression := range rawImpressions {
+		if !strings.Contains(rawImpression, `"n":"other"`) {
+			t.Error("Impressions of this instance should have been removed. Got:", rawImpression)
+			break
+		}
+	}
+}
+
+func TestImpressionStorageFeatureTTLs(t *testing.T) {
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "featureTTLs",
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer prefixedClient.Del(redisImpressionsQueue, featureQueueKey("highVolume"))
+
+	metadata := &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}
+	impressionStorage := NewRedisImpressionStorageWithTTLs(
+		prefixedClient,
+		metadata,
+		0,
+		map[string]time.Duration{"highVolume": 5 * time.Minute},
+		nil,
+		logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone}),
+	)
+
+	impressionStorage.LogImpressions([]storage.Impression{
+		{FeatureName: "highVolume", KeyName: "key1", Treatment: "on"},
+		{FeatureName: "other", KeyName: "key1", Treatment: "off"},
+	})
+
+	overridden := prefixedClient.TTL(featureQueueKey("highVolume")).Val()
+	if overridden <= 0 || overridden > 5*time.Minute {
+		t.Error("The impressions of highVolume should expire after its own TTL. Got:", overridden)
+	}
+	if length := prefixedClient.LLen(featureQueueKey("highVolume")).Val(); length != 1 {
+		t.Error("The impression of highVolume should be stored in its own list. Got:", length)
+	}
+
+	standard := prefixedClient.TTL(redisImpressionsQueue).Val()
+	if standard <= 5*time.Minute || standard > time.Duration(redisImpressionsTTL)*time.Minute {
+		t.Error("The impressions of other features should expire after the default TTL. Got:", standard)
+	}
+	if length := prefixedClient.LLen(redisImpressionsQueue).Val(); length != 1 {
+		t.Error("The impression of other features should be stored in the default list. Got:", length)
+	}
+
+	impressions, err := impressionStorage.PopN(1)
+	if err != nil || len(impressions) != 1 || impressions[0].FeatureName != "other" {
+		t.Error("The shared queue should be drained first. Got:", impressions, err)
+	}
+	impressions, err = impressionStorage.PopN(10)
+	if err != nil || len(impressions) != 1 || impressions[0].FeatureName != "highVolume" {
+		t.Error("The lists of the features with their own TTL should be drained too. Got:", impressions, err)
+	}
+
+	impressionStorage.LogImpressions([]storage.Impression{{FeatureName: "highVolume", KeyName: "key2", Treatment: "on"}})
+	if err := impressionStorage.Purge(); err != nil {
+		t.Error(err)
+	}
+	if length := prefixedClient.LLen(featureQueueKey("highVolume")).Val(); length != 0 {
+		t.Error("Purge should remove the impressions of the features with their own TTL. Got:", length)
+	}
+}
+
+func TestPrefixedRedisClientSentinel(t *testing.T) {
+	_, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Sentinel: conf.SentinelConfig{MasterName: "mymaster", SentinelAddrs: []string{"localhost:26379"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Host and Sentinel can't be set at once") {
+		t.Error("Setting both a host and sentinels should fail. Got:", err)
+	}
+
+	_, err = NewPrefixedRedisClient(&conf.RedisConfig{Sentinel: conf.SentinelConfig{SentinelAddrs: []string{"localhost:26379"}}})
+	if err == nil || !strings.Contains(err.Error(), "requires both a MasterName") {
+		t.Error("Sentinels without a master name should fail. Got:", err)
+	}
+
+	// No sentinel listens on this port, so the master can't be discovered
+	_, err = NewPrefixedRedisClient(&conf.RedisConfig{
+		Prefix:           "sentinel",
+		Sentinel:         conf.SentinelConfig{MasterName: "mymaster", SentinelAddrs: []string{"localhost:1"}},
+		VerifyConnection: true,
+	})
+	if err == nil {
+		t.Error("An error should be returned if the master can't be discovered")
+	}
+}
+
+func TestPrefixedRedisClientCluster(t *testing.T) {
+	_, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:    "localhost",
+		Cluster: conf.ClusterConfig{Addrs: []string{"localhost:7000"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "can't be set along with Host or Sentinel") {
+		t.Error("Setting both a host and a cluster should fail. Got:", err)
+	}
+
+	_, err = NewPrefixedRedisClient(&conf.RedisConfig{Database: 1, Cluster: conf.ClusterConfig{Addrs: []string{"localhost:7000"}}})
+	if err == nil || !strings.Contains(err.Error(), "only supports Database 0") {
+		t.Error("A cluster with a database other than 0 should fail. Got:", err)
+	}
+
+	// Hash tags are prepended to the prefixed keys and removed when listing them
+	client := &PrefixedRedisClient{
+		prefixable: newPrefixable("cluster", "", "splitio"),
+		client:     redis.NewClient(&redis.Options{Addr: "localhost:6379"}),
+	}
+	client.Set("SPLITIO.split.feature1", "value1", 0)
+	defer client.Del("SPLITIO.split.feature1")
+	if client.client.Get("{splitio}cluster.SPLITIO.split.feature1").Val() != "value1" {
+		t.Error("The key should be stored with the hash tag")
+	}
+	keys, _ := client.Keys("SPLITIO.split.*")
+	if len(keys) != 1 || keys[0] != "SPLITIO.split.feature1" {
+		t.Error("Keys should be returned without hash tag nor prefix. Got:", keys)
+	}
+
+	// Keys spread over several slots are fetched one by one
+	client.multiSlot = true
+	client.Set("SPLITIO.split.feature2", "value2", 0)
+	defer client.Del("SPLITIO.split.feature2")
+	values, err := client.Mget([]string{"SPLITIO.split.feature1", "SPLITIO.split.missing", "SPLITIO.split.feature2"})
+	if err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	if len(values) != 3 || values[0] != "value1" || values[1] != nil || values[2] != "value2" {
+		t.Error("Values should be returned in order with nil for missing keys. Got:", values)
+	}
+
+	// And deleted one by one
+	client.Set("SPLITIO.split.feature3", "value3", 0)
+	client.Set("SPLITIO.split.feature4", "value4", 0)
+	if deleted, err := client.Del("SPLITIO.split.feature1", "SPLITIO.split.missing", "SPLITIO.split.feature2"); deleted != 2 || err != nil {
+		t.Error("Existing keys should be deleted. Got:", deleted, err)
+	}
+	err = client.TxPipelined(func(p *prefixedPipe) { p.Del("SPLITIO.split.feature3", "SPLITIO.split.feature4") })
+	if err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	if keys, _ := client.Keys("SPLITIO.split.*"); len(keys) != 0 {
+		t.Error("Every key should have been deleted. Got:", keys)
+	}
+}
+
+func TestPrefixedRedisClientPoolSettings(t *testing.T) {
+	_, err := NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, PoolSize: -1})
+	if err == nil || !strings.Contains(err.Error(), "PoolSize must be a non-negative number") {
+		t.Error("A negative pool size should fail. Got:", err)
+	}
+
+	client, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:         "localhost",
+		Port:         6379,
+		PoolSize:     3,
+		DialTimeout:  500,
+		ReadTimeout:  200,
+		WriteTimeout: 200,
+	})
+	if err != nil {
+		t.Fatal("No error should be returned. Got:", err)
+	}
+	defer client.Close()
+	options := client.client.(*redis.Client).Options()
+	if options.PoolSize != 3 || options.DialTimeout != 500*time.Millisecond ||
+		options.ReadTimeout != 200*time.Millisecond || options.WriteTimeout != 200*time.Millisecond {
+		t.Error("The pool settings should be passed to the redis client. Got:", options)
+	}
+
+	client, err = NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379})
+	if err != nil {
+		t.Fatal("No error should be returned. Got:", err)
+	}
+	defer client.Close()
+	if options := client.client.(*redis.Client).Options(); options.PoolSize <= 0 || options.ReadTimeout != 3*time.Second {
+		t.Error("The library defaults should be kept when unset. Got:", options)
+	}
+}
+
+func TestSegmentKeysCount(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, Prefix: "segmentCount"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	segmentStorage := NewRedisSegmentStorage(prefixedClient, logger)
+	segmentStorage.Put("segment1", set.NewSet("item1", "item2", "item3"), 123)
+	defer segmentStorage.Remove("segment1")
+
+	if count, err := segmentStorage.SegmentKeysCount("segment1"); err != nil || count != 3 {
+		t.Error("The segment should have 3 keys. Got:", count, err)
+	}
+	if count, err := segmentStorage.SegmentKeysCount("missing"); err != nil || count != 0 {
+		t.Error("Missing segments should have 0 keys. Got:", count, err)
+	}
+
+	keys, err := segmentStorage.Keys("segment1")
+	if err != nil || !keys.IsEqual(set.NewSet("item1", "item2", "item3")) {
+		t.Error("The segment keys should be returned. Got:", keys, err)
+	}
+	if keys, err := segmentStorage.Keys("missing"); err != nil || !keys.IsEmpty() {
+		t.Error("Missing segments should be returned empty. Got:", keys, err)
+	}
+
+	// A key of another type makes the set commands fail
+	prefixedClient.Set("SPLITIO.segment.wrongType", "value", 0)
+	defer prefixedClient.Del("SPLITIO.segment.wrongType")
+	if _, err := segmentStorage.SegmentKeysCount("wrongType"); err == nil {
+		t.Error("The error should be returned")
+	}
+	if _, err := segmentStorage.Keys("wrongType"); err == nil {
+		t.Error("The error should be returned")
+	}
+	if segmentStorage.Get("wrongType") != nil {
+		t.Error("Get should still return nil on errors")
+	}
+}
+
+func TestImpressionStorageConcurrentPopN(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, Prefix: "concurrentPop"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	metadata := &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}
+	impressionStorage := NewRedisImpressionStorage(prefixedClient, metadata, logger)
+	defer prefixedClient.Del(impressionStorage.redisKey)
+
+	const total = 1000
+	impressions := make([]storage.Impression, 0, total)
+	for i := 0; i < total; i++ {
+		impressions = append(impressions, storage.Impression{KeyName: strconv.Itoa(i), FeatureName: "feature", Treatment: "on"})
+	}
+	impressionStorage.LogImpressions(impressions)
+
+	// Each consumer has its own storage, as separate processes would
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[string]int, total)
+	for consumer := 0; consumer < 2; consumer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumerStorage := NewRedisImpressionStorage(prefixedClient, metadata, logger)
+			for {
+				popped, err := consumerStorage.PopN(7)
+				if err != nil {
+					t.Error("No error should be returned. Got:", err)
+					return
+				}
+				if len(popped) == 0 {
+					return
+				}
+				mutex.Lock()
+				for _, impression := range popped {
+					seen[impression.KeyName]++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != total {
+		t.Error("Every impression should have been popped. Got:", len(seen))
+	}
+	for key, times := range seen {
+		if times != 1 {
+			t.Errorf("Impression %s was popped %d times", key, times)
+		}
+	}
+}
+
+func TestPrefixedRedisClientVerifyConnection(t *testing.T) {
+	// Nothing listens on this port
+	client, err := NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 1})
+	if err != nil {
+		t.Fatal("The connection should not be verified unless asked to. Got:", err)
+	}
+	if client.Ping() == nil {
+		t.Error("Ping should fail if redis is unreachable")
+	}
+	client.Close()
+
+	_, err = NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 1, VerifyConnection: true})
+	if err == nil || !strings.Contains(err.Error(), "could not connect to redis at localhost:1") {
+		t.Error("An unreachable redis should fail naming its host and port. Got:", err)
+	}
+
+	client, err = NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, VerifyConnection: true})
+	if err != nil {
+		t.Fatal("No error should be returned. Got:", err)
+	}
+	defer client.Close()
+	if err := client.Ping(); err != nil {
+		t.Error("Ping should succeed if redis is reachable. Got:", err)
+	}
+}
+
+func TestPrefixSeparator(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:            "localhost",
+		Port:            6379,
+		Prefix:          "separator",
+		PrefixSeparator: ":",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer prefixedClient.Close()
+
+	splitStorage := NewRedisSplitStorage(prefixedClient, logger)
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split1"}, {Name: "split2"}}, 123)
+	defer prefixedClient.Del(redisSplitTill)
+	if prefixedClient.client.Exists("separator:SPLITIO.split.split1", "separator:SPLITIO.split.split2").Val() != 2 {
+		t.Error("Splits should be stored with the separator between the prefix and the key")
+	}
+	fetched := splitStorage.FetchMany([]string{"split1", "split2"})
+	if len(fetched) != 2 || fetched["split1"] == nil || fetched["split2"] == nil {
+		t.Error("Splits should be fetched with the separator. Got:", fetched)
+	}
+	if names := splitStorage.SplitNames(); len(names) != 2 {
+		t.Error("Split names should be returned without prefix nor separator. Got:", names)
+	}
+
+	prefixedClient.client.Set("separator:SPLITIO.trafficType.user", 1, 0)
+	defer prefixedClient.client.Del("separator:SPLITIO.trafficType.user")
+	if !splitStorage.TrafficTypeExists("user") {
+		t.Error("Traffic types should be read with the separator")
+	}
+
+	splitStorage.Clear()
+	if prefixedClient.client.Exists("separator:SPLITIO.split.split1", "separator:SPLITIO.split.split2").Val() != 0 {
+		t.Error("Splits stored with the separator should be cleared")
+	}
+
+	metricsStorage := NewRedisMetricsStorage(prefixedClient, &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}, logger)
+	metricsStorage.IncCounter("count1")
+	if prefixedClient.client.Exists("separator:SPLITIO/go-test/instance123/count.count1").Val() != 1 {
+		t.Error("Metrics should be stored with the separator")
+	}
+	if counters := metricsStorage.PopCounters(); len(counters) != 1 || counters[0].MetricName != "count1" {
+		t.Error("Metrics stored with the separator should be popped. Got:", counters)
+	}
+}
+
+func TestRedisSplitStorageFlagSets(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testFlagSets",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	splitStorage := NewRedisSplitStorage(prefixedClient, logger)
+	defer prefixedClient.Del(redisSplitTill)
+	defer splitStorage.Clear()
+
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "split1", Sets: []string{"backend", "frontend"}},
+		{Name: "split2", Sets: []string{"backend"}},
+		{Name: "split3"},
+	}, 1)
+
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 2 || names[0] != "split1" || names[1] != "split2" {
+		t.Error("Splits should be indexed by flag set. Got:", names)
+	}
+	if names := splitStorage.SplitNamesByFlagSet("unknown"); names == nil || len(names) != 0 {
+		t.Error("Unknown flag sets should have no splits. Got:", names)
+	}
+
+	// Updates move splits between flag sets
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split1", Sets: []string{"frontend"}}}, 2)
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 1 || names[0] != "split2" {
+		t.Error("Updated splits should be removed from the flag sets they left. Got:", names)
+	}
+
+	splitStorage.Remove("split1")
+	if names := splitStorage.SplitNamesByFlagSet("frontend"); len(names) != 0 {
+		t.Error("Removed splits should be removed from their flag sets. Got:", names)
+	}
+
+	if err := splitStorage.RemoveMany([]string{"split2"}); err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 0 {
+		t.Error("Splits removed in bulk should be removed from their flag sets. Got:", names)
+	}
+
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split4", Sets: []string{"backend"}}}, 3)
+	splitStorage.Clear()
+	if keys, _ := prefixedClient.Keys("SPLITIO.flagSet.*"); len(keys) != 0 {
+		t.Error("Flag sets should be cleared along with the splits. Got:", keys)
+	}
+}
+
+func TestReadReplica(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	_, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Cluster:     conf.ClusterConfig{Addrs: []string{"localhost:7000"}},
+		ReadReplica: conf.ReadReplicaConfig{Host: "localhost", Port: 6380},
+	})
+	if err == nil || !strings.Contains(err.Error(), "ReadReplica can't be set along with Cluster") {
+		t.Error("A replica along with a cluster should fail. Got:", err)
+	}
+
+	primary, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:        "localhost",
+		Port:        6379,
+		Database:    1,
+		Prefix:      "readReplica",
+		ReadReplica: conf.ReadReplicaConfig{Host: "localhost", Port: 6379},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer primary.Close()
+	if primary.replica == nil {
+		t.Fatal("A replica client should be created")
+	}
+
+	// The replica is emulated by another database so that reads can be told apart
+	primary.replica.client.Close()
+	primary.replica.client = redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 2})
+	splitStorage := NewRedisSplitStorage(primary, logger)
+	segmentStorage := NewRedisSegmentStorage(primary, logger)
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split1", ChangeNumber: 1}}, 1)
+	defer primary.Del("SPLITIO.split.split1", redisSplitTill)
+	primary.replica.Set("SPLITIO.split.split1", `{"name":"split1","changeNumber":2}`, 0)
+	defer primary.replica.Del("SPLITIO.split.split1")
+	primary.replica.SAdd("SPLITIO.segment.employees", "key1")
+	defer primary.replica.Del("SPLITIO.segment.employees")
+
+	if split := splitStorage.Get("split1"); split == nil || split.ChangeNumber != 2 {
+		t.Error("Splits should be read from the replica. Got:", split)
+	}
+	if splits := splitStorage.FetchMany([]string{"split1"}); splits["split1"] == nil || splits["split1"].ChangeNumber != 2 {
+		t.Error("Splits should be fetched from the replica. Got:", splits)
+	}
+	if contains, _ := segmentStorage.SegmentContainsKey("employees", "key1"); !contains {
+		t.Error("Segments should be read from the replica")
+	}
+
+	// Nothing listens on this port, so reads fall back to the primary
+	primary.replica.client.Close()
+	primary.replica.client = redis.NewClient(&redis.Options{Addr: "localhost:1"})
+	if split := splitStorage.Get("split1"); split == nil || split.ChangeNumber != 1 {
+		t.Error("Splits should be read from the primary if the replica fails. Got:", split)
+	}
+	if contains, err := segmentStorage.SegmentContainsKey("employees", "key1"); contains || err != nil {
+		t.Error("Segments should be read from the primary while the replica is skipped. Got:", contains, err)
+	}
+}
+
+func TestPrefixedRedisClientWithContext(t *testing.T) {
+	client, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:        "localhost",
+		Port:        6379,
+		Prefix:      "withContext",
+		ReadReplica: conf.ReadReplicaConfig{Host: "localhost", Port: 6379},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer client.Close()
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	bound := client.WithContext(ctx)
+	if bound.Context() != ctx || bound.replica.Context() != ctx {
+		t.Error("The client and its replica should be bound to the context")
+	}
+	if client.Context() == ctx {
+		t.Error("The original client should be left untouched")
+	}
+	if bound.prefix != "withContext" {
+		t.Error("The prefix should be kept. Got:", bound.prefix)
+	}
+
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	if NewRedisSplitStorage(client, logger).WithContext(ctx).client.Context() != ctx {
+		t.Error("The split storage should read through the bound client")
+	}
+	if NewRedisSegmentStorage(client, logger).WithContext(ctx).(*RedisSegmentStorage).client.Context() != ctx {
+		t.Error("The segment storage should read through the bound client")
+	}
+
+	// Nothing listens on this port, so the read through the bound copy falls back to the primary
+	client.replica.client.Close()
+	client.replica.client = redis.NewClient(&redis.Options{Addr: "localhost:1"})
+	NewRedisSegmentStorage(client, logger).WithContext(ctx).SegmentContainsKey("employees", "key1")
+	if client.WithContext(ctx).replicaFailedAt != client.replicaFailedAt || *client.replicaFailedAt == 0 {
+		t.Error("Replica failures seen by bound copies should be shared with the client")
+	}
+}
+
+func TestImpressionStorageCustomTTL(t *testing.T) {
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, Prefix: "impressionsTTL"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer prefixedClient.Close()
+	defer prefixedClient.Del(redisImpressionsQueue)
+
+	metadata := &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	impressionStorage := NewRedisImpressionStorageWithTTLs(prefixedClient, metadata, 2*time.Hour, nil, nil, logger)
+	impressionStorage.LogImpressions([]storage.Impression{{FeatureName: "feature1", KeyName: "key1", Treatment: "on"}})
+
+	ttl := prefixedClient.TTL(redisImpressionsQueue).Val()
+	if ttl <= time.Hour || ttl > 2*time.Hour {
+		t.Error("The impressions list should expire after the configured TTL. Got:", ttl)
+	}
+
+	if NewRedisImpressionStorageWithTTLs(prefixedClient, metadata, 0, nil, nil, logger).impressionsTTL != redisImpressionsTTL*time.Minute {
+		t.Error("The default TTL should be used if none is set")
+	}
+}
+
+func TestRedisSplitStorageGetWithError(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testGetWithError",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	splitStorage := NewRedisSplitStorage(prefixedClient, logger)
+	defer splitStorage.Clear()
+
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split1", TrafficTypeName: "user"}}, 10)
+	if split, err := splitStorage.GetWithError("split1"); err != nil || split == nil || split.Name != "split1" {
+		t.Error("The split should be fetched. Got:", split, err)
+	}
+	if split, err := splitStorage.GetWithError("nonexistent"); err != nil || split != nil {
+		t.Error("A missing split should return neither a split nor an error. Got:", split, err)
+	}
+
+	prefixedClient.Set(strings.Replace(redisSplit, "{split}", "malformed", 1), "{not json", 0)
+	defer prefixedClient.Del(strings.Replace(redisSplit, "{split}", "malformed", 1))
+	if split, err := splitStorage.GetWithError("malformed"); err == nil || split != nil {
+		t.Error("A malformed split should return an error. Got:", split, err)
+	}
+	if splitStorage.Get("malformed") != nil {
+		t.Error("Get should swallow the error and return nil")
+	}
+
+	// Nothing listens on this port
+	unreachable, _ := NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 1})
+	defer unreachable.Close()
+	if split, err := NewRedisSplitStorage(unreachable, logger).GetWithError("split1"); err == nil || split != nil {
+		t.Error("An unreachable redis should return an error. Got:", split, err)
+	}
+}
+
+// writeSelfSignedCertificate writes a self signed certificate & its key to PEM files in dir
+func writeSelfSignedCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis.test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "redistls")
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeSelfSignedCertificate(t, dir)
+
+	if tlsConfig, err := newTLSConfig(&conf.RedisConfig{CertificateAuthorities: []string{certFile}}); err != nil || tlsConfig != nil {
+		t.Error("No TLS config should be built unless TLSEnabled is set", tlsConfig, err)
+	}
+
+	tlsConfig, err := newTLSConfig(&conf.RedisConfig{
+		TLSEnabled:             true,
+		CertificateAuthorities: []string{certFile},
+		ClientCertificate:      certFile,
+		ClientKey:              keyFile,
+		ServerName:             "redis.test",
+	})
+	if err != nil {
+		t.Fatal("No error was expected", err)
+	}
+	if tlsConfig.RootCAs == nil || len(tlsConfig.Certificates) != 1 || tlsConfig.ServerName != "redis.test" {
+		t.Error("The TLS config should be built from the convenience fields", tlsConfig)
+	}
+
+	raw := &tls.Config{ServerName: "raw"}
+	if tlsConfig, _ := newTLSConfig(&conf.RedisConfig{TLSConfig: raw, TLSEnabled: true, ServerName: "redis.test"}); tlsConfig != raw {
+		t.Error("The raw TLS config should be preferred", tlsConfig)
+	}
+
+	if _, err := newTLSConfig(&conf.RedisConfig{TLSEnabled: true, CertificateAuthorities: []string{filepath.Join(dir, "missing.pem")}}); err == nil {
+		t.Error("A missing certificate authority should fail")
+	}
+	if _, err := newTLSConfig(&conf.RedisConfig{TLSEnabled: true, CertificateAuthorities: []string{keyFile}}); err == nil {
+		t.Error("A certificate authority without certificates should fail")
+	}
+	if _, err := newTLSConfig(&conf.RedisConfig{TLSEnabled: true, ClientCertificate: certFile}); err == nil {
+		t.Error("A client certificate without its key should fail")
+	}
+
+	_, err = NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, TLSEnabled: true, ClientKey: keyFile})
+	if err == nil || !strings.Contains(err.Error(), "ClientCertificate and ClientKey") {
+		t.Error("Invalid TLS fields should fail creating the client", err)
+	}
+}
+
+func TestRedisSplitStorageReconcileTrafficTypes(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	prefixedClient, err := NewPrefixedRedisClient(&conf.RedisConfig{
+		Host:     "localhost",
+		Port:     6379,
+		Database: 1,
+		Prefix:   "testReconcile",
+		// The replica is emulated by an empty database, whose view must not be used to correct the primary
+		ReadReplica: conf.ReadReplicaConfig{Host: "localhost", Port: 6379},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	prefixedClient.replica.client.Close()
+	prefixedClient.replica.client = redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 2})
+	splitStorage := NewRedisSplitStorage(prefixedClient, logger)
+	defer splitStorage.Clear()
+	defer prefixedClient.Del("SPLITIO.trafficType.user", "SPLITIO.trafficType.account", "SPLITIO.trafficType.legacy")
+
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{Name: "split1", TrafficTypeName: "user", Sets: []string{"backend"}},
+		{Name: "split2", TrafficTypeName: "user"},
+	}, 10)
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split1", TrafficTypeName: "user", Sets: []string{"frontend"}}}, 10)
+	if names := splitStorage.SplitNamesByFlagSet("backend"); len(names) != 0 {
+		t.Error("Flag sets should be updated from the splits in the primary. Got:", names)
+	}
+	prefixedClient.Set("SPLITIO.trafficType.user", 2, 0)
+	prefixedClient.Set("SPLITIO.trafficType.legacy", 3, 0)
+
+	// split2 moves from user to account, but the synchronizer doesn't update the counters
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split2", TrafficTypeName: "account"}}, 11)
+	if count, _ := splitStorage.TrafficTypeCount("user"); count != 2 {
+		t.Error("The user counter should have drifted", count)
+	}
+
+	if err := splitStorage.ReconcileTrafficTypes(); err != nil {
+		t.Error("Reconciling traffic types should not fail", err)
+	}
+	for trafficType, expected := range map[string]int64{"user": 1, "account": 1, "legacy": 0, "unknown": 0} {
+		if count, err := splitStorage.TrafficTypeCount(trafficType); err != nil || count != expected {
+			t.Errorf("Traffic type %s should count %d splits. Got: %d %v", trafficType, expected, count, err)
+		}
+	}
+	if exists, _ := prefixedClient.Exists("SPLITIO.trafficType.legacy"); exists {
+		t.Error("Counters of traffic types no split belongs to should be removed")
+	}
+	if !splitStorage.TrafficTypeExists("account") || splitStorage.TrafficTypeExists("legacy") {
+		t.Error("Existence should reflect the reconciled counters")
+	}
+
+	// Keys spread over several cluster slots are corrected without watching them
+	prefixedClient.multiSlot = true
+	prefixedClient.Set("SPLITIO.trafficType.user", 5, 0)
+	prefixedClient.Set("SPLITIO.trafficType.legacy", 3, 0)
+	if err := splitStorage.ReconcileTrafficTypes(); err != nil {
+		t.Error("Reconciling traffic types should not fail", err)
+	}
+	for trafficType, expected := range map[string]int64{"user": 1, "account": 1, "legacy": 0} {
+		if count, err := splitStorage.TrafficTypeCount(trafficType); err != nil || count != expected {
+			t.Errorf("Traffic type %s should count %d splits. Got: %d %v", trafficType, expected, count, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package storage
+
+// StorageResolver should be implemented by structs that map a tenant identifier to the storages
+// holding that tenant's data
+type StorageResolver interface {
+	Splits(tenant string) SplitStorageConsumer
+	Segments(tenant string) SegmentStorageConsumer
+}
+
+// SingleTenantResolver is a StorageResolver that returns the same storages for every tenant
+type SingleTenantResolver struct {
+	splits   SplitStorageConsumer
+	segments SegmentStorageConsumer
+}
+
+// NewSingleTenantResolver instantiates a new SingleTenantResolver
+func NewSingleTenantResolver(splits SplitStorageConsumer, segments SegmentStorageConsumer) *SingleTenantResolver {
+	return &SingleTenantResolver{
+		splits:   splits,
+		segments: segments,
+	}
+}
+
+// Splits returns the split storage regardless of the tenant
+func (r *SingleTenantResolver) Splits(tenant string) SplitStorageConsumer {
+	return r.splits
+}
+
+// Segments returns the segment storage regardless of the tenant
+func (r *SingleTenantResolver) Segments(tenant string) SegmentStorageConsumer {
+	return r.segments
+}
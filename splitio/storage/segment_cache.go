@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/splitio/go-toolkit/datastructures/set"
+)
+
+// segmentTillCheckInterval is the minimum time between checks of the changeNumber of a segment
+const segmentTillCheckInterval = time.Second
+
+type membershipEntry struct {
+	segment   string
+	key       string
+	member    bool
+	expiresAt time.Time
+}
+
+type segmentTill struct {
+	till      int64
+	checkedAt time.Time
+}
+
+// CachedSegmentStorage is a read-through cache of segment memberships on top of another segment storage.
+// Cached memberships expire after the configured TTL. The changeNumber of each segment is checked at most once
+// per second and, if it has advanced, every membership cached for that segment is purged. When the cache is full,
+// the least recently used membership is evicted
+type CachedSegmentStorage struct {
+	hits    int64 // accessed atomically, kept first for 64-bit alignment
+	inner   SegmentStorage
+	size    int
+	ttl     time.Duration
+	tillTTL time.Duration
+	entries map[string]map[string]*list.Element
+	lru     *list.List
+	tills   map[string]segmentTill
+	mutex   *sync.Mutex
+}
+
+// NewCachedSegmentStorage instantiates a new CachedSegmentStorage holding up to size memberships for ttl
+func NewCachedSegmentStorage(inner SegmentStorage, size int, ttl time.Duration) *CachedSegmentStorage {
+	return &CachedSegmentStorage{
+		inner:   inner,
+		size:    size,
+		ttl:     ttl,
+		tillTTL: segmentTillCheckInterval,
+		entries: make(map[string]map[string]*list.Element),
+		lru:     list.New(),
+		tills:   make(map[string]segmentTill),
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// WithContext returns a view of the cache whose misses are read from the underlying storage bound to ctx, if it can
+// be bound. The cached memberships are shared with the view
+func (c *CachedSegmentStorage) WithContext(ctx context.Context) SegmentStorage {
+	binder, ok := c.inner.(SegmentStorageContextBinder)
+	if !ok {
+		return c
+	}
+	return &boundCachedSegmentStorage{CachedSegmentStorage: c, inner: binder.WithContext(ctx)}
+}
+
+// Get returns the segment from the underlying storage
+func (c *CachedSegmentStorage) Get(segmentName string) *set.ThreadUnsafeSet {
+	return c.inner.Get(segmentName)
+}
+
+// SegmentContainsKey returns true if the segment contains the key, serving it from cache when possible
+func (c *CachedSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	return c.segmentContainsKey(c.inner, segmentName, key)
+}
+
+// segmentContainsKey serves the membership from cache when possible, reading it from inner otherwise
+func (c *CachedSegmentStorage) segmentContainsKey(inner SegmentStorage, segmentName string, key string) (bool, error) {
+	now := time.Now()
+	c.refreshTill(inner, segmentName, now)
+
+	c.mutex.Lock()
+	if element, ok := c.entries[segmentName][key]; ok {
+		entry := element.Value.(*membershipEntry)
+		if now.Before(entry.expiresAt) {
+			c.lru.MoveToFront(element)
+			c.mutex.Unlock()
+			atomic.AddInt64(&c.hits, 1)
+			return entry.member, nil
+		}
+		c.removeElement(element)
+	}
+	c.mutex.Unlock()
+
+	member, err := inner.SegmentContainsKey(segmentName, key)
+	if err != nil {
+		return member, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.add(&membershipEntry{segment: segmentName, key: key, member: member, expiresAt: now.Add(c.ttl)})
+	return member, nil
+}
+
+// SegmentContainsKeys checks many keys at once through the underlying storage if it supports it,
+// falling back to checking them one by one through the cache otherwise
+func (c *CachedSegmentStorage) SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error) {
+	return c.segmentContainsKeys(c.inner, segmentName, keys)
+}
+
+func (c *CachedSegmentStorage) segmentContainsKeys(inner SegmentStorage, segmentName string, keys []string) (map[string]bool, error) {
+	if bulk, ok := inner.(SegmentStorageBulkConsumer); ok {
+		return bulk.SegmentContainsKeys(segmentName, keys)
+	}
+
+	memberships := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		member, err := c.segmentContainsKey(inner, segmentName, key)
+		if err != nil {
+			return nil, err
+		}
+		memberships[key] = member
+	}
+	return memberships, nil
+}
+
+// refreshTill purges the cached memberships of a segment if its changeNumber has advanced
+func (c *CachedSegmentStorage) refreshTill(inner SegmentStorage, segmentName string, now time.Time) {
+	c.mutex.Lock()
+	current, checked := c.tills[segmentName]
+	c.mutex.Unlock()
+	if checked && now.Sub(current.checkedAt) < c.tillTTL {
+		return
+	}
+
+	till := inner.Till(segmentName)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if checked && till != current.till {
+		c.purge(segmentName)
+	}
+	c.tills[segmentName] = segmentTill{till: till, checkedAt: now}
+}
+
+// add caches a membership evicting the least recently used one if the cache is full. Must be called with the lock held
+func (c *CachedSegmentStorage) add(entry *membershipEntry) {
+	if element, ok := c.entries[entry.segment][entry.key]; ok {
+		c.removeElement(element)
+	}
+
+	if _, ok := c.entries[entry.segment]; !ok {
+		c.entries[entry.segment] = make(map[string]*list.Element)
+	}
+	c.entries[entry.segment][entry.key] = c.lru.PushFront(entry)
+
+	if c.lru.Len() > c.size {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// removeElement removes a cached membership. Must be called with the lock held
+func (c *CachedSegmentStorage) removeElement(element *list.Element) {
+	entry := element.Value.(*membershipEntry)
+	c.lru.Remove(element)
+	delete(c.entries[entry.segment], entry.key)
+	if len(c.entries[entry.segment]) == 0 {
+		delete(c.entries, entry.segment)
+	}
+}
+
+// purge removes every cached membership of a segment. Must be called with the lock held
+func (c *CachedSegmentStorage) purge(segmentName string) {
+	for _, element := range c.entries[segmentName] {
+		c.lru.Remove(element)
+	}
+	delete(c.entries, segmentName)
+}
+
+// SegmentSizes returns the sizes reported by the underlying storage if it supports it
+func (c *CachedSegmentStorage) SegmentSizes(segmentNames []string) (map[string]int64, error) {
+	return segmentSizes(c.inner, segmentNames)
+}
+
+func segmentSizes(inner SegmentStorage, segmentNames []string) (map[string]int64, error) {
+	if stats, ok := inner.(SegmentStorageStats); ok {
+		return stats.SegmentSizes(segmentNames)
+	}
+	return make(map[string]int64), nil
+}
+
+// Hits returns the amount of memberships served from cache
+func (c *CachedSegmentStorage) Hits() int64 {
+	return atomic.LoadInt64(&c.hits)
+}
+
+// Put writes the segment to the underlying storage and purges its cached memberships
+func (c *CachedSegmentStorage) Put(name string, segment *set.ThreadUnsafeSet, changeNumber int64) {
+	c.inner.Put(name, segment, changeNumber)
+	c.invalidate(name)
+}
+
+// Remove removes the segment from the underlying storage and purges its cached memberships
+func (c *CachedSegmentStorage) Remove(segmentName string) {
+	c.inner.Remove(segmentName)
+	c.invalidate(segmentName)
+}
+
+// Till returns the changeNumber of the segment in the underlying storage
+func (c *CachedSegmentStorage) Till(segmentName string) int64 {
+	return c.inner.Till(segmentName)
+}
+
+// Clear clears the underlying storage and the cache
+func (c *CachedSegmentStorage) Clear() {
+	c.inner.Clear()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]map[string]*list.Element)
+	c.lru.Init()
+	c.tills = make(map[string]segmentTill)
+}
+
+func (c *CachedSegmentStorage) invalidate(segmentName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.purge(segmentName)
+	delete(c.tills, segmentName)
+}
+
+// boundCachedSegmentStorage reads through the cache of a CachedSegmentStorage, asking the underlying storage bound
+// to a context on misses. Writes go through the CachedSegmentStorage itself
+type boundCachedSegmentStorage struct {
+	*CachedSegmentStorage
+	inner SegmentStorage
+}
+
+// Get returns the segment from the bound underlying storage
+func (b *boundCachedSegmentStorage) Get(segmentName string) *set.ThreadUnsafeSet {
+	return b.inner.Get(segmentName)
+}
+
+// SegmentContainsKey returns true if the segment contains the key, serving it from cache when possible
+func (b *boundCachedSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	return b.segmentContainsKey(b.inner, segmentName, key)
+}
+
+// SegmentContainsKeys checks many keys at once through the bound underlying storage if it supports it,
+// falling back to checking them one by one through the cache otherwise
+func (b *boundCachedSegmentStorage) SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error) {
+	return b.segmentContainsKeys(b.inner, segmentName, keys)
+}
+
+// SegmentSizes returns the sizes reported by the bound underlying storage if it supports it
+func (b *boundCachedSegmentStorage) SegmentSizes(segmentNames []string) (map[string]int64, error) {
+	return segmentSizes(b.inner, segmentNames)
+}
+
+// Till returns the changeNumber of the segment in the bound underlying storage
+func (b *boundCachedSegmentStorage) Till(segmentName string) int64 {
+	return b.inner.Till(segmentName)
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/datastructures/set"
+)
+
+func TestCachedSegmentStorage(t *testing.T) {
+	inner := mutexmap.NewMMSegmentStorage()
+	inner.Put("segment1", set.NewSet("key1"), 1)
+
+	cache := NewCachedSegmentStorage(inner, 10, time.Minute)
+	cache.tillTTL = 0
+
+	if member, _ := cache.SegmentContainsKey("segment1", "key1"); !member {
+		t.Error("key1 should be part of segment1")
+	}
+	if cache.Hits() != 0 {
+		t.Error("The first check should not be served from cache")
+	}
+
+	if member, _ := cache.SegmentContainsKey("segment1", "key1"); !member {
+		t.Error("key1 should be part of segment1")
+	}
+	if cache.Hits() != 1 {
+		t.Error("The second check should be served from cache. Hits:", cache.Hits())
+	}
+
+	// The synchronizer updates the segment bypassing the cache
+	inner.Put("segment1", set.NewSet("key2"), 2)
+	if member, _ := cache.SegmentContainsKey("segment1", "key1"); member {
+		t.Error("A segment update should invalidate cached memberships")
+	}
+	if cache.Hits() != 1 {
+		t.Error("Memberships of an updated segment should not be served from cache. Hits:", cache.Hits())
+	}
+}
+
+func TestCachedSegmentStorageEviction(t *testing.T) {
+	inner := mutexmap.NewMMSegmentStorage()
+	inner.Put("segment1", set.NewSet("key1", "key2", "key3"), 1)
+
+	cache := NewCachedSegmentStorage(inner, 2, time.Minute)
+	cache.SegmentContainsKey("segment1", "key1")
+	cache.SegmentContainsKey("segment1", "key2")
+	cache.SegmentContainsKey("segment1", "key1")
+	cache.SegmentContainsKey("segment1", "key3")
+	if cache.lru.Len() != 2 {
+		t.Error("The cache should not hold more memberships than its size")
+	}
+
+	hits := cache.Hits()
+	cache.SegmentContainsKey("segment1", "key2")
+	if cache.Hits() != hits {
+		t.Error("The least recently used membership should have been evicted")
+	}
+
+	cache.Put("segment1", set.NewSet("key1"), 2)
+	if member, _ := cache.SegmentContainsKey("segment1", "key3"); member {
+		t.Error("Updating the segment through the cache should purge its memberships")
+	}
+}
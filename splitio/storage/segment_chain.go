@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/splitio/go-toolkit/datastructures/set"
+)
+
+// ChainedSegmentStorage walks an ordered chain of segment storages, ie: an in-process cache, a replica and
+// a primary, until one of them answers without error. A storage reporting that a key is not a member of a
+// segment is a valid answer and stops the walk; only errors move on to the next storage.
+// Writes are applied to every storage in the chain
+type ChainedSegmentStorage struct {
+	chain []SegmentStorage
+}
+
+// NewChainedSegmentStorage instantiates a new ChainedSegmentStorage walking the storages in the given order
+func NewChainedSegmentStorage(chain ...SegmentStorage) *ChainedSegmentStorage {
+	return &ChainedSegmentStorage{chain: chain}
+}
+
+// WithContext returns a copy of the chain whose storages able to bind their reads to ctx are bound to it
+func (c *ChainedSegmentStorage) WithContext(ctx context.Context) SegmentStorage {
+	chain := make([]SegmentStorage, 0, len(c.chain))
+	for _, segmentStorage := range c.chain {
+		if binder, ok := segmentStorage.(SegmentStorageContextBinder); ok {
+			segmentStorage = binder.WithContext(ctx)
+		}
+		chain = append(chain, segmentStorage)
+	}
+	return &ChainedSegmentStorage{chain: chain}
+}
+
+// Get returns the segment from the first storage in the chain that has it
+func (c *ChainedSegmentStorage) Get(segmentName string) *set.ThreadUnsafeSet {
+	for _, segmentStorage := range c.chain {
+		if segment := segmentStorage.Get(segmentName); segment != nil {
+			return segment
+		}
+	}
+	return nil
+}
+
+// SegmentContainsKey returns the membership reported by the first storage in the chain that answers without error.
+// If every storage fails, the error of the last one is returned
+func (c *ChainedSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	err := errors.New("no segment storage in the chain")
+	for _, segmentStorage := range c.chain {
+		var member bool
+		member, err = segmentStorage.SegmentContainsKey(segmentName, key)
+		if err == nil {
+			return member, nil
+		}
+	}
+	return false, err
+}
+
+// Put writes the segment to every storage in the chain
+func (c *ChainedSegmentStorage) Put(name string, segment *set.ThreadUnsafeSet, changeNumber int64) {
+	for _, segmentStorage := range c.chain {
+		segmentStorage.Put(name, segment, changeNumber)
+	}
+}
+
+// Till returns the changeNumber of the segment in the first storage of the chain
+func (c *ChainedSegmentStorage) Till(segmentName string) int64 {
+	if len(c.chain) == 0 {
+		return -1
+	}
+	return c.chain[0].Till(segmentName)
+}
+
+// Remove removes the segment from every storage in the chain
+func (c *ChainedSegmentStorage) Remove(segmentName string) {
+	for _, segmentStorage := range c.chain {
+		segmentStorage.Remove(segmentName)
+	}
+}
+
+// Clear clears every storage in the chain
+func (c *ChainedSegmentStorage) Clear() {
+	for _, segmentStorage := range c.chain {
+		segmentStorage.Clear()
+	}
+}
+
+// SegmentSizes returns the sizes reported by the last storage in the chain able to report them, which is the
+// most authoritative one
+func (c *ChainedSegmentStorage) SegmentSizes(segmentNames []string) (map[string]int64, error) {
+	for index := len(c.chain) - 1; index >= 0; index-- {
+		if stats, ok := c.chain[index].(SegmentStorageStats); ok {
+			return stats.SegmentSizes(segmentNames)
+		}
+	}
+	return nil, errors.New("no segment storage in the chain reports segment sizes")
+}
+
+// SegmentContainsKeys returns the memberships reported by the last storage in the chain able to check many keys
+// at once, which is the most authoritative one, falling back to checking them one by one through the chain otherwise
+func (c *ChainedSegmentStorage) SegmentContainsKeys(segmentName string, keys []string) (map[string]bool, error) {
+	for index := len(c.chain) - 1; index >= 0; index-- {
+		if bulk, ok := c.chain[index].(SegmentStorageBulkConsumer); ok {
+			return bulk.SegmentContainsKeys(segmentName, keys)
+		}
+	}
+
+	memberships := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		member, err := c.SegmentContainsKey(segmentName, key)
+		if err != nil {
+			return nil, err
+		}
+		memberships[key] = member
+	}
+	return memberships, nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/datastructures/set"
+)
+
+type failingSegmentStorage struct {
+	*mutexmap.MMSegmentStorage
+	calls int
+}
+
+func (f *failingSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	f.calls++
+	return false, errors.New("storage unavailable")
+}
+
+// plainSegmentStorage hides the optional interfaces implemented by the storage it wraps
+type plainSegmentStorage struct {
+	SegmentStorage
+}
+
+func TestChainedSegmentStorage(t *testing.T) {
+	failing := &failingSegmentStorage{MMSegmentStorage: mutexmap.NewMMSegmentStorage()}
+	replica := mutexmap.NewMMSegmentStorage()
+	replica.Put("employees", set.NewSet("key1"), 1)
+	primary := mutexmap.NewMMSegmentStorage()
+	primary.Put("employees", set.NewSet("key1", "key2"), 2)
+
+	chain := NewChainedSegmentStorage(failing, replica, primary)
+
+	if member, err := chain.SegmentContainsKey("employees", "key1"); !member || err != nil {
+		t.Error("The membership should be answered by the second storage when the first one fails", member, err)
+	}
+	if failing.calls != 1 {
+		t.Error("The first storage should have been asked first")
+	}
+
+	if member, err := chain.SegmentContainsKey("employees", "key2"); member || err != nil {
+		t.Error("A genuine non-membership should not advance to the next storage", member, err)
+	}
+
+	if _, err := NewChainedSegmentStorage(failing).SegmentContainsKey("employees", "key1"); err == nil {
+		t.Error("An error should be returned if every storage fails")
+	}
+
+	chain.Put("admins", set.NewSet("key3"), 5)
+	if !replica.Get("admins").Has("key3") || !primary.Get("admins").Has("key3") || chain.Till("admins") != 5 {
+		t.Error("Writes should be applied to every storage in the chain")
+	}
+}
+
+func TestChainedSegmentStorageBulkOperations(t *testing.T) {
+	replica := mutexmap.NewMMSegmentStorage()
+	replica.Put("employees", set.NewSet("key1"), 1)
+	primary := mutexmap.NewMMSegmentStorage()
+	primary.Put("employees", set.NewSet("key1", "key2"), 2)
+
+	chain := NewChainedSegmentStorage(replica, primary, &plainSegmentStorage{SegmentStorage: replica})
+
+	if sizes, err := chain.SegmentSizes([]string{"employees"}); err != nil || sizes["employees"] != 2 {
+		t.Error("Sizes should be reported by the last storage able to report them", sizes, err)
+	}
+	memberships, err := chain.SegmentContainsKeys("employees", []string{"key1", "key2", "key3"})
+	if err != nil || !memberships["key1"] || !memberships["key2"] || memberships["key3"] {
+		t.Error("Memberships should be checked by the last storage able to check many keys", memberships, err)
+	}
+
+	plain := NewChainedSegmentStorage(&plainSegmentStorage{SegmentStorage: primary})
+	if _, err := plain.SegmentSizes([]string{"employees"}); err == nil {
+		t.Error("An error should be returned if no storage reports segment sizes")
+	}
+	memberships, err = plain.SegmentContainsKeys("employees", []string{"key2", "key3"})
+	if err != nil || !memberships["key2"] || memberships["key3"] {
+		t.Error("Memberships should be checked one by one if no storage checks many keys at once", memberships, err)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/splitio/go-toolkit/datastructures/set"
+)
+
+// PinnedSegmentStorage remembers the first answer of the underlying storage for each segment & key, so that every
+// read made through it sees the same memberships even if the segments are updated in the meantime. It's meant to
+// live for the duration of a single batch of evaluations. Errors are not remembered
+type PinnedSegmentStorage struct {
+	inner       SegmentStorageConsumer
+	memberships map[string]map[string]bool
+	segments    map[string]*set.ThreadUnsafeSet
+	mutex       sync.Mutex
+}
+
+// NewPinnedSegmentStorage instantiates a new PinnedSegmentStorage reading through to the supplied storage
+func NewPinnedSegmentStorage(inner SegmentStorageConsumer) *PinnedSegmentStorage {
+	return &PinnedSegmentStorage{
+		inner:       inner,
+		memberships: make(map[string]map[string]bool),
+		segments:    make(map[string]*set.ThreadUnsafeSet),
+	}
+}
+
+// Get returns the segment as it was the first time it was read
+func (p *PinnedSegmentStorage) Get(segmentName string) *set.ThreadUnsafeSet {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if segment, ok := p.segments[segmentName]; ok {
+		return segment
+	}
+	segment := p.inner.Get(segmentName)
+	p.segments[segmentName] = segment
+	return segment
+}
+
+// SegmentContainsKey returns the membership of the key as it was the first time it was checked
+func (p *PinnedSegmentStorage) SegmentContainsKey(segmentName string, key string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if isMember, ok := p.memberships[segmentName][key]; ok {
+		return isMember, nil
+	}
+	isMember, err := p.inner.SegmentContainsKey(segmentName, key)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := p.memberships[segmentName]; !ok {
+		p.memberships[segmentName] = make(map[string]bool)
+	}
+	p.memberships[segmentName][key] = isMember
+	return isMember, nil
+}
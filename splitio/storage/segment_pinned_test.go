@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/datastructures/set"
+)
+
+func TestPinnedSegmentStorage(t *testing.T) {
+	inner := mutexmap.NewMMSegmentStorage()
+	inner.Put("employees", set.NewSet("key1"), 1)
+	pinned := NewPinnedSegmentStorage(inner)
+
+	if member, err := pinned.SegmentContainsKey("employees", "key1"); !member || err != nil {
+		t.Error("key1 should be a member of employees", member, err)
+	}
+	if member, _ := pinned.SegmentContainsKey("employees", "key2"); member {
+		t.Error("key2 should not be a member of employees")
+	}
+	if pinned.Get("employees").Size() != 1 {
+		t.Error("The segment should be read from the underlying storage")
+	}
+
+	inner.Put("employees", set.NewSet("key2"), 2)
+	if member, _ := pinned.SegmentContainsKey("employees", "key1"); !member {
+		t.Error("key1 should still be a member, as it was when first checked")
+	}
+	if member, _ := pinned.SegmentContainsKey("employees", "key2"); member {
+		t.Error("key2 should still not be a member, as it was when first checked")
+	}
+	if !pinned.Get("employees").Has("key1") {
+		t.Error("The segment should be returned as it was when first read")
+	}
+
+	failing := NewPinnedSegmentStorage(&failingSegmentStorage{MMSegmentStorage: inner})
+	failing.SegmentContainsKey("employees", "key1")
+	if _, err := failing.SegmentContainsKey("employees", "key1"); err == nil {
+		t.Error("Errors should not be pinned")
+	}
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+)
+
+// ExportSnapshot builds a snapshot with every split in storage and the segments they reference
+func ExportSnapshot(splitStorage SplitStorage, segmentStorage SegmentStorage) *dtos.SnapshotDTO {
+	snapshot := &dtos.SnapshotDTO{
+		Till:     splitStorage.Till(),
+		Splits:   splitStorage.GetAll(),
+		Segments: make([]dtos.SegmentSnapshotDTO, 0),
+	}
+
+	segmentNames := splitStorage.SegmentNames()
+	if segmentNames == nil {
+		return snapshot
+	}
+
+	for _, name := range segmentNames.List() {
+		segmentName, ok := name.(string)
+		if !ok {
+			continue
+		}
+		segment := segmentStorage.Get(segmentName)
+		if segment == nil {
+			continue
+		}
+
+		keys := make([]string, 0, segment.Size())
+		for _, key := range segment.List() {
+			if asString, ok := key.(string); ok {
+				keys = append(keys, asString)
+			}
+		}
+		snapshot.Segments = append(snapshot.Segments, dtos.SegmentSnapshotDTO{
+			Name: segmentName,
+			Keys: keys,
+			Till: segmentStorage.Till(segmentName),
+		})
+	}
+	return snapshot
+}
+
+// WriteSnapshotFile serializes the snapshot to the supplied path. The snapshot is written to a temporary
+// file in the same directory which is then renamed, so that a crash never leaves a partially written file
+func WriteSnapshotFile(path string, snapshot *dtos.SnapshotDTO) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadSnapshotFile reads a snapshot previously written with WriteSnapshotFile
+func ReadSnapshotFile(path string) (*dtos.SnapshotDTO, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot dtos.SnapshotDTO
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
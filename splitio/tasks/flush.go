@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"errors"
+
+	"github.com/splitio/go-client/splitio/service"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// FlushImpressions synchronously submits the queued impressions in bulks of bulkSize, returning the first error.
+// It stops once a bulk comes out short, so that impressions queued meanwhile don't keep it running
+func FlushImpressions(
+	impressionStorage storage.ImpressionStorageConsumer,
+	impressionRecorder service.ImpressionsRecorder,
+	bulkSize int64,
+	logger logging.LoggerInterface,
+) error {
+	for {
+		queuedImpressions, err := impressionStorage.PopN(bulkSize)
+		if err != nil {
+			logger.Error("Error reading impressions queue", err)
+			return errors.New("Error reading impressions queue")
+		}
+		if len(queuedImpressions) == 0 {
+			return nil
+		}
+		if err := impressionRecorder.Record(queuedImpressions); err != nil {
+			return err
+		}
+		if int64(len(queuedImpressions)) < bulkSize {
+			return nil
+		}
+	}
+}
+
+// FlushEvents synchronously submits the queued events in bulks of bulkSize, returning the first error
+func FlushEvents(
+	eventStorage storage.EventStorageConsumer,
+	eventRecorder service.EventsRecorder,
+	bulkSize int64,
+	logger logging.LoggerInterface,
+) error {
+	for !eventStorage.Empty() {
+		queuedEvents, err := eventStorage.PopN(bulkSize)
+		if err != nil {
+			logger.Error("Error reading events queue", err)
+			return errors.New("Error reading events queue")
+		}
+		if len(queuedEvents) == 0 {
+			return nil
+		}
+		if err := eventRecorder.Record(queuedEvents); err != nil {
+			return err
+		}
+		if int64(len(queuedEvents)) < bulkSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// FlushMetrics synchronously submits the stored gauges, counters & latencies, returning the first error
+func FlushMetrics(metricsStorage storage.MetricsStorageConsumer, metricsRecorder service.MetricsRecorder) error {
+	var first error
+	for _, submit := range []func(storage.MetricsStorageConsumer, service.MetricsRecorder) error{
+		submitGauges,
+		submitCounters,
+		submitLatencies,
+	} {
+		if err := submit(metricsStorage, metricsRecorder); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// FlushMetricsBatch synchronously submits the stored gauges, counters & latencies at once
+func FlushMetricsBatch(metricsStorage storage.MetricsStorageConsumer, metricsRecorder service.MetricsBatchRecorder) error {
+	return submitMetricsBatch(metricsStorage, metricsRecorder)
+}
@@ -0,0 +1,92 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-client/splitio/storage/mutexqueue"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+type impressionBulksRecorderMock struct {
+	bulks []int
+	err   error
+}
+
+func (m *impressionBulksRecorderMock) Record(impressions []storage.Impression) error {
+	m.bulks = append(m.bulks, len(impressions))
+	return m.err
+}
+
+type eventBulksRecorderMock struct {
+	bulks []int
+}
+
+func (m *eventBulksRecorderMock) Record(events []dtos.EventDTO) error {
+	m.bulks = append(m.bulks, len(events))
+	return nil
+}
+
+func TestFlushImpressionsAndEvents(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	impressionStorage := mutexqueue.NewMQImpressionsStorage(100, make(chan string, 1), logger)
+	impressions := make([]storage.Impression, 0, 5)
+	for i := 0; i < 5; i++ {
+		impressions = append(impressions, storage.Impression{KeyName: "key", FeatureName: "feature", Treatment: "on"})
+	}
+	impressionStorage.LogImpressions(impressions)
+
+	impressionRecorder := &impressionBulksRecorderMock{}
+	if err := FlushImpressions(impressionStorage, impressionRecorder, 2, logger); err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	if len(impressionRecorder.bulks) != 3 || impressionRecorder.bulks[2] != 1 {
+		t.Error("Every impression should be submitted in bulks. Got:", impressionRecorder.bulks)
+	}
+
+	impressionStorage.LogImpressions(impressions)
+	impressionRecorder = &impressionBulksRecorderMock{err: errors.New("some error")}
+	if err := FlushImpressions(impressionStorage, impressionRecorder, 2, logger); err == nil || len(impressionRecorder.bulks) != 1 {
+		t.Error("The flush should stop at the first error. Got:", err, impressionRecorder.bulks)
+	}
+
+	eventStorage := mutexqueue.NewMQEventsStorage(100, make(chan string, 1), logger)
+	for i := 0; i < 3; i++ {
+		eventStorage.Push(dtos.EventDTO{Key: "key", TrafficTypeName: "user", EventTypeID: "click"}, 0)
+	}
+	eventRecorder := &eventBulksRecorderMock{}
+	if err := FlushEvents(eventStorage, eventRecorder, 2, logger); err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	if len(eventRecorder.bulks) != 2 || !eventStorage.Empty() {
+		t.Error("Every event should be submitted in bulks. Got:", eventRecorder.bulks)
+	}
+}
+
+func TestFlushMetrics(t *testing.T) {
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	metricsStorage.IncCounter("c1")
+	metricsStorage.PutGauge("g1", 1)
+	metricsStorage.IncLatency("l1", 1)
+
+	recorder := &metricsRecorderMock{}
+	recorder.counterIterations.Store(0)
+	recorder.gaugeIterations.Store(0)
+	recorder.latencyIterations.Store(0)
+	if err := FlushMetrics(metricsStorage, recorder); err != nil {
+		t.Error("No error should be returned. Got:", err)
+	}
+	if recorder.counterIterations.Load().(int) != 1 || recorder.gaugeIterations.Load().(int) != 1 ||
+		recorder.latencyIterations.Load().(int) != 1 {
+		t.Error("Every kind of metric should be submitted")
+	}
+
+	metricsStorage.IncCounter("c1")
+	batchRecorder := &metricsBatchRecorderMock{}
+	if err := FlushMetricsBatch(metricsStorage, batchRecorder); err != nil || len(batchRecorder.batches) != 1 {
+		t.Error("The metrics should be submitted in a single batch. Got:", err, batchRecorder.batches)
+	}
+}
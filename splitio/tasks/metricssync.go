@@ -3,6 +3,7 @@ package tasks
 import (
 	"errors"
 	"github.com/splitio/go-client/splitio/service"
+	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/storage"
 	"github.com/splitio/go-toolkit/asynctask"
 	"github.com/splitio/go-toolkit/logging"
@@ -49,6 +50,47 @@ func submitLatencies(
 	return nil
 }
 
+// buildMetricsBatch pops latencies, counters & gauges from the storage into a single payload
+func buildMetricsBatch(metricsStorage storage.MetricsStorageConsumer) dtos.MetricsBatchDTO {
+	return dtos.MetricsBatchDTO{
+		Latencies: metricsStorage.PopLatencies(),
+		Counters:  metricsStorage.PopCounters(),
+		Gauges:    metricsStorage.PopGauges(),
+	}
+}
+
+func submitMetricsBatch(
+	metricsStorage storage.MetricsStorageConsumer,
+	metricsRecorder service.MetricsBatchRecorder,
+) error {
+	batch := buildMetricsBatch(metricsStorage)
+	if batch.IsEmpty() {
+		return nil
+	}
+	return metricsRecorder.RecordMetricsBatch(batch)
+}
+
+// NewRecordMetricsTask creates a new task that submits latencies, counters & gauges together
+func NewRecordMetricsTask(
+	metricsStorage storage.MetricsStorageConsumer,
+	metricsRecorder service.MetricsBatchRecorder,
+	period int,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	record := func(logger logging.LoggerInterface) error {
+		return submitMetricsBatch(
+			metricsStorage,
+			metricsRecorder,
+		)
+	}
+
+	onStop := func(l logging.LoggerInterface) {
+		record(logger)
+	}
+
+	return asynctask.NewAsyncTask("SubmitMetrics", record, period, nil, onStop, logger)
+}
+
 // NewRecordCountersTask creates a new splits fetching and storing task
 func NewRecordCountersTask(
 	metricsStorage storage.MetricsStorageConsumer,
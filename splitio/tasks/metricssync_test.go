@@ -235,3 +235,45 @@ func TestMetricsFlushWhenTaskIsStopped(t *testing.T) {
 		t.Error("Latency Task should have ran twice")
 	}
 }
+
+type metricsBatchRecorderMock struct {
+	batches []dtos.MetricsBatchDTO
+}
+
+func (m *metricsBatchRecorderMock) RecordMetricsBatch(batch dtos.MetricsBatchDTO) error {
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func TestMetricsBatch(t *testing.T) {
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	metricsStorage.PutGauge("g1", 123)
+	metricsStorage.IncLatency("metric1", 5)
+	metricsStorage.IncCounter("counter1")
+	metricsStorage.IncCounter("counter1")
+
+	recorder := &metricsBatchRecorderMock{}
+	err := submitMetricsBatch(metricsStorage, recorder)
+	if err != nil || len(recorder.batches) != 1 {
+		t.Error("A single batch should have been submitted", err)
+		return
+	}
+
+	batch := recorder.batches[0]
+	if len(batch.Latencies) != 1 || batch.Latencies[0].MetricName != "metric1" || batch.Latencies[0].Latencies[5] != 1 {
+		t.Error("Latencies should be part of the batch", batch.Latencies)
+	}
+
+	if len(batch.Counters) != 1 || batch.Counters[0].MetricName != "counter1" || batch.Counters[0].Count != 2 {
+		t.Error("Counters should be part of the batch", batch.Counters)
+	}
+
+	if len(batch.Gauges) != 1 || batch.Gauges[0].MetricName != "g1" || batch.Gauges[0].Gauge != 123 {
+		t.Error("Gauges should be part of the batch", batch.Gauges)
+	}
+
+	submitMetricsBatch(metricsStorage, recorder)
+	if len(recorder.batches) != 1 {
+		t.Error("Empty batches should not be submitted")
+	}
+}
@@ -0,0 +1,38 @@
+package tasks
+
+import (
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/util/impressions"
+	"github.com/splitio/go-toolkit/asynctask"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+// NewPersistImpressionObserverTask creates a new task that periodically saves the combinations tracked by the
+// impression deduper to stateStorage, so that they keep being deduped after a restart
+func NewPersistImpressionObserverTask(
+	deduper *impressions.ImpressionDeduper,
+	stateStorage storage.ImpressionObserverStateStorage,
+	period int,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	persist := func(logger logging.LoggerInterface) error {
+		return deduper.Persist(stateStorage)
+	}
+
+	return asynctask.NewAsyncTask("PersistImpressionObserver", persist, period, nil, nil, logger)
+}
+
+// NewReportDedupMetricsTask creates a new task that periodically reports the impressions dropped by the impression
+// deduper and the amount of combinations it tracks
+func NewReportDedupMetricsTask(
+	deduper *impressions.ImpressionDeduper,
+	period int,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	report := func(logger logging.LoggerInterface) error {
+		deduper.ReportMetrics()
+		return nil
+	}
+
+	return asynctask.NewAsyncTask("ReportDedupMetrics", report, period, nil, nil, logger)
+}
@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/asynctask"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+const (
+	// ImpressionsQueueDepthGauge is the gauge holding the number of impressions waiting to be submitted
+	ImpressionsQueueDepthGauge = "sdk.queue.impressions.depth"
+	// EventsQueueDepthGauge is the gauge holding the number of events waiting to be submitted
+	EventsQueueDepthGauge = "sdk.queue.events.depth"
+	// SegmentsQueueDepthGauge is the gauge holding the number of segments waiting to be synchronized
+	SegmentsQueueDepthGauge = "sdk.queue.segments.depth"
+)
+
+// QueueDepthSource is implemented by queues able to report their depth cheaply
+type QueueDepthSource interface {
+	Depth() int64
+}
+
+// QueueDepthFunc adapts a function to the QueueDepthSource interface
+type QueueDepthFunc func() int64
+
+// Depth returns the result of calling the function
+func (f QueueDepthFunc) Depth() int64 {
+	return f()
+}
+
+func sampleQueueDepths(metricsStorage storage.MetricsStorageProducer, sources map[string]QueueDepthSource) {
+	for gauge, source := range sources {
+		metricsStorage.PutGauge(gauge, float64(source.Depth()))
+	}
+}
+
+// NewSampleQueueDepthsTask creates a new task that periodically stores the depth of each queue as a gauge,
+// so that it's submitted along with the rest of the metrics
+func NewSampleQueueDepthsTask(
+	metricsStorage storage.MetricsStorageProducer,
+	sources map[string]QueueDepthSource,
+	period int,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	sample := func(logger logging.LoggerInterface) error {
+		sampleQueueDepths(metricsStorage, sources)
+		return nil
+	}
+
+	return asynctask.NewAsyncTask("SampleQueueDepths", sample, period, nil, nil, logger)
+}
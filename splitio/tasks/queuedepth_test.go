@@ -0,0 +1,49 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-client/splitio/storage/mutexqueue"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestSampleQueueDepths(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	impressionsQueue := mutexqueue.NewMQImpressionsStorage(10, make(chan string, 1), logger)
+	eventsQueue := mutexqueue.NewMQEventsStorage(10, make(chan string, 1), logger)
+	status := NewSegmentSyncStatus()
+
+	impressionsQueue.LogImpressions([]storage.Impression{{FeatureName: "f1"}, {FeatureName: "f2"}, {FeatureName: "f3"}})
+	eventsQueue.Push(dtos.EventDTO{Key: "key"}, 10)
+	status.enqueued()
+	status.enqueued()
+
+	sources := map[string]QueueDepthSource{
+		ImpressionsQueueDepthGauge: impressionsQueue,
+		EventsQueueDepthGauge:      eventsQueue,
+		SegmentsQueueDepthGauge:    QueueDepthFunc(status.QueueDepth),
+	}
+	sampleQueueDepths(metricsStorage, sources)
+
+	gauges := make(map[string]float64)
+	for _, gauge := range metricsStorage.PopGauges() {
+		gauges[gauge.MetricName] = gauge.Gauge
+	}
+	if gauges[ImpressionsQueueDepthGauge] != 3 || gauges[EventsQueueDepthGauge] != 1 || gauges[SegmentsQueueDepthGauge] != 2 {
+		t.Error("Gauges should reflect the depth of each queue", gauges)
+	}
+
+	impressionsQueue.PopN(2)
+	status.dequeued()
+	sampleQueueDepths(metricsStorage, sources)
+	for _, gauge := range metricsStorage.PopGauges() {
+		gauges[gauge.MetricName] = gauge.Gauge
+	}
+	if gauges[ImpressionsQueueDepthGauge] != 1 || gauges[SegmentsQueueDepthGauge] != 1 {
+		t.Error("Gauges should be updated after each sample", gauges)
+	}
+}
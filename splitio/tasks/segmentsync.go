@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/splitio/go-client/splitio/service"
 	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/util/backoff"
 	"github.com/splitio/go-toolkit/asynctask"
 	"github.com/splitio/go-toolkit/datastructures/set"
 	"github.com/splitio/go-toolkit/logging"
@@ -45,12 +48,114 @@ func updateSegment(
 	return segmentChanges.Since == segmentChanges.Till, nil
 }
 
+// segmentSyncAttempts is how many times each segment is attempted during the initial synchronization
+const segmentSyncAttempts = 3
+
+// SegmentSyncStatus keeps track of the outcome of the last synchronization of each segment,
+// so that segments that keep failing can be spotted while the rest are kept up to date
+type SegmentSyncStatus struct {
+	queued      int64 // accessed atomically, kept first for 64-bit alignment
+	mutex       sync.RWMutex
+	lastSuccess map[string]time.Time
+	lastError   map[string]error
+}
+
+// NewSegmentSyncStatus instantiates a new SegmentSyncStatus
+func NewSegmentSyncStatus() *SegmentSyncStatus {
+	return &SegmentSyncStatus{
+		lastSuccess: make(map[string]time.Time),
+		lastError:   make(map[string]error),
+	}
+}
+
+func (s *SegmentSyncStatus) record(segmentName string, err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil {
+		s.lastError[segmentName] = err
+		return
+	}
+	delete(s.lastError, segmentName)
+	s.lastSuccess[segmentName] = time.Now()
+}
+
+// LastSuccess returns when the segment was last synchronized successfully, and false if it never was
+func (s *SegmentSyncStatus) LastSuccess(segmentName string) (time.Time, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	lastSuccess, ok := s.lastSuccess[segmentName]
+	return lastSuccess, ok
+}
+
+// Failing returns the segments whose last synchronization failed along with the error
+func (s *SegmentSyncStatus) Failing() map[string]error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	failing := make(map[string]error, len(s.lastError))
+	for name, err := range s.lastError {
+		failing[name] = err
+	}
+	return failing
+}
+
+// enqueued counts a segment queued for the workers
+func (s *SegmentSyncStatus) enqueued() {
+	if s != nil {
+		atomic.AddInt64(&s.queued, 1)
+	}
+}
+
+// dequeued discounts a segment picked up by a worker, or that couldn't be queued
+func (s *SegmentSyncStatus) dequeued() {
+	if s != nil {
+		atomic.AddInt64(&s.queued, -1)
+	}
+}
+
+// QueueDepth returns the number of segments waiting to be picked up by the workers
+func (s *SegmentSyncStatus) QueueDepth() int64 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&s.queued)
+}
+
+// syncSegment fetches a segment until it's up to date, retrying up to segmentSyncAttempts times on error
+func syncSegment(
+	segmentFetcher service.SegmentFetcher,
+	segmentStorage storage.SegmentStorage,
+	segmentName string,
+	backoffStrategy backoff.Strategy,
+	logger logging.LoggerInterface,
+) error {
+	var err error
+	for attempt := 1; attempt <= segmentSyncAttempts; attempt++ {
+		ready := false
+		for !ready && err == nil {
+			ready, err = updateSegment(segmentFetcher, segmentStorage, segmentName)
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt < segmentSyncAttempts {
+			logger.Warning(fmt.Sprintf("Error fetching segment %s, retrying: %s", segmentName, err.Error()))
+			time.Sleep(backoffStrategy.Next(attempt - 1))
+			err = nil
+		}
+	}
+	return err
+}
+
 // SegmentWorker struct contains resources and functions for fetching segments and storing them
 type SegmentWorker struct {
 	name           string
 	failureTime    int64
 	segmentStorage storage.SegmentStorage
 	segmentFetcher service.SegmentFetcher
+	status         *SegmentSyncStatus
 }
 
 // Name Returns the name of the worker
@@ -69,8 +174,10 @@ func (w *SegmentWorker) DoWork(msg interface{}) error {
 	if !ok {
 		return errors.New("segment name popped from queue is not a string")
 	}
+	w.status.dequeued()
 
 	_, err := updateSegment(w.segmentFetcher, w.segmentStorage, segmentName)
+	w.status.record(segmentName, err)
 	return err
 }
 
@@ -83,12 +190,15 @@ func (w *SegmentWorker) Cleanup() error { return nil }
 func updateSegments(
 	splitStorage storage.SplitStorageConsumer,
 	admin *workerpool.WorkerAdmin,
+	status *SegmentSyncStatus,
 	logger logging.LoggerInterface,
 ) error {
 	segmentList := splitStorage.SegmentNames().List()
 	for _, name := range segmentList {
+		status.enqueued()
 		ok := admin.QueueMessage(name)
 		if !ok {
+			status.dequeued()
 			logger.Error(
 				fmt.Sprintf("Segment %s could not be added because the job queue is full.\n", name),
 				fmt.Sprintf(
@@ -103,7 +213,10 @@ func updateSegments(
 	return nil
 }
 
-// NewFetchSegmentsTask creates a new segment fetching and storing task
+// NewFetchSegmentsTask creates a new segment fetching and storing task. A segment that fails to be fetched
+// doesn't prevent the rest from being stored, and the outcome of each segment is recorded in status if not nil.
+// Failed segments are retried waiting as told by backoffStrategy, or the default one if nil. Once the initial
+// synchronization is done SEGMENTS_READY is reported, or SEGMENTS_PARTIAL if any segment failed
 func NewFetchSegmentsTask(
 	splitStorage storage.SplitStorageConsumer,
 	segmentStorage storage.SegmentStorage,
@@ -113,13 +226,18 @@ func NewFetchSegmentsTask(
 	queueSize int,
 	logger logging.LoggerInterface,
 	readyChannel chan string,
+	status *SegmentSyncStatus,
+	backoffStrategy backoff.Strategy,
 ) *asynctask.AsyncTask {
 	admin := workerpool.NewWorkerAdmin(queueSize, logger)
+	if backoffStrategy == nil {
+		backoffStrategy = newDefaultBackoff()
+	}
 
 	init := func(logger logging.LoggerInterface) error {
 		segmentNames := splitStorage.SegmentNames().List()
 		wg := sync.WaitGroup{}
-		wg.Add(len(segmentNames))
+		failedMutex := sync.Mutex{}
 		failedSegments := make([]string, 0)
 		for _, name := range segmentNames {
 			conv, ok := name.(string)
@@ -127,23 +245,23 @@ func NewFetchSegmentsTask(
 				logger.Warning("Skipping non-string segment present in storage at initialization-time!")
 				continue
 			}
+			wg.Add(1)
 			go func(segmentName string) {
 				defer wg.Done() // Make sure the "finished" signal is always sent
-				ready := false
-				var err error
-				for !ready {
-					ready, err = updateSegment(segmentFetcher, segmentStorage, segmentName)
-					if err != nil {
-						failedSegments = append(failedSegments, segmentName)
-						return
-					}
+				err := syncSegment(segmentFetcher, segmentStorage, segmentName, backoffStrategy, logger)
+				status.record(segmentName, err)
+				if err != nil {
+					failedMutex.Lock()
+					failedSegments = append(failedSegments, segmentName)
+					failedMutex.Unlock()
 				}
 			}(conv)
 		}
 		wg.Wait()
 
+		// Segments that failed are retried by the workers, they shouldn't prevent the rest from being used
 		if len(failedSegments) > 0 {
-			return fmt.Errorf("The following segments failed to be fetched %v", failedSegments)
+			logger.Error(fmt.Sprintf("The following segments failed to be fetched %v", failedSegments))
 		}
 
 		// After all segments are in sync, add workers to the pool that will keep them up to date
@@ -154,15 +272,20 @@ func NewFetchSegmentsTask(
 				failureTime:    0,
 				segmentFetcher: segmentFetcher,
 				segmentStorage: segmentStorage,
+				status:         status,
 			})
 		}
 
-		readyChannel <- "SEGMENTS_READY"
+		if len(failedSegments) > 0 {
+			readyChannel <- "SEGMENTS_PARTIAL"
+		} else {
+			readyChannel <- "SEGMENTS_READY"
+		}
 		return nil
 	}
 
 	update := func(logger logging.LoggerInterface) error {
-		return updateSegments(splitStorage, admin, logger)
+		return updateSegments(splitStorage, admin, status, logger)
 	}
 
 	cleanup := func(logger logging.LoggerInterface) {
@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sync/atomic"
@@ -12,6 +13,7 @@ import (
 	"github.com/splitio/go-client/splitio/service/api"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-client/splitio/util/backoff"
 	"github.com/splitio/go-toolkit/logging"
 )
 
@@ -126,6 +128,8 @@ func TestSegmentSyncTask(t *testing.T) {
 		100,
 		logger,
 		readyChannel,
+		nil,
+		nil,
 	)
 
 	segmentTask.Start()
@@ -168,3 +172,81 @@ func TestSegmentSyncTask(t *testing.T) {
 		t.Error("Task should be stopped")
 	}
 }
+
+type failingSegmentFetcher struct {
+	failing string
+}
+
+func (f *failingSegmentFetcher) Fetch(name string, changeNumber int64) (*dtos.SegmentChangesDTO, error) {
+	if name == f.failing {
+		return nil, errors.New("segment unavailable")
+	}
+	return &dtos.SegmentChangesDTO{Name: name, Added: []string{name + "_key"}, Since: 10, Till: 10}, nil
+}
+
+func TestSegmentSyncIsolatesFailures(t *testing.T) {
+	splitStorage := mutexmap.NewMMSplitStorage()
+	conditions := make([]dtos.ConditionDTO, 0)
+	for _, name := range []string{"s1", "s2", "s3"} {
+		conditions = append(conditions, dtos.ConditionDTO{
+			MatcherGroup: dtos.MatcherGroupDTO{
+				Matchers: []dtos.MatcherDTO{
+					{UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: name}},
+				},
+			},
+		})
+	}
+	splitStorage.PutMany([]dtos.SplitDTO{{Name: "split", Conditions: conditions}}, 123)
+
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+	status := NewSegmentSyncStatus()
+	readyChannel := make(chan string, 1)
+	segmentTask := NewFetchSegmentsTask(
+		splitStorage,
+		segmentStorage,
+		&failingSegmentFetcher{failing: "s2"},
+		100,
+		2,
+		100,
+		logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone}),
+		readyChannel,
+		status,
+		backoff.Constant(10*time.Millisecond),
+	)
+
+	segmentTask.Start()
+	defer segmentTask.Stop()
+
+	select {
+	case msg := <-readyChannel:
+		if msg != "SEGMENTS_PARTIAL" {
+			t.Error("Incorrect msg receieved", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("SEGMENTS_PARTIAL signal not received")
+		return
+	}
+
+	for _, name := range []string{"s1", "s3"} {
+		segment := segmentStorage.Get(name)
+		if segment == nil || !segment.Has(name+"_key") {
+			t.Error("Segment should have been stored despite another one failing", name)
+		}
+		if _, ok := status.LastSuccess(name); !ok {
+			t.Error("Last success should have been recorded", name)
+		}
+	}
+
+	if segmentStorage.Get("s2") != nil {
+		t.Error("Failing segment should not be stored")
+	}
+
+	if _, ok := status.LastSuccess("s2"); ok {
+		t.Error("Failing segment should have no last success")
+	}
+
+	failing := status.Failing()
+	if len(failing) != 1 || failing["s2"] == nil {
+		t.Error("Only s2 should be reported as failing", failing)
+	}
+}
@@ -0,0 +1,44 @@
+package tasks
+
+import (
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/asynctask"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func persistSnapshot(
+	splitStorage storage.SplitStorage,
+	segmentStorage storage.SegmentStorage,
+	path string,
+	logger logging.LoggerInterface,
+) error {
+	if splitStorage.Till() <= 0 {
+		logger.Debug("No splits have been synchronized yet. Skipping snapshot persistence")
+		return nil
+	}
+
+	err := storage.WriteSnapshotFile(path, storage.ExportSnapshot(splitStorage, segmentStorage))
+	if err != nil {
+		logger.Error("Error persisting snapshot to ", path, ": ", err.Error())
+	}
+	return err
+}
+
+// NewPersistSnapshotTask creates a new task that periodically writes the splits & segments in storage to a file
+func NewPersistSnapshotTask(
+	splitStorage storage.SplitStorage,
+	segmentStorage storage.SegmentStorage,
+	path string,
+	period int,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	persist := func(logger logging.LoggerInterface) error {
+		return persistSnapshot(splitStorage, segmentStorage, path, logger)
+	}
+
+	onStop := func(logger logging.LoggerInterface) {
+		persistSnapshot(splitStorage, segmentStorage, path, logger)
+	}
+
+	return asynctask.NewAsyncTask("PersistSnapshot", persist, period, nil, onStop, logger)
+}
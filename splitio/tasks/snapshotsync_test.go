@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/datastructures/set"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestPersistSnapshot(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelError})
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "splits.snapshot")
+
+	splitStorage := mutexmap.NewMMSplitStorage()
+	segmentStorage := mutexmap.NewMMSegmentStorage()
+
+	if err := persistSnapshot(splitStorage, segmentStorage, file, logger); err != nil {
+		t.Error("Nothing should be persisted before splits are synchronized", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("No file should have been written")
+	}
+
+	splitStorage.PutMany([]dtos.SplitDTO{{
+		Name:   "split1",
+		Status: "ACTIVE",
+		Conditions: []dtos.ConditionDTO{{
+			MatcherGroup: dtos.MatcherGroupDTO{Matchers: []dtos.MatcherDTO{{
+				MatcherType:        "IN_SEGMENT",
+				UserDefinedSegment: &dtos.UserDefinedSegmentMatcherDataDTO{SegmentName: "segment1"},
+			}}},
+		}},
+	}}, 123)
+	segmentStorage.Put("segment1", set.NewSet("key1", "key2"), 456)
+
+	if err := persistSnapshot(splitStorage, segmentStorage, file, logger); err != nil {
+		t.Error("Snapshot should have been persisted", err)
+	}
+
+	snapshot, err := storage.ReadSnapshotFile(file)
+	if err != nil {
+		t.Error("Snapshot should be readable", err)
+		return
+	}
+
+	if snapshot.Till != 123 || len(snapshot.Splits) != 1 || snapshot.Splits[0].Name != "split1" {
+		t.Error("Unexpected splits in snapshot", snapshot)
+	}
+
+	if len(snapshot.Segments) != 1 || snapshot.Segments[0].Name != "segment1" ||
+		snapshot.Segments[0].Till != 456 || len(snapshot.Segments[0].Keys) != 2 {
+		t.Error("Unexpected segments in snapshot", snapshot.Segments)
+	}
+}
@@ -0,0 +1,154 @@
+package tasks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/splitio/go-client/splitio/engine/grammar"
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-toolkit/asynctask"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+const (
+	// InvalidSplitsGauge is the gauge holding the number of stored splits that fail validation
+	InvalidSplitsGauge = "sdk.splits.invalid"
+
+	// maxReportedInvalidSplits is the maximum number of invalid splits named in each self-check log
+	maxReportedInvalidSplits = 5
+)
+
+// splitsSelfCheck validates the stored splits in pages of at most batchSize splits per run, so that large accounts
+// are checked over several runs. Results are remembered so that every run reports the state of all splits checked so far
+type splitsSelfCheck struct {
+	splitStorage   storage.SplitStorageConsumer
+	metricsStorage storage.MetricsStorageProducer
+	batchSize      int
+	offset         int
+	invalid        map[string]string
+	logger         logging.LoggerInterface
+}
+
+func newSplitsSelfCheck(
+	splitStorage storage.SplitStorageConsumer,
+	metricsStorage storage.MetricsStorageProducer,
+	batchSize int,
+	logger logging.LoggerInterface,
+) *splitsSelfCheck {
+	return &splitsSelfCheck{
+		splitStorage:   splitStorage,
+		metricsStorage: metricsStorage,
+		batchSize:      batchSize,
+		invalid:        make(map[string]string),
+		logger:         logger,
+	}
+}
+
+// page returns the names of the splits to check in this run and advances the offset
+func (c *splitsSelfCheck) page(names []string) []string {
+	if c.batchSize <= 0 || len(names) <= c.batchSize {
+		c.offset = 0
+		return names
+	}
+
+	if c.offset >= len(names) {
+		c.offset = 0
+	}
+	end := c.offset + c.batchSize
+	if end > len(names) {
+		end = len(names)
+	}
+	page := names[c.offset:end]
+	c.offset = end
+	return page
+}
+
+// run validates a page of splits, stores the number of invalid splits as a gauge and returns it
+func (c *splitsSelfCheck) run() int {
+	names := c.splitStorage.SplitNames()
+	sort.Strings(names)
+
+	// Forget splits that are no longer stored
+	stored := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		stored[name] = struct{}{}
+	}
+	for name := range c.invalid {
+		if _, ok := stored[name]; !ok {
+			delete(c.invalid, name)
+		}
+	}
+
+	page := c.page(names)
+	splits := c.splitStorage.FetchMany(page)
+	if splits == nil {
+		// A single unreadable split makes the whole fetch fail, fall back to fetching them one by one
+		splits = make(map[string]*dtos.SplitDTO, len(page))
+		for _, name := range page {
+			splits[name] = c.splitStorage.Get(name)
+		}
+	}
+
+	for _, name := range page {
+		split := splits[name]
+		if split == nil {
+			c.invalid[name] = fmt.Sprintf("split %s could not be read", name)
+			continue
+		}
+		if err := grammar.ValidateSplit(split); err != nil {
+			c.invalid[name] = err.Error()
+		} else {
+			delete(c.invalid, name)
+		}
+	}
+
+	c.metricsStorage.PutGauge(InvalidSplitsGauge, float64(len(c.invalid)))
+	if len(c.invalid) > 0 {
+		c.logger.Warning(fmt.Sprintf(
+			"Splits self-check: %d out of %d splits failed validation. %s",
+			len(c.invalid),
+			len(names),
+			c.describeInvalid(),
+		))
+	}
+	return len(c.invalid)
+}
+
+// describeInvalid lists the validation errors of the first invalid splits in alphabetical order
+func (c *splitsSelfCheck) describeInvalid() string {
+	names := make([]string, 0, len(c.invalid))
+	for name := range c.invalid {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]string, 0, maxReportedInvalidSplits)
+	for _, name := range names {
+		if len(errs) == maxReportedInvalidSplits {
+			errs = append(errs, fmt.Sprintf("and %d more", len(names)-maxReportedInvalidSplits))
+			break
+		}
+		errs = append(errs, c.invalid[name])
+	}
+	return strings.Join(errs, "; ")
+}
+
+// NewSplitsSelfCheckTask creates a new task that periodically validates up to batchSize stored splits per run,
+// storing the number of splits that failed validation as a gauge and logging them. A batchSize <= 0 checks every split
+func NewSplitsSelfCheckTask(
+	splitStorage storage.SplitStorageConsumer,
+	metricsStorage storage.MetricsStorageProducer,
+	period int,
+	batchSize int,
+	logger logging.LoggerInterface,
+) *asynctask.AsyncTask {
+	check := newSplitsSelfCheck(splitStorage, metricsStorage, batchSize, logger)
+	run := func(logger logging.LoggerInterface) error {
+		check.run()
+		return nil
+	}
+
+	return asynctask.NewAsyncTask("SplitsSelfCheck", run, period, nil, nil, logger)
+}
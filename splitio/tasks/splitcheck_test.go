@@ -0,0 +1,52 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/splitio/go-client/splitio/service/dtos"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestSplitsSelfCheck(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	metricsStorage := mutexmap.NewMMMetricsStorage()
+	splitStorage := mutexmap.NewMMSplitStorage()
+	splitStorage.PutMany([]dtos.SplitDTO{
+		{
+			Name:       "valid",
+			Status:     "ACTIVE",
+			Conditions: []dtos.ConditionDTO{{Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}}}},
+		},
+		{
+			Name:       "malformed",
+			Status:     "ACTIVE",
+			Conditions: []dtos.ConditionDTO{{Partitions: []dtos.PartitionDTO{{Size: 30, Treatment: "on"}}}},
+		},
+	}, 1)
+
+	check := newSplitsSelfCheck(splitStorage, metricsStorage, 0, logger)
+	if invalid := check.run(); invalid != 1 {
+		t.Error("Exactly one split should fail validation", invalid)
+	}
+	if _, ok := check.invalid["malformed"]; !ok {
+		t.Error("The malformed split should be reported", check.invalid)
+	}
+
+	gauges := metricsStorage.PopGauges()
+	if len(gauges) != 1 || gauges[0].MetricName != InvalidSplitsGauge || gauges[0].Gauge != 1 {
+		t.Error("The number of invalid splits should be stored as a gauge", gauges)
+	}
+
+	// Checking one split per run still reports every invalid split found so far
+	paged := newSplitsSelfCheck(splitStorage, metricsStorage, 1, logger)
+	paged.run()
+	if invalid := paged.run(); invalid != 1 {
+		t.Error("Paginated checks should remember invalid splits found in previous runs", invalid)
+	}
+
+	splitStorage.Remove("malformed")
+	if invalid := paged.run(); invalid != 0 {
+		t.Error("Removed splits should no longer be reported", invalid)
+	}
+}
@@ -1,14 +1,37 @@
 package tasks
 
 import (
+	"time"
+
+	"github.com/splitio/go-client/splitio/engine/grammar"
 	"github.com/splitio/go-client/splitio/service"
 	"github.com/splitio/go-client/splitio/service/dtos"
 	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/util/backoff"
 	"github.com/splitio/go-toolkit/asynctask"
 	"github.com/splitio/go-toolkit/logging"
 )
 
-func updateSplits(splitStorage storage.SplitStorageProducer, splitFetcher service.SplitFetcher) (bool, error) {
+// SplitSyncOptions sets up the synchronization of splits
+// - Period - Seconds between synchronizations once the initial one is done.
+// - InitialSyncTimeout - Transient errors of the initial synchronization are retried for up to this long. <= 0 disables retries.
+// - StrictPartitions - Splits whose partitions don't add up to 100 are removed from storage, instead of only being logged.
+// - BackoffStrategy - How long to wait between retries. If nil, retries wait exponentially from one second up to ten.
+type SplitSyncOptions struct {
+	Period             int
+	InitialSyncTimeout time.Duration
+	StrictPartitions   bool
+	BackoffStrategy    backoff.Strategy
+}
+
+// updateSplits fetches and stores the latest splits. Splits whose partitions don't add up to 100 are logged and,
+// if StrictPartitions is set, removed, so that a previous version isn't served once the change number moves past them
+func updateSplits(
+	splitStorage storage.SplitStorageProducer,
+	splitFetcher service.SplitFetcher,
+	options SplitSyncOptions,
+	logger logging.LoggerInterface,
+) (bool, error) {
 	till := splitStorage.Till()
 	if till == 0 {
 		till = -1
@@ -23,52 +46,117 @@ func updateSplits(splitStorage storage.SplitStorageProducer, splitFetcher servic
 	activeSplits := make([]dtos.SplitDTO, 0)
 	for _, split := range splits.Splits {
 		if split.Status == "ACTIVE" {
+			if err := grammar.ValidateSplit(&split); err != nil {
+				if options.StrictPartitions {
+					logger.Warning("Rejecting invalid split, removing it: ", err.Error())
+					inactiveSplits = append(inactiveSplits, split)
+					continue
+				}
+				logger.Warning("Invalid split, some keys may get the default treatment: ", err.Error())
+			}
 			activeSplits = append(activeSplits, split)
 		} else {
 			inactiveSplits = append(inactiveSplits, split)
 		}
 	}
 
-	// Add/Update active splits
-	splitStorage.PutMany(activeSplits, splits.Till)
-
-	// Remove inactive splits
+	// Remove inactive & rejected splits
 	for _, split := range inactiveSplits {
 		splitStorage.Remove(split.Name)
 	}
 
+	// Add/Update active splits. The change number is updated last, so that once it moves every change is in storage
+	splitStorage.PutMany(activeSplits, splits.Till)
+
 	if splits.Since == splits.Till {
 		return true, nil
 	}
 	return false, nil
 }
 
-// NewFetchSplitsTask creates a new splits fetching and storing task
+// newDefaultBackoff returns the strategy used by tasks when none is supplied
+func newDefaultBackoff() backoff.Strategy {
+	return backoff.NewExponentialBackoff(time.Second, 10*time.Second)
+}
+
+// permanentError is implemented by errors that won't go away by retrying the request, such as an invalid apikey
+type permanentError interface {
+	IsPermanent() bool
+}
+
+func isPermanentError(err error) bool {
+	permanent, ok := err.(permanentError)
+	return ok && permanent.IsPermanent()
+}
+
+// initialSplitsSync fetches splits until they're up to date. Transient errors are retried waiting as told by
+// the BackoffStrategy until InitialSyncTimeout elapses, in which case SPLITS_TIMEOUT is reported. Permanent errors
+// are reported right away as SPLITS_ERROR
+func initialSplitsSync(
+	splitStorage storage.SplitStorageProducer,
+	splitFetcher service.SplitFetcher,
+	options SplitSyncOptions,
+	logger logging.LoggerInterface,
+) (string, error) {
+	backoffStrategy := options.BackoffStrategy
+	if backoffStrategy == nil {
+		backoffStrategy = newDefaultBackoff()
+	}
+
+	deadline := time.Now().Add(options.InitialSyncTimeout)
+	retries := 0
+	for {
+		ready, err := updateSplits(splitStorage, splitFetcher, options, logger)
+		if err == nil {
+			if ready {
+				backoffStrategy.Reset()
+				return "SPLITS_READY", nil
+			}
+			continue
+		}
+
+		if isPermanentError(err) {
+			logger.Error("Initial splits synchronization failed with a non retryable error: ", err.Error())
+			return "SPLITS_ERROR", err
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			if retries == 0 {
+				return "SPLITS_ERROR", err
+			}
+			logger.Error("Initial splits synchronization timed out after ", retries, " retries: ", err.Error())
+			return "SPLITS_TIMEOUT", err
+		}
+
+		wait := backoffStrategy.Next(retries)
+		if wait > remaining {
+			wait = remaining
+		}
+		logger.Warning("Initial splits synchronization failed, retrying in ", wait.String(), ": ", err.Error())
+		time.Sleep(wait)
+		retries++
+	}
+}
+
+// NewFetchSplitsTask creates a new splits fetching and storing task, set up by options
 func NewFetchSplitsTask(
 	splitStorage storage.SplitStorageProducer,
 	splitFetcher service.SplitFetcher,
-	period int,
+	options SplitSyncOptions,
 	logger logging.LoggerInterface,
 	readyChannel chan string,
 ) *asynctask.AsyncTask {
 	init := func(logger logging.LoggerInterface) error {
-		ready := false
-		var err error
-		for !ready {
-			ready, err = updateSplits(splitStorage, splitFetcher)
-			if err != nil {
-				readyChannel <- "SPLITS_ERROR"
-				return err
-			}
-		}
-		readyChannel <- "SPLITS_READY"
-		return nil
+		status, err := initialSplitsSync(splitStorage, splitFetcher, options, logger)
+		readyChannel <- status
+		return err
 	}
 
 	update := func(logger logging.LoggerInterface) error {
-		_, err := updateSplits(splitStorage, splitFetcher)
+		_, err := updateSplits(splitStorage, splitFetcher, options, logger)
 		return err
 	}
 
-	return asynctask.NewAsyncTask("UpdateSplits", update, period, init, nil, logger)
+	return asynctask.NewAsyncTask("UpdateSplits", update, options.Period, init, nil, logger)
 }
@@ -59,13 +59,7 @@ func TestSplitSyncTask(t *testing.T) {
 	splitStorage.PutMany([]dtos.SplitDTO{}, -1)
 
 	readyChannel := make(chan string, 1)
-	splitTask := NewFetchSplitsTask(
-		splitStorage,
-		splitFetcher,
-		3,
-		logger,
-		readyChannel,
-	)
+	splitTask := NewFetchSplitsTask(splitStorage, splitFetcher, SplitSyncOptions{Period: 3}, logger, readyChannel)
 
 	splitTask.Start()
 
@@ -187,7 +181,7 @@ func TestSplitSyncTaskStatus(t *testing.T) {
 	splitStorage := mutexmap.NewMMSplitStorage()
 	splitStorage.PutMany([]dtos.SplitDTO{{}}, -1)
 
-	updateSplits(splitStorage, splitFetcher)
+	updateSplits(splitStorage, splitFetcher, SplitSyncOptions{}, logger)
 
 	if !splitStorage.TrafficTypeExists("one") {
 		t.Error("It should exists")
@@ -208,7 +202,7 @@ func TestSplitSyncTaskStatus(t *testing.T) {
 		logger,
 	)
 
-	updateSplits(splitStorage, splitFetcher2)
+	updateSplits(splitStorage, splitFetcher2, SplitSyncOptions{}, logger)
 
 	s1 := splitStorage.Get("split1")
 	if s1 != nil {
@@ -240,3 +234,111 @@ func TestSplitSyncTaskStatus(t *testing.T) {
 		t.Error("It should exists")
 	}
 }
+
+type recordingBackoff struct {
+	attempts []int
+	resets   int
+}
+
+func (r *recordingBackoff) Next(attempt int) time.Duration {
+	r.attempts = append(r.attempts, attempt)
+	return 10 * time.Millisecond
+}
+
+func (r *recordingBackoff) Reset() {
+	r.resets++
+}
+
+type flakySplitFetcher struct {
+	errors []error
+	calls  int
+}
+
+func (f *flakySplitFetcher) Fetch(changeNumber int64) (*dtos.SplitChangesDTO, error) {
+	f.calls++
+	if f.calls <= len(f.errors) {
+		return nil, f.errors[f.calls-1]
+	}
+	if f.errors == nil {
+		return nil, &api.HTTPError{Code: http.StatusServiceUnavailable, Message: "unavailable"}
+	}
+	return &dtos.SplitChangesDTO{Since: 5, Till: 5}, nil
+}
+
+func TestInitialSplitsSyncRetries(t *testing.T) {
+	strategy := &recordingBackoff{}
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+
+	transient := &flakySplitFetcher{errors: []error{
+		&api.HTTPError{Code: http.StatusInternalServerError, Message: "internal error"},
+		&api.HTTPError{Code: http.StatusTooManyRequests, Message: "too many requests"},
+	}}
+	status, err := initialSplitsSync(mutexmap.NewMMSplitStorage(), transient, SplitSyncOptions{InitialSyncTimeout: time.Second, BackoffStrategy: strategy}, logger)
+	if status != "SPLITS_READY" || err != nil || transient.calls != 3 {
+		t.Error("Transient errors should be retried until splits are synchronized", status, err, transient.calls)
+	}
+
+	if len(strategy.attempts) != 2 || strategy.attempts[0] != 0 || strategy.attempts[1] != 1 || strategy.resets != 1 {
+		t.Error("The injected strategy should be asked for each retry and reset on success", strategy.attempts, strategy.resets)
+	}
+
+	permanent := &flakySplitFetcher{errors: []error{&api.HTTPError{Code: http.StatusUnauthorized, Message: "unauthorized"}}}
+	before := time.Now()
+	status, err = initialSplitsSync(mutexmap.NewMMSplitStorage(), permanent, SplitSyncOptions{InitialSyncTimeout: 10 * time.Second, BackoffStrategy: strategy}, logger)
+	if status != "SPLITS_ERROR" || err == nil || permanent.calls != 1 || time.Since(before) > time.Second {
+		t.Error("Permanent errors should fail without retrying", status, err, permanent.calls)
+	}
+
+	unavailable := &flakySplitFetcher{}
+	status, err = initialSplitsSync(mutexmap.NewMMSplitStorage(), unavailable, SplitSyncOptions{InitialSyncTimeout: 100 * time.Millisecond, BackoffStrategy: strategy}, logger)
+	if status != "SPLITS_TIMEOUT" || err == nil || unavailable.calls < 2 {
+		t.Error("Retries should stop once the timeout elapses", status, err, unavailable.calls)
+	}
+
+	noRetries := &flakySplitFetcher{}
+	status, _ = initialSplitsSync(mutexmap.NewMMSplitStorage(), noRetries, SplitSyncOptions{BackoffStrategy: strategy}, logger)
+	if status != "SPLITS_ERROR" || noRetries.calls != 1 {
+		t.Error("Errors should not be retried when there's no timeout", status, noRetries.calls)
+	}
+}
+
+type staticSplitFetcher struct {
+	splits []dtos.SplitDTO
+}
+
+func (f *staticSplitFetcher) Fetch(changeNumber int64) (*dtos.SplitChangesDTO, error) {
+	return &dtos.SplitChangesDTO{Splits: f.splits, Since: 1, Till: 1}, nil
+}
+
+func TestUpdateSplitsPartitionsValidation(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	fetcher := &staticSplitFetcher{splits: []dtos.SplitDTO{
+		{
+			Name:   "valid",
+			Status: "ACTIVE",
+			Conditions: []dtos.ConditionDTO{
+				{Partitions: []dtos.PartitionDTO{{Size: 100, Treatment: "on"}}},
+			},
+		},
+		{
+			Name:   "invalid",
+			Status: "ACTIVE",
+			Conditions: []dtos.ConditionDTO{
+				{Partitions: []dtos.PartitionDTO{{Size: 49, Treatment: "on"}, {Size: 50, Treatment: "off"}}},
+			},
+		},
+	}}
+
+	lenient := mutexmap.NewMMSplitStorage()
+	updateSplits(lenient, fetcher, SplitSyncOptions{}, logger)
+	if lenient.Get("valid") == nil || lenient.Get("invalid") == nil {
+		t.Error("Invalid splits should only be logged when strict partitions are disabled")
+	}
+
+	strict := mutexmap.NewMMSplitStorage()
+	strict.PutMany([]dtos.SplitDTO{{Name: "invalid", Status: "ACTIVE"}}, 0)
+	updateSplits(strict, fetcher, SplitSyncOptions{StrictPartitions: true}, logger)
+	if strict.Get("valid") == nil || strict.Get("invalid") != nil {
+		t.Error("Invalid splits should be rejected, removing their previous version, when strict partitions are enabled")
+	}
+}
@@ -0,0 +1,68 @@
+// Package backoff contains the strategies used to decide how long to wait before retrying an operation
+package backoff
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Strategy decides how long to wait before each retry. Attempts start at 0 for the first retry.
+// Reset is called once the operation succeeds, so that stateful strategies can start over.
+// Implementations must be safe for concurrent use, since a strategy may be shared by several tasks
+type Strategy interface {
+	Next(attempt int) time.Duration
+	Reset()
+}
+
+// ExponentialBackoff doubles the wait after each attempt, starting at base and never exceeding max.
+// A random jitter of up to half the wait is subtracted, so waits fall within [wait/2, wait]
+type ExponentialBackoff struct {
+	base   time.Duration
+	max    time.Duration
+	random *rand.Rand
+	mutex  sync.Mutex
+}
+
+// NewExponentialBackoff instantiates a new ExponentialBackoff
+func NewExponentialBackoff(base time.Duration, max time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		base:   base,
+		max:    max,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns the wait before the given attempt
+func (e *ExponentialBackoff) Next(attempt int) time.Duration {
+	wait := e.max
+	if attempt < 32 {
+		if exponential := e.base << uint(attempt); exponential > 0 && exponential < e.max {
+			wait = exponential
+		}
+	}
+
+	half := int64(wait / 2)
+	if half <= 0 {
+		return wait
+	}
+
+	e.mutex.Lock()
+	jitter := e.random.Int63n(half + 1)
+	e.mutex.Unlock()
+	return wait - time.Duration(jitter)
+}
+
+// Reset does nothing, since waits only depend on the attempt
+func (e *ExponentialBackoff) Reset() {}
+
+// Constant waits the same amount of time before every attempt
+type Constant time.Duration
+
+// Next returns the constant wait
+func (c Constant) Next(attempt int) time.Duration {
+	return time.Duration(c)
+}
+
+// Reset does nothing
+func (c Constant) Reset() {}
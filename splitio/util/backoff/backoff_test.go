@@ -0,0 +1,63 @@
+package backoff
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoffGrowth(t *testing.T) {
+	strategy := NewExponentialBackoff(100*time.Millisecond, 2*time.Second)
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1600 * time.Millisecond,
+		2 * time.Second,
+		2 * time.Second,
+	}
+	for attempt, wait := range expected {
+		for i := 0; i < 100; i++ {
+			next := strategy.Next(attempt)
+			if next > wait || next < wait/2 {
+				t.Errorf("Wait for attempt %d should be within [%s, %s], got %s", attempt, wait/2, wait, next)
+			}
+		}
+	}
+
+	if next := strategy.Next(1000); next > 2*time.Second || next < time.Second {
+		t.Error("Large attempts should be capped at max", next)
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	strategy := NewExponentialBackoff(time.Second, time.Minute)
+
+	distinct := make(map[time.Duration]bool)
+	wg := sync.WaitGroup{}
+	mutex := sync.Mutex{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next := strategy.Next(3)
+			mutex.Lock()
+			distinct[next] = true
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(distinct) < 2 {
+		t.Error("Waits should be randomized")
+	}
+}
+
+func TestConstant(t *testing.T) {
+	strategy := Constant(time.Second)
+	if strategy.Next(0) != time.Second || strategy.Next(10) != time.Second {
+		t.Error("Constant strategy should always return the same wait")
+	}
+}
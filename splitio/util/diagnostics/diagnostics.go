@@ -0,0 +1,85 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/splitio/go-toolkit/logging"
+)
+
+const (
+	// CategoryStorage is the category of errors raised while reading or writing the storages
+	CategoryStorage = "storage"
+	// CategorySync is the category of errors raised while synchronizing with Split servers
+	CategorySync = "sync"
+	// CategoryEvaluation is the category of errors raised while validating inputs or evaluating features
+	CategoryEvaluation = "evaluation"
+)
+
+// SDKError is an internal error recorded by the SDK
+type SDKError struct {
+	Time     time.Time
+	Category string
+	Message  string
+}
+
+// Recorder keeps the last internal errors in a ring buffer of a fixed size, overwriting the oldest ones once it's full
+type Recorder struct {
+	errors []SDKError
+	next   int
+	full   bool
+	mutex  sync.Mutex
+}
+
+// NewRecorder instantiates a new Recorder keeping up to size errors. Returns nil if size is not positive
+func NewRecorder(size int) *Recorder {
+	if size <= 0 {
+		return nil
+	}
+	return &Recorder{errors: make([]SDKError, size)}
+}
+
+// Record stores an error of the given category, evicting the oldest one if the buffer is full
+func (r *Recorder) Record(category string, message string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errors[r.next] = SDKError{Time: time.Now(), Category: category, Message: message}
+	r.next = (r.next + 1) % len(r.errors)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns a copy of the recorded errors, oldest first
+func (r *Recorder) Recent() []SDKError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.full {
+		return append([]SDKError(nil), r.errors[:r.next]...)
+	}
+	recent := make([]SDKError, 0, len(r.errors))
+	recent = append(recent, r.errors[r.next:]...)
+	return append(recent, r.errors[:r.next]...)
+}
+
+// recordingLogger forwards every message to a logger, recording errors in a Recorder
+type recordingLogger struct {
+	logging.LoggerInterface
+	recorder *Recorder
+	category string
+}
+
+func (l *recordingLogger) Error(msg ...interface{}) {
+	l.recorder.Record(l.category, fmt.Sprint(msg...))
+	l.LoggerInterface.Error(msg...)
+}
+
+// NewRecordingLogger wraps a logger so that the errors logged through it are recorded under a category.
+// Returns the logger as is if recorder is nil
+func NewRecordingLogger(logger logging.LoggerInterface, recorder *Recorder, category string) logging.LoggerInterface {
+	if recorder == nil {
+		return logger
+	}
+	return &recordingLogger{LoggerInterface: logger, recorder: recorder, category: category}
+}
@@ -0,0 +1,46 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestRecorderKeepsLastErrors(t *testing.T) {
+	if NewRecorder(0) != nil {
+		t.Error("A recorder should not be created for a non positive size")
+	}
+
+	recorder := NewRecorder(3)
+	recorder.Record(CategorySync, "e1")
+	recorder.Record(CategoryStorage, "e2")
+	if recent := recorder.Recent(); len(recent) != 2 || recent[0].Message != "e1" || recent[1].Message != "e2" {
+		t.Error("Errors should be returned oldest first", recent)
+	}
+
+	recorder.Record(CategoryEvaluation, "e3")
+	recorder.Record(CategoryEvaluation, "e4")
+	recent := recorder.Recent()
+	if len(recent) != 3 || recent[0].Message != "e2" || recent[1].Message != "e3" || recent[2].Message != "e4" {
+		t.Error("The oldest errors should be evicted once the buffer is full", recent)
+	}
+	if recent[0].Category != CategoryStorage || recent[0].Time.IsZero() {
+		t.Error("Errors should keep their category and time", recent[0])
+	}
+}
+
+func TestRecordingLogger(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	if NewRecordingLogger(logger, nil, CategorySync) != logger {
+		t.Error("The logger should not be wrapped without a recorder")
+	}
+
+	recorder := NewRecorder(10)
+	recording := NewRecordingLogger(logger, recorder, CategorySync)
+	recording.Warning("not recorded")
+	recording.Error("fetch failed: ", 500)
+	recent := recorder.Recent()
+	if len(recent) != 1 || recent[0].Category != CategorySync || recent[0].Message != "fetch failed: 500" {
+		t.Error("Only errors should be recorded", recent)
+	}
+}
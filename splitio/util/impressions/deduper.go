@@ -0,0 +1,86 @@
+package impressions
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage"
+)
+
+// ImpressionDeduper drops the impressions whose key/feature/treatment/changeNumber combination has already been
+// forwarded within a time window, so that only one occurrence per window is stored. Dropped impressions don't
+// extend the window. Forwarded impressions carry the time their combination was previously forwarded, if it was.
+// Dropped impressions are counted in memory, and reported along with the amount of tracked combinations by ReportMetrics
+type ImpressionDeduper struct {
+	deduped  int64 // accessed atomically, kept first for 64-bit alignment
+	observer *ImpressionObserver
+	window   int64
+	metrics  storage.MetricsStorageProducer
+}
+
+// NewImpressionDeduper instantiates a new ImpressionDeduper tracking up to size combinations.
+// metrics is optional and is used to report dropped impressions and the amount of tracked combinations
+func NewImpressionDeduper(size int, window time.Duration, metrics storage.MetricsStorageProducer) *ImpressionDeduper {
+	return &ImpressionDeduper{
+		observer: NewImpressionObserver(size, nil),
+		window:   int64(window / time.Millisecond),
+		metrics:  metrics,
+	}
+}
+
+// RestoreImpressionDeduper instantiates a new ImpressionDeduper whose observer is restored from stateStorage, so that
+// the combinations forwarded before a restart keep being deduped. If the state can't be loaded, the deduper starts
+// empty and the error is returned along with it
+func RestoreImpressionDeduper(
+	size int,
+	window time.Duration,
+	metrics storage.MetricsStorageProducer,
+	stateStorage storage.ImpressionObserverStateStorage,
+) (*ImpressionDeduper, error) {
+	observer, err := RestoreImpressionObserver(size, nil, stateStorage)
+	return &ImpressionDeduper{
+		observer: observer,
+		window:   int64(window / time.Millisecond),
+		metrics:  metrics,
+	}, err
+}
+
+// Persist saves the combinations forwarded within the window to stateStorage, expiring them with the window
+func (d *ImpressionDeduper) Persist(stateStorage storage.ImpressionObserverStateStorage) error {
+	return d.observer.Persist(stateStorage, time.Duration(d.window)*time.Millisecond)
+}
+
+// Process sets the previous time of the impressions and returns the ones that should be forwarded
+func (d *ImpressionDeduper) Process(impressions []storage.Impression) []storage.Impression {
+	forwarded := make([]storage.Impression, 0, len(impressions))
+	for _, impression := range impressions {
+		previous, forward := d.observer.testAndSetOutside(&impression, d.window)
+		impression.PreviousTime = previous
+		if !forward {
+			atomic.AddInt64(&d.deduped, 1)
+			continue
+		}
+		forwarded = append(forwarded, impression)
+	}
+	return forwarded
+}
+
+// ReportMetrics adds the impressions dropped since the last report to DedupedCounter, and sets ObserverSizeGauge
+// to the amount of tracked combinations. It's meant to be called periodically, so that deduping impressions
+// doesn't write metrics on every evaluation
+func (d *ImpressionDeduper) ReportMetrics() {
+	if d.metrics == nil {
+		return
+	}
+
+	if deduped := atomic.SwapInt64(&d.deduped, 0); deduped > 0 {
+		if batch, ok := d.metrics.(storage.MetricsCounterBatchProducer); ok {
+			batch.IncCounterBy(DedupedCounter, deduped)
+		} else {
+			for i := int64(0); i < deduped; i++ {
+				d.metrics.IncCounter(DedupedCounter)
+			}
+		}
+	}
+	d.metrics.PutGauge(ObserverSizeGauge, float64(d.observer.Len()))
+}
@@ -0,0 +1,83 @@
+package impressions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+)
+
+func TestImpressionDeduper(t *testing.T) {
+	metrics := mutexmap.NewMMMetricsStorage()
+	deduper := NewImpressionDeduper(10, time.Second, metrics)
+
+	forwarded := deduper.Process([]storage.Impression{
+		{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: 1000},
+		{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: 1500},
+		{KeyName: "key1", FeatureName: "feature1", Treatment: "off", ChangeNumber: 1, Time: 1500},
+	})
+	if len(forwarded) != 2 || forwarded[0].Time != 1000 || forwarded[1].Treatment != "off" {
+		t.Error("Repeated impressions within the window should be dropped. Got:", forwarded)
+	}
+	if forwarded[0].PreviousTime != 0 || forwarded[1].PreviousTime != 0 {
+		t.Error("Impressions not seen before should have no previous time")
+	}
+
+	// The window has elapsed since the combination was last forwarded
+	forwarded = deduper.Process([]storage.Impression{
+		{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: 3000},
+	})
+	if len(forwarded) != 1 || forwarded[0].PreviousTime != 1000 {
+		t.Error("Impressions outside of the window should be forwarded with the previous time. Got:", forwarded)
+	}
+
+	// A new change number is a new combination
+	forwarded = deduper.Process([]storage.Impression{
+		{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 2, Time: 3100},
+	})
+	if len(forwarded) != 1 {
+		t.Error("Impressions of a new change number should be forwarded")
+	}
+
+	if counters := metrics.PopCounters(); len(counters) != 0 {
+		t.Error("Dropped impressions should only be counted in memory until reported. Got:", counters)
+	}
+
+	deduper.ReportMetrics()
+	var dropped int64
+	for _, counter := range metrics.PopCounters() {
+		if counter.MetricName == DedupedCounter {
+			dropped = counter.Count
+		}
+	}
+	if dropped != 1 {
+		t.Error("Dropped impressions should be counted. Got:", dropped)
+	}
+
+	for _, gauge := range metrics.PopGauges() {
+		if gauge.MetricName == ObserverSizeGauge && gauge.Gauge != 3 {
+			t.Error("Observer size gauge should be 3. Got:", gauge.Gauge)
+		}
+	}
+}
+
+func TestImpressionDeduperFixedWindow(t *testing.T) {
+	deduper := NewImpressionDeduper(10, time.Second, nil)
+
+	// Evaluated every half window over 4 windows, dropped impressions must not extend the window
+	var forwarded []storage.Impression
+	for now := int64(0); now < 4000; now += 500 {
+		forwarded = append(forwarded, deduper.Process([]storage.Impression{
+			{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: now},
+		})...)
+	}
+	if len(forwarded) != 4 {
+		t.Fatal("One impression per window should be forwarded. Got:", forwarded)
+	}
+	for index, impression := range forwarded {
+		if impression.Time != int64(index)*1000 {
+			t.Error("Impressions should be forwarded once each window elapses. Got:", impression.Time)
+		}
+	}
+}
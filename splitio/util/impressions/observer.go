@@ -0,0 +1,162 @@
+package impressions
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/splitio/go-client/splitio/storage"
+)
+
+const (
+	// DedupedCounter is incremented each time an impression has already been seen by the observer
+	DedupedCounter = "sdk.impressions.deduped"
+	// ObserverSizeGauge holds the amount of impressions tracked by the observer
+	ObserverSizeGauge = "sdk.impressions.observerSize"
+)
+
+type observedImpression struct {
+	hash string
+	time int64
+}
+
+// ImpressionObserver keeps track of the last time each key/feature/treatment/changeNumber combination was seen.
+// When the amount of tracked combinations reaches the configured size, the least recently seen one is evicted
+type ImpressionObserver struct {
+	size    int
+	items   map[string]*list.Element
+	lru     *list.List
+	metrics storage.MetricsStorageProducer
+	mutex   *sync.Mutex
+}
+
+// NewImpressionObserver instantiates a new ImpressionObserver tracking up to size combinations.
+// metrics is optional and is used to report dedup hits and the amount of tracked combinations
+func NewImpressionObserver(size int, metrics storage.MetricsStorageProducer) *ImpressionObserver {
+	return &ImpressionObserver{
+		size:    size,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+		metrics: metrics,
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// hashImpression identifies the combination of an impression. Each text field is prefixed by its length, so that
+// separators within them, ie: a feature name with a ":", can't make two combinations collide
+func hashImpression(impression *storage.Impression) string {
+	return fmt.Sprintf(
+		"%d:%s:%d:%s:%d:%s:%d",
+		len(impression.KeyName),
+		impression.KeyName,
+		len(impression.FeatureName),
+		impression.FeatureName,
+		len(impression.Treatment),
+		impression.Treatment,
+		impression.ChangeNumber,
+	)
+}
+
+// TestAndSet records the impression and returns the time at which the same combination was last seen,
+// or 0 if it's the first time it's seen
+func (o *ImpressionObserver) TestAndSet(impression *storage.Impression) int64 {
+	previous, _ := o.testAndSetOutside(impression, 0)
+	return previous
+}
+
+// testAndSetOutside records the impression unless the same combination was recorded less than window milliseconds
+// before it. Returns the time the combination was last recorded, or 0 if it's the first time it's seen, and whether
+// the impression was recorded
+func (o *ImpressionObserver) testAndSetOutside(impression *storage.Impression, window int64) (int64, bool) {
+	hash := hashImpression(impression)
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if element, exists := o.items[hash]; exists {
+		observed := element.Value.(*observedImpression)
+		previous := observed.time
+		recorded := impression.Time-previous >= window
+		if recorded {
+			observed.time = impression.Time
+		}
+		o.lru.MoveToFront(element)
+		if o.metrics != nil {
+			o.metrics.IncCounter(DedupedCounter)
+		}
+		return previous, recorded
+	}
+
+	o.items[hash] = o.lru.PushFront(&observedImpression{hash: hash, time: impression.Time})
+	if o.lru.Len() > o.size {
+		oldest := o.lru.Back()
+		o.lru.Remove(oldest)
+		delete(o.items, oldest.Value.(*observedImpression).hash)
+	}
+
+	if o.metrics != nil {
+		o.metrics.PutGauge(ObserverSizeGauge, float64(o.lru.Len()))
+	}
+	return 0, true
+}
+
+// Len returns the amount of combinations currently tracked
+func (o *ImpressionObserver) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.lru.Len()
+}
+
+// Export returns the combinations seen at or after since (in milliseconds, as impression times), indexed by hash
+// and holding the last time they were seen
+func (o *ImpressionObserver) Export(since int64) map[string]int64 {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	state := make(map[string]int64, o.lru.Len())
+	for element := o.lru.Front(); element != nil; element = element.Next() {
+		observed := element.Value.(*observedImpression)
+		if observed.time >= since {
+			state[observed.hash] = observed.time
+		}
+	}
+	return state
+}
+
+// Persist saves the combinations seen within the last window so that an observer restored from stateStorage
+// keeps deduping them. The saved state expires once the window elapses
+func (o *ImpressionObserver) Persist(stateStorage storage.ImpressionObserverStateStorage, window time.Duration) error {
+	since := time.Now().Add(-window).UnixNano() / int64(time.Millisecond)
+	return stateStorage.SaveObserverState(o.Export(since), window)
+}
+
+// RestoreImpressionObserver instantiates a new ImpressionObserver tracking the combinations saved in stateStorage.
+// If there are more than size of them, the most recently seen ones are kept. If the state can't be loaded,
+// an empty observer is returned along with the error
+func RestoreImpressionObserver(
+	size int,
+	metrics storage.MetricsStorageProducer,
+	stateStorage storage.ImpressionObserverStateStorage,
+) (*ImpressionObserver, error) {
+	observer := NewImpressionObserver(size, metrics)
+	state, err := stateStorage.LoadObserverState()
+	if err != nil {
+		return observer, err
+	}
+
+	restored := make([]*observedImpression, 0, len(state))
+	for hash, lastSeen := range state {
+		restored = append(restored, &observedImpression{hash: hash, time: lastSeen})
+	}
+	sort.Slice(restored, func(i, j int) bool { return restored[i].time > restored[j].time })
+	if len(restored) > size {
+		restored = restored[:size]
+	}
+
+	// The most recently seen combination goes first, as if it had been seen last
+	for _, observed := range restored {
+		observer.items[observed.hash] = observer.lru.PushBack(observed)
+	}
+	return observer, nil
+}
@@ -0,0 +1,116 @@
+package impressions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/splitio/go-client/splitio"
+	"github.com/splitio/go-client/splitio/conf"
+	"github.com/splitio/go-client/splitio/storage"
+	"github.com/splitio/go-client/splitio/storage/mutexmap"
+	"github.com/splitio/go-client/splitio/storage/redisdb"
+	"github.com/splitio/go-toolkit/logging"
+)
+
+func TestImpressionObserver(t *testing.T) {
+	metrics := mutexmap.NewMMMetricsStorage()
+	observer := NewImpressionObserver(2, metrics)
+
+	impression1 := storage.Impression{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: 100}
+	impression2 := storage.Impression{KeyName: "key2", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: 200}
+	impression3 := storage.Impression{KeyName: "key3", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: 300}
+
+	if observer.TestAndSet(&impression1) != 0 || observer.TestAndSet(&impression2) != 0 {
+		t.Error("Impressions not seen before should return 0")
+	}
+
+	repeated := impression1
+	repeated.Time = 400
+	if previous := observer.TestAndSet(&repeated); previous != 100 {
+		t.Error("The time of the previous impression should be returned. Got:", previous)
+	}
+
+	// key2 is now the least recently seen impression and should be evicted
+	observer.TestAndSet(&impression3)
+	if observer.Len() != 2 {
+		t.Error("The observer should not track more impressions than its size. Got:", observer.Len())
+	}
+
+	repeated = impression2
+	repeated.Time = 500
+	if observer.TestAndSet(&repeated) != 0 {
+		t.Error("Evicted impressions should be treated as new")
+	}
+
+	var deduped int64
+	for _, counter := range metrics.PopCounters() {
+		if counter.MetricName == DedupedCounter {
+			deduped = counter.Count
+		}
+	}
+	if deduped != 1 {
+		t.Error("A single dedup hit should have been counted. Got:", deduped)
+	}
+
+	for _, gauge := range metrics.PopGauges() {
+		if gauge.MetricName == ObserverSizeGauge && gauge.Gauge != 2 {
+			t.Error("Observer size gauge should be 2. Got:", gauge.Gauge)
+		}
+	}
+}
+
+func TestImpressionObserverSeparatorsInNames(t *testing.T) {
+	observer := NewImpressionObserver(10, mutexmap.NewMMMetricsStorage())
+
+	impression1 := storage.Impression{KeyName: "a:b", FeatureName: "c", Treatment: "on", ChangeNumber: 1, Time: 100}
+	impression2 := storage.Impression{KeyName: "a", FeatureName: "b:c", Treatment: "on", ChangeNumber: 1, Time: 200}
+
+	observer.TestAndSet(&impression1)
+	if previous := observer.TestAndSet(&impression2); previous != 0 {
+		t.Error("Impressions with different keys and features should not collide. Got:", previous)
+	}
+}
+
+func TestImpressionObserverRestart(t *testing.T) {
+	logger := logging.NewLogger(&logging.LoggerOptions{LogLevel: logging.LevelNone})
+	client, err := redisdb.NewPrefixedRedisClient(&conf.RedisConfig{Host: "localhost", Port: 6379, Database: 1, Prefix: "testObserver"})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	metadata := &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "instance123"}
+	stateStorage := redisdb.NewRedisImpressionObserverStorage(client, metadata, logger)
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	recent := storage.Impression{KeyName: "key1", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: now}
+	stale := storage.Impression{KeyName: "key2", FeatureName: "feature1", Treatment: "on", ChangeNumber: 1, Time: now - 2*time.Hour.Nanoseconds()/int64(time.Millisecond)}
+
+	observer := NewImpressionObserver(10, nil)
+	observer.TestAndSet(&stale)
+	observer.TestAndSet(&recent)
+	if err := observer.Persist(stateStorage, time.Hour); err != nil {
+		t.Error("The observer state should be persisted", err)
+	}
+
+	restarted, err := RestoreImpressionObserver(10, nil, stateStorage)
+	if err != nil || restarted.Len() != 1 {
+		t.Error("Only combinations seen within the window should be restored", err, restarted.Len())
+	}
+
+	repeated := recent
+	repeated.Time = now + 1
+	if previous := restarted.TestAndSet(&repeated); previous != now {
+		t.Error("A recently seen impression should still be deduped after a restart", previous)
+	}
+
+	repeated = stale
+	repeated.Time = now + 1
+	if previous := restarted.TestAndSet(&repeated); previous != 0 {
+		t.Error("Impressions seen before the window should be treated as new", previous)
+	}
+
+	other := redisdb.NewRedisImpressionObserverStorage(client, &splitio.SdkMetadata{SDKVersion: "go-test", MachineName: "other"}, logger)
+	if state, _ := other.LoadObserverState(); len(state) != 0 {
+		t.Error("State should be namespaced per instance", state)
+	}
+}
@@ -0,0 +1,49 @@
+// Package ratelimit contains a lock-free rate limiter used to protect the SDK's pipelines from floods of calls
+package ratelimit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TokenBucket allows up to rate calls per second, with bursts of up to rate calls. It's implemented as a
+// generic cell rate algorithm, which behaves as a token bucket but keeps its whole state in a single
+// timestamp updated atomically, so that concurrent callers never block each other
+type TokenBucket struct {
+	tat      int64 // theoretical arrival time in nanoseconds, accessed atomically
+	interval int64
+	burst    int64
+	now      func() time.Time
+}
+
+// NewTokenBucket instantiates a new TokenBucket allowing rate calls per second
+func NewTokenBucket(rate int) *TokenBucket {
+	if rate <= 0 {
+		rate = 1
+	}
+	interval := int64(time.Second) / int64(rate)
+	return &TokenBucket{
+		interval: interval,
+		burst:    interval * int64(rate),
+		now:      time.Now,
+	}
+}
+
+// Allow consumes a token and returns true if one is available, otherwise returns false without waiting
+func (t *TokenBucket) Allow() bool {
+	now := t.now().UnixNano()
+	for {
+		tat := atomic.LoadInt64(&t.tat)
+		next := tat
+		if next < now {
+			next = now
+		}
+		next += t.interval
+		if next-now > t.burst {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&t.tat, tat, next) {
+			return true
+		}
+	}
+}
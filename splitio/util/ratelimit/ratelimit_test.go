@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := NewTokenBucket(10)
+	bucket.now = func() time.Time { return now }
+
+	allowed := 0
+	for i := 0; i < 25; i++ {
+		if bucket.Allow() {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Error("A burst of up to the rate should be allowed", allowed)
+	}
+
+	now = now.Add(200 * time.Millisecond)
+	allowed = 0
+	for i := 0; i < 10; i++ {
+		if bucket.Allow() {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Error("Tokens should be refilled at the configured rate", allowed)
+	}
+}
+
+func TestTokenBucketConcurrency(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := NewTokenBucket(100)
+	bucket.now = func() time.Time { return now }
+
+	var allowed int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if bucket.Allow() {
+					atomic.AddInt64(&allowed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 100 {
+		t.Error("Concurrent callers should not get more tokens than available", allowed)
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	semaphore := NewSemaphore(2)
+	if !semaphore.Acquire(0) || !semaphore.Acquire(0) {
+		t.Error("Slots should be taken while available")
+	}
+
+	before := time.Now()
+	if semaphore.Acquire(20 * time.Millisecond) {
+		t.Error("No slot should be taken once all of them are in use")
+	}
+	if time.Since(before) < 20*time.Millisecond {
+		t.Error("Acquire should wait for the timeout before giving up")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		semaphore.Release()
+	}()
+	if !semaphore.Acquire(time.Second) {
+		t.Error("A released slot should be taken by a waiting caller")
+	}
+}
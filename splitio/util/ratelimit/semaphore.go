@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Semaphore bounds the number of callers running a section concurrently. Callers exceeding the limit wait for a
+// slot to be released, for up to a timeout
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore instantiates a new Semaphore allowing up to size concurrent callers
+func NewSemaphore(size int) *Semaphore {
+	if size <= 0 {
+		size = 1
+	}
+	return &Semaphore{slots: make(chan struct{}, size)}
+}
+
+// Acquire takes a slot, waiting for up to timeout for one to be released if all of them are taken.
+// Returns false if no slot could be taken. Every successful call must be followed by a call to Release
+func (s *Semaphore) Acquire(timeout time.Duration) bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (s *Semaphore) Release() {
+	<-s.slots
+}